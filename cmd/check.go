@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"context"
+
 	"github.com/DrakkarStorm/deadlinkr/logger"
-	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/DrakkarStorm/deadlinkr/pkg/deadlinkr"
 	"github.com/DrakkarStorm/deadlinkr/utils"
 	"github.com/spf13/cobra"
 )
@@ -12,29 +14,43 @@ var checkCmd = &cobra.Command{
 	Use:   "check [url]",
 	Short: "Check a single page",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		pageURL := args[0]
 
-		// Initialize
-		model.Results = []model.LinkResult{}
+		// Arguments are valid past this point, failures are reported by Execute through the exit code
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+
+		policy, err := failurePolicy()
+		if err != nil {
+			return err
+		}
+
+		scanner, err := deadlinkr.NewScanner(scannerOptions())
+		if err != nil {
+			return &exitError{code: exitConfigError, err: err}
+		}
 
 		logger.Debugf("Checking links on %s", pageURL)
 
 		// Check single page without recursion
-		utils.CheckLinks(pageURL, pageURL)
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		results, err := scanner.Check(ctx, pageURL)
+		if err != nil {
+			logger.Errorf("Error during check: %s", err)
+			return &exitError{code: exitCrawlError, err: err}
+		}
 
-		logger.Infof("Check complete. Found %d links, %d broken.", len(model.Results), utils.CountBrokenLinks())
+		logger.Infof("Check complete. Found %d links, %d broken.", len(results), utils.CountBrokenLinks(results))
 
-		// Auto-detect format from output file if not specified
-		format := model.Format
-		if format == "" && model.Output != "" {
-			format = utils.DetectFormatFromOutput(model.Output)
-		}
-		
-		logger.Debugf("Exporting results with format: %s, output: %s", format, model.Output)
-		if format != "" || model.Output != "" {
-			utils.ExportResults(format)
+		if err := exportReport(results); err != nil {
+			return err
 		}
+
+		return checkFailureThresholds(policy, results)
 	},
 }
 
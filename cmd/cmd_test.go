@@ -2,12 +2,21 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
-	"sync"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/DrakkarStorm/deadlinkr/internal"
 	"github.com/DrakkarStorm/deadlinkr/logger"
 	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/DrakkarStorm/deadlinkr/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -15,30 +24,28 @@ import (
 // Helper function to set up test environment
 func setupCmdTest() func() {
 	// Save original state
-	originalResults := model.Results
-	originalDepth := model.Depth
-	originalTimeout := model.Timeout
-	originalConcurrency := model.Concurrency
-	originalQuiet := model.Quiet
-	originalLogLevel := model.LogLevel
+	originalOptions := options
+	originalTimeout := timeoutSeconds
+	originalQuiet := quiet
+	originalLogLevel := logLevel
+	originalOutput := outputFile
 
 	// Set up test environment
-	model.Results = []model.LinkResult{}
-	model.VisitedURLs = sync.Map{}
-	model.Depth = 1
-	model.Timeout = 5
-	model.Concurrency = 10
-	model.Quiet = true // Avoid log file creation in tests
-	model.LogLevel = "info"
+	options.Depth = 1
+	options.Concurrency = 10
+	timeoutSeconds = 5
+	quiet = true // Avoid log file creation in tests
+	logger.SetQuiet(true)
+	logLevel = "info"
 
 	// Return a function to restore original state
 	return func() {
-		model.Results = originalResults
-		model.Depth = originalDepth
-		model.Timeout = originalTimeout
-		model.Concurrency = originalConcurrency
-		model.Quiet = originalQuiet
-		model.LogLevel = originalLogLevel
+		options = originalOptions
+		timeoutSeconds = originalTimeout
+		quiet = originalQuiet
+		logger.SetQuiet(originalQuiet)
+		logLevel = originalLogLevel
+		outputFile = originalOutput
 		logger.CloseLogger()
 		
 		// Clean up test files
@@ -49,6 +56,13 @@ func setupCmdTest() func() {
 	}
 }
 
+// readReport reads the results of a command from its JSON report
+func readReport(t *testing.T, path string) []model.LinkResult {
+	results, err := utils.LoadReport(path)
+	require.NoError(t, err)
+	return results
+}
+
 func TestRootCmd(t *testing.T) {
 	teardown := setupCmdTest()
 	defer teardown()
@@ -170,22 +184,22 @@ func TestFlagDefaults(t *testing.T) {
 
 	t.Run("Default values are set correctly", func(t *testing.T) {
 		// Reset to defaults
-		model.Timeout = 10
-		model.Concurrency = 20
-		model.Depth = 1
-		model.UserAgent = "DeadLinkr/1.0"
+		timeoutSeconds = 10
+		options.Concurrency = 20
+		options.Depth = 1
+		options.UserAgent = "DeadLinkr/1.0"
 		
 		// Test default timeout
-		assert.Equal(t, 10, model.Timeout)
+		assert.Equal(t, 10*time.Second, scannerOptions().Timeout)
 		
 		// Test default concurrency
-		assert.Equal(t, 20, model.Concurrency)
+		assert.Equal(t, 20, options.Concurrency)
 		
 		// Test default depth
-		assert.Equal(t, 1, model.Depth)
+		assert.Equal(t, 1, options.Depth)
 		
 		// Test default user agent
-		assert.Equal(t, "DeadLinkr/1.0", model.UserAgent)
+		assert.Equal(t, "DeadLinkr/1.0", options.UserAgent)
 	})
 }
 
@@ -200,7 +214,7 @@ func TestPersistentPreRun(t *testing.T) {
 		os.Stdout = w
 
 		// Test the persistent pre-run function
-		model.LogLevel = "debug"
+		logLevel = "debug"
 		rootCmd.PersistentPreRun(rootCmd, []string{})
 
 		// Restore stdout
@@ -214,6 +228,443 @@ func TestPersistentPreRun(t *testing.T) {
 
 		// The function should have run without error
 		// (We can't easily test the exact behavior due to global state)
-		assert.Equal(t, "debug", model.LogLevel)
+		assert.Equal(t, "debug", logLevel)
+	})
+}
+func TestExitCodes(t *testing.T) {
+	teardown := setupCmdTest()
+	defer teardown()
+
+	t.Run("Errors map to exit codes", func(t *testing.T) {
+		assert.Equal(t, exitSuccess, exitCodeOf(nil))
+		assert.Equal(t, exitBrokenLinks, exitCodeOf(&exitError{code: exitBrokenLinks}))
+		assert.Equal(t, exitCrawlError, exitCodeOf(fmt.Errorf("scan: %w", &exitError{code: exitCrawlError, err: errors.New("unreachable")})))
+		assert.Equal(t, exitConfigError, exitCodeOf(errors.New("unknown flag: --nope")))
+	})
+
+	t.Run("Failure thresholds", func(t *testing.T) {
+		originalFailOn, originalMaxBroken, originalFailOnExternal := failOn, maxBroken, failOnExternal
+		defer func() {
+			failOn, maxBroken, failOnExternal = originalFailOn, originalMaxBroken, originalFailOnExternal
+		}()
+
+		results := []model.LinkResult{
+			{TargetURL: "https://example.com/ok", Status: 200},
+			{TargetURL: "https://example.com/missing", Status: 404},
+			{TargetURL: "https://external.com/missing", Status: 404, IsExternal: true},
+		}
+		failOn = []string{"4xx", "5xx"}
+		maxBroken = 1
+		failOnExternal = true
+
+		policy, err := failurePolicy()
+		require.NoError(t, err)
+		assert.Equal(t, exitBrokenLinks, exitCodeOf(checkFailureThresholds(policy, results)))
+
+		failOnExternal = false
+		policy, err = failurePolicy()
+		require.NoError(t, err)
+		assert.NoError(t, checkFailureThresholds(policy, results))
+
+		failOn = []string{"404"}
+		_, err = failurePolicy()
+		assert.Equal(t, exitConfigError, exitCodeOf(err))
+	})
+}
+
+func TestDiffCmd(t *testing.T) {
+	teardown := setupCmdTest()
+	defer teardown()
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	writeReport := func(name string, results []model.LinkResult) string {
+		content, err := json.Marshal(results)
+		require.NoError(t, err)
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, content, 0o644))
+		return path
+	}
+	oldReport := writeReport("old.json", []model.LinkResult{
+		{SourceURL: "https://example.com", TargetURL: "https://example.com/legacy", Status: 404},
+		{SourceURL: "https://example.com", TargetURL: "https://example.com/page", Status: 200},
+	})
+	newReport := writeReport("new.json", []model.LinkResult{
+		{SourceURL: "https://example.com", TargetURL: "https://example.com/legacy", Status: 404},
+		{SourceURL: "https://example.com", TargetURL: "https://example.com/page", Status: 200},
+	})
+	regressedReport := writeReport("regressed.json", []model.LinkResult{
+		{SourceURL: "https://example.com", TargetURL: "https://example.com/legacy", Status: 404},
+		{SourceURL: "https://example.com", TargetURL: "https://example.com/page", Status: 500},
+	})
+
+	originalFailOn, originalFailOnNewOnly := failOn, failOnNewOnly
+	defer func() {
+		failOn, failOnNewOnly = originalFailOn, originalFailOnNewOnly
+	}()
+	failOn = []string{"4xx", "5xx"}
+	outputFile = "diff.json"
+
+	t.Run("Diff command exists", func(t *testing.T) {
+		assert.Equal(t, "diff [old.json] [new.json]", diffCmd.Use)
+		assert.NotNil(t, diffCmd.PersistentFlags().Lookup("fail-on-new-only"))
+		assert.NotNil(t, scanCmd.PersistentFlags().Lookup("baseline"))
+	})
+
+	t.Run("Legacy breakage fails unless only new breakage counts", func(t *testing.T) {
+		failOnNewOnly = false
+		assert.Equal(t, exitBrokenLinks, exitCodeOf(diffCmd.RunE(diffCmd, []string{oldReport, newReport})))
+		assert.Equal(t, model.ChangeStillBroken, readReport(t, outputFile)[0].Change)
+
+		failOnNewOnly = true
+		assert.NoError(t, diffCmd.RunE(diffCmd, []string{oldReport, newReport}))
+	})
+
+	t.Run("Regressions fail with --fail-on-new-only", func(t *testing.T) {
+		failOnNewOnly = true
+		assert.Equal(t, exitBrokenLinks, exitCodeOf(diffCmd.RunE(diffCmd, []string{oldReport, regressedReport})))
+		assert.Equal(t, model.ChangeNewlyBroken, readReport(t, outputFile)[1].Change)
 	})
-}
\ No newline at end of file
+
+	t.Run("Unreadable reports are configuration errors", func(t *testing.T) {
+		err := diffCmd.RunE(diffCmd, []string{oldReport, filepath.Join(dir, "missing.json")})
+		assert.Equal(t, exitConfigError, exitCodeOf(err))
+	})
+
+	t.Run("Unwritable reports are configuration errors", func(t *testing.T) {
+		defer func() { outputFile = "diff.json" }()
+		outputFile = filepath.Join("missing", "diff.json")
+		err := diffCmd.RunE(diffCmd, []string{oldReport, newReport})
+		assert.Equal(t, exitConfigError, exitCodeOf(err))
+	})
+}
+
+func TestScanCDPSettings(t *testing.T) {
+	teardown := setupCmdTest()
+	defer teardown()
+	t.Chdir(t.TempDir())
+
+	for _, name := range []string{"cdp-url", "cdp-wait", "cdp-wait-selector", "cdp-wait-delay", "cdp-timeout", "cdp-max-tabs"} {
+		assert.NotNil(t, scanCmd.PersistentFlags().Lookup(name), name)
+	}
+
+	options.CDPURL, options.CDPWaitUntil = "http://localhost:9222", "idle"
+	err := scanCmd.RunE(scanCmd, []string{"https://example.com"})
+	assert.Equal(t, exitConfigError, exitCodeOf(err))
+
+	options.CDPURL, options.CDPWaitUntil = "localhost:9222", "load"
+	err = scanCmd.RunE(scanCmd, []string{"https://example.com"})
+	assert.Equal(t, exitConfigError, exitCodeOf(err))
+
+	browser := httptest.NewServer(http.NotFoundHandler())
+	browser.Close()
+	options.CDPURL = browser.URL
+	err = scanCmd.RunE(scanCmd, []string{"https://example.com"})
+	assert.Equal(t, exitConfigError, exitCodeOf(err), "an unreachable browser is a configuration error")
+}
+
+func TestServeCmd(t *testing.T) {
+	assert.Equal(t, "serve", serveCmd.Use)
+	for _, name := range []string{"addr", "max-scans", "retain-scans", "metrics-addr", "depth", "concurrency"} {
+		assert.NotNil(t, serveCmd.Flags().Lookup(name), name)
+	}
+	assert.Equal(t, "localhost:8080", serveCmd.Flags().Lookup("addr").DefValue)
+}
+
+func TestScanMetricsAddr(t *testing.T) {
+	teardown := setupCmdTest()
+	defer teardown()
+	t.Chdir(t.TempDir())
+
+	originalAddr := metricsAddr
+	defer func() { metricsAddr = originalAddr }()
+
+	assert.NotNil(t, scanCmd.PersistentFlags().Lookup("metrics-addr"))
+
+	metricsAddr = "invalid:address:port"
+	err := scanCmd.RunE(scanCmd, []string{"https://example.com"})
+	assert.Equal(t, exitConfigError, exitCodeOf(err))
+}
+
+func TestScanMaxDuration(t *testing.T) {
+	teardown := setupCmdTest()
+	defer teardown()
+	t.Chdir(t.TempDir())
+
+	originalMaxDuration := maxDuration
+	defer func() { maxDuration = originalMaxDuration }()
+
+	release := make(chan struct{})
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><a href="/ok">OK</a><a href="/slow">Slow</a></body></html>`))
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("OK"))
+		}
+	}))
+	defer site.Close()
+	defer close(release)
+
+	assert.NotNil(t, scanCmd.PersistentFlags().Lookup("max-duration"))
+
+	maxDuration = 500 * time.Millisecond
+	outputFile = "report.json"
+	start := time.Now()
+	err := scanCmd.RunE(scanCmd, []string{site.URL + "/"})
+	assert.Less(t, time.Since(start), 5*time.Second, "the scan stops after --max-duration")
+	assert.NoError(t, err, "links checked before the limit are reported")
+
+	targets := []string{}
+	for _, result := range readReport(t, outputFile) {
+		targets = append(targets, result.TargetURL)
+	}
+	assert.Contains(t, targets, site.URL+"/ok")
+	assert.NotContains(t, targets, site.URL+"/slow")
+}
+
+func TestScanReportToStdout(t *testing.T) {
+	teardown := setupCmdTest()
+	defer teardown()
+	t.Chdir(t.TempDir())
+
+	originalFormat := outputFormat
+	defer func() { outputFormat = originalFormat }()
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><a href="/ok">OK</a><a href="/missing">Missing</a></body></html>`))
+		case "/ok":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("OK"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	// Without --quiet the progress would otherwise be shown
+	quiet = false
+	outputFormat = "ndjson"
+	outputFile = "-"
+
+	old := os.Stdout
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = w
+	output := make(chan []byte)
+	go func() {
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(r)
+		output <- buf.Bytes()
+	}()
+
+	err = scanCmd.RunE(scanCmd, []string{site.URL + "/"})
+	_ = w.Close()
+	os.Stdout = old
+	assert.Equal(t, exitBrokenLinks, exitCodeOf(err))
+
+	lines := strings.Split(strings.TrimSpace(string(<-output)), "\n")
+	require.Len(t, lines, 2, "one line per link on the standard output")
+	for _, line := range lines {
+		var result model.LinkResult
+		assert.NoError(t, json.Unmarshal([]byte(line), &result), "stdout line %q is not a JSON result", line)
+	}
+}
+
+func TestScanResume(t *testing.T) {
+	teardown := setupCmdTest()
+	defer teardown()
+	t.Chdir(t.TempDir())
+
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			t.Error("pages crawled before the interruption are not crawled again")
+		case "/about":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><a href="/team">Team</a></body></html>`))
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("OK"))
+		}
+	}))
+	defer site.Close()
+
+	for _, flag := range []string{"checkpoint", "checkpoint-interval", "resume"} {
+		assert.NotNil(t, scanCmd.PersistentFlags().Lookup(flag))
+	}
+
+	t.Run("Missing checkpoint", func(t *testing.T) {
+		options.ResumeFile = "missing.json"
+		err := scanCmd.RunE(scanCmd, []string{site.URL + "/"})
+		assert.Equal(t, exitConfigError, exitCodeOf(err))
+	})
+
+	t.Run("Continues an interrupted scan", func(t *testing.T) {
+		options.ResumeFile = "state.json"
+		outputFile = "report.json"
+		require.NoError(t, internal.SaveCrawlState(options.ResumeFile, &internal.CrawlState{
+			Version: 1,
+			Target:  site.URL + "/",
+			Pending: []internal.PendingJob{{BaseURL: site.URL + "/", TargetURL: site.URL + "/about", CurrentDepth: 1}},
+			Visited: []string{site.URL + "/"},
+			Results: []model.LinkResult{{SourceURL: site.URL + "/", TargetURL: site.URL + "/about", Status: 200}},
+		}))
+
+		require.NoError(t, scanCmd.RunE(scanCmd, []string{site.URL + "/"}))
+
+		targets := []string{}
+		for _, result := range readReport(t, outputFile) {
+			targets = append(targets, result.TargetURL)
+		}
+		assert.ElementsMatch(t, []string{site.URL + "/about", site.URL + "/team"}, targets)
+		assert.NoFileExists(t, options.ResumeFile, "the checkpoint of a completed scan is removed")
+	})
+
+	t.Run("Checkpoint of another site", func(t *testing.T) {
+		options.ResumeFile = "other.json"
+		require.NoError(t, internal.SaveCrawlState(options.ResumeFile, &internal.CrawlState{Version: 1, Target: "https://example.com"}))
+		err := scanCmd.RunE(scanCmd, []string{site.URL + "/"})
+		assert.Equal(t, exitCrawlError, exitCodeOf(err))
+	})
+}
+
+// captureStdout returns what a command writes to the standard output
+func captureStdout(t *testing.T, run func() error) (string, error) {
+	old := os.Stdout
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = w
+	output := make(chan []byte)
+	go func() {
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(r)
+		output <- buf.Bytes()
+	}()
+
+	err = run()
+	_ = w.Close()
+	os.Stdout = old
+	return string(<-output), err
+}
+
+func TestReportsToStdout(t *testing.T) {
+	teardown := setupCmdTest()
+	defer teardown()
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	originalFormat, originalBaseline := outputFormat, baseline
+	defer func() { outputFormat, baseline = originalFormat, originalBaseline }()
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><a href="/ok">OK</a><a href="/missing">Missing</a></body></html>`))
+		case "/ok":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("OK"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	report, err := json.Marshal([]model.LinkResult{{SourceURL: site.URL + "/", TargetURL: site.URL + "/ok", Status: 200}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile("baseline.json", report, 0o644))
+	require.NoError(t, os.WriteFile("README.md", []byte("# Project\n\n[Missing](missing.md)\n"), 0o644))
+
+	// Without --quiet the diff summaries would otherwise be printed
+	quiet = false
+	outputFile = "-"
+
+	commands := []struct {
+		name string
+		run  func() error
+	}{
+		{name: "check", run: func() error { return checkCmd.RunE(checkCmd, []string{site.URL + "/"}) }},
+		{name: "markdown", run: func() error { return markdownCmd.RunE(markdownCmd, []string{"README.md"}) }},
+		{name: "diff", run: func() error { return diffCmd.RunE(diffCmd, []string{"baseline.json", "baseline.json"}) }},
+		{name: "scan --baseline", run: func() error {
+			baseline = "baseline.json"
+			defer func() { baseline = "" }()
+			return scanCmd.RunE(scanCmd, []string{site.URL + "/"})
+		}},
+	}
+	for _, command := range commands {
+		for _, format := range []string{"json", "sarif"} {
+			t.Run(command.name+" "+format, func(t *testing.T) {
+				outputFormat = format
+				output, err := captureStdout(t, command.run)
+				assert.NotEqual(t, exitConfigError, exitCodeOf(err))
+				assert.True(t, json.Valid([]byte(output)), "stdout is not a %s report: %q", format, output)
+				assert.NoFileExists(t, "-")
+			})
+		}
+	}
+
+	for _, format := range []string{"csv", "html", "junit"} {
+		t.Run("check "+format, func(t *testing.T) {
+			outputFormat = format
+			output, err := captureStdout(t, commands[0].run)
+			assert.Equal(t, exitBrokenLinks, exitCodeOf(err))
+			assert.Contains(t, output, site.URL+"/missing")
+			assert.NoFileExists(t, "-")
+		})
+	}
+}
+
+func TestMarkdownCmd(t *testing.T) {
+	teardown := setupCmdTest()
+	defer teardown()
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	require.NoError(t, os.MkdirAll("docs", 0o755))
+	require.NoError(t, os.WriteFile("README.md", []byte("# Project\n\n[Guide](docs/guide.md#usage)\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join("docs", "guide.md"), []byte("## Usage\n\n[Home](/README.md#project)\n[Missing](missing.md)\n"), 0o644))
+
+	t.Run("Reports broken links with their file and line", func(t *testing.T) {
+		outputFile = "report.json"
+		err := markdownCmd.RunE(markdownCmd, []string{})
+		assert.Equal(t, exitBrokenLinks, exitCodeOf(err))
+
+		var broken []model.LinkResult
+		for _, result := range readReport(t, outputFile) {
+			if result.Status >= 400 || result.Error != "" {
+				broken = append(broken, result)
+			}
+		}
+		require.Len(t, broken, 1)
+		assert.Equal(t, "docs/missing.md", broken[0].TargetURL)
+		assert.Equal(t, "docs/guide.md", broken[0].SourceURL)
+		assert.Equal(t, 4, broken[0].Line)
+	})
+
+	t.Run("Missing path", func(t *testing.T) {
+		err := markdownCmd.RunE(markdownCmd, []string{"nowhere"})
+		assert.Equal(t, exitConfigError, exitCodeOf(err))
+	})
+
+	t.Run("Unreadable file", func(t *testing.T) {
+		require.NoError(t, os.Symlink("nowhere.md", filepath.Join("docs", "dangling.md")))
+		defer func() { _ = os.Remove(filepath.Join("docs", "dangling.md")) }()
+
+		err := markdownCmd.RunE(markdownCmd, []string{})
+		assert.Equal(t, exitCrawlError, exitCodeOf(err))
+		assert.ErrorContains(t, err, "dangling.md")
+	})
+}
@@ -0,0 +1,666 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileNames are the file names looked up when no --config is given
+var configFileNames = []string{"deadlinkr.yaml", "deadlinkr.yml"}
+
+// envPrefix prefixes the environment variables overriding flags, e.g. DEADLINKR_RATE_LIMIT
+const envPrefix = "DEADLINKR_"
+
+// authFlags maps the keys of the auth section to their flags
+var authFlags = map[string]string{
+	"basic":   "auth-basic",
+	"bearer":  "auth-bearer",
+	"headers": "auth-header",
+	"cookies": "auth-cookies",
+}
+
+// secretFlags are masked by config print
+var secretFlags = map[string]bool{
+	"auth-basic":   true,
+	"auth-bearer":  true,
+	"auth-header":  true,
+	"auth-cookies": true,
+}
+
+// Configuration sources, from lowest to highest precedence
+const (
+	sourceDefault = "default"
+	sourceFile    = "file"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+)
+
+// allFlagsAnnotation marks commands the configuration of every command applies to
+const allFlagsAnnotation = "deadlinkr/all-flags"
+
+// configSources records where the effective value of each flag came from
+var configSources = map[string]string{}
+
+// configCmd groups configuration subcommands
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the deadlinkr configuration",
+}
+
+// configPrintCmd prints the effective configuration
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the effective configuration merged from file, environment and flags",
+	Args:  cobra.NoArgs,
+	Annotations: map[string]string{
+		allFlagsAnnotation: "true",
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Arguments are valid past this point, failures are reported by Execute through the exit code
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+
+		output, err := renderConfig(configFlagSets(cmd))
+		if err != nil {
+			return &exitError{code: exitConfigError, err: fmt.Errorf("rendering configuration: %w", err)}
+		}
+		fmt.Print(output)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configPrintCmd)
+}
+
+// loadConfiguration applies the configuration file and environment variables to the flags
+// that were not set on the command line. Precedence is file < env < flags.
+func loadConfiguration(cmd *cobra.Command) error {
+	flagSets := configFlagSets(cmd)
+
+	// Remember what the command line set before applying other sources
+	configSources = map[string]string{}
+	for _, flags := range flagSets {
+		flags.VisitAll(func(flag *pflag.Flag) {
+			configSources[flag.Name] = sourceDefault
+			if flag.Changed {
+				configSources[flag.Name] = sourceFlag
+			}
+		})
+	}
+
+	path, err := findConfigFile()
+	if err != nil {
+		return err
+	}
+	configFile = path
+
+	if path != "" {
+		if err := applyConfigFile(path, cmd.Root(), flagSets); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return applyEnvironment(flagSets)
+}
+
+// configFlagSets returns the flag sets the configuration applies to.
+// config print also covers the flags of every command so the whole configuration is shown.
+func configFlagSets(cmd *cobra.Command) []*pflag.FlagSet {
+	flagSets := []*pflag.FlagSet{cmd.Flags()}
+	if cmd.Annotations[allFlagsAnnotation] == "true" {
+		for _, command := range cmd.Root().Commands() {
+			flagSets = append(flagSets, command.PersistentFlags())
+		}
+	}
+	return flagSets
+}
+
+// findConfigFile returns the configuration file to use: --config, DEADLINKR_CONFIG,
+// then deadlinkr.yaml in the current directory or $HOME/.config/deadlinkr/. It returns "" if none exists.
+func findConfigFile() (string, error) {
+	if configFile != "" {
+		if _, err := os.Stat(configFile); err != nil {
+			return "", fmt.Errorf("config file: %w", err)
+		}
+		return configFile, nil
+	}
+
+	if path := os.Getenv(envPrefix + "CONFIG"); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("config file from %sCONFIG: %w", envPrefix, err)
+		}
+		return path, nil
+	}
+
+	dirs := []string{"."}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".config", "deadlinkr"))
+	}
+
+	for _, dir := range dirs {
+		for _, name := range configFileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// applyConfigFile sets the flags not given on the command line from a YAML file
+func applyConfigFile(path string, root *cobra.Command, flagSets []*pflag.FlagSet) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("invalid YAML: %w", err)
+	}
+
+	settings, domains, err := flattenConfig(values)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range sortedKeys(settings) {
+		flag := lookupFlag(flagSets, name)
+		if flag == nil {
+			if !isKnownFlag(root, name) {
+				return fmt.Errorf("unknown setting %q", strings.ReplaceAll(name, "-", "_"))
+			}
+			continue // Belongs to another command
+		}
+		if configSources[flag.Name] == sourceFlag {
+			continue
+		}
+		if err := setFlagValue(flag, settings[name]); err != nil {
+			return fmt.Errorf("setting %q: %w", strings.ReplaceAll(name, "-", "_"), err)
+		}
+		configSources[flag.Name] = sourceFile
+	}
+
+	options.DomainRateLimits = map[string]float64{}
+	options.DomainSoft404Phrases = map[string][]string{}
+	options.DomainRules = map[string]model.DomainRule{}
+	for domain, settings := range domains {
+		if settings.rateLimit > 0 {
+			options.DomainRateLimits[domain] = settings.rateLimit
+		}
+		if len(settings.soft404Phrases) > 0 {
+			options.DomainSoft404Phrases[domain] = settings.soft404Phrases
+		}
+		if settings.hasRule {
+			options.DomainRules[domain] = settings.rule
+		}
+	}
+	return nil
+}
+
+// domainConfig holds the overrides of one domain from the domains section
+type domainConfig struct {
+	rateLimit      float64          // Requests per second, 0 when not set
+	soft404Phrases []string         // Phrases marking pages of the domain as soft 404s
+	rule           model.DomainRule // Request overrides
+	hasRule        bool             // Whether any request override is set
+}
+
+// flattenConfig converts the YAML settings to flag values, expanding the auth section,
+// and extracts the per-domain overrides
+func flattenConfig(values map[string]interface{}) (map[string]interface{}, map[string]domainConfig, error) {
+	settings := map[string]interface{}{}
+	domains := map[string]domainConfig{}
+
+	for key, value := range values {
+		name := strings.ReplaceAll(strings.ToLower(key), "_", "-")
+
+		switch name {
+		case "auth":
+			auth, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, nil, fmt.Errorf("auth must be a mapping")
+			}
+			for authKey, authValue := range auth {
+				flagName, known := authFlags[strings.ToLower(authKey)]
+				if !known {
+					return nil, nil, fmt.Errorf("unknown auth setting %q", authKey)
+				}
+				settings[flagName] = authValue
+			}
+		case "domains":
+			domainValues, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, nil, fmt.Errorf("domains must be a mapping")
+			}
+			for domain, domainValue := range domainValues {
+				if strings.Contains(strings.TrimPrefix(domain, "*."), "*") {
+					return nil, nil, fmt.Errorf("domain %q: only a leading \"*.\" wildcard is supported", domain)
+				}
+				domainSettings, err := parseDomainConfig(domainValue)
+				if err != nil {
+					return nil, nil, fmt.Errorf("domain %q: %w", domain, err)
+				}
+				domains[strings.ToLower(domain)] = domainSettings
+			}
+		default:
+			settings[name] = value
+		}
+	}
+
+	return settings, domains, nil
+}
+
+// parseDomainConfig reads the overrides of one domain
+func parseDomainConfig(value interface{}) (domainConfig, error) {
+	domain := domainConfig{}
+	settings, ok := value.(map[string]interface{})
+	if !ok {
+		return domain, fmt.Errorf("must be a mapping")
+	}
+
+	for key, setting := range settings {
+		switch strings.ReplaceAll(strings.ToLower(key), "-", "_") {
+		case "rate_limit":
+			parsed, err := strconv.ParseFloat(fmt.Sprint(setting), 64)
+			if err != nil || parsed <= 0 {
+				return domain, fmt.Errorf("rate_limit must be a positive number")
+			}
+			domain.rateLimit = parsed
+		case "soft_404_phrases":
+			phrases, err := parseStringList(setting)
+			if err != nil {
+				return domain, fmt.Errorf("soft_404_phrases %w", err)
+			}
+			domain.soft404Phrases = phrases
+		case "burst":
+			parsed, err := strconv.ParseFloat(fmt.Sprint(setting), 64)
+			if err != nil || parsed < 1 {
+				return domain, fmt.Errorf("burst must be a number of at least 1")
+			}
+			domain.rule.Burst = parsed
+			domain.hasRule = true
+		case "timeout":
+			timeout, err := parseTimeout(setting)
+			if err != nil {
+				return domain, err
+			}
+			domain.rule.Timeout = timeout
+			domain.hasRule = true
+		case "retries":
+			parsed, err := strconv.Atoi(fmt.Sprint(setting))
+			if err != nil || parsed < 0 {
+				return domain, fmt.Errorf("retries must be a positive integer or 0")
+			}
+			domain.rule.Retries = &parsed
+			domain.hasRule = true
+		case "head":
+			parsed, err := strconv.ParseBool(fmt.Sprint(setting))
+			if err != nil {
+				return domain, fmt.Errorf("head must be true or false")
+			}
+			domain.rule.Head = &parsed
+			domain.hasRule = true
+		case "skip":
+			parsed, err := strconv.ParseBool(fmt.Sprint(setting))
+			if err != nil {
+				return domain, fmt.Errorf("skip must be true or false")
+			}
+			domain.rule.Skip = parsed
+			domain.hasRule = true
+		case "headers":
+			headers, ok := setting.(map[string]interface{})
+			if !ok {
+				return domain, fmt.Errorf("headers must be a mapping")
+			}
+			domain.rule.Headers = make(map[string]string, len(headers))
+			for name, value := range headers {
+				domain.rule.Headers[name] = fmt.Sprint(value)
+			}
+			domain.hasRule = true
+		case "auth":
+			if err := parseDomainAuth(setting, &domain.rule); err != nil {
+				return domain, err
+			}
+			domain.hasRule = true
+		default:
+			return domain, fmt.Errorf("unknown setting %q", key)
+		}
+	}
+	return domain, nil
+}
+
+// parseTimeout reads a timeout given as a duration like "30s", or as a number of seconds like the --timeout flag
+func parseTimeout(value interface{}) (time.Duration, error) {
+	text := fmt.Sprint(value)
+	timeout, err := time.ParseDuration(text)
+	if err != nil {
+		seconds, parseErr := strconv.ParseFloat(text, 64)
+		if parseErr != nil {
+			return 0, fmt.Errorf("timeout must be a duration like \"30s\" or a number of seconds")
+		}
+		timeout = time.Duration(seconds * float64(time.Second))
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("timeout must be positive")
+	}
+	return timeout, nil
+}
+
+// parseDomainAuth reads the credentials of a domain, given like those of the auth section
+func parseDomainAuth(value interface{}, rule *model.DomainRule) error {
+	auth, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("auth must be a mapping")
+	}
+
+	for key, setting := range auth {
+		switch strings.ToLower(key) {
+		case "basic":
+			user, password, found := strings.Cut(fmt.Sprint(setting), ":")
+			if !found || user == "" || password == "" {
+				return fmt.Errorf("auth basic must be in 'user:password' format")
+			}
+			rule.BasicUser, rule.BasicPassword = user, password
+		case "bearer":
+			rule.BearerToken = fmt.Sprint(setting)
+		default:
+			return fmt.Errorf("unknown auth setting %q", key)
+		}
+	}
+	return nil
+}
+
+// parseStringList reads a YAML list of strings, accepting a single string as a list of one
+func parseStringList(value interface{}) ([]string, error) {
+	switch typed := value.(type) {
+	case string:
+		return []string{typed}, nil
+	case []interface{}:
+		values := make([]string, 0, len(typed))
+		for _, item := range typed {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("must be a list of strings")
+			}
+			values = append(values, text)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("must be a list of strings")
+}
+
+// applyEnvironment sets the flags not given on the command line from DEADLINKR_<FLAG> variables
+func applyEnvironment(flagSets []*pflag.FlagSet) error {
+	for _, flags := range flagSets {
+		var applyErr error
+		flags.VisitAll(func(flag *pflag.Flag) {
+			if applyErr != nil || flag.Name == "config" || flag.Name == "help" || configSources[flag.Name] == sourceFlag {
+				return
+			}
+			value, found := os.LookupEnv(envVarName(flag.Name))
+			if !found {
+				return
+			}
+
+			var err error
+			if _, isSlice := flag.Value.(pflag.SliceValue); isSlice && flag.Value.Type() == "stringSlice" {
+				err = setFlagValue(flag, strings.Split(value, ","))
+			} else {
+				err = setFlagValue(flag, value)
+			}
+			if err != nil {
+				applyErr = fmt.Errorf("%s: %w", envVarName(flag.Name), err)
+				return
+			}
+			configSources[flag.Name] = sourceEnv
+		})
+		if applyErr != nil {
+			return applyErr
+		}
+	}
+	return nil
+}
+
+// envVarName returns the environment variable overriding a flag
+func envVarName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// setFlagValue sets a flag from a YAML or environment value
+func setFlagValue(flag *pflag.Flag, value interface{}) error {
+	var items []string
+	switch typed := value.(type) {
+	case []interface{}:
+		for _, item := range typed {
+			items = append(items, fmt.Sprint(item))
+		}
+	case []string:
+		items = typed
+	case map[string]interface{}:
+		// Mappings are only meaningful for headers, e.g. {X-Api-Key: secret}
+		for _, key := range sortedKeys(typed) {
+			items = append(items, fmt.Sprintf("%s: %v", key, typed[key]))
+		}
+	case nil:
+		items = []string{}
+	default:
+		return flag.Value.Set(fmt.Sprint(typed))
+	}
+
+	if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+		return sliceValue.Replace(items)
+	}
+	// Lists for plain string flags, e.g. exclude_html_tags, are comma separated
+	return flag.Value.Set(strings.Join(items, ","))
+}
+
+// lookupFlag finds a flag by name in the given flag sets
+func lookupFlag(flagSets []*pflag.FlagSet, name string) *pflag.Flag {
+	for _, flags := range flagSets {
+		if flag := flags.Lookup(name); flag != nil {
+			return flag
+		}
+	}
+	return nil
+}
+
+// isKnownFlag checks whether any command defines the flag
+func isKnownFlag(root *cobra.Command, name string) bool {
+	if root.PersistentFlags().Lookup(name) != nil {
+		return true
+	}
+	for _, command := range root.Commands() {
+		if command.Flags().Lookup(name) != nil || command.PersistentFlags().Lookup(name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// renderConfig renders the effective configuration as YAML, annotated with the source of non-default values
+func renderConfig(flagSets []*pflag.FlagSet) (string, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	auth := &yaml.Node{Kind: yaml.MappingNode}
+
+	flagsByName := map[string]*pflag.Flag{}
+	for _, flags := range flagSets {
+		flags.VisitAll(func(flag *pflag.Flag) {
+			switch flag.Name {
+			case "help", "version", "config":
+				return
+			}
+			flagsByName[flag.Name] = flag
+		})
+	}
+
+	authKeys := map[string]string{}
+	for key, flagName := range authFlags {
+		authKeys[flagName] = key
+	}
+
+	for _, name := range sortedKeys(flagsByName) {
+		flag := flagsByName[name]
+		valueNode := flagValueNode(flag)
+		if secretFlags[name] && flag.Value.String() != "" && flag.Value.String() != "[]" {
+			valueNode = &yaml.Node{Kind: yaml.ScalarNode, Value: "********"}
+		}
+		if source := configSources[name]; source != "" && source != sourceDefault {
+			valueNode.LineComment = source
+		}
+
+		if authKey, isAuth := authKeys[name]; isAuth {
+			auth.Content = append(auth.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: authKey}, valueNode)
+			continue
+		}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: strings.ReplaceAll(name, "-", "_")}, valueNode)
+	}
+
+	if len(auth.Content) > 0 {
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "auth"}, auth)
+	}
+
+	if domains := renderDomains(); len(domains.Content) > 0 {
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "domains"}, domains)
+	}
+
+	var buf bytes.Buffer
+	if configFile != "" {
+		buf.WriteString("# Configuration file: " + configFile + "\n")
+	}
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// renderDomains renders the per-domain overrides of the configuration file
+func renderDomains() *yaml.Node {
+	names := map[string]bool{}
+	for domain := range options.DomainRateLimits {
+		names[domain] = true
+	}
+	for domain := range options.DomainSoft404Phrases {
+		names[domain] = true
+	}
+	for domain := range options.DomainRules {
+		names[domain] = true
+	}
+
+	domains := &yaml.Node{Kind: yaml.MappingNode}
+	for _, domain := range sortedKeys(names) {
+		settings := &yaml.Node{Kind: yaml.MappingNode}
+		if rateLimit, found := options.DomainRateLimits[domain]; found {
+			settings.Content = append(settings.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "rate_limit"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: strconv.FormatFloat(rateLimit, 'f', -1, 64), Tag: "!!float"})
+		}
+		if rule, found := options.DomainRules[domain]; found {
+			settings.Content = append(settings.Content, renderDomainRule(rule)...)
+		}
+		if phrases := options.DomainSoft404Phrases[domain]; len(phrases) > 0 {
+			list := &yaml.Node{Kind: yaml.SequenceNode}
+			for _, phrase := range phrases {
+				list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: phrase, Tag: "!!str"})
+			}
+			settings.Content = append(settings.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "soft_404_phrases"}, list)
+		}
+		domains.Content = append(domains.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: domain}, settings)
+	}
+	return domains
+}
+
+// renderDomainRule renders the request overrides of a domain, masking its credentials and headers
+func renderDomainRule(rule model.DomainRule) []*yaml.Node {
+	nodes := []*yaml.Node{}
+	add := func(key string, value *yaml.Node) {
+		nodes = append(nodes, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	}
+	masked := func() *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: "********"}
+	}
+
+	if rule.Burst > 0 {
+		add("burst", &yaml.Node{Kind: yaml.ScalarNode, Value: strconv.FormatFloat(rule.Burst, 'f', -1, 64), Tag: "!!float"})
+	}
+	if rule.Timeout > 0 {
+		add("timeout", &yaml.Node{Kind: yaml.ScalarNode, Value: rule.Timeout.String()})
+	}
+	if rule.Retries != nil {
+		add("retries", &yaml.Node{Kind: yaml.ScalarNode, Value: strconv.Itoa(*rule.Retries), Tag: "!!int"})
+	}
+	if rule.Head != nil {
+		add("head", &yaml.Node{Kind: yaml.ScalarNode, Value: strconv.FormatBool(*rule.Head), Tag: "!!bool"})
+	}
+	if rule.Skip {
+		add("skip", &yaml.Node{Kind: yaml.ScalarNode, Value: "true", Tag: "!!bool"})
+	}
+	if len(rule.Headers) > 0 {
+		headers := &yaml.Node{Kind: yaml.MappingNode}
+		for _, name := range sortedKeys(rule.Headers) {
+			headers.Content = append(headers.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, masked())
+		}
+		add("headers", headers)
+	}
+	if rule.BasicUser != "" || rule.BearerToken != "" {
+		auth := &yaml.Node{Kind: yaml.MappingNode}
+		if rule.BasicUser != "" {
+			auth.Content = append(auth.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "basic"}, masked())
+		}
+		if rule.BearerToken != "" {
+			auth.Content = append(auth.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "bearer"}, masked())
+		}
+		add("auth", auth)
+	}
+	return nodes
+}
+
+// flagValueNode converts a flag value to a YAML node of the matching type
+func flagValueNode(flag *pflag.Flag) *yaml.Node {
+	if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+		node := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, item := range sliceValue.GetSlice() {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: item})
+		}
+		return node
+	}
+
+	// Numbers and booleans are left untagged so they render plainly (a float of 5 as "5", not "!!float 5")
+	node := &yaml.Node{Kind: yaml.ScalarNode, Value: flag.Value.String()}
+	switch flag.Value.Type() {
+	case "bool", "int", "float64", "duration":
+	default:
+		node.Tag = "!!str"
+	}
+	return node
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testConfigCommand builds a small command tree mirroring the root and scan flags
+type testConfigCommand struct {
+	root      *cobra.Command
+	scan      *cobra.Command
+	rateLimit float64
+	depth     int
+	htmlTags  string
+	elements  []string
+	headers   []string
+	bearer    string
+	optimize  bool
+}
+
+func newTestConfigCommand(t *testing.T, args ...string) *testConfigCommand {
+	tc := &testConfigCommand{
+		root: &cobra.Command{Use: "deadlinkr"},
+		scan: &cobra.Command{Use: "scan", Run: func(cmd *cobra.Command, args []string) {}},
+	}
+	tc.root.PersistentFlags().Float64Var(&tc.rateLimit, "rate-limit", 2.0, "")
+	tc.root.PersistentFlags().StringVar(&tc.htmlTags, "exclude-html-tags", "", "")
+	tc.root.PersistentFlags().StringSliceVar(&tc.elements, "include-elements", []string{}, "")
+	tc.root.PersistentFlags().StringArrayVar(&tc.headers, "auth-header", []string{}, "")
+	tc.root.PersistentFlags().StringVar(&tc.bearer, "auth-bearer", "", "")
+	tc.root.PersistentFlags().BoolVar(&tc.optimize, "optimize-head", true, "")
+	tc.scan.PersistentFlags().IntVar(&tc.depth, "depth", 1, "")
+	tc.root.AddCommand(tc.scan)
+
+	require.NoError(t, tc.scan.ParseFlags(args))
+	return tc
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "deadlinkr.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func useConfigFile(t *testing.T, path string) {
+	originalConfigFile := configFile
+	originalDomainRateLimits := options.DomainRateLimits
+	originalDomainSoft404Phrases := options.DomainSoft404Phrases
+	originalDomainRules := options.DomainRules
+	configFile = path
+	t.Cleanup(func() {
+		configFile = originalConfigFile
+		options.DomainRateLimits = originalDomainRateLimits
+		options.DomainSoft404Phrases = originalDomainSoft404Phrases
+		options.DomainRules = originalDomainRules
+	})
+}
+
+func TestLoadConfiguration(t *testing.T) {
+	t.Run("Applies file, environment and flags in order", func(t *testing.T) {
+		useConfigFile(t, writeConfigFile(t, `
+rate_limit: 3.5
+depth: 4
+optimize_head: false
+exclude_html_tags:
+  - nav
+  - .footer
+include_elements: [a, img]
+auth:
+  bearer: secret-token
+  headers:
+    X-Api-Key: abc
+domains:
+  slow.example.com:
+    rate_limit: 0.5
+  cms.example.com:
+    soft_404_phrases: ["Page not found", "Seite nicht gefunden"]
+`))
+		t.Setenv("DEADLINKR_RATE_LIMIT", "4")
+		t.Setenv("DEADLINKR_DEPTH", "9")
+
+		tc := newTestConfigCommand(t, "--depth", "2")
+		require.NoError(t, loadConfiguration(tc.scan))
+
+		assert.Equal(t, 4.0, tc.rateLimit)
+		assert.Equal(t, 2, tc.depth)
+		assert.False(t, tc.optimize)
+		assert.Equal(t, "nav,.footer", tc.htmlTags)
+		assert.Equal(t, []string{"a", "img"}, tc.elements)
+		assert.Equal(t, "secret-token", tc.bearer)
+		assert.Equal(t, []string{"X-Api-Key: abc"}, tc.headers)
+		assert.Equal(t, map[string]float64{"slow.example.com": 0.5}, options.DomainRateLimits)
+		assert.Equal(t, map[string][]string{"cms.example.com": {"Page not found", "Seite nicht gefunden"}}, options.DomainSoft404Phrases)
+
+		assert.Equal(t, sourceEnv, configSources["rate-limit"])
+		assert.Equal(t, sourceFlag, configSources["depth"])
+		assert.Equal(t, sourceFile, configSources["optimize-head"])
+	})
+
+	t.Run("Ignores settings of other commands", func(t *testing.T) {
+		useConfigFile(t, writeConfigFile(t, "depth: 3\n"))
+
+		tc := newTestConfigCommand(t)
+		require.NoError(t, loadConfiguration(tc.root))
+		assert.Equal(t, 1, tc.depth)
+	})
+
+	t.Run("Reads per-domain rules", func(t *testing.T) {
+		useConfigFile(t, writeConfigFile(t, `
+domains:
+  "*.example.com":
+    rate_limit: 1
+    burst: 2
+    timeout: 30s
+    retries: 0
+    head: false
+  api.example.com:
+    timeout: 5
+    headers:
+      X-Api-Key: abc
+    auth:
+      basic: user:pass
+  ads.example.net:
+    skip: true
+`))
+
+		tc := newTestConfigCommand(t)
+		require.NoError(t, loadConfiguration(tc.scan))
+
+		assert.Equal(t, map[string]float64{"*.example.com": 1}, options.DomainRateLimits)
+		wildcard := options.DomainRules["*.example.com"]
+		assert.Equal(t, 2.0, wildcard.Burst)
+		assert.Equal(t, 30*time.Second, wildcard.Timeout)
+		require.NotNil(t, wildcard.Retries)
+		assert.Equal(t, 0, *wildcard.Retries)
+		require.NotNil(t, wildcard.Head)
+		assert.False(t, *wildcard.Head)
+
+		api := options.DomainRules["api.example.com"]
+		assert.Equal(t, 5*time.Second, api.Timeout)
+		assert.Equal(t, map[string]string{"X-Api-Key": "abc"}, api.Headers)
+		assert.Equal(t, "user", api.BasicUser)
+		assert.Equal(t, "pass", api.BasicPassword)
+		assert.Nil(t, api.Retries)
+
+		assert.True(t, options.DomainRules["ads.example.net"].Skip)
+	})
+
+	t.Run("Rejects invalid per-domain rules", func(t *testing.T) {
+		for _, domains := range []string{
+			"example.com:\n    retries: -1",
+			"example.com:\n    timeout: soon",
+			"example.com:\n    head: maybe",
+			"example.com:\n    auth:\n      basic: user",
+			"example.com:\n    headers: [X-Api-Key]",
+			"www.*.example.com:\n    skip: true",
+		} {
+			useConfigFile(t, writeConfigFile(t, "domains:\n  "+domains+"\n"))
+
+			tc := newTestConfigCommand(t)
+			assert.Error(t, loadConfiguration(tc.scan), domains)
+		}
+	})
+
+	t.Run("Rejects unknown settings", func(t *testing.T) {
+		useConfigFile(t, writeConfigFile(t, "rate_limt: 3\n"))
+
+		tc := newTestConfigCommand(t)
+		err := loadConfiguration(tc.scan)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "rate_limt")
+	})
+
+	t.Run("Rejects invalid values", func(t *testing.T) {
+		useConfigFile(t, writeConfigFile(t, "depth: deep\n"))
+
+		tc := newTestConfigCommand(t)
+		assert.Error(t, loadConfiguration(tc.scan))
+	})
+
+	t.Run("Fails on a missing explicit file", func(t *testing.T) {
+		useConfigFile(t, filepath.Join(t.TempDir(), "missing.yaml"))
+
+		tc := newTestConfigCommand(t)
+		assert.Error(t, loadConfiguration(tc.scan))
+	})
+}
+
+func TestRenderConfig(t *testing.T) {
+	useConfigFile(t, writeConfigFile(t, "auth:\n  bearer: secret-token\ndomains:\n  slow.example.com:\n    rate_limit: 0.5\n  cms.example.com:\n    soft_404_phrases: Page not found\n"))
+
+	tc := newTestConfigCommand(t, "--rate-limit", "5")
+	require.NoError(t, loadConfiguration(tc.scan))
+
+	output, err := renderConfig(configFlagSets(tc.scan))
+	require.NoError(t, err)
+
+	assert.Contains(t, output, "rate_limit: 5 # flag")
+	assert.Contains(t, output, "depth: 1\n")
+	assert.Contains(t, output, "bearer: '********' # file")
+	assert.NotContains(t, output, "secret-token")
+	assert.Contains(t, output, "slow.example.com:\n    rate_limit: 0.5")
+	assert.Contains(t, output, "cms.example.com:\n    soft_404_phrases:\n      - Page not found")
+}
+
+func TestRenderConfigDomainRules(t *testing.T) {
+	useConfigFile(t, writeConfigFile(t, "domains:\n  api.example.com:\n    timeout: 5s\n    head: false\n    headers:\n      X-Api-Key: secret-key\n    auth:\n      bearer: secret-token\n"))
+
+	tc := newTestConfigCommand(t)
+	require.NoError(t, loadConfiguration(tc.scan))
+
+	output, err := renderConfig(configFlagSets(tc.scan))
+	require.NoError(t, err)
+
+	assert.Contains(t, output, "api.example.com:\n    timeout: 5s\n    head: false\n")
+	assert.Contains(t, output, "X-Api-Key: '********'")
+	assert.Contains(t, output, "bearer: '********'")
+	assert.NotContains(t, output, "secret-key")
+	assert.NotContains(t, output, "secret-token")
+}
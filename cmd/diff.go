@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/utils"
+	"github.com/spf13/cobra"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff [old.json] [new.json]",
+	Short: "Compare two JSON reports and classify links as newly broken, fixed, still broken, or new and OK",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Arguments are valid past this point, failures are reported by Execute through the exit code
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+
+		policy, err := failurePolicy()
+		if err != nil {
+			return err
+		}
+
+		baseline, err := utils.LoadReport(args[0])
+		if err != nil {
+			return &exitError{code: exitConfigError, err: err}
+		}
+		current, err := utils.LoadReport(args[1])
+		if err != nil {
+			return &exitError{code: exitConfigError, err: err}
+		}
+
+		results := utils.DiffResults(baseline, current)
+		logger.Debugf("Compared %d links from %s to %d links from %s", len(baseline), args[0], len(current), args[1])
+
+		// The summary would corrupt a report written to the standard output
+		if !quiet && outputFile != "-" {
+			utils.DisplayDiff(results, showAll)
+		}
+
+		if err := exportReport(results); err != nil {
+			return err
+		}
+
+		return checkFailureThresholds(policy, results)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.PersistentFlags().BoolVar(&failOnNewOnly, "fail-on-new-only", false, "Only count links newly broken since the old report against the failure thresholds")
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/DrakkarStorm/deadlinkr/utils"
+)
+
+// Exit codes of the deadlinkr command
+const (
+	exitSuccess     = 0 // No failing links
+	exitBrokenLinks = 1 // Failing links beyond the --fail-on / --max-broken thresholds
+	exitCrawlError  = 2 // The scan could not run, e.g. the start page is unreachable
+	exitConfigError = 3 // Invalid flags, arguments or configuration
+)
+
+// exitError carries the exit code of a failed command
+type exitError struct {
+	code int
+	err  error // Optional cause printed before exiting
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// exitCodeOf returns the exit code for an error returned by a command.
+// Errors raised by cobra itself are flag or argument errors.
+func exitCodeOf(err error) int {
+	if err == nil {
+		return exitSuccess
+	}
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return exitConfigError
+}
+
+// failurePolicy builds the failure policy from the --fail-on, --max-broken, --fail-on-external and --fail-on-new-only flags
+func failurePolicy() (*utils.FailurePolicy, error) {
+	policy, err := utils.NewFailurePolicy(failOn, maxBroken, failOnExternal)
+	if err != nil {
+		return nil, &exitError{code: exitConfigError, err: err}
+	}
+	policy.SetNewOnly(failOnNewOnly)
+	return policy, nil
+}
+
+// exportFormat returns the export format selected with --format, or detected from --output
+func exportFormat() string {
+	if outputFormat == "" && outputFile != "" {
+		return utils.DetectFormatFromOutput(outputFile)
+	}
+	return outputFormat
+}
+
+// exportReport exports the results with --format and --output when either is set.
+// A report that cannot be written fails the run like a stream that cannot be opened.
+func exportReport(results []model.LinkResult) error {
+	format := exportFormat()
+	logger.Debugf("Exporting results with format: %s, output: %s", format, outputFile)
+	if format == "" && outputFile == "" {
+		return nil
+	}
+	if err := utils.ExportResults(format, results, reportOptions()); err != nil {
+		return &exitError{code: exitConfigError, err: err}
+	}
+	return nil
+}
+
+// checkFailureThresholds returns an exitError when the results fail the run
+func checkFailureThresholds(policy *utils.FailurePolicy, results []model.LinkResult) error {
+	failures, _ := policy.Exceeded(results)
+	return checkFailureCount(policy, failures)
+}
+
+// checkFailureCount returns an exitError when a number of failing links fails the run
+func checkFailureCount(policy *utils.FailurePolicy, failures int) error {
+	if !policy.ExceedsMaxBroken(failures) {
+		return nil
+	}
+	logger.Infof("%d failing links exceed the threshold of %d", failures, maxBroken)
+	return &exitError{code: exitBrokenLinks}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/pkg/deadlinkr"
+	"github.com/DrakkarStorm/deadlinkr/utils"
+	"github.com/spf13/cobra"
+)
+
+// markdownCmd represents the markdown command
+var markdownCmd = &cobra.Command{
+	Use:   "markdown [paths...]",
+	Short: "Check the links of Markdown files",
+	Long: `Check the links of Markdown files, walking directories for .md and .markdown files (the current directory by default).
+Relative links are checked on disk, including the heading anchors of the Markdown files they point to, and absolute links over HTTP.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths := args
+		if len(paths) == 0 {
+			paths = []string{"."}
+		}
+
+		// Arguments are valid past this point, failures are reported by Execute through the exit code
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+
+		policy, err := failurePolicy()
+		if err != nil {
+			return err
+		}
+
+		for _, path := range paths {
+			if _, err := os.Stat(path); err != nil {
+				return &exitError{code: exitConfigError, err: err}
+			}
+		}
+
+		scanner, err := deadlinkr.NewScanner(scannerOptions())
+		if err != nil {
+			return &exitError{code: exitConfigError, err: err}
+		}
+
+		logger.Debugf("Checking links of Markdown files in %v", paths)
+
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		results, err := scanner.CheckMarkdown(ctx, paths...)
+		if err != nil {
+			logger.Errorf("Error during check: %s", err)
+			return &exitError{code: exitCrawlError, err: err}
+		}
+
+		logger.Infof("Check complete. Found %d links, %d broken.", len(results), utils.CountBrokenLinks(results))
+
+		if err := exportReport(results); err != nil {
+			return err
+		}
+
+		return checkFailureThresholds(policy, results)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(markdownCmd)
+
+	markdownCmd.Flags().IntVarP(&options.Concurrency, "concurrency", "c", 20, "Number of links checked at once")
+	markdownCmd.Flags().StringVar(&options.MarkdownRoot, "root", "", "Directory root-relative links like /docs/guide.md point into, e.g. the repository root (default: the current directory)")
+}
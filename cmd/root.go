@@ -1,15 +1,64 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"time"
 
 	"github.com/DrakkarStorm/deadlinkr/logger"
-	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/DrakkarStorm/deadlinkr/pkg/deadlinkr"
+	"github.com/DrakkarStorm/deadlinkr/utils"
 	"github.com/spf13/cobra"
 )
 
+// options holds the scanner options set by the flags. Options the flags express in other units
+// are applied by scannerOptions.
+var options = deadlinkr.DefaultOptions()
+
+// Settings of the command line itself
+var (
+	configFile      string // Configuration file in use, if any
+	quiet           bool
+	logLevel        string
+	timeoutSeconds  int
+	cacheTTLMinutes int
+	outputFile      string
+	outputFormat    string
+	showAll         bool
+	onlyExternal    bool
+	failOn          []string // Failure categories failing the run
+	maxBroken       int      // Failing links tolerated before the run fails
+	failOnExternal  bool
+	failOnNewOnly   bool
+)
+
+// scannerOptions returns the scanner options of the flags
+func scannerOptions() deadlinkr.Options {
+	scanner := options
+	scanner.Timeout = time.Duration(timeoutSeconds) * time.Second
+	scanner.CacheTTL = time.Duration(cacheTTLMinutes) * time.Minute
+	// Keep the progress bar out of reports written to the standard output
+	scanner.ShowProgress = !quiet && !reportsToStdout()
+	return scanner
+}
+
+// reportsToStdout checks whether the report is written to the standard output, for "-" and
+// for the text format without output file
+func reportsToStdout() bool {
+	return outputFile == "-" || (outputFile == "" && exportFormat() == "text")
+}
+
+// reportOptions returns the report settings of the flags
+func reportOptions() utils.ReportOptions {
+	return utils.ReportOptions{
+		Output:       outputFile,
+		ShowAll:      showAll,
+		OnlyInternal: options.OnlyInternal,
+		OnlyExternal: onlyExternal,
+	}
+}
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "deadlinkr",
@@ -18,14 +67,24 @@ var rootCmd = &cobra.Command{
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		// This function is executed before each command, after the flags have been parsed
 
-		model.TimeExecution = time.Now()
-
-        if model.LogLevel == "" {
-            model.LogLevel = "info"
-        }
-        fmt.Println("Initializing logger with level:", model.LogLevel)
-        logger.InitLogger(model.LogLevel)
-    },
+		logger.SetStartTime(time.Now())
+		logger.SetQuiet(quiet)
+
+		// Apply the configuration file and environment to flags not set on the command line
+		if err := loadConfiguration(cmd); err != nil {
+			fmt.Fprintln(os.Stderr, "Error loading configuration:", err)
+			os.Exit(exitConfigError)
+		}
+
+		if logLevel == "" {
+			logLevel = "info"
+		}
+		// Diagnostics go to the standard error, the standard output may carry the report
+		if !quiet {
+			fmt.Fprintln(os.Stderr, "Initializing logger with level:", logLevel)
+		}
+		logger.InitLogger(logLevel)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -35,45 +94,67 @@ func Execute() {
 
 	err := rootCmd.Execute()
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		// Cobra already printed its own flag and argument errors
+		var exitErr *exitError
+		if errors.As(err, &exitErr) && exitErr.err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", exitErr.err)
+		}
+		logger.CloseLogger()
+		os.Exit(exitCodeOf(err))
 	}
 }
 
 func init() {
-	rootCmd.PersistentFlags().BoolVar(&model.Quiet, "quiet", false, "Disable output")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Configuration file (default: deadlinkr.yaml in the current directory or $HOME/.config/deadlinkr/)")
+
+	rootCmd.PersistentFlags().BoolVar(&quiet, "quiet", false, "Disable output")
+
+    rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Log level (debug, info, warn, error, fatal)")
+
+	rootCmd.PersistentFlags().IntVarP(&timeoutSeconds, "timeout", "t", 15, "Request timeout in seconds")
 
-    rootCmd.PersistentFlags().StringVar(&model.LogLevel, "log-level", "info", "Log level (debug, info, warn, error, fatal)")
+	rootCmd.PersistentFlags().BoolVar(&options.OnlyInternal, "only-internal", false, "Check only internal links")
 
-	rootCmd.PersistentFlags().IntVarP(&model.Timeout, "timeout", "t", 15, "Request timeout in seconds")
+	rootCmd.PersistentFlags().StringVar(&options.UserAgent, "user-agent", "DeadLinkr/1.0", "Custom user agent")
 
-	rootCmd.PersistentFlags().BoolVar(&model.OnlyInternal, "only-internal", false, "Check only internal links")
+	rootCmd.PersistentFlags().BoolVar(&options.RespectRobots, "respect-robots", true, "Honor robots.txt Disallow and Crawl-delay rules for the user agent")
+	rootCmd.PersistentFlags().BoolVar(&options.CheckDisallowed, "check-disallowed", false, "Check links disallowed by robots.txt without crawling them")
 
-	rootCmd.PersistentFlags().StringVar(&model.UserAgent, "user-agent", "DeadLinkr/1.0", "Custom user agent")
+	rootCmd.PersistentFlags().StringVar(&options.IncludePattern, "include-pattern", "", "Only include URLs matching this regex")
+	rootCmd.PersistentFlags().StringVar(&options.ExcludePattern, "exclude-pattern", "", "Exclude URLs matching this regex")
 
-	rootCmd.PersistentFlags().StringVar(&model.IncludePattern, "include-pattern", "", "Only include URLs matching this regex")
-	rootCmd.PersistentFlags().StringVar(&model.ExcludePattern, "exclude-pattern", "", "Exclude URLs matching this regex")
+	rootCmd.PersistentFlags().StringVar(&options.ExcludeHtmlTags, "exclude-html-tags", "", "Exclude specific HTML tags separated by commas")
+	rootCmd.PersistentFlags().StringSliceVar(&options.IncludeElements, "include-elements", []string{}, "Only check links from these element types (a, area, img, script, link, canonical, source, iframe, form, video, audio, meta)")
+	rootCmd.PersistentFlags().BoolVar(&options.CheckAnchors, "check-anchors", false, "Report #fragment links whose target page has no matching id or name")
+	rootCmd.PersistentFlags().BoolVar(&options.DetectSoft404, "soft-404", false, "Report pages answering 200 that look like their site's not found page, probing a random path per host")
+	rootCmd.PersistentFlags().StringSliceVar(&options.Soft404Phrases, "soft-404-phrases", []string{}, "Report pages containing any of these phrases as soft 404s, e.g. \"Page not found\"")
+	rootCmd.PersistentFlags().StringSliceVar(&options.ExcludeElements, "exclude-elements", []string{}, "Do not check links from these element types")
+	rootCmd.PersistentFlags().StringSliceVar(&options.FlagRedirects, "flag-redirects", []string{}, "Report redirect problems: permanent (link should be updated), chain, downgrade (https to http), login, or all")
+	rootCmd.PersistentFlags().IntVar(&options.MaxRedirectHops, "max-redirect-hops", 3, "Redirect chains longer than this are reported with --flag-redirects=chain")
 
-	rootCmd.PersistentFlags().StringVar(&model.ExcludeHtmlTags, "exclude-html-tags", "", "Exclude specific HTML tags separated by commas")
+	rootCmd.PersistentFlags().BoolVar(&showAll, "show-all", false, "Show all links including working ones (default: only broken links)")
+	rootCmd.PersistentFlags().BoolVar(&onlyExternal, "only-external", false, "Show only external links")
 
-	rootCmd.PersistentFlags().BoolVar(&model.ShowAll, "show-all", false, "Show all links including working ones (default: only broken links)")
-	rootCmd.PersistentFlags().BoolVar(&model.DisplayOnlyExternal, "only-external", false, "Show only external links")
+	rootCmd.PersistentFlags().StringSliceVar(&failOn, "fail-on", append([]string{}, utils.DefaultFailOn...), "Failure categories that fail the run with exit code 1 (4xx, 5xx, timeout, error, anchor, soft404, redirect)")
+	rootCmd.PersistentFlags().IntVar(&maxBroken, "max-broken", 0, "Number of failing links tolerated before the run fails")
+	rootCmd.PersistentFlags().BoolVar(&failOnExternal, "fail-on-external", true, "Count failing external links against the failure thresholds")
 
-	rootCmd.PersistentFlags().StringVarP(&model.Output, "output", "o", "", "Output file path (format auto-detected from extension: .csv, .json, .html)")
-	rootCmd.PersistentFlags().StringVarP(&model.Format, "format", "f", "", "Export format (csv, json, html) - overrides auto-detection from output file")
+	rootCmd.PersistentFlags().StringVarP(&outputFile, "output", "o", "", "Output file path (format auto-detected from extension: .csv, .json, .ndjson, .html, .xml, .sarif), or - for the standard output")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "format", "f", "", "Export format (csv, json, ndjson, html, junit, sarif, text) - overrides auto-detection from output file; csv, ndjson and text reports are written while scanning")
 
-	rootCmd.PersistentFlags().Float64Var(&model.RateLimitRequestsPerSecond, "rate-limit", 2.0, "Requests per second per domain")
-	rootCmd.PersistentFlags().Float64Var(&model.RateLimitBurst, "rate-burst", 5.0, "Burst capacity for rate limiting")
+	rootCmd.PersistentFlags().Float64Var(&options.RateLimit, "rate-limit", 2.0, "Requests per second per domain")
+	rootCmd.PersistentFlags().Float64Var(&options.RateBurst, "rate-burst", 5.0, "Burst capacity for rate limiting")
 
-	rootCmd.PersistentFlags().BoolVar(&model.OptimizeWithHeadRequests, "optimize-head", true, "Use HEAD requests when possible to reduce bandwidth")
+	rootCmd.PersistentFlags().BoolVar(&options.OptimizeWithHeadRequests, "optimize-head", true, "Use HEAD requests when possible to reduce bandwidth")
 
-	rootCmd.PersistentFlags().BoolVar(&model.CacheEnabled, "cache", true, "Enable intelligent caching of link check results")
-	rootCmd.PersistentFlags().IntVar(&model.CacheSize, "cache-size", 1000, "Maximum number of entries in the cache")
-	rootCmd.PersistentFlags().IntVar(&model.CacheTTLMinutes, "cache-ttl", 60, "Cache time-to-live in minutes")
+	rootCmd.PersistentFlags().BoolVar(&options.CacheEnabled, "cache", true, "Enable intelligent caching of link check results, requires --optimize-head")
+	rootCmd.PersistentFlags().IntVar(&options.CacheSize, "cache-size", 1000, "Maximum number of entries in the in-memory cache (the persistent cache of --cache-dir is not limited)")
+	rootCmd.PersistentFlags().IntVar(&cacheTTLMinutes, "cache-ttl", 60, "Cache time-to-live in minutes")
+	rootCmd.PersistentFlags().StringVar(&options.CacheDir, "cache-dir", "", "Directory of a persistent link cache shared across runs, requires --cache and --optimize-head (disabled if empty)")
 
 	// Authentication flags
-	rootCmd.PersistentFlags().StringVar(&model.AuthBasic, "auth-basic", "", "Basic authentication in 'user:password' format (or use DEADLINKR_AUTH_USER/DEADLINKR_AUTH_PASS env vars)")
-	rootCmd.PersistentFlags().StringVar(&model.AuthBearer, "auth-bearer", "", "Bearer token authentication (or use DEADLINKR_AUTH_TOKEN env var)")
-	rootCmd.PersistentFlags().StringArrayVar(&model.AuthHeaders, "auth-header", []string{}, "Custom authentication headers in 'Key: Value' format (can be used multiple times)")
-	rootCmd.PersistentFlags().StringVar(&model.AuthCookies, "auth-cookies", "", "Cookie authentication string")
+	rootCmd.PersistentFlags().StringVar(&options.AuthBasic, "auth-basic", "", "Basic authentication in 'user:password' format (or use DEADLINKR_AUTH_USER/DEADLINKR_AUTH_PASS env vars)")
+	rootCmd.PersistentFlags().StringVar(&options.AuthBearer, "auth-bearer", "", "Bearer token authentication (or use DEADLINKR_AUTH_TOKEN env var)")
+	rootCmd.PersistentFlags().StringArrayVar(&options.AuthHeaders, "auth-header", []string{}, "Custom authentication headers in 'Key: Value' format (can be used multiple times)")
+	rootCmd.PersistentFlags().StringVar(&options.AuthCookies, "auth-cookies", "", "Cookie authentication string")
 }
@@ -1,51 +1,158 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
 	"github.com/DrakkarStorm/deadlinkr/logger"
 	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/DrakkarStorm/deadlinkr/pkg/deadlinkr"
 	"github.com/DrakkarStorm/deadlinkr/utils"
 	"github.com/spf13/cobra"
 )
 
+// Settings of the scan command
+var (
+	baseline    string        // JSON report the results are compared to
+	maxDuration time.Duration // Time after which the scan stops (no limit if 0)
+	metricsAddr string        // Address metrics are exposed on (disabled if empty)
+)
+
 // scanCmd represents the scan command
 var scanCmd = &cobra.Command{
 	Use:   "scan [url]",
-	Short: "Scan a website for broken links",
+	Short: "Scan a website or a static site build directory for broken links",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		baseURL := args[0]
 
-		// Reset global state
-		model.Results = []model.LinkResult{}
+		// Arguments are valid past this point, failures are reported by Execute through the exit code
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+
+		policy, err := failurePolicy()
+		if err != nil {
+			return err
+		}
+
+		// Load the baseline before scanning so a bad report fails fast
+		var baselineResults []model.LinkResult
+		if baseline != "" {
+			if baselineResults, err = utils.LoadReport(baseline); err != nil {
+				return &exitError{code: exitConfigError, err: err}
+			}
+		} else if failOnNewOnly {
+			return &exitError{code: exitConfigError, err: errors.New("--fail-on-new-only requires --baseline")}
+		}
+
+		if options.ResumeFile != "" {
+			if _, err := os.Stat(options.ResumeFile); err != nil {
+				return &exitError{code: exitConfigError, err: fmt.Errorf("cannot resume scan: %w", err)}
+			}
+		}
 
-		logger.Debugf("Starting scan of %s with depth %d", baseURL, model.Depth)
+		scanOptions := scannerOptions()
+		if metricsAddr != "" {
+			scanOptions.Metrics = deadlinkr.NewMetrics()
+			stopMetrics, err := utils.StartMetricsServer(metricsAddr, scanOptions.Metrics)
+			if err != nil {
+				return &exitError{code: exitConfigError, err: err}
+			}
+			defer stopMetrics()
+		}
 
-		// Use optimized crawler by default
-		err := utils.CrawlWithOptimizedServices(baseURL, baseURL, 0)
+		logger.Debugf("Starting scan of %s with depth %d", baseURL, scanOptions.Depth)
+
+		ctx, cancel := scanContext(cmd)
+		defer cancel()
+
+		// Auto-detect format from output file if not specified
+		format := exportFormat()
+
+		// Write reports in a streaming format while scanning, unless results must first be compared to the baseline
+		var stream *utils.ResultStream
+		if baselineResults == nil && utils.IsStreamingFormat(format) {
+			if stream, err = utils.OpenResultStream(format, reportOptions(), policy); err != nil {
+				return &exitError{code: exitConfigError, err: err}
+			}
+			defer func() { _ = stream.Close() }()
+			scanOptions.Sink = stream
+		}
+
+		scanner, err := deadlinkr.NewScanner(scanOptions)
+		if err != nil {
+			return &exitError{code: exitConfigError, err: err}
+		}
+
+		// Check a static site build directory from disk, otherwise crawl over HTTP with the optimized crawler
+		results, err := scanner.Scan(ctx, baseURL)
 		if err != nil {
 			logger.Errorf("Error during scan: %s", err)
-			return
+			return &exitError{code: exitCrawlError, err: err}
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			logger.Warnf("Scan stopped after --max-duration of %s, reporting the links checked so far", maxDuration)
 		}
 
-		logger.Infof("Scan complete. Found %d links, %d broken.\n", len(model.Results), utils.CountBrokenLinks())
+		if stream != nil {
+			logger.Infof("Scan complete. Found %d links, %d broken.\n", stream.Links(), stream.Broken())
+			if err := stream.Close(); err != nil {
+				return &exitError{code: exitConfigError, err: fmt.Errorf("error writing %s report: %w", format, err)}
+			}
+			return checkFailureCount(policy, stream.Failures())
+		}
 
-		// Auto-detect format from output file if not specified
-		format := model.Format
-		if format == "" && model.Output != "" {
-			format = utils.DetectFormatFromOutput(model.Output)
+		logger.Infof("Scan complete. Found %d links, %d broken.\n", len(results), utils.CountBrokenLinks(results))
+
+		if baselineResults != nil {
+			results = utils.DiffResults(baselineResults, results)
+			// The summary would corrupt a report written to the standard output
+			if !quiet && outputFile != "-" {
+				utils.DisplayDiff(results, showAll)
+			}
 		}
 
-		logger.Debugf("Exporting results with format: %s, output: %s", format, model.Output)
-		if format != "" || model.Output != "" {
-			utils.ExportResults(format)
+		if err := exportReport(results); err != nil {
+			return err
 		}
+
+		return checkFailureThresholds(policy, results)
 	},
 }
 
+// scanContext returns the context of a scan, ending after --max-duration when set
+func scanContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if maxDuration > 0 {
+		return context.WithTimeout(ctx, maxDuration)
+	}
+	return context.WithCancel(ctx)
+}
+
 func init() {
 	rootCmd.AddCommand(scanCmd)
 
-	scanCmd.PersistentFlags().IntVarP(&model.Concurrency, "concurrency", "c", 20, "Number of concurrent requests")
-	scanCmd.PersistentFlags().IntVarP(&model.Depth, "depth", "d", 1, "Maximum crawl depth")
+	scanCmd.PersistentFlags().IntVarP(&options.Concurrency, "concurrency", "c", 20, "Number of concurrent requests")
+	scanCmd.PersistentFlags().IntVarP(&options.Depth, "depth", "d", 1, "Maximum crawl depth")
+	scanCmd.PersistentFlags().DurationVar(&maxDuration, "max-duration", 0, "Stop the scan after this long, e.g. 10m, reporting the links checked so far (no limit if 0)")
+	scanCmd.PersistentFlags().StringVar(&options.CheckpointFile, "checkpoint", "", "Periodically save the state of the scan to this file, removed once the scan completes, to continue it with --resume if interrupted")
+	scanCmd.PersistentFlags().DurationVar(&options.CheckpointInterval, "checkpoint-interval", time.Minute, "Time between two saves of the scan state")
+	scanCmd.PersistentFlags().StringVar(&options.ResumeFile, "resume", "", "Continue an interrupted scan from the state saved in this file, which keeps being updated unless --checkpoint is set")
+	scanCmd.PersistentFlags().BoolVar(&options.UseSitemap, "sitemap", false, "Also crawl pages listed in sitemaps found via robots.txt or /sitemap.xml, reporting broken sitemap entries")
+	scanCmd.PersistentFlags().StringVar(&baseline, "baseline", "", "JSON report of a previous scan to classify links as newly broken, fixed, still broken, or new and OK")
+	scanCmd.PersistentFlags().BoolVar(&failOnNewOnly, "fail-on-new-only", false, "Only count links newly broken since the baseline against the failure thresholds")
+	scanCmd.PersistentFlags().StringVar(&options.CDPURL, "cdp-url", "", "Render pages in a headless browser through its DevTools endpoint before extracting links, e.g. http://localhost:9222")
+	scanCmd.PersistentFlags().StringVar(&options.CDPWaitUntil, "cdp-wait", "load", "Page event to wait for before extracting links from a rendered page (load, domcontentloaded, networkidle)")
+	scanCmd.PersistentFlags().StringVar(&options.CDPWaitSelector, "cdp-wait-selector", "", "Also wait for an element matching this CSS selector in rendered pages")
+	scanCmd.PersistentFlags().DurationVar(&options.CDPWaitDelay, "cdp-wait-delay", 0, "Extra time given to scripts of rendered pages once the wait conditions are met")
+	scanCmd.PersistentFlags().DurationVar(&options.CDPTimeout, "cdp-timeout", 30*time.Second, "Maximum time to render a page")
+	scanCmd.PersistentFlags().IntVar(&options.CDPMaxTabs, "cdp-max-tabs", 4, "Number of pages rendered at once")
+	scanCmd.PersistentFlags().StringVar(&metricsAddr, "metrics-addr", "", "Expose Prometheus metrics of the scan on this address under /metrics while it runs, e.g. localhost:9090 (disabled if empty)")
 
 }
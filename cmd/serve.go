@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/pkg/deadlinkr"
+	"github.com/DrakkarStorm/deadlinkr/utils"
+	"github.com/spf13/cobra"
+)
+
+// serveShutdownTimeout is the time given to open connections once the scans are cancelled
+const serveShutdownTimeout = 10 * time.Second
+
+// Settings of the serve command
+var (
+	serveAddr        string // Address the scan API listens on
+	serveMaxScans    int    // Number of scans running at once
+	serveRetainScans int    // Number of finished scans kept
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run an HTTP server exposing a REST API to submit, follow and cancel scans",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Arguments are valid past this point, failures are reported by Execute through the exit code
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+
+		// Check the browser settings once rather than on each scan
+		defaults := scannerOptions()
+		defaults.ShowProgress = false
+		if _, err := deadlinkr.NewScanner(defaults); err != nil {
+			return &exitError{code: exitConfigError, err: err}
+		}
+
+		listener, err := net.Listen("tcp", serveAddr)
+		if err != nil {
+			return &exitError{code: exitConfigError, err: err}
+		}
+
+		if metricsAddr != "" {
+			defaults.Metrics = deadlinkr.NewMetrics()
+			stopMetrics, err := utils.StartMetricsServer(metricsAddr, defaults.Metrics)
+			if err != nil {
+				_ = listener.Close()
+				return &exitError{code: exitConfigError, err: err}
+			}
+			defer stopMetrics()
+		}
+
+		scanServer := utils.NewScanServer(defaults, serveMaxScans, serveRetainScans)
+		httpServer := &http.Server{
+			Handler:           scanServer.Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		served := make(chan error, 1)
+		go func() {
+			served <- httpServer.Serve(listener)
+		}()
+		logger.Infof("Scan API listening on http://%s", listener.Addr())
+
+		select {
+		case err := <-served:
+			scanServer.Shutdown()
+			return &exitError{code: exitCrawlError, err: err}
+		case <-ctx.Done():
+		}
+
+		// Cancel the scans first so progress streams end before the server waits for its connections
+		logger.Infof("Shutting down, cancelling running scans...")
+		scanServer.Shutdown()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return &exitError{code: exitCrawlError, err: err}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "localhost:8080", "Address the scan API listens on")
+	serveCmd.Flags().IntVar(&serveMaxScans, "max-scans", 2, "Number of scans running at once, others wait in a queue")
+	serveCmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "Address Prometheus metrics of the scans are exposed on under /metrics (disabled if empty)")
+	serveCmd.Flags().IntVar(&serveRetainScans, "retain-scans", 100, "Number of finished scans kept with their results")
+	serveCmd.Flags().IntVarP(&options.Depth, "depth", "d", 1, "Default maximum crawl depth of submitted scans")
+	serveCmd.Flags().IntVarP(&options.Concurrency, "concurrency", "c", 20, "Default number of concurrent requests of submitted scans")
+}
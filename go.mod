@@ -5,7 +5,10 @@ go 1.25.8
 require (
 	github.com/PuerkitoBio/goquery v1.10.2
 	github.com/spf13/cobra v1.9.1
+	github.com/spf13/pflag v1.0.6
 	github.com/stretchr/testify v1.10.0
+	golang.org/x/net v0.45.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -13,7 +16,4 @@ require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/spf13/pflag v1.0.6 // indirect
-	golang.org/x/net v0.45.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
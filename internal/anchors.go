@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// anchorEntry holds the anchors of one page, loaded at most once
+type anchorEntry struct {
+	once    sync.Once
+	anchors map[string]bool // nil when the page is not an HTML document
+}
+
+// AnchorIndex records the id and name attributes of parsed pages so fragment links can be validated
+type AnchorIndex struct {
+	entries map[string]*anchorEntry
+	mutex   sync.Mutex
+}
+
+// NewAnchorIndex creates a new empty AnchorIndex
+func NewAnchorIndex() *AnchorIndex {
+	return &AnchorIndex{
+		entries: make(map[string]*anchorEntry),
+	}
+}
+
+// Record stores the anchors of a parsed page, unless they are already known
+func (ai *AnchorIndex) Record(pageURL string, doc *goquery.Document) {
+	entry := ai.entry(pageURL)
+	entry.once.Do(func() {
+		entry.anchors = collectAnchors(doc)
+	})
+}
+
+// Lookup returns the anchors of a page, calling load to parse it if it was not recorded yet.
+// It returns nil when the page is not an HTML document.
+func (ai *AnchorIndex) Lookup(pageURL string, load func() *goquery.Document) map[string]bool {
+	entry := ai.entry(pageURL)
+	entry.once.Do(func() {
+		if doc := load(); doc != nil {
+			entry.anchors = collectAnchors(doc)
+		}
+	})
+	return entry.anchors
+}
+
+// entry returns the index entry of a page, creating it if needed
+func (ai *AnchorIndex) entry(pageURL string) *anchorEntry {
+	key := stripFragment(pageURL)
+
+	ai.mutex.Lock()
+	defer ai.mutex.Unlock()
+
+	entry, exists := ai.entries[key]
+	if !exists {
+		entry = &anchorEntry{}
+		ai.entries[key] = entry
+	}
+	return entry
+}
+
+// collectAnchors returns the id and name attribute values of a document
+func collectAnchors(doc *goquery.Document) map[string]bool {
+	anchors := make(map[string]bool)
+	doc.Find("[id], a[name]").Each(func(i int, s *goquery.Selection) {
+		if id, exists := s.Attr("id"); exists && id != "" {
+			anchors[id] = true
+		}
+		if name, exists := s.Attr("name"); exists && name != "" && goquery.NodeName(s) == "a" {
+			anchors[name] = true
+		}
+	})
+	return anchors
+}
+
+// isCheckableFragment reports whether a fragment should exist as an anchor in the target page.
+// Empty and "top" fragments always work, and client-side routes or text fragments are not anchors.
+func isCheckableFragment(fragment string) bool {
+	if fragment == "" || strings.EqualFold(fragment, "top") {
+		return false
+	}
+	return !strings.HasPrefix(fragment, "/") && !strings.HasPrefix(fragment, "!") && !strings.HasPrefix(fragment, ":~:")
+}
+
+// hasAnchor checks a fragment against a set of anchors, accepting percent-encoded ids
+func hasAnchor(anchors map[string]bool, fragment string) bool {
+	if anchors[fragment] {
+		return true
+	}
+	if unescaped, err := url.PathUnescape(fragment); err == nil && anchors[unescaped] {
+		return true
+	}
+	return false
+}
+
+// stripFragment removes the fragment from a URL
+func stripFragment(rawURL string) string {
+	if idx := strings.Index(rawURL, "#"); idx >= 0 {
+		return rawURL[:idx]
+	}
+	return rawURL
+}
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAnchorServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			_, _ = w.Write([]byte(`<html><body>
+<h2 id="local">Local</h2>
+<a href="#local">ok</a>
+<a href="#gone">missing</a>
+<a href="#top">top</a>
+<a href="/docs#intro">ok</a>
+<a href="/docs#renamed">missing</a>
+<a href="/docs#legacy">ok by name</a>
+<a href="/docs#caf%C3%A9">ok encoded</a>
+<a href="/image.png#part">not html</a>
+</body></html>`))
+		case "/docs":
+			_, _ = w.Write([]byte(`<html><body><h1 id="intro">Intro</h1><a name="legacy"></a><h2 id="café">Café</h2></body></html>`))
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestAnchorValidation(t *testing.T) {
+	logger.SetQuiet(true)
+
+	server := newAnchorServer()
+	defer server.Close()
+
+	factory := NewServiceFactory()
+	config := factory.CreateCrawlConfigFromParams(1, 2, false, "", "", "")
+	config.CheckAnchors = true
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	crawler, err := factory.CreateOptimizedCrawlerServiceWithRateLimit(config, "TestAgent", 5*time.Second, httpClient, 100, 100)
+	require.NoError(t, err)
+	defer crawler.Stop()
+
+	require.NoError(t, crawler.Crawl(context.Background(), server.URL, server.URL+"/", 0))
+	crawler.Wait()
+
+	failures := make(map[string]string)
+	for _, result := range crawler.GetResults() {
+		failures[strings.TrimPrefix(result.TargetURL, server.URL)] = result.FailureType
+	}
+
+	assert.Equal(t, map[string]string{
+		"/#local":         "",
+		"/#gone":          model.FailureMissingAnchor,
+		"/docs#intro":     "",
+		"/docs#renamed":   model.FailureMissingAnchor,
+		"/docs#legacy":    "",
+		"/docs#caf%C3%A9": "",
+		"/image.png#part": "",
+	}, failures)
+	assert.Equal(t, 2, crawler.CountBrokenLinks())
+}
+
+func TestAnchorValidationDisabled(t *testing.T) {
+	logger.SetQuiet(true)
+
+	server := newAnchorServer()
+	defer server.Close()
+
+	factory := NewServiceFactory()
+	config := factory.CreateCrawlConfigFromParams(0, 2, false, "", "", "")
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	crawler, err := factory.CreateOptimizedCrawlerServiceWithRateLimit(config, "TestAgent", 5*time.Second, httpClient, 100, 100)
+	require.NoError(t, err)
+	defer crawler.Stop()
+
+	require.NoError(t, crawler.Crawl(context.Background(), server.URL, server.URL+"/", 0))
+	crawler.Wait()
+
+	// Same-page links are skipped and fragments are not validated
+	assert.Len(t, crawler.GetResults(), 5)
+	assert.Equal(t, 0, crawler.CountBrokenLinks())
+}
+
+func TestAnchorIndex(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<div id="a"></div><a name="b"></a><span name="c"></span>`))
+	require.NoError(t, err)
+
+	index := NewAnchorIndex()
+	index.Record("https://example.com/page#ignored", doc)
+
+	loads := 0
+	anchors := index.Lookup("https://example.com/page", func() *goquery.Document {
+		loads++
+		return nil
+	})
+	assert.Equal(t, 0, loads)
+	assert.Equal(t, map[string]bool{"a": true, "b": true}, anchors)
+
+	assert.Nil(t, index.Lookup("https://example.com/file.pdf", func() *goquery.Document { return nil }))
+
+	assert.False(t, isCheckableFragment("top"))
+	assert.False(t, isCheckableFragment("/route"))
+	assert.False(t, isCheckableFragment(":~:text=hello"))
+	assert.True(t, isCheckableFragment("section-1"))
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
 )
 
 // AuthConfig holds authentication configuration
@@ -28,6 +29,9 @@ type AuthConfig struct {
 	// Cookies
 	Cookies        string
 	CookiesEnabled bool
+	
+	// Per-domain credentials and headers, keyed by host or "*.domain" pattern
+	DomainRules map[string]model.DomainRule
 }
 
 // NewAuthConfig creates a new authentication configuration
@@ -39,12 +43,12 @@ func NewAuthConfig() *AuthConfig {
 
 // AuthenticatedHTTPClient wraps an HTTP client with authentication capabilities
 type AuthenticatedHTTPClient struct {
-	client *http.Client
+	client HTTPClient
 	config *AuthConfig
 }
 
 // NewAuthenticatedHTTPClient creates a new authenticated HTTP client
-func NewAuthenticatedHTTPClient(client *http.Client, config *AuthConfig) *AuthenticatedHTTPClient {
+func NewAuthenticatedHTTPClient(client HTTPClient, config *AuthConfig) *AuthenticatedHTTPClient {
 	if config == nil {
 		config = NewAuthConfig()
 	}
@@ -94,9 +98,32 @@ func (ac *AuthenticatedHTTPClient) applyAuthentication(req *http.Request) error
 		}
 	}
 	
+	// Apply the credentials and headers of the request's domain last, so they replace the global ones
+	ac.applyDomainRule(req)
+	
 	return nil
 }
 
+// applyDomainRule applies the credentials and headers configured for the host of the request
+func (ac *AuthenticatedHTTPClient) applyDomainRule(req *http.Request) {
+	rule, found := lookupDomain(ac.config.DomainRules, req.URL.Host)
+	if !found {
+		return
+	}
+	
+	if rule.BasicUser != "" {
+		req.SetBasicAuth(rule.BasicUser, rule.BasicPassword)
+		logger.Debugf("Applied basic auth of %s for user: %s", req.URL.Host, rule.BasicUser)
+	}
+	if rule.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+rule.BearerToken)
+		logger.Debugf("Applied bearer token of %s", req.URL.Host)
+	}
+	for key, value := range rule.Headers {
+		req.Header.Set(key, value)
+	}
+}
+
 // applyBasicAuth applies Basic Authentication to the request
 func (ac *AuthenticatedHTTPClient) applyBasicAuth(req *http.Request) error {
 	if ac.config.BasicUser == "" || ac.config.BasicPassword == "" {
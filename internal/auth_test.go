@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"encoding/base64"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -8,6 +9,8 @@ import (
 
 	"github.com/DrakkarStorm/deadlinkr/logger"
 	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func init() {
@@ -383,28 +386,9 @@ func TestAuthSummary(t *testing.T) {
 
 // Test integration with factory
 func TestFactoryAuthIntegration(t *testing.T) {
-	// Save original model values
-	origBasic := model.AuthBasic
-	origBearer := model.AuthBearer
-	origHeaders := model.AuthHeaders
-	origCookies := model.AuthCookies
-	
-	// Restore after test
-	defer func() {
-		model.AuthBasic = origBasic
-		model.AuthBearer = origBearer
-		model.AuthHeaders = origHeaders
-		model.AuthCookies = origCookies
-	}()
-
-	t.Run("Factory creates authenticated client from model config", func(t *testing.T) {
-		// Set up model config
-		model.AuthBasic = "user:pass"
-		model.AuthBearer = "token123"
-		model.AuthHeaders = []string{"X-API-Key: secret", "X-Version: 1.0"}
-		model.AuthCookies = "session=abc123"
-		
+	t.Run("Factory creates authenticated client from its credentials", func(t *testing.T) {
 		factory := NewServiceFactory()
+		factory.SetCredentials("user:pass", "token123", []string{"X-API-Key: secret", "X-Version: 1.0"}, "session=abc123")
 		authClient := factory.createAuthenticatedClient(&http.Client{})
 		
 		if authClient == nil {
@@ -426,4 +410,35 @@ func TestFactoryAuthIntegration(t *testing.T) {
 			t.Error("Expected cookies to be configured")
 		}
 	})
-}
\ No newline at end of file
+}
+func TestAuthenticatedHTTPClientDomainRules(t *testing.T) {
+	var authorization, apiKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization, apiKey = r.Header.Get("Authorization"), r.Header.Get("X-Api-Key")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	config := NewAuthConfig()
+	config.BearerToken, config.BearerEnabled = "global-token", true
+	config.DomainRules = map[string]model.DomainRule{
+		host: {BasicUser: "user", BasicPassword: "pass", Headers: map[string]string{"X-Api-Key": "abc"}},
+	}
+	client := NewAuthenticatedHTTPClient(&http.Client{}, config)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte("user:pass")), authorization, "domain credentials replace the global ones")
+	assert.Equal(t, "abc", apiKey)
+
+	req, err = http.NewRequest(http.MethodGet, "http://other.invalid/", nil)
+	require.NoError(t, err)
+	require.NoError(t, client.applyAuthentication(req))
+	assert.Equal(t, "Bearer global-token", req.Header.Get("Authorization"))
+	assert.Empty(t, req.Header.Get("X-Api-Key"))
+}
@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+)
+
+// maxThrottledAttempts is the number of times a request asked to slow down is sent again once its domain backed off
+const maxThrottledAttempts = 3
+
+// ThrottledErrorMessage is the error message of links whose domain still asked to slow down after backing off
+const ThrottledErrorMessage = "throttled: the domain still asks to slow down after backing off"
+
+// maxRetryAfter caps the pause a Retry-After header can impose on a domain
+const maxRetryAfter = 5 * time.Minute
+
+// parseRetryAfter reads a Retry-After header given as a number of seconds or as an HTTP date.
+// It returns false when the header is missing or invalid.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		delay = date.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+	} else {
+		return 0, false
+	}
+
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+	return delay, true
+}
+
+// sleepContext pauses for a duration, returning the context error if ctx is done first
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isThrottledResponse checks whether a response asks to send the request again later:
+// 429 Too Many Requests, or 503 Service Unavailable with a Retry-After
+func isThrottledResponse(resp *model.HTTPResponse) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	_, hasRetryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	return resp.StatusCode == http.StatusServiceUnavailable && hasRetryAfter
+}
+
+// fetchWithBackoff sends a request with fetch, which waits for the rate limiter. When the domain answers
+// 429 Too Many Requests, or 503 Service Unavailable with a Retry-After, the domain is throttled and the request
+// is sent again once the rate limiter lets it through, up to maxThrottledAttempts times and as long as sending
+// it again, after its Retry-After, takes no longer than maxWait in total. Other answers let the
+// domain recover toward its configured rate.
+func fetchWithBackoff(limiter *DomainRateLimiter, targetURL string, maxWait time.Duration, fetch func() (*model.HTTPResponse, error)) (*model.HTTPResponse, error) {
+	domain, err := extractDomain(targetURL)
+	if err != nil {
+		return fetch()
+	}
+
+	var throttledSince time.Time
+	for attempt := 0; ; attempt++ {
+		resp, err := fetch()
+		if err != nil {
+			return resp, err
+		}
+		if attempt == 0 {
+			throttledSince = time.Now()
+		}
+
+		retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		throttled := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+		if !throttled {
+			limiter.Recover(domain)
+			return resp, nil
+		}
+
+		limiter.Throttle(domain, retryAfter)
+		if !isThrottledResponse(resp) || attempt == maxThrottledAttempts {
+			return resp, nil
+		}
+		if time.Since(throttledSince)+retryAfter > maxWait {
+			logger.Debugf("%s asks to wait %v, not checking %s again beyond %v", domain, retryAfter, targetURL, maxWait)
+			return resp, nil
+		}
+
+		logger.Debugf("%s answered %d, checking %s again after backing off", domain, resp.StatusCode, targetURL)
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		if err := resp.Body.Close(); err != nil {
+			logger.Errorf("Error closing response body for %s: %s", targetURL, err)
+		}
+	}
+}
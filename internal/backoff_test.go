@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+		valid    bool
+	}{
+		{name: "Seconds", value: "120", expected: 2 * time.Minute, valid: true},
+		{name: "HTTP date", value: "Fri, 02 Jan 2026 15:04:35 GMT", expected: 30 * time.Second, valid: true},
+		{name: "Past date", value: "Fri, 02 Jan 2026 15:00:00 GMT", expected: 0, valid: true},
+		{name: "Capped", value: "86400", expected: maxRetryAfter, valid: true},
+		{name: "Missing", value: ""},
+		{name: "Negative", value: "-5"},
+		{name: "Invalid", value: "soon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, valid := parseRetryAfter(tt.value, now)
+			assert.Equal(t, tt.valid, valid)
+			assert.Equal(t, tt.expected, delay)
+		})
+	}
+}
+
+func TestDomainRateLimiterThrottle(t *testing.T) {
+	logger.SetQuiet(true)
+
+	limiter := NewDomainRateLimiter(4, 2)
+	require.NoError(t, limiter.Wait(context.Background(), "https://example.com/"))
+
+	limiter.Throttle("example.com", 0)
+	limiter.Throttle("example.com", 0)
+	assert.Equal(t, 1.0, limiter.GetStats()["example.com"].Rate, "each throttle halves the rate")
+
+	for i := 0; i < 5; i++ {
+		limiter.Recover("example.com")
+	}
+	assert.InDelta(t, 3.0, limiter.GetStats()["example.com"].Rate, 0.001, "the rate recovers step by step")
+	for i := 0; i < 10; i++ {
+		limiter.Recover("example.com")
+	}
+	assert.Equal(t, 4.0, limiter.GetStats()["example.com"].Rate, "the rate never exceeds the configured one")
+
+	for i := 0; i < 20; i++ {
+		limiter.Throttle("example.com", 0)
+	}
+	assert.Equal(t, minThrottledRate, limiter.GetStats()["example.com"].Rate)
+
+	t.Run("Retry-After pauses the domain", func(t *testing.T) {
+		limiter := NewDomainRateLimiter(100, 10)
+		limiter.Throttle("example.org", 200*time.Millisecond)
+
+		start := time.Now()
+		require.NoError(t, limiter.Wait(context.Background(), "https://example.org/"))
+		assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+		start = time.Now()
+		require.NoError(t, limiter.Wait(context.Background(), "https://example.net/"))
+		assert.Less(t, time.Since(start), 100*time.Millisecond, "other domains are not paused")
+	})
+}
+
+func TestLinkCheckerBacksOff(t *testing.T) {
+	logger.SetQuiet(true)
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/busy":
+			// Rate limited twice, then served
+			if requests.Add(1) <= 2 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><body>Page</body></html>"))
+	}))
+	defer server.Close()
+	domain, err := extractDomain(server.URL)
+	require.NoError(t, err)
+
+	checker := NewCachedOptimizedLinkCheckerService(&http.Client{Timeout: 5 * time.Second}, "Test/1.0", 5*time.Second, 20, 20, 100, time.Hour)
+
+	status, errMsg := checker.CheckLink(context.Background(), server.URL+"/busy")
+	assert.Equal(t, http.StatusOK, status, "throttled checks are sent again instead of being reported")
+	assert.Empty(t, errMsg)
+	assert.Equal(t, int32(3), requests.Load())
+	assert.Less(t, checker.GetRateLimiterStats()[domain].Rate, 20.0, "the domain was throttled")
+
+	status, _ = checker.CheckLink(context.Background(), server.URL+"/down")
+	assert.Equal(t, http.StatusServiceUnavailable, status, "503 without Retry-After is reported right away")
+
+	t.Run("Persistent 429 is reported but not cached", func(t *testing.T) {
+		var limited atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limited.Add(1)
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		status, _ := checker.CheckLink(context.Background(), server.URL)
+		assert.Equal(t, http.StatusTooManyRequests, status)
+		assert.Equal(t, int32(1+maxThrottledAttempts), limited.Load(), "the check is sent again a bounded number of times")
+		assert.Equal(t, 2, checker.GetCacheStats().Size, "only the links of the first server are cached")
+	})
+
+	t.Run("Persistent 503 with Retry-After is reported but not cached", func(t *testing.T) {
+		var unavailable atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			unavailable.Add(1)
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		status, errMsg := checker.CheckLink(context.Background(), server.URL)
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+		assert.Equal(t, ThrottledErrorMessage, errMsg)
+		assert.Equal(t, int32(1+maxThrottledAttempts), unavailable.Load())
+		assert.Equal(t, 2, checker.GetCacheStats().Size, "only the links of the first server are cached")
+	})
+
+	t.Run("Retry-After beyond the timeout is not waited for", func(t *testing.T) {
+		var limited atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limited.Add(1)
+			w.Header().Set("Retry-After", "86400")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		checker := NewOptimizedLinkCheckerService(&http.Client{}, "Test/1.0", time.Second, 20, 20)
+		start := time.Now()
+		status, errMsg := checker.CheckLink(context.Background(), server.URL)
+		assert.Equal(t, http.StatusTooManyRequests, status)
+		assert.Equal(t, ThrottledErrorMessage, errMsg)
+		assert.Equal(t, int32(1), limited.Load(), "the check is not sent again after a wait longer than the timeout")
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
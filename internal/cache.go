@@ -2,6 +2,7 @@ package internal
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -25,9 +26,9 @@ type LinkCache struct {
 	defaultTTL time.Duration
 	maxSize    int
 	
-	// Statistics
-	hits   int64
-	misses int64
+	// Statistics, updated atomically since Get only holds the read lock
+	hits   atomic.Int64
+	misses atomic.Int64
 }
 
 // NewLinkCache creates a new link cache
@@ -46,17 +47,17 @@ func (lc *LinkCache) Get(url string) (int, string, bool) {
 	
 	entry, exists := lc.cache[url]
 	if !exists {
-		lc.misses++
+		lc.misses.Add(1)
 		return 0, "", false
 	}
 	
 	if entry.IsExpired() {
 		// Don't remove here to avoid write lock, cleanup will handle it
-		lc.misses++
+		lc.misses.Add(1)
 		return 0, "", false
 	}
 	
-	lc.hits++
+	lc.hits.Add(1)
 	return entry.Status, entry.Message, true
 }
 
@@ -120,8 +121,8 @@ func (lc *LinkCache) Clear() {
 	defer lc.mutex.Unlock()
 	
 	lc.cache = make(map[string]*CacheEntry)
-	lc.hits = 0
-	lc.misses = 0
+	lc.hits.Store(0)
+	lc.misses.Store(0)
 }
 
 // Cleanup removes expired entries and returns the number of entries removed
@@ -134,20 +135,60 @@ func (lc *LinkCache) Cleanup() int {
 	return initialSize - len(lc.cache)
 }
 
+// Entries returns a copy of all non-expired entries
+func (lc *LinkCache) Entries() map[string]*CacheEntry {
+	lc.mutex.RLock()
+	defer lc.mutex.RUnlock()
+	
+	entries := make(map[string]*CacheEntry, len(lc.cache))
+	for url, entry := range lc.cache {
+		if entry.IsExpired() {
+			continue
+		}
+		entryCopy := *entry
+		entries[url] = &entryCopy
+	}
+	return entries
+}
+
+// Restore loads previously saved entries, keeping their original timestamp and TTL.
+// Expired entries are ignored and the cache size limit still applies. Returns the number of entries restored.
+func (lc *LinkCache) Restore(entries map[string]*CacheEntry) int {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	
+	restored := 0
+	for url, entry := range entries {
+		if entry == nil || entry.IsExpired() {
+			continue
+		}
+		
+		if len(lc.cache) >= lc.maxSize {
+			lc.removeOldest()
+		}
+		
+		entryCopy := *entry
+		lc.cache[url] = &entryCopy
+		restored++
+	}
+	return restored
+}
+
 // Stats returns cache statistics
 func (lc *LinkCache) Stats() CacheStats {
 	lc.mutex.RLock()
 	defer lc.mutex.RUnlock()
 	
-	total := lc.hits + lc.misses
+	hits, misses := lc.hits.Load(), lc.misses.Load()
+	total := hits + misses
 	var hitRate float64
 	if total > 0 {
-		hitRate = float64(lc.hits) / float64(total)
+		hitRate = float64(hits) / float64(total)
 	}
 	
 	return CacheStats{
-		Hits:     lc.hits,
-		Misses:   lc.misses,
+		Hits:     hits,
+		Misses:   misses,
 		Size:     len(lc.cache),
 		HitRate:  hitRate,
 		MaxSize:  lc.maxSize,
@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+)
+
+// CacheStore persists link cache entries between runs
+type CacheStore interface {
+	Load() (map[string]*CacheEntry, error)
+	Save(entries map[string]*CacheEntry) error
+}
+
+// cacheFileName is the name of the cache file inside the cache directory
+const cacheFileName = "link-cache.jsonl"
+
+// FileCacheStore stores cache entries as JSON lines in a file under a cache directory
+type FileCacheStore struct {
+	path string
+}
+
+// persistedCacheEntry is the on-disk representation of a CacheEntry
+type persistedCacheEntry struct {
+	URL        string    `json:"url"`
+	Status     int       `json:"status"`
+	Message    string    `json:"message,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+	TTLSeconds float64   `json:"ttl_seconds"`
+}
+
+// newPersistedCacheEntry returns the on-disk representation of the cache entry of a URL
+func newPersistedCacheEntry(url string, entry *CacheEntry) persistedCacheEntry {
+	return persistedCacheEntry{
+		URL:        url,
+		Status:     entry.Status,
+		Message:    entry.Message,
+		Timestamp:  entry.Timestamp,
+		TTLSeconds: entry.TTL.Seconds(),
+	}
+}
+
+// entry returns the cache entry of a record
+func (record persistedCacheEntry) entry() *CacheEntry {
+	return &CacheEntry{
+		Status:    record.Status,
+		Message:   record.Message,
+		Timestamp: record.Timestamp,
+		TTL:       time.Duration(record.TTLSeconds * float64(time.Second)),
+	}
+}
+
+// NewFileCacheStore creates a file-backed cache store, creating the directory if needed
+func NewFileCacheStore(dir string) (*FileCacheStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("cache directory is empty")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory %s: %w", dir, err)
+	}
+
+	return &FileCacheStore{
+		path: filepath.Join(dir, cacheFileName),
+	}, nil
+}
+
+// Path returns the location of the cache file
+func (fcs *FileCacheStore) Path() string {
+	return fcs.path
+}
+
+// Load reads all non-expired entries from the cache file.
+// A missing file is not an error and yields an empty cache.
+func (fcs *FileCacheStore) Load() (map[string]*CacheEntry, error) {
+	entries := make(map[string]*CacheEntry)
+
+	file, err := os.Open(fcs.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return entries, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			logger.Errorf("Error closing cache file %s: %s", fcs.path, err)
+		}
+	}()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		var record persistedCacheEntry
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			logger.Warnf("Skipping invalid cache entry at %s:%d: %s", fcs.path, lineNumber, err)
+			continue
+		}
+
+		entry := record.entry()
+		if record.URL == "" || entry.IsExpired() {
+			continue
+		}
+
+		entries[record.URL] = entry
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Save writes the given entries to the cache file, replacing its previous content.
+// The file is written to a temporary location first so a crash never leaves it truncated.
+func (fcs *FileCacheStore) Save(entries map[string]*CacheEntry) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(fcs.path), cacheFileName+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+
+	writer := bufio.NewWriter(tmpFile)
+	encoder := json.NewEncoder(writer)
+	for url, entry := range entries {
+		if entry.IsExpired() {
+			continue
+		}
+
+		if err := encoder.Encode(newPersistedCacheEntry(url, entry)); err != nil {
+			_ = tmpFile.Close()
+			_ = os.Remove(tmpPath)
+			return err
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, fcs.path)
+}
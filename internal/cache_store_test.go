@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileCacheStore(t *testing.T) {
+	logger.SetQuiet(true)
+
+	t.Run("Round trips entries with their TTL", func(t *testing.T) {
+		store, err := NewFileCacheStore(t.TempDir())
+		require.NoError(t, err)
+
+		timestamp := time.Now().Add(-time.Minute).Truncate(time.Second)
+		err = store.Save(map[string]*CacheEntry{
+			"https://example.com":         {Status: 200, Timestamp: timestamp, TTL: 2 * time.Hour},
+			"https://example.com/missing": {Status: 404, Message: "not found", Timestamp: timestamp, TTL: time.Hour},
+		})
+		require.NoError(t, err)
+
+		entries, err := store.Load()
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+
+		entry := entries["https://example.com/missing"]
+		require.NotNil(t, entry)
+		assert.Equal(t, 404, entry.Status)
+		assert.Equal(t, "not found", entry.Message)
+		assert.Equal(t, time.Hour, entry.TTL)
+		assert.True(t, timestamp.Equal(entry.Timestamp))
+	})
+
+	t.Run("Skips expired entries", func(t *testing.T) {
+		store, err := NewFileCacheStore(t.TempDir())
+		require.NoError(t, err)
+
+		err = store.Save(map[string]*CacheEntry{
+			"https://example.com/old": {Status: 200, Timestamp: time.Now().Add(-2 * time.Hour), TTL: time.Hour},
+			"https://example.com/new": {Status: 200, Timestamp: time.Now(), TTL: time.Hour},
+		})
+		require.NoError(t, err)
+
+		entries, err := store.Load()
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Contains(t, entries, "https://example.com/new")
+	})
+
+	t.Run("Missing file yields empty cache", func(t *testing.T) {
+		store, err := NewFileCacheStore(t.TempDir())
+		require.NoError(t, err)
+
+		entries, err := store.Load()
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("Ignores corrupted lines", func(t *testing.T) {
+		store, err := NewFileCacheStore(t.TempDir())
+		require.NoError(t, err)
+
+		content := "not json\n" +
+			`{"url":"https://example.com","status":200,"timestamp":"` + time.Now().Format(time.RFC3339) + `","ttl_seconds":3600}` + "\n"
+		require.NoError(t, os.WriteFile(store.Path(), []byte(content), 0o644))
+
+		entries, err := store.Load()
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+}
+
+func TestCachedLinkCheckerPersistence(t *testing.T) {
+	logger.SetQuiet(true)
+
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><body>OK</body></html>"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	// First run checks the link and flushes the cache to disk
+	store, err := NewFileCacheStore(dir)
+	require.NoError(t, err)
+	first := NewCachedOptimizedLinkCheckerService(client, "Test/1.0", 5*time.Second, 100, 100, 10, time.Hour)
+	require.NoError(t, first.SetStore(store))
+
+	status, _ := first.CheckLink(context.Background(), server.URL)
+	assert.Equal(t, 200, status)
+	require.NoError(t, first.Flush())
+	requestsAfterFirstRun := atomic.LoadInt64(&requests)
+
+	// Second run loads the cache and does not hit the server again
+	store, err = NewFileCacheStore(dir)
+	require.NoError(t, err)
+	second := NewCachedOptimizedLinkCheckerService(client, "Test/1.0", 5*time.Second, 100, 100, 10, time.Hour)
+	require.NoError(t, second.SetStore(store))
+
+	status, _ = second.CheckLink(context.Background(), server.URL)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, requestsAfterFirstRun, atomic.LoadInt64(&requests))
+	assert.Equal(t, int64(1), second.GetCacheStats().Hits)
+
+	// TTL stored on disk is the one chosen by IntelligentTTLStrategy
+	entries, err := store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, IntelligentTTLStrategy(200, time.Hour), entries[server.URL].TTL)
+}
+
+func TestCachedLinkCheckerPersistenceBeyondCacheSize(t *testing.T) {
+	logger.SetQuiet(true)
+
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><body>OK</body></html>"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	client := &http.Client{Timeout: 5 * time.Second}
+	links := []string{server.URL + "/a", server.URL + "/b", server.URL + "/c", server.URL + "/d"}
+
+	// The in-memory cache holds 2 entries, fewer than the links checked
+	store, err := NewFileCacheStore(dir)
+	require.NoError(t, err)
+	first := NewCachedOptimizedLinkCheckerService(client, "Test/1.0", 5*time.Second, 100, 100, 2, time.Hour)
+	require.NoError(t, first.SetStore(store))
+	for _, link := range links {
+		status, _ := first.CheckLink(context.Background(), link)
+		assert.Equal(t, 200, status)
+	}
+	require.NoError(t, first.Flush())
+
+	entries, err := store.Load()
+	require.NoError(t, err)
+	assert.Len(t, entries, len(links), "entries evicted from the cache are saved")
+
+	// Another run with the same small cache reuses every saved entry and saves them again
+	requestsAfterFirstRun := atomic.LoadInt64(&requests)
+	second := NewCachedOptimizedLinkCheckerService(client, "Test/1.0", 5*time.Second, 100, 100, 2, time.Hour)
+	require.NoError(t, second.SetStore(store))
+	for _, link := range links {
+		status, _ := second.CheckLink(context.Background(), link)
+		assert.Equal(t, 200, status)
+	}
+	assert.Equal(t, requestsAfterFirstRun, atomic.LoadInt64(&requests))
+	require.NoError(t, second.Flush())
+
+	entries, err = store.Load()
+	require.NoError(t, err)
+	assert.Len(t, entries, len(links))
+}
@@ -0,0 +1,477 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/websocket"
+)
+
+// Wait conditions of a page rendered through the Chrome DevTools Protocol
+const (
+	CDPWaitLoad             = "load"             // The load event fired
+	CDPWaitDOMContentLoaded = "domcontentloaded" // The DOMContentLoaded event fired
+	CDPWaitNetworkIdle      = "networkidle"      // The network has been idle for 500ms after loading
+)
+
+const (
+	defaultCDPTimeout       = 30 * time.Second
+	cdpSelectorPollInterval = 100 * time.Millisecond
+)
+
+// renderedPageScript returns the content type and the HTML of the document rendered in a tab
+const renderedPageScript = `({contentType: document.contentType, html: document.documentElement ? document.documentElement.outerHTML : ""})`
+
+// CDPOptions configures how pages are rendered through the Chrome DevTools Protocol
+type CDPOptions struct {
+	WaitUntil    string        // Page event to wait for: load, domcontentloaded or networkidle
+	WaitSelector string        // CSS selector of an element to wait for once the event fired, if any
+	WaitDelay    time.Duration // Extra time given to scripts once the other conditions are met
+	Timeout      time.Duration // Maximum time to render a page
+	MaxTabs      int           // Maximum number of pages rendered at once
+}
+
+// CDPBrowser renders pages in a browser driven through the Chrome DevTools Protocol, opening a tab per page
+type CDPBrowser struct {
+	endpoint *url.URL // HTTP endpoint of the DevTools protocol, e.g. http://localhost:9222
+	client   *http.Client
+	options  CDPOptions
+	tabs     chan struct{} // Limits the number of tabs open at once
+}
+
+// NewCDPBrowser creates a CDPBrowser for the DevTools endpoint of a browser started with --remote-debugging-port.
+// Both the http:// endpoint and the ws:// URL of the browser are accepted.
+func NewCDPBrowser(endpoint string, options CDPOptions) (*CDPBrowser, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CDP URL %s: %w", endpoint, err)
+	}
+
+	switch parsed.Scheme {
+	case "http", "https":
+		parsed.Path = strings.TrimSuffix(parsed.Path, "/")
+	case "ws", "wss":
+		// Only the host of a browser websocket URL locates its HTTP endpoint
+		parsed.Scheme = strings.Replace(parsed.Scheme, "ws", "http", 1)
+		parsed.Path = ""
+	default:
+		return nil, fmt.Errorf("invalid CDP URL %s: expected an http:// or ws:// URL", endpoint)
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("invalid CDP URL %s: no host found", endpoint)
+	}
+	parsed.RawQuery, parsed.Fragment = "", ""
+
+	switch options.WaitUntil {
+	case "":
+		options.WaitUntil = CDPWaitLoad
+	case CDPWaitLoad, CDPWaitDOMContentLoaded, CDPWaitNetworkIdle:
+	default:
+		return nil, fmt.Errorf("invalid CDP wait condition %q: expected %s, %s or %s", options.WaitUntil, CDPWaitLoad, CDPWaitDOMContentLoaded, CDPWaitNetworkIdle)
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaultCDPTimeout
+	}
+	if options.MaxTabs <= 0 {
+		options.MaxTabs = 1
+	}
+
+	return &CDPBrowser{
+		endpoint: parsed,
+		client:   &http.Client{Timeout: options.Timeout},
+		options:  options,
+		tabs:     make(chan struct{}, options.MaxTabs),
+	}, nil
+}
+
+// Render loads a page in a new tab and waits for the configured conditions,
+// returning the HTML of the rendered document and its content type. Cancelling ctx aborts the rendering.
+func (b *CDPBrowser) Render(ctx context.Context, pageURL string) ([]byte, string, error) {
+	select {
+	case b.tabs <- struct{}{}:
+	case <-ctx.Done():
+		return nil, "", ctx.Err()
+	}
+	defer func() { <-b.tabs }()
+
+	target, err := b.openTarget(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer b.closeTarget(target.ID)
+
+	deadline := time.Now().Add(b.options.Timeout)
+	session, err := b.connect(ctx, target.WebSocketDebuggerURL, deadline)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		if err := session.Close(); err != nil {
+			logger.Debugf("Error closing CDP session of %s: %s", pageURL, err)
+		}
+	}()
+
+	// Cancelling ctx moves the deadline of the session to now, interrupting the commands waiting for the browser
+	stopInterrupt := context.AfterFunc(ctx, func() {
+		_ = session.SetDeadline(time.Now())
+	})
+	defer stopInterrupt()
+
+	page := struct {
+		ContentType string `json:"contentType"`
+		HTML        string `json:"html"`
+	}{}
+	err = b.load(ctx, session, pageURL, deadline)
+	if err == nil {
+		err = session.evaluate(renderedPageScript, &page)
+	}
+	if ctx.Err() != nil {
+		return nil, "", ctx.Err()
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return nil, "", fmt.Errorf("rendering %s timed out after %s", pageURL, b.options.Timeout)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return []byte(page.HTML), page.ContentType, nil
+}
+
+// load navigates a tab to a page and waits until the page is considered rendered
+func (b *CDPBrowser) load(ctx context.Context, session *cdpSession, pageURL string, deadline time.Time) error {
+	if err := session.call("Page.enable", nil, nil); err != nil {
+		return err
+	}
+	if b.options.WaitUntil == CDPWaitNetworkIdle {
+		if err := session.call("Page.setLifecycleEventsEnabled", map[string]any{"enabled": true}, nil); err != nil {
+			return err
+		}
+	}
+
+	navigation := struct {
+		LoaderID  string `json:"loaderId"`
+		ErrorText string `json:"errorText"`
+	}{}
+	if err := session.call("Page.navigate", map[string]any{"url": pageURL}, &navigation); err != nil {
+		return err
+	}
+	if navigation.ErrorText != "" {
+		return fmt.Errorf("loading %s: %s", pageURL, navigation.ErrorText)
+	}
+
+	if err := session.waitFor(b.waitEvent(navigation.LoaderID)); err != nil {
+		return err
+	}
+	if b.options.WaitSelector != "" {
+		if err := b.waitSelector(ctx, session, deadline); err != nil {
+			return err
+		}
+	}
+	if b.options.WaitDelay > 0 {
+		return sleepContext(ctx, b.options.WaitDelay)
+	}
+	return nil
+}
+
+// waitEvent returns the matcher of the event ending the wait for a navigation
+func (b *CDPBrowser) waitEvent(loaderID string) func(cdpMessage) bool {
+	switch b.options.WaitUntil {
+	case CDPWaitDOMContentLoaded:
+		return func(event cdpMessage) bool { return event.Method == "Page.domContentEventFired" }
+	case CDPWaitNetworkIdle:
+		return func(event cdpMessage) bool {
+			if event.Method != "Page.lifecycleEvent" {
+				return false
+			}
+			lifecycle := struct {
+				LoaderID string `json:"loaderId"`
+				Name     string `json:"name"`
+			}{}
+			return json.Unmarshal(event.Params, &lifecycle) == nil && lifecycle.Name == "networkIdle" &&
+				(loaderID == "" || lifecycle.LoaderID == loaderID)
+		}
+	default:
+		return func(event cdpMessage) bool { return event.Method == "Page.loadEventFired" }
+	}
+}
+
+// waitSelector polls the rendered document until an element matches the wait selector
+func (b *CDPBrowser) waitSelector(ctx context.Context, session *cdpSession, deadline time.Time) error {
+	selector, err := json.Marshal(b.options.WaitSelector)
+	if err != nil {
+		return err
+	}
+	script := fmt.Sprintf("document.querySelector(%s) !== null", selector)
+
+	for {
+		found := false
+		if err := session.evaluate(script, &found); err != nil {
+			return fmt.Errorf("waiting for %s: %w", b.options.WaitSelector, err)
+		}
+		if found {
+			return nil
+		}
+		if time.Now().Add(cdpSelectorPollInterval).After(deadline) {
+			return fmt.Errorf("waiting for %s: %w", b.options.WaitSelector, os.ErrDeadlineExceeded)
+		}
+		if err := sleepContext(ctx, cdpSelectorPollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// cdpTarget is a browser tab as described by the DevTools HTTP endpoint
+type cdpTarget struct {
+	ID                   string `json:"id"`
+	WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+}
+
+// Ping checks that the browser answers on its DevTools endpoint
+func (b *CDPBrowser) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.endpoint.String()+"/json/version", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("browser unreachable through %s: %w", b.endpoint, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Errorf("Error closing response body for %s: %s", req.URL, err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("browser unreachable through %s: status %d", b.endpoint, resp.StatusCode)
+	}
+	return nil
+}
+
+// openTarget opens a blank tab in the browser
+func (b *CDPBrowser) openTarget(ctx context.Context) (*cdpTarget, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, b.endpoint.String()+"/json/new?about:blank", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("opening a tab through %s: %w", b.endpoint, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Errorf("Error closing response body for %s: %s", req.URL, err)
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("opening a tab through %s: %w", b.endpoint, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("opening a tab through %s: status %d: %s", b.endpoint, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	target := &cdpTarget{}
+	if err := json.Unmarshal(body, target); err != nil || target.WebSocketDebuggerURL == "" {
+		return nil, fmt.Errorf("opening a tab through %s: unexpected response %s", b.endpoint, strings.TrimSpace(string(body)))
+	}
+	return target, nil
+}
+
+// closeTarget closes a tab opened by openTarget
+func (b *CDPBrowser) closeTarget(id string) {
+	resp, err := b.client.Get(b.endpoint.String() + "/json/close/" + url.PathEscape(id))
+	if err != nil {
+		logger.Debugf("Error closing CDP target %s: %s", id, err)
+		return
+	}
+	if err := resp.Body.Close(); err != nil {
+		logger.Debugf("Error closing response body for CDP target %s: %s", id, err)
+	}
+}
+
+// connect opens a DevTools session on a tab. The browser endpoint is sent as the origin of the connection,
+// which the browser must allow with --remote-allow-origins.
+func (b *CDPBrowser) connect(ctx context.Context, debuggerURL string, deadline time.Time) (*cdpSession, error) {
+	config, err := websocket.NewConfig(debuggerURL, b.endpoint.Scheme+"://"+b.endpoint.Host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CDP target URL %s: %w", debuggerURL, err)
+	}
+	config.Dialer = &net.Dialer{Deadline: deadline}
+
+	conn, err := config.DialContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", debuggerURL, err)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return &cdpSession{Conn: conn}, nil
+}
+
+// cdpMessage is a command response or an event received from the browser
+type cdpMessage struct {
+	ID     int64           `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *cdpError       `json:"error,omitempty"`
+}
+
+// cdpError is the error answered to a failed command
+type cdpError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// cdpCommand is a command sent to the browser
+type cdpCommand struct {
+	ID     int64  `json:"id"`
+	Method string `json:"method"`
+	Params any    `json:"params,omitempty"`
+}
+
+// cdpSession sends commands to a tab, keeping the events received while waiting for their responses
+type cdpSession struct {
+	*websocket.Conn
+	lastID int64
+	events []cdpMessage
+}
+
+// call sends a command and decodes its result into result, when not nil
+func (s *cdpSession) call(method string, params any, result any) error {
+	s.lastID++
+	id := s.lastID
+	if err := websocket.JSON.Send(s.Conn, cdpCommand{ID: id, Method: method, Params: params}); err != nil {
+		return fmt.Errorf("sending %s: %w", method, err)
+	}
+
+	for {
+		message := cdpMessage{}
+		if err := websocket.JSON.Receive(s.Conn, &message); err != nil {
+			return fmt.Errorf("waiting for %s: %w", method, err)
+		}
+		if message.ID != id {
+			if message.Method != "" {
+				s.events = append(s.events, message)
+			}
+			continue
+		}
+		if message.Error != nil {
+			return fmt.Errorf("%s failed: %s", method, message.Error.Message)
+		}
+		if result == nil || len(message.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(message.Result, result)
+	}
+}
+
+// waitFor returns once an event matching the given function is received
+func (s *cdpSession) waitFor(match func(cdpMessage) bool) error {
+	for i, event := range s.events {
+		if match(event) {
+			s.events = s.events[i+1:]
+			return nil
+		}
+	}
+	s.events = nil
+
+	for {
+		event := cdpMessage{}
+		if err := websocket.JSON.Receive(s.Conn, &event); err != nil {
+			return fmt.Errorf("waiting for the page to load: %w", err)
+		}
+		if match(event) {
+			return nil
+		}
+	}
+}
+
+// evaluate runs a script in the page and decodes the value it returns into value
+func (s *cdpSession) evaluate(expression string, value any) error {
+	evaluation := struct {
+		Result struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Text      string `json:"text"`
+			Exception struct {
+				Description string `json:"description"`
+			} `json:"exception"`
+		} `json:"exceptionDetails"`
+	}{}
+	if err := s.call("Runtime.evaluate", map[string]any{"expression": expression, "returnByValue": true}, &evaluation); err != nil {
+		return err
+	}
+	if details := evaluation.ExceptionDetails; details != nil {
+		if details.Exception.Description != "" {
+			return fmt.Errorf("script failed: %s", details.Exception.Description)
+		}
+		return fmt.Errorf("script failed: %s", details.Text)
+	}
+	return json.Unmarshal(evaluation.Result.Value, value)
+}
+
+// CDPPageParser implements the PageParser interface by rendering pages in a browser before extracting their links,
+// so links added by scripts are found. The extracted links are still checked over HTTP.
+type CDPPageParser struct {
+	*PageParserService
+	browser *CDPBrowser
+}
+
+// NewCDPPageParser creates a new CDPPageParser rendering pages with the given browser
+func NewCDPPageParser(browser *CDPBrowser, linkChecker LinkChecker, urlProcessor URLProcessor, excludeHtmlTags string, onlyInternal bool) *CDPPageParser {
+	parser := &CDPPageParser{
+		PageParserService: NewPageParserService(linkChecker, urlProcessor, excludeHtmlTags, onlyInternal),
+		browser:           browser,
+	}
+	// Anchors added by scripts are found by rendering the linked pages as well
+	parser.loadDocument = parser.renderDocument
+	return parser
+}
+
+// ParsePage renders and parses a web page, keeping its rendered HTML to locate the extracted links
+func (cp *CDPPageParser) ParsePage(ctx context.Context, pageURL string) (*goquery.Document, error) {
+	doc, content, err := cp.renderDocument(ctx, pageURL)
+	if doc != nil {
+		cp.RecordSource(doc, content)
+	}
+	return doc, err
+}
+
+// renderDocument renders and parses a web page, returning its rendered HTML along with the document.
+// Documents that are not HTML return a nil document.
+func (cp *CDPPageParser) renderDocument(ctx context.Context, pageURL string) (*goquery.Document, []byte, error) {
+	content, contentType, err := cp.browser.Render(ctx, pageURL)
+	if err != nil {
+		logger.Errorf("Failed to render %s: %s", pageURL, err)
+		return nil, nil, err
+	}
+
+	if !strings.Contains(contentType, "html") {
+		return nil, nil, nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(content))
+	if err != nil {
+		logger.Errorf("Error parsing HTML from %s: %s", pageURL, err)
+		return nil, nil, err
+	}
+
+	return doc, content, nil
+}
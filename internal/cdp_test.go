@@ -0,0 +1,279 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+// fakeDevTools is a DevTools endpoint answering like a browser whose scripts turn each page into its rendered HTML
+type fakeDevTools struct {
+	rendered      map[string]string // Page URL -> HTML once rendered
+	selectorAfter int               // Number of polls before the wait selector matches
+	skipLoad      bool              // Never fire the load event
+
+	mutex    sync.Mutex
+	opened   int
+	closed   []string
+	commands []string
+}
+
+func (fd *fakeDevTools) start(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/json/version", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"Browser": "HeadlessChrome/130.0.0.0"}`))
+	})
+	mux.HandleFunc("/json/new", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Using unsafe HTTP verb GET to invoke /json/new", http.StatusMethodNotAllowed)
+			return
+		}
+		fd.mutex.Lock()
+		fd.opened++
+		fd.mutex.Unlock()
+		_ = json.NewEncoder(w).Encode(cdpTarget{
+			ID:                   "TARGET",
+			WebSocketDebuggerURL: "ws://" + r.Host + "/devtools/page/TARGET",
+		})
+	})
+	mux.HandleFunc("/json/close/", func(w http.ResponseWriter, r *http.Request) {
+		fd.mutex.Lock()
+		fd.closed = append(fd.closed, strings.TrimPrefix(r.URL.Path, "/json/close/"))
+		fd.mutex.Unlock()
+		_, _ = w.Write([]byte("Target is closing"))
+	})
+	mux.Handle("/devtools/page/", websocket.Handler(fd.serveSession))
+	return server
+}
+
+func (fd *fakeDevTools) serveSession(conn *websocket.Conn) {
+	page := ""
+	polls := 0
+	for {
+		command := struct {
+			ID     int64          `json:"id"`
+			Method string         `json:"method"`
+			Params map[string]any `json:"params"`
+		}{}
+		if err := websocket.JSON.Receive(conn, &command); err != nil {
+			return
+		}
+		fd.mutex.Lock()
+		fd.commands = append(fd.commands, command.Method)
+		fd.mutex.Unlock()
+
+		result := map[string]any{}
+		events := []map[string]any{}
+		switch command.Method {
+		case "Page.navigate":
+			page, _ = command.Params["url"].(string)
+			if _, found := fd.rendered[page]; !found {
+				result["errorText"] = "net::ERR_NAME_NOT_RESOLVED"
+				break
+			}
+			result = map[string]any{"frameId": "FRAME", "loaderId": "LOADER"}
+			events = append(events,
+				map[string]any{"method": "Page.domContentEventFired", "params": map[string]any{}},
+				map[string]any{"method": "Page.lifecycleEvent", "params": map[string]any{"loaderId": "LOADER", "name": "networkIdle"}})
+			if !fd.skipLoad {
+				events = append(events, map[string]any{"method": "Page.loadEventFired", "params": map[string]any{}})
+			}
+		case "Runtime.evaluate":
+			expression, _ := command.Params["expression"].(string)
+			if strings.Contains(expression, "querySelector") {
+				polls++
+				result["result"] = map[string]any{"type": "boolean", "value": polls > fd.selectorAfter}
+			} else {
+				result["result"] = map[string]any{"type": "object", "value": map[string]any{"contentType": "text/html", "html": fd.rendered[page]}}
+			}
+		}
+
+		if err := websocket.JSON.Send(conn, map[string]any{"id": command.ID, "result": result}); err != nil {
+			return
+		}
+		for _, event := range events {
+			if err := websocket.JSON.Send(conn, event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func TestNewCDPBrowser(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		options  CDPOptions
+		expected string
+		wantErr  bool
+	}{
+		{name: "HTTP endpoint", endpoint: "http://localhost:9222/", expected: "http://localhost:9222"},
+		{name: "Browser websocket URL", endpoint: "ws://127.0.0.1:9222/devtools/browser/abc", expected: "http://127.0.0.1:9222"},
+		{name: "Secure websocket URL", endpoint: "wss://chrome.internal/devtools/browser/abc", expected: "https://chrome.internal"},
+		{name: "Network idle", endpoint: "http://localhost:9222", options: CDPOptions{WaitUntil: CDPWaitNetworkIdle}, expected: "http://localhost:9222"},
+		{name: "Unknown scheme", endpoint: "localhost:9222", wantErr: true},
+		{name: "Missing host", endpoint: "http:///json", wantErr: true},
+		{name: "Unknown wait condition", endpoint: "http://localhost:9222", options: CDPOptions{WaitUntil: "idle"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			browser, err := NewCDPBrowser(tt.endpoint, tt.options)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, browser.endpoint.String())
+			assert.NotEmpty(t, browser.options.WaitUntil)
+			assert.Equal(t, defaultCDPTimeout, browser.options.Timeout)
+		})
+	}
+}
+
+func TestCDPBrowserRender(t *testing.T) {
+	logger.SetQuiet(true)
+
+	devTools := &fakeDevTools{
+		rendered:      map[string]string{"https://example.com/app": `<html><body><a href="/docs">Docs</a></body></html>`},
+		selectorAfter: 2,
+	}
+	server := devTools.start(t)
+
+	for _, waitUntil := range []string{CDPWaitLoad, CDPWaitDOMContentLoaded, CDPWaitNetworkIdle} {
+		t.Run(waitUntil, func(t *testing.T) {
+			browser, err := NewCDPBrowser(server.URL, CDPOptions{WaitUntil: waitUntil, Timeout: 5 * time.Second})
+			require.NoError(t, err)
+
+			content, contentType, err := browser.Render(context.Background(), "https://example.com/app")
+			require.NoError(t, err)
+			assert.Equal(t, "text/html", contentType)
+			assert.Contains(t, string(content), `<a href="/docs">`)
+		})
+	}
+
+	t.Run("Wait selector", func(t *testing.T) {
+		browser, err := NewCDPBrowser(server.URL, CDPOptions{WaitSelector: "#app a", Timeout: 5 * time.Second})
+		require.NoError(t, err)
+
+		_, _, err = browser.Render(context.Background(), "https://example.com/app")
+		require.NoError(t, err)
+
+		devTools.mutex.Lock()
+		defer devTools.mutex.Unlock()
+		assert.Equal(t, devTools.opened, len(devTools.closed), "every tab is closed")
+		evaluations := 0
+		for _, command := range devTools.commands {
+			if command == "Runtime.evaluate" {
+				evaluations++
+			}
+		}
+		assert.GreaterOrEqual(t, evaluations, 3+1, "the selector is polled until it matches, then the page is read")
+	})
+
+	t.Run("Navigation error", func(t *testing.T) {
+		browser, err := NewCDPBrowser(server.URL, CDPOptions{Timeout: 5 * time.Second})
+		require.NoError(t, err)
+
+		_, _, err = browser.Render(context.Background(), "https://unknown.invalid/")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "net::ERR_NAME_NOT_RESOLVED")
+	})
+}
+
+func TestCDPBrowserRenderTimeout(t *testing.T) {
+	logger.SetQuiet(true)
+
+	devTools := &fakeDevTools{
+		rendered: map[string]string{"https://example.com/slow": `<html></html>`},
+		skipLoad: true,
+	}
+	server := devTools.start(t)
+
+	browser, err := NewCDPBrowser(server.URL, CDPOptions{Timeout: 300 * time.Millisecond})
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, _, err = browser.Render(context.Background(), "https://example.com/slow")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+	assert.Less(t, time.Since(start), 3*time.Second)
+}
+
+func TestCrawlRendersPages(t *testing.T) {
+	logger.SetQuiet(true)
+
+	// The site only links its pages once scripts ran
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/", "/docs":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><div id="app"></div><script src="/app.js"></script></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	devTools := &fakeDevTools{rendered: map[string]string{
+		site.URL + "/": `<html><head></head><body><div id="app">
+<a href="/docs">Docs</a>
+<a href="/missing">Missing</a>
+</div></body></html>`,
+		site.URL + "/docs": `<html><head></head><body><div id="app"><a href="/">Home</a></div></body></html>`,
+	}}
+	browserServer := devTools.start(t)
+
+	factory := NewServiceFactory()
+	config := factory.CreateCrawlConfigFromParams(1, 2, false, "", "", "")
+	config.CDPURL = browserServer.URL
+	config.CDPOptions = CDPOptions{Timeout: 5 * time.Second, MaxTabs: 2}
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	crawler, err := factory.CreateCachedOptimizedCrawlerService(config, "TestAgent", 5*time.Second, httpClient, 100, 100, 100, time.Hour)
+	require.NoError(t, err)
+	defer crawler.Stop()
+
+	require.NoError(t, crawler.StartCrawl(context.Background(), site.URL, site.URL+"/", 0))
+	crawler.Wait()
+
+	results := make(map[string]model.LinkResult)
+	for _, result := range crawler.GetResults() {
+		results[strings.TrimPrefix(result.SourceURL, site.URL)+" -> "+strings.TrimPrefix(result.TargetURL, site.URL)] = result
+	}
+
+	docs := results["/ -> /docs"]
+	assert.Equal(t, http.StatusOK, docs.Status)
+	assert.Equal(t, 2, docs.Line, "links are located in the rendered HTML")
+
+	assert.Equal(t, http.StatusNotFound, results["/ -> /missing"].Status)
+	assert.Equal(t, http.StatusOK, results["/docs -> /"].Status, "rendered pages found through rendered links are crawled too")
+}
+
+func TestCrawlWithUnreachableBrowser(t *testing.T) {
+	logger.SetQuiet(true)
+
+	// An endpoint nothing listens on anymore
+	browserServer := httptest.NewServer(http.NotFoundHandler())
+	browserServer.Close()
+
+	factory := NewServiceFactory()
+	config := factory.CreateCrawlConfigFromParams(1, 2, false, "", "", "")
+	config.CDPURL = browserServer.URL
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	_, err := factory.CreateCachedOptimizedCrawlerService(config, "TestAgent", 5*time.Second, httpClient, 100, 100, 100, time.Hour)
+	assert.ErrorContains(t, err, "browser unreachable")
+}
@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/model"
+)
+
+// crawlStateVersion is the version of the checkpoint file format
+const crawlStateVersion = 1
+
+// CrawlState is a checkpoint of a crawl, from which an interrupted crawl is resumed
+type CrawlState struct {
+	Version int                   `json:"version"`
+	Target  string                `json:"target"` // Site or directory the crawl was started on
+	SavedAt time.Time             `json:"saved_at"`
+	Pending []PendingJob          `json:"pending"` // Pages queued or being crawled, crawled again when resuming
+	Visited []string              `json:"visited"` // Pages crawled completely
+	Results []model.LinkResult    `json:"results"`
+	Cache   []persistedCacheEntry `json:"cache,omitempty"`
+}
+
+// PendingJob is a crawl job saved in a checkpoint
+type PendingJob struct {
+	BaseURL      string `json:"base_url"`
+	TargetURL    string `json:"target_url"`
+	CurrentDepth int    `json:"depth"`
+	SourceURL    string `json:"source_url,omitempty"`
+}
+
+// LoadCrawlState reads a checkpoint file
+func LoadCrawlState(path string) (*CrawlState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &CrawlState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	if state.Version != crawlStateVersion {
+		return nil, fmt.Errorf("unsupported checkpoint version %d in %s", state.Version, path)
+	}
+	return state, nil
+}
+
+// SaveCrawlState writes a checkpoint file, replacing its previous content.
+// The file is written to a temporary location first so an interruption never leaves it truncated.
+func SaveCrawlState(path string, state *CrawlState) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+
+	if err := json.NewEncoder(tmpFile).Encode(state); err != nil {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// removeCrawlState deletes a checkpoint file, a missing file is not an error
+func removeCrawlState(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// newCrawlState creates the checkpoint of a crawl from a snapshot of its worker pool and its link cache
+func newCrawlState(target string, pending []Job, results []model.LinkResult, visited []string, cache map[string]*CacheEntry) *CrawlState {
+	state := &CrawlState{
+		Version: crawlStateVersion,
+		Target:  target,
+		SavedAt: time.Now(),
+		Pending: make([]PendingJob, 0, len(pending)),
+		Visited: visited,
+		Results: results,
+	}
+
+	// Shallow pages first, so a resumed crawl proceeds in the same order
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].CurrentDepth != pending[j].CurrentDepth {
+			return pending[i].CurrentDepth < pending[j].CurrentDepth
+		}
+		return pending[i].TargetURL < pending[j].TargetURL
+	})
+	for _, job := range pending {
+		state.Pending = append(state.Pending, PendingJob{
+			BaseURL:      job.BaseURL,
+			TargetURL:    job.TargetURL,
+			CurrentDepth: job.CurrentDepth,
+			SourceURL:    job.SourceURL,
+		})
+	}
+
+	sort.Strings(state.Visited)
+	for url, entry := range cache {
+		state.Cache = append(state.Cache, newPersistedCacheEntry(url, entry))
+	}
+
+	return state
+}
+
+// cacheEntries returns the link cache entries of a checkpoint
+func (state *CrawlState) cacheEntries() map[string]*CacheEntry {
+	entries := make(map[string]*CacheEntry, len(state.Cache))
+	for _, record := range state.Cache {
+		if record.URL != "" {
+			entries[record.URL] = record.entry()
+		}
+	}
+	return entries
+}
@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCrawlStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	cache := map[string]*CacheEntry{
+		"https://example.com/a": {Status: 200, Timestamp: time.Now(), TTL: time.Hour},
+	}
+	pending := []Job{
+		{BaseURL: "https://example.com", TargetURL: "https://example.com/deep", CurrentDepth: 2},
+		{BaseURL: "https://example.com", TargetURL: "https://example.com/b", CurrentDepth: 1, SourceURL: "https://example.com/sitemap.xml"},
+	}
+	results := []model.LinkResult{{SourceURL: "https://example.com", TargetURL: "https://example.com/a", Status: 200}}
+
+	state := newCrawlState("https://example.com", pending, results, []string{"https://example.com"}, cache)
+	require.NoError(t, SaveCrawlState(path, state))
+
+	loaded, err := LoadCrawlState(path)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", loaded.Target)
+	assert.Equal(t, results, loaded.Results)
+	assert.Equal(t, []string{"https://example.com"}, loaded.Visited)
+	require.Len(t, loaded.Pending, 2)
+	assert.Equal(t, "https://example.com/b", loaded.Pending[0].TargetURL, "shallow pages come first")
+	assert.Equal(t, "https://example.com/sitemap.xml", loaded.Pending[0].SourceURL)
+	assert.Contains(t, loaded.cacheEntries(), "https://example.com/a")
+
+	t.Run("Rejects other versions", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte(`{"version": 99}`), 0o644))
+		_, err := LoadCrawlState(path)
+		assert.Error(t, err)
+	})
+
+	t.Run("Removing a missing file is not an error", func(t *testing.T) {
+		assert.NoError(t, removeCrawlState(path))
+		assert.NoError(t, removeCrawlState(path))
+	})
+}
+
+func TestOptimizedCrawlerCheckpointResume(t *testing.T) {
+	// / -> /a, /b; /a -> /c; /b -> /d. Crawling /b hangs until the crawl is resumed.
+	pages := map[string]string{
+		"/":  `<html><body><a href="/a">A</a><a href="/b">B</a></body></html>`,
+		"/a": `<html><body><a href="/c">C</a></body></html>`,
+		"/b": `<html><body><a href="/d">D</a></body></html>`,
+		"/c": `<html><body>C</body></html>`,
+		"/d": `<html><body>D</body></html>`,
+	}
+	var hanging atomic.Bool
+	hanging.Store(true)
+	hung := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/b" && r.Method == http.MethodGet && hanging.Load() {
+			select {
+			case hung <- struct{}{}:
+			default:
+			}
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	newCrawler := func() *OptimizedCrawlerService {
+		factory := NewServiceFactory()
+		config := factory.CreateCrawlConfigFromParams(2, 2, false, "", "", "")
+		httpClient := &http.Client{Timeout: 5 * time.Second}
+		crawler, err := factory.CreateCachedOptimizedCrawlerService(config, "TestAgent", 5*time.Second, httpClient, 100, 100, 100, time.Hour)
+		require.NoError(t, err)
+		crawler.EnableCheckpoints(path, server.URL, 10*time.Millisecond)
+		return crawler
+	}
+	waitCrawl := func(crawler *OptimizedCrawlerService) {
+		done := make(chan struct{})
+		go func() {
+			crawler.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("Crawler did not complete within timeout")
+		}
+	}
+	pendingTargets := func(state *CrawlState) []string {
+		targets := []string{}
+		for _, job := range state.Pending {
+			targets = append(targets, job.TargetURL)
+		}
+		return targets
+	}
+
+	// Interrupt the crawl while /b hangs
+	ctx, cancel := context.WithCancel(context.Background())
+	crawler := newCrawler()
+	require.NoError(t, crawler.StartCrawl(ctx, server.URL, server.URL+"/", 0))
+	go func() {
+		<-hung
+		assert.Eventually(t, func() bool {
+			state, err := LoadCrawlState(path)
+			return err == nil && assert.ObjectsAreEqual([]string{server.URL + "/b"}, pendingTargets(state))
+		}, 5*time.Second, 10*time.Millisecond, "checkpoints are saved while the crawl runs")
+		cancel()
+	}()
+	waitCrawl(crawler)
+	crawler.Stop()
+
+	state, err := LoadCrawlState(path)
+	require.NoError(t, err, "the interrupted crawl leaves a checkpoint")
+	assert.Equal(t, []string{server.URL + "/b"}, pendingTargets(state))
+	assert.Contains(t, state.Visited, server.URL+"/")
+	assert.NotContains(t, state.Visited, server.URL+"/b", "pages being crawled are crawled again")
+	assert.Len(t, state.Results, 3)
+	assert.NotEmpty(t, state.Cache)
+
+	// Resume the crawl
+	hanging.Store(false)
+	resumed := newCrawler()
+	require.NoError(t, resumed.Resume(context.Background(), state))
+	waitCrawl(resumed)
+	resumed.Stop()
+
+	links := map[string]int{}
+	for _, result := range resumed.GetResults() {
+		links[result.SourceURL+" -> "+result.TargetURL]++
+	}
+	assert.Equal(t, map[string]int{
+		server.URL + "/ -> " + server.URL + "/a":  1,
+		server.URL + "/ -> " + server.URL + "/b":  1,
+		server.URL + "/a -> " + server.URL + "/c": 1,
+		server.URL + "/b -> " + server.URL + "/d": 1,
+	}, links, "the resumed crawl reports every link once")
+
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist, "the checkpoint of a completed crawl is removed")
+}
@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"context"
+	"net/url"
 	"sync"
 
 	"github.com/DrakkarStorm/deadlinkr/logger"
@@ -14,6 +16,7 @@ type CrawlerService struct {
 	resultCollector ResultCollector
 	config          *CrawlConfig
 	wg              *sync.WaitGroup
+	linkChecker     LinkChecker // Optional, used to check pages referenced outside crawled pages
 }
 
 // NewCrawlerService creates a new CrawlerService
@@ -27,21 +30,24 @@ func NewCrawlerService(pageParser PageParser, urlProcessor URLProcessor, resultC
 	}
 }
 
-// Crawl crawls the given URL and its links up to the specified depth
-func (c *CrawlerService) Crawl(baseURL, currentURL string, currentDepth int) error {
-	// Stop if max depth reached
-	if currentDepth > c.config.MaxDepth {
+// Crawl crawls the given URL and its links up to the specified depth, until ctx is cancelled
+func (c *CrawlerService) Crawl(ctx context.Context, baseURL, currentURL string, currentDepth int) error {
+	// Stop if max depth reached or the crawl was cancelled
+	if currentDepth > c.config.MaxDepth || ctx.Err() != nil {
 		return nil
 	}
 
-	// Check if URL already visited
-	if c.resultCollector.IsVisited(currentURL) {
+	// Mark as visited, unless it already was
+	if !c.resultCollector.MarkVisited(currentURL) {
 		logger.Debugf("→ skip (already visited) : %s", currentURL)
 		return nil
 	}
 
-	// Mark as visited
-	c.resultCollector.MarkVisited(currentURL)
+	// Never crawl pages disallowed by robots.txt
+	if !c.isCrawlAllowed(currentURL) {
+		logger.Infof("Not crawling %s, disallowed by robots.txt", currentURL)
+		return nil
+	}
 
 	logger.Debugf("Crawling: %s (depth %d)", currentURL, currentDepth)
 
@@ -52,7 +58,7 @@ func (c *CrawlerService) Crawl(baseURL, currentURL string, currentDepth int) err
 	}
 
 	// Parse the page and extract links
-	doc, err := c.pageParser.ParsePage(currentURL)
+	doc, err := c.pageParser.ParsePage(ctx, currentURL)
 	if err != nil {
 		return err
 	}
@@ -60,7 +66,7 @@ func (c *CrawlerService) Crawl(baseURL, currentURL string, currentDepth int) err
 		return nil
 	}
 
-	links := c.pageParser.ExtractLinks(baseUrlParsed, currentURL, doc)
+	links := c.pageParser.ExtractLinks(ctx, baseUrlParsed, currentURL, doc)
 	logger.Debugf("Found %d links on %s", len(links), currentURL)
 
 	// Add results to collector
@@ -72,16 +78,16 @@ func (c *CrawlerService) Crawl(baseURL, currentURL string, currentDepth int) err
 	if currentDepth < c.config.MaxDepth {
 		// Iterate over each link found on the current page
 		for _, link := range links {
-			// Only recursively crawl internal links
-			if !link.IsExternal {
+			// Only recursively crawl internal links to pages
+			if !link.IsExternal && c.isCrawlableLink(link) {
 				// Start a new goroutine for each internal link to crawl it
 				c.wg.Add(1)
 				go func(targetURL string) {
 					defer c.wg.Done()
-					if err := c.Crawl(baseURL, targetURL, currentDepth+1); err != nil {
+					if err := c.Crawl(ctx, baseURL, targetURL, currentDepth+1); err != nil {
 						logger.Errorf("Error crawling %s: %s", targetURL, err)
 					}
-				}(link.TargetURL)
+				}(stripFragment(link.TargetURL))
 			}
 		}
 	}
@@ -89,6 +95,25 @@ func (c *CrawlerService) Crawl(baseURL, currentURL string, currentDepth int) err
 	return nil
 }
 
+// isCrawlAllowed checks whether robots.txt allows crawling the page
+func (c *CrawlerService) isCrawlAllowed(pageURL string) bool {
+	parsed, err := url.Parse(pageURL)
+	if err != nil {
+		return true
+	}
+	return c.urlProcessor.IsCrawlAllowed(parsed)
+}
+
+// isCrawlableLink checks whether a link leads to a page worth crawling, e.g. an anchor rather than an image
+func (c *CrawlerService) isCrawlableLink(link model.LinkResult) bool {
+	if crawlable, ok := c.pageParser.(interface {
+		IsCrawlable(link model.LinkResult) bool
+	}); ok {
+		return crawlable.IsCrawlable(link)
+	}
+	return link.Element == "" || link.Element == "a"
+}
+
 // SetConfig updates the crawler configuration
 func (c *CrawlerService) SetConfig(config *CrawlConfig) {
 	c.config = config
@@ -100,11 +125,11 @@ func (c *CrawlerService) Wait() {
 }
 
 // StartCrawl starts the initial crawl with proper waitgroup management
-func (c *CrawlerService) StartCrawl(baseURL, currentURL string, currentDepth int) error {
+func (c *CrawlerService) StartCrawl(ctx context.Context, baseURL, currentURL string, currentDepth int) error {
 	c.wg.Add(1)
 	go func() {
 		defer c.wg.Done()
-		if err := c.Crawl(baseURL, currentURL, currentDepth); err != nil {
+		if err := c.Crawl(ctx, baseURL, currentURL, currentDepth); err != nil {
 			logger.Errorf("Error in crawl: %s", err)
 		}
 	}()
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -11,22 +12,30 @@ import (
 
 // OptimizedCrawlerService implements the Crawler interface with a worker pool
 type OptimizedCrawlerService struct {
-	pageParser       PageParser
-	urlProcessor     URLProcessor
-	resultCollector  ResultCollector
-	config           *CrawlConfig
-	workerPool       *WorkerPool
-	activeJobs       sync.WaitGroup
-	started          bool
-	progressTracker  *ProgressTracker
-	shutdownManager  *ShutdownManager
+	pageParser        PageParser
+	urlProcessor      URLProcessor
+	resultCollector   ResultCollector
+	config            *CrawlConfig
+	workerPool        *WorkerPool
+	frontier          *Frontier
+	started           bool
+	startedMutex      sync.Mutex // Guards started, the crawl being stopped by Wait or by a shutdown hook
+	progressTracker   *ProgressTracker
+	shutdownManager   *ShutdownManager
+	sitemapDiscoverer *SitemapDiscoverer
+	seedErr           error // First error crawling a seed page, e.g. the start page is unreachable
+	seedErrMutex      sync.Mutex
+	stopWatches       []func() bool // Stop watching the contexts the crawl was started with
+	monitorDone       chan struct{} // Closed once the shutdown signal monitoring started by Wait returns
+	checkpointPath    string        // File checkpoints are saved to, none if empty
+	checkpointTarget  string        // Site or directory recorded in checkpoints
+	checkpointEvery   time.Duration // Interval between checkpoints saved while the crawl runs
+	checkpointArmed   bool          // Set by Wait once every seed is scheduled, checkpoints taken earlier would miss seeds
+	checkpointMutex   sync.Mutex
 }
 
 // NewOptimizedCrawlerService creates a new optimized crawler service
 func NewOptimizedCrawlerService(pageParser PageParser, urlProcessor URLProcessor, resultCollector ResultCollector, config *CrawlConfig) *OptimizedCrawlerService {
-	// Progress tracking enabled if not in quiet mode
-	progressEnabled := !model.Quiet
-	
 	// Create shutdown manager
 	shutdownManager := NewShutdownManager()
 	
@@ -36,18 +45,29 @@ func NewOptimizedCrawlerService(pageParser PageParser, urlProcessor URLProcessor
 		resultCollector:  resultCollector,
 		config:           config,
 		started:          false,
-		progressTracker:  NewProgressTracker(progressEnabled),
+		progressTracker:  NewProgressTracker(config.ShowProgress),
 		shutdownManager:  shutdownManager,
 	}
 	
+	// Sitemap discovery fetches through the same link checker as pages
+	linkChecker := linkCheckerOf(pageParser)
+	if linkChecker != nil {
+		crawler.sitemapDiscoverer = NewSitemapDiscoverer(linkChecker)
+	}
+	
 	// Create worker pool - use concurrency setting as worker count
 	crawler.workerPool = NewWorkerPool(config.Concurrency, &CrawlerService{
 		pageParser:      pageParser,
 		urlProcessor:    urlProcessor,
 		resultCollector: resultCollector,
 		config:          config,
+		linkChecker:     linkChecker,
 	})
 	
+	// Create frontier scheduler so discovered pages are crawled up to MaxDepth
+	crawler.frontier = NewFrontier(crawler.workerPool, config.MaxDepth)
+	crawler.workerPool.SetFrontier(crawler.frontier)
+	
 	// Connect progress tracker to worker pool
 	crawler.workerPool.SetProgressTracker(crawler.progressTracker)
 	
@@ -57,86 +77,249 @@ func NewOptimizedCrawlerService(pageParser PageParser, urlProcessor URLProcessor
 	return crawler
 }
 
-// Crawl starts crawling using the worker pool
-func (c *OptimizedCrawlerService) Crawl(baseURL, currentURL string, currentDepth int) error {
-	if !c.started {
-		c.workerPool.Start()
-		c.started = true
-	}
+// Crawl starts crawling using the worker pool. Cancelling ctx stops the crawl like a shutdown signal,
+// aborting the requests in flight; Wait then returns with the results collected so far.
+func (c *OptimizedCrawlerService) Crawl(ctx context.Context, baseURL, currentURL string, currentDepth int) error {
+	c.start(ctx)
 	
 	// Create initial job
 	job := Job{
 		BaseURL:      baseURL,
 		TargetURL:    currentURL,
 		CurrentDepth: currentDepth,
-		Callback: func(results []model.LinkResult, err error) {
-			c.activeJobs.Done() // Signal completion
-		},
+		Callback:     c.recordSeedError,
+	}
+	
+	// Enqueue initial job
+	if !c.frontier.Enqueue(job) {
+		if c.frontier.IsStopped() {
+			logger.Errorf("Failed to enqueue initial job for %s", currentURL)
+			return fmt.Errorf("failed to enqueue initial job for %s", currentURL)
+		}
+		logger.Debugf("→ skip (already scheduled or too deep) : %s", currentURL)
+	}
+	
+	return nil
+}
+
+// CrawlSitemaps discovers the pages listed in the site's sitemaps and enqueues them as crawl seeds.
+// Each listed page is checked and reported with its sitemap as source, so stale sitemap entries show up as broken links.
+// It returns the number of pages found. Cancelling ctx stops the crawl, as for Crawl.
+func (c *OptimizedCrawlerService) CrawlSitemaps(ctx context.Context, baseURL string) (int, error) {
+	if c.sitemapDiscoverer == nil {
+		return 0, fmt.Errorf("sitemap discovery is not supported by this page parser")
+	}
+	
+	c.start(ctx)
+	
+	entries, err := c.sitemapDiscoverer.Discover(ctx, baseURL)
+	if err != nil {
+		return 0, err
+	}
+	
+	logger.Infof("Discovered %d pages from sitemaps of %s", len(entries), baseURL)
+	
+	for _, entry := range entries {
+		job := Job{
+			BaseURL:      baseURL,
+			TargetURL:    entry.Loc,
+			CurrentDepth: 0,
+			SourceURL:    entry.Sitemap,
+		}
+		if !c.frontier.Enqueue(job) && c.frontier.IsStopped() {
+			return len(entries), fmt.Errorf("crawler stopped while enqueuing sitemap pages")
+		}
+	}
+	
+	return len(entries), nil
+}
+
+// Resume restores the checkpoint of an interrupted crawl and schedules its pending pages, in place of Crawl.
+// The checkpointed results, visited pages and link cache are restored. Cancelling ctx stops the crawl, as for Crawl.
+func (c *OptimizedCrawlerService) Resume(ctx context.Context, state *CrawlState) error {
+	for _, result := range state.Results {
+		c.resultCollector.AddResult(result)
+	}
+	for _, pageURL := range state.Visited {
+		c.resultCollector.MarkVisited(pageURL)
+	}
+	if cachedChecker := c.cachedLinkChecker(); cachedChecker != nil {
+		restored := cachedChecker.cache.Restore(state.cacheEntries())
+		logger.Debugf("Restored %d cached link results from checkpoint", restored)
+	}
+	
+	c.start(ctx)
+	
+	for _, pending := range state.Pending {
+		job := Job{
+			BaseURL:      pending.BaseURL,
+			TargetURL:    pending.TargetURL,
+			CurrentDepth: pending.CurrentDepth,
+			SourceURL:    pending.SourceURL,
+		}
+		// Pages were crawled from a seed, sitemap pages are checked on their own
+		if job.SourceURL == "" {
+			job.Callback = c.recordSeedError
+		}
+		if !c.frontier.Enqueue(job) && c.frontier.IsStopped() {
+			return fmt.Errorf("crawler stopped while enqueuing pending pages")
+		}
 	}
 	
-	// Submit initial job
-	c.activeJobs.Add(1)
-	if !c.workerPool.Submit(job) {
-		c.activeJobs.Done()
-		logger.Errorf("Failed to submit initial job for %s", currentURL)
-		return fmt.Errorf("failed to submit initial job for %s", currentURL)
+	return nil
+}
+
+// EnableCheckpoints saves the state of the crawl of target to path every interval while Wait runs,
+// and once more when the crawl is interrupted. The file is removed once the crawl completes.
+func (c *OptimizedCrawlerService) EnableCheckpoints(path, target string, interval time.Duration) {
+	c.checkpointMutex.Lock()
+	defer c.checkpointMutex.Unlock()
+	
+	c.checkpointPath = path
+	c.checkpointTarget = target
+	c.checkpointEvery = interval
+}
+
+// Checkpoint saves the state of the crawl to the checkpoint file, if checkpoints are enabled.
+// Nothing is saved before Wait is called, when seeds may still be missing.
+func (c *OptimizedCrawlerService) Checkpoint() error {
+	c.checkpointMutex.Lock()
+	defer c.checkpointMutex.Unlock()
+	
+	if c.checkpointPath == "" || !c.checkpointArmed {
+		return nil
+	}
+	
+	pending, results, visited := c.workerPool.Snapshot()
+	var cache map[string]*CacheEntry
+	if cachedChecker := c.cachedLinkChecker(); cachedChecker != nil {
+		cache = cachedChecker.cache.Entries()
+	}
+	
+	state := newCrawlState(c.checkpointTarget, pending, results, visited, cache)
+	if err := SaveCrawlState(c.checkpointPath, state); err != nil {
+		return fmt.Errorf("failed to save checkpoint %s: %w", c.checkpointPath, err)
 	}
 	
+	logger.Debugf("Saved checkpoint to %s: %d pages pending, %d links checked", c.checkpointPath, len(state.Pending), len(state.Results))
 	return nil
 }
 
+// armCheckpoints enables saving checkpoints, returning the interval between them (0 when disabled)
+func (c *OptimizedCrawlerService) armCheckpoints() time.Duration {
+	c.checkpointMutex.Lock()
+	defer c.checkpointMutex.Unlock()
+	
+	if c.checkpointPath == "" {
+		return 0
+	}
+	c.checkpointArmed = true
+	return c.checkpointEvery
+}
+
+// runCheckpoints saves a checkpoint every interval until stop is closed, then closes done
+func (c *OptimizedCrawlerService) runCheckpoints(interval time.Duration, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	
+	if interval <= 0 {
+		return
+	}
+	
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := c.Checkpoint(); err != nil {
+				logger.Errorf("%s", err)
+			}
+		}
+	}
+}
+
+// discardCheckpoint removes the checkpoint file of a completed crawl and stops saving checkpoints
+func (c *OptimizedCrawlerService) discardCheckpoint() {
+	c.checkpointMutex.Lock()
+	defer c.checkpointMutex.Unlock()
+	
+	c.checkpointArmed = false
+	if c.checkpointPath == "" {
+		return
+	}
+	if err := removeCrawlState(c.checkpointPath); err != nil {
+		logger.Errorf("Failed to remove checkpoint %s: %s", c.checkpointPath, err)
+	}
+}
+
+// start starts the worker pool on the first call, and cancels the crawl once ctx is done
+func (c *OptimizedCrawlerService) start(ctx context.Context) {
+	c.startedMutex.Lock()
+	if !c.started {
+		c.workerPool.Start()
+		c.frontier.Start()
+		c.started = true
+	}
+	c.startedMutex.Unlock()
+	
+	if ctx.Done() != nil {
+		c.stopWatches = append(c.stopWatches, context.AfterFunc(ctx, c.Cancel))
+	}
+}
+
 // Wait waits for all crawling to complete
 func (c *OptimizedCrawlerService) Wait() {
 	// Start shutdown signal monitoring
-	go c.shutdownManager.WaitForShutdown()
+	monitorDone := make(chan struct{})
+	c.monitorDone = monitorDone
+	go func() {
+		defer close(monitorDone)
+		c.shutdownManager.WaitForShutdown()
+	}()
 	
 	// Start progress updates if enabled
 	if c.progressTracker.IsEnabled() {
 		go c.runProgressUpdates()
 	}
 	
-	// Wait for all jobs to complete or shutdown signal
-	done := make(chan bool, 1)
-	go func() {
-		c.activeJobs.Wait()
-		
-		// Wait for worker pool to become idle
-		for !c.workerPool.IsIdle() {
-			select {
-			case <-c.shutdownManager.Context().Done():
-				logger.Infof("Shutdown signal received, stopping crawler")
-				c.forceStop()
-				done <- true
-				return
-			case <-time.After(100 * time.Millisecond):
-				c.updateProgressStats()
-			}
-		}
-		done <- true
-	}()
+	// No more seeds will be added, so the crawl ends once the frontier drains
+	c.frontier.Seal()
+	
+	// Save checkpoints now that every seed is scheduled
+	stopCheckpoints := make(chan struct{})
+	checkpointsDone := make(chan struct{})
+	go c.runCheckpoints(c.armCheckpoints(), stopCheckpoints, checkpointsDone)
 	
 	// Wait for completion or shutdown
+	completed := false
 	select {
-	case <-done:
+	case <-c.frontier.Done():
 		logger.Debugf("Crawling completed normally")
+		completed = true
 	case <-c.shutdownManager.Context().Done():
 		logger.Infof("Crawling interrupted by shutdown signal")
 		c.forceStop()
 	}
+	close(stopCheckpoints)
+	<-checkpointsDone
 	
 	// Finish progress tracking
 	c.progressTracker.Finish()
 	
 	// Stop the worker pool
-	if c.started {
-		c.workerPool.Stop()
-		c.started = false
-	}
+	c.stopPool(false)
 	
 	// Wait for shutdown to complete if it was initiated
 	if c.shutdownManager.IsShuttingDown() {
 		c.shutdownManager.WaitForCompletion()
 	}
+	
+	// A completed crawl has nothing left to resume
+	if completed {
+		c.discardCheckpoint()
+	}
 }
 
 // SetConfig updates the crawler configuration
@@ -145,8 +328,27 @@ func (c *OptimizedCrawlerService) SetConfig(config *CrawlConfig) {
 }
 
 // StartCrawl starts the initial crawl with proper management
-func (c *OptimizedCrawlerService) StartCrawl(baseURL, currentURL string, currentDepth int) error {
-	return c.Crawl(baseURL, currentURL, currentDepth)
+func (c *OptimizedCrawlerService) StartCrawl(ctx context.Context, baseURL, currentURL string, currentDepth int) error {
+	return c.Crawl(ctx, baseURL, currentURL, currentDepth)
+}
+
+// SeedError returns the first error that prevented crawling a seed page, such as an unreachable start URL
+func (c *OptimizedCrawlerService) SeedError() error {
+	c.seedErrMutex.Lock()
+	defer c.seedErrMutex.Unlock()
+	return c.seedErr
+}
+
+// recordSeedError is the callback of seed page jobs
+func (c *OptimizedCrawlerService) recordSeedError(_ []model.LinkResult, err error) {
+	if err == nil {
+		return
+	}
+	c.seedErrMutex.Lock()
+	defer c.seedErrMutex.Unlock()
+	if c.seedErr == nil {
+		c.seedErr = err
+	}
 }
 
 // GetResults returns the collected results
@@ -154,35 +356,85 @@ func (c *OptimizedCrawlerService) GetResults() []model.LinkResult {
 	return c.resultCollector.GetResults()
 }
 
+// CountResults returns the number of links checked so far
+func (c *OptimizedCrawlerService) CountResults() int {
+	return c.resultCollector.CountResults()
+}
+
 // CountBrokenLinks returns the count of broken links
 func (c *OptimizedCrawlerService) CountBrokenLinks() int {
 	return c.resultCollector.CountBrokenLinks()
 }
 
+// PendingJobs returns the number of pages queued or being crawled
+func (c *OptimizedCrawlerService) PendingJobs() int64 {
+	return c.frontier.Pending()
+}
+
 // GetStats returns worker pool statistics
 func (c *OptimizedCrawlerService) GetStats() PoolStats {
 	return c.workerPool.GetStats()
 }
 
+// Progress returns the current progress statistics of the crawl
+func (c *OptimizedCrawlerService) Progress() ProgressStats {
+	c.updateProgressStats()
+	return c.progressTracker.GetStats()
+}
+
+// SetProgressDisplay enables or disables rendering the progress bar in the terminal.
+// Progress statistics are collected either way.
+func (c *OptimizedCrawlerService) SetProgressDisplay(enabled bool) {
+	c.progressTracker.SetEnabled(enabled)
+}
+
+// Cancel stops the crawl as a shutdown signal would, aborting the requests in flight.
+// Wait returns with the results collected so far.
+func (c *OptimizedCrawlerService) Cancel() {
+	c.shutdownManager.InitiateShutdown()
+}
+
 // Stop gracefully stops the crawler
 func (c *OptimizedCrawlerService) Stop() {
-	if c.started {
-		c.workerPool.Stop()
-		c.started = false
+	for _, stopWatch := range c.stopWatches {
+		stopWatch()
 	}
 	
-	// Cleanup shutdown manager
+	c.stopPool(false)
+	
+	// Persist the link cache for the next run
+	if err := c.flushCache(); err != nil {
+		logger.Errorf("%s", err)
+	}
+	
+	// Cleanup shutdown manager, ending the signal monitoring
 	c.shutdownManager.Cleanup()
+	if c.monitorDone != nil {
+		<-c.monitorDone
+	}
 }
 
 // forceStop immediately stops the crawler (used during shutdown)
 func (c *OptimizedCrawlerService) forceStop() {
 	logger.Infof("Force stopping crawler...")
+	c.stopPool(true)
+}
+
+// stopPool stops the frontier and the worker pool if they were started, waiting for the workers unless forced
+func (c *OptimizedCrawlerService) stopPool(force bool) {
+	c.startedMutex.Lock()
+	defer c.startedMutex.Unlock()
 	
-	if c.started {
-		c.workerPool.ForceStop() // Will implement this method
-		c.started = false
+	if !c.started {
+		return
 	}
+	c.frontier.Stop()
+	if force {
+		c.workerPool.ForceStop()
+	} else {
+		c.workerPool.Stop()
+	}
+	c.started = false
 }
 
 // registerShutdownHooks registers cleanup functions for graceful shutdown
@@ -190,10 +442,9 @@ func (c *OptimizedCrawlerService) registerShutdownHooks() {
 	// Register worker pool cleanup
 	c.shutdownManager.AddShutdownHook(func() error {
 		logger.Debugf("Shutdown hook: stopping worker pool")
-		if c.started {
-			c.workerPool.Stop()
-			c.started = false
-		}
+		// Abort the requests in flight instead of waiting for them
+		c.workerPool.Cancel()
+		c.stopPool(false)
 		return nil
 	})
 	
@@ -206,27 +457,82 @@ func (c *OptimizedCrawlerService) registerShutdownHooks() {
 		return nil
 	})
 	
+	// Register persistent cache flush
+	c.shutdownManager.AddShutdownHook(func() error {
+		logger.Debugf("Shutdown hook: flushing link cache")
+		if err := c.flushCache(); err != nil {
+			return NewShutdownError("link cache", err)
+		}
+		return nil
+	})
+	
+	// Register checkpoint saving, once the workers are stopped, so the crawl can be resumed
+	c.shutdownManager.AddShutdownHook(func() error {
+		logger.Debugf("Shutdown hook: saving checkpoint")
+		if err := c.Checkpoint(); err != nil {
+			return NewShutdownError("checkpoint", err)
+		}
+		return nil
+	})
+	
 	// Register result collection finalization
 	c.shutdownManager.AddShutdownHook(func() error {
 		logger.Debugf("Shutdown hook: finalizing results")
-		results := c.resultCollector.GetResults()
 		logger.Infof("Final results: %d links checked, %d broken", 
-			len(results), c.resultCollector.CountBrokenLinks())
+			c.resultCollector.CountResults(), c.resultCollector.CountBrokenLinks())
 		return nil
 	})
 }
 
+// linkCheckerOf returns the link checker used by a page parser, if it exposes one
+func linkCheckerOf(pageParser PageParser) LinkChecker {
+	switch parser := pageParser.(type) {
+	case *PageParserService:
+		return parser.LinkChecker
+	case *FileSystemPageParser:
+		return parser.LinkChecker
+	case *CDPPageParser:
+		return parser.LinkChecker
+	default:
+		return nil
+	}
+}
+
+// cachedLinkChecker returns the caching link checker used by the page parser, if any
+func (c *OptimizedCrawlerService) cachedLinkChecker() *CachedLinkCheckerService {
+	switch checker := linkCheckerOf(c.pageParser).(type) {
+	case *CachedOptimizedLinkCheckerService:
+		return checker.CachedLinkCheckerService
+	case *CachedLinkCheckerService:
+		return checker
+	default:
+		return nil
+	}
+}
+
+// flushCache writes the link cache to its persistent store, if one is configured
+func (c *OptimizedCrawlerService) flushCache() error {
+	if cachedChecker := c.cachedLinkChecker(); cachedChecker != nil {
+		return cachedChecker.Flush()
+	}
+	return nil
+}
+
 // runProgressUpdates runs the progress update loop
 func (c *OptimizedCrawlerService) runProgressUpdates() {
 	ticker := time.NewTicker(500 * time.Millisecond)
 	defer ticker.Stop()
 	
-	for range ticker.C {
-		if c.workerPool.IsIdle() && c.activeJobs == (sync.WaitGroup{}) {
+	for {
+		select {
+		case <-c.frontier.Done():
 			return // Crawling is done
+		case <-c.shutdownManager.Context().Done():
+			return
+		case <-ticker.C:
+			c.updateProgressStats()
+			c.progressTracker.RenderProgressBar()
 		}
-		c.updateProgressStats()
-		c.progressTracker.RenderProgressBar()
 	}
 }
 
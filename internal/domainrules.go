@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/model"
+)
+
+// lookupDomain returns the value configured for a host. Entries for the host with its port, then for its name,
+// win over "*.domain" patterns, the closest parent domain first.
+func lookupDomain[V any](values map[string]V, host string) (V, bool) {
+	var zero V
+	if len(values) == 0 || host == "" {
+		return zero, false
+	}
+
+	host = strings.ToLower(host)
+	if value, found := values[host]; found {
+		return value, true
+	}
+
+	hostname := host
+	if parsed, err := url.Parse("//" + host); err == nil && parsed.Hostname() != "" {
+		hostname = parsed.Hostname()
+	}
+	if value, found := values[hostname]; found {
+		return value, true
+	}
+
+	for parent := hostname; ; {
+		dot := strings.IndexByte(parent, '.')
+		if dot < 0 {
+			return zero, false
+		}
+		parent = parent[dot+1:]
+		if value, found := values["*."+parent]; found {
+			return value, true
+		}
+	}
+}
+
+// domainRuleFor returns the rule of the host of a URL, the zero rule when none applies
+func domainRuleFor(rules map[string]model.DomainRule, targetURL string) model.DomainRule {
+	domain, err := extractDomain(targetURL)
+	if err != nil {
+		return model.DomainRule{}
+	}
+	rule, _ := lookupDomain(rules, domain)
+	return rule
+}
+
+// attemptsFor returns the number of attempts of a GET request under a rule, defaulting to attempts
+func attemptsFor(rule model.DomainRule, method string, attempts int) int {
+	if rule.Retries == nil || method == http.MethodHead {
+		return attempts
+	}
+	return *rule.Retries + 1
+}
+
+// timeoutFor returns the timeout of a request under a rule, defaulting to timeout
+func timeoutFor(rule model.DomainRule, timeout time.Duration) time.Duration {
+	if rule.Timeout > 0 {
+		return rule.Timeout
+	}
+	return timeout
+}
+
+// doWithTimeout sends a request, aborting it when the response is not fully read within the timeout (0 for none)
+func doWithTimeout(client HTTPClient, req *http.Request, timeout time.Duration) (*http.Response, error) {
+	if timeout <= 0 {
+		return client.Do(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil || resp == nil {
+		cancel()
+		return resp, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases the context of a request once its response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (cc *cancelOnClose) Close() error {
+	err := cc.ReadCloser.Close()
+	cc.cancel()
+	return err
+}
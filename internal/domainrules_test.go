@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingClient counts the requests it fails
+type countingClient struct {
+	requests atomic.Int32
+}
+
+func (cc *countingClient) Do(req *http.Request) (*http.Response, error) {
+	cc.requests.Add(1)
+	return nil, errors.New("connection reset")
+}
+
+func TestLookupDomain(t *testing.T) {
+	values := map[string]string{
+		"example.com":       "exact",
+		"localhost:8080":    "port",
+		"*.example.com":     "subdomains",
+		"*.cdn.example.com": "cdn",
+	}
+
+	tests := []struct {
+		host     string
+		expected string
+		found    bool
+	}{
+		{host: "example.com", expected: "exact", found: true},
+		{host: "EXAMPLE.com:443", expected: "exact", found: true},
+		{host: "www.example.com", expected: "subdomains", found: true},
+		{host: "img.cdn.example.com", expected: "cdn", found: true},
+		{host: "localhost:8080", expected: "port", found: true},
+		{host: "localhost:9090", found: false},
+		{host: "example.org", found: false},
+		{host: "notexample.com", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			value, found := lookupDomain(values, tt.host)
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+}
+
+func TestLinkCheckerDomainRules(t *testing.T) {
+	logger.SetQuiet(true)
+
+	var heads, gets atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(500 * time.Millisecond)
+		}
+		if r.Method == http.MethodHead {
+			heads.Add(1)
+		} else {
+			gets.Add(1)
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><body>Page</body></html>"))
+	}))
+	defer server.Close()
+	host, err := extractDomain(server.URL)
+	require.NoError(t, err)
+
+	noHead, noRetries := false, 0
+
+	t.Run("Skipped domains are not requested", func(t *testing.T) {
+		client := &countingClient{}
+		checker := NewOptimizedLinkCheckerService(client, "Test/1.0", 5*time.Second, 100, 100)
+		checker.SetDomainRules(map[string]model.DomainRule{"*.example.com": {Skip: true}})
+
+		status, errMsg := checker.CheckLink(context.Background(), "https://www.example.com/")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Empty(t, errMsg)
+		assert.Zero(t, client.requests.Load())
+	})
+
+	t.Run("HEAD requests can be disabled", func(t *testing.T) {
+		heads.Store(0)
+		gets.Store(0)
+		checker := NewOptimizedLinkCheckerService(&http.Client{}, "Test/1.0", 5*time.Second, 100, 100)
+		checker.SetDomainRules(map[string]model.DomainRule{host: {Head: &noHead}})
+
+		status, _ := checker.CheckLink(context.Background(), server.URL+"/page")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Zero(t, heads.Load())
+		assert.Equal(t, int32(1), gets.Load())
+	})
+
+	t.Run("Retries are configured per domain", func(t *testing.T) {
+		client := &countingClient{}
+		checker := NewLinkCheckerServiceWithRateLimit(client, "Test/1.0", 5*time.Second, 100, 100)
+		checker.SetDomainRules(map[string]model.DomainRule{"flaky.example.com": {Retries: &noRetries}})
+
+		status, _ := checker.CheckLink(context.Background(), "https://flaky.example.com/")
+		assert.Equal(t, 0, status)
+		assert.Equal(t, int32(1), client.requests.Load(), "failed requests are not retried")
+	})
+
+	t.Run("Timeouts are configured per domain", func(t *testing.T) {
+		checker := NewOptimizedLinkCheckerService(&http.Client{}, "Test/1.0", 5*time.Second, 100, 100)
+		checker.SetDomainRules(map[string]model.DomainRule{host: {Timeout: 50 * time.Millisecond, Head: &noHead, Retries: &noRetries}})
+
+		start := time.Now()
+		status, errMsg := checker.CheckLink(context.Background(), server.URL+"/slow")
+		assert.Equal(t, 0, status)
+		assert.Contains(t, errMsg, "deadline exceeded")
+		assert.Less(t, time.Since(start), 400*time.Millisecond)
+
+		status, errMsg = checker.CheckLink(context.Background(), server.URL+"/page")
+		assert.Equal(t, http.StatusOK, status, "responses within the timeout are read")
+		assert.Empty(t, errMsg)
+	})
+
+	t.Run("Domains without a rule use the checker's timeout", func(t *testing.T) {
+		optimized := NewOptimizedLinkCheckerService(&http.Client{}, "Test/1.0", 50*time.Millisecond, 100, 100)
+		start := time.Now()
+		_, err := optimized.FetchWithRetryMethod(context.Background(), server.URL+"/slow", 1, http.MethodGet)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 400*time.Millisecond)
+
+		checker := NewLinkCheckerServiceWithRateLimit(&http.Client{}, "Test/1.0", 50*time.Millisecond, 100, 100)
+		start = time.Now()
+		_, err = checker.FetchWithRetry(context.Background(), server.URL+"/slow", 1)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 400*time.Millisecond)
+	})
+}
+
+func TestDomainRateLimiterPatterns(t *testing.T) {
+	logger.SetQuiet(true)
+
+	limiter := NewDomainRateLimiter(10, 5)
+	require.NoError(t, limiter.Wait(context.Background(), "https://www.example.com/"))
+
+	limiter.UpdateConfig("*.example.com", 0.5)
+	limiter.UpdateBurst("*.example.com", 2)
+	limiter.UpdateBurst("api.example.com", 1)
+	require.NoError(t, limiter.Wait(context.Background(), "https://api.example.com/"))
+	require.NoError(t, limiter.Wait(context.Background(), "https://example.org/"))
+
+	stats := limiter.GetStats()
+	assert.Equal(t, 0.5, stats["www.example.com"].Rate, "existing buckets follow new patterns")
+	assert.Equal(t, 2.0, stats["www.example.com"].MaxTokens)
+	assert.Equal(t, 0.5, stats["api.example.com"].Rate)
+	assert.Equal(t, 1.0, stats["api.example.com"].MaxTokens, "exact domains win over patterns")
+	assert.Equal(t, 10.0, stats["example.org"].Rate)
+	assert.Equal(t, 5.0, stats["example.org"].MaxTokens)
+}
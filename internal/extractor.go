@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+)
+
+// ExtractorRule describes an element attribute holding a URL to check
+type ExtractorRule struct {
+	Element  string // Element type recorded on results, e.g. "img"
+	Selector string // CSS selector of the elements carrying the URL
+	Attr     string // Attribute holding the URL
+	Srcset   bool   // Attribute holds a srcset list of candidate URLs
+	Crawl    bool   // Links from this rule point to pages that can be crawled
+}
+
+// ExtractorRegistry holds the rules used to extract links from pages
+type ExtractorRegistry struct {
+	rules []ExtractorRule
+}
+
+// NewExtractorRegistry creates a registry with the given rules
+func NewExtractorRegistry(rules ...ExtractorRule) *ExtractorRegistry {
+	registry := &ExtractorRegistry{}
+	for _, rule := range rules {
+		registry.Register(rule)
+	}
+	return registry
+}
+
+// DefaultExtractorRegistry creates a registry covering every link-bearing element deadlinkr knows about
+func DefaultExtractorRegistry() *ExtractorRegistry {
+	return NewExtractorRegistry(
+		ExtractorRule{Element: "a", Selector: "a[href]", Attr: "href", Crawl: true},
+		ExtractorRule{Element: "area", Selector: "area[href]", Attr: "href", Crawl: true},
+		ExtractorRule{Element: "img", Selector: "img[src]", Attr: "src"},
+		ExtractorRule{Element: "img", Selector: "img[srcset]", Attr: "srcset", Srcset: true},
+		ExtractorRule{Element: "script", Selector: "script[src]", Attr: "src"},
+		ExtractorRule{Element: "link", Selector: "link[href][rel~=stylesheet], link[href][rel~=icon], link[href][rel~=apple-touch-icon], link[href][rel~=manifest], link[href][rel~=preload]", Attr: "href"},
+		ExtractorRule{Element: "canonical", Selector: "link[href][rel~=canonical]", Attr: "href"},
+		ExtractorRule{Element: "source", Selector: "source[src]", Attr: "src"},
+		ExtractorRule{Element: "source", Selector: "source[srcset]", Attr: "srcset", Srcset: true},
+		ExtractorRule{Element: "iframe", Selector: "iframe[src]", Attr: "src"},
+		ExtractorRule{Element: "form", Selector: `form[action]:not([method="post"]):not([method="POST"])`, Attr: "action"},
+		ExtractorRule{Element: "video", Selector: "video[src]", Attr: "src"},
+		ExtractorRule{Element: "video", Selector: "video[poster]", Attr: "poster"},
+		ExtractorRule{Element: "audio", Selector: "audio[src]", Attr: "src"},
+		ExtractorRule{Element: "meta", Selector: `meta[property="og:image"][content], meta[property="og:image:url"][content], meta[property="og:image:secure_url"][content], meta[property="og:url"][content], meta[property="og:video"][content], meta[property="og:audio"][content]`, Attr: "content"},
+	)
+}
+
+// Register adds a rule to the registry
+func (er *ExtractorRegistry) Register(rule ExtractorRule) {
+	er.rules = append(er.rules, rule)
+}
+
+// Rules returns the registered rules
+func (er *ExtractorRegistry) Rules() []ExtractorRule {
+	return er.rules
+}
+
+// ElementTypes returns the sorted, distinct element types of the registered rules
+func (er *ExtractorRegistry) ElementTypes() []string {
+	seen := make(map[string]bool)
+	types := []string{}
+	for _, rule := range er.rules {
+		if !seen[rule.Element] {
+			seen[rule.Element] = true
+			types = append(types, rule.Element)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// IsCrawlable checks whether links extracted for an element type lead to crawlable pages
+func (er *ExtractorRegistry) IsCrawlable(element string) bool {
+	for _, rule := range er.rules {
+		if rule.Element == element {
+			return rule.Crawl
+		}
+	}
+	return false
+}
+
+// Filter returns a registry restricted to the included element types (all when empty) minus the excluded ones.
+// Unknown element types are reported and ignored.
+func (er *ExtractorRegistry) Filter(include, exclude []string) *ExtractorRegistry {
+	known := make(map[string]bool)
+	for _, element := range er.ElementTypes() {
+		known[element] = true
+	}
+
+	normalize := func(elements []string) map[string]bool {
+		set := make(map[string]bool)
+		for _, element := range elements {
+			element = strings.ToLower(strings.TrimSpace(element))
+			if element == "" {
+				continue
+			}
+			if !known[element] {
+				logger.Warnf("Unknown element type %q, expected one of: %s", element, strings.Join(er.ElementTypes(), ", "))
+				continue
+			}
+			set[element] = true
+		}
+		return set
+	}
+
+	included := normalize(include)
+	excluded := normalize(exclude)
+
+	filtered := &ExtractorRegistry{}
+	for _, rule := range er.rules {
+		if len(included) > 0 && !included[rule.Element] {
+			continue
+		}
+		if excluded[rule.Element] {
+			continue
+		}
+		filtered.Register(rule)
+	}
+	return filtered
+}
+
+// parseSrcset returns the candidate URLs of a srcset attribute, ignoring width and density descriptors
+func parseSrcset(srcset string) []string {
+	urls := []string{}
+	rest := srcset
+
+	for {
+		// Skip separators before the next candidate
+		rest = strings.TrimLeft(rest, " \t\n\r\f,")
+		if rest == "" {
+			return urls
+		}
+
+		end := strings.IndexAny(rest, " \t\n\r\f")
+		if end < 0 {
+			end = len(rest)
+		}
+		candidate := rest[:end]
+		rest = rest[end:]
+
+		// A URL directly followed by a comma has no descriptors
+		if trimmed := strings.TrimRight(candidate, ","); trimmed != candidate {
+			if trimmed != "" {
+				urls = append(urls, trimmed)
+			}
+			continue
+		}
+		urls = append(urls, candidate)
+
+		rest = skipSrcsetDescriptors(rest)
+	}
+}
+
+// skipSrcsetDescriptors returns the remainder of a srcset after the current candidate's descriptors
+func skipSrcsetDescriptors(rest string) string {
+	depth := 0
+	for i := 0; i < len(rest); i++ {
+		switch rest[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				return rest[i:]
+			}
+		}
+	}
+	return ""
+}
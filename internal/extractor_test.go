@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const extractorTestPage = `<html>
+<head>
+  <link rel="stylesheet" href="/style.css">
+  <link rel="canonical" href="/canonical">
+  <link rel="preconnect" href="https://cdn.example.com">
+  <meta property="og:image" content="/og.png">
+  <script src="/app.js"></script>
+</head>
+<body>
+  <a href="/page">Page</a>
+  <a href="#top">Top</a>
+  <img src="/logo.png" srcset="/logo-1x.png 1x, /logo-2x.png 2x">
+  <picture><source srcset="/hero.webp"></picture>
+  <iframe src="/embed"></iframe>
+  <form action="/search"></form>
+  <form action="/login" method="post"></form>
+  <video src="/movie.mp4" poster="/poster.jpg"></video>
+  <audio src="/sound.mp3"></audio>
+</body>
+</html>`
+
+func extractFromTestPage(t *testing.T, extractors *ExtractorRegistry) map[string]string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	parser := NewPageParserService(NewLinkCheckerServiceWithRateLimit(client, "Test/1.0", 5*time.Second, 100, 100), NewURLProcessorService("", ""), "", false)
+	if extractors != nil {
+		parser.SetExtractors(extractors)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(extractorTestPage))
+	require.NoError(t, err)
+
+	base, _ := url.Parse(server.URL)
+	elements := make(map[string]string)
+	for _, link := range parser.ExtractLinks(context.Background(), base, server.URL+"/", doc) {
+		elements[strings.TrimPrefix(link.TargetURL, server.URL)] = link.Element
+	}
+	return elements
+}
+
+func TestExtractLinks(t *testing.T) {
+	logger.SetQuiet(true)
+
+	t.Run("Extracts every link-bearing element", func(t *testing.T) {
+		elements := extractFromTestPage(t, nil)
+		assert.Equal(t, map[string]string{
+			"/style.css":   "link",
+			"/canonical":   "canonical",
+			"/og.png":      "meta",
+			"/app.js":      "script",
+			"/page":        "a",
+			"/logo.png":    "img",
+			"/logo-1x.png": "img",
+			"/logo-2x.png": "img",
+			"/hero.webp":   "source",
+			"/embed":       "iframe",
+			"/search":      "form",
+			"/movie.mp4":   "video",
+			"/poster.jpg":  "video",
+			"/sound.mp3":   "audio",
+		}, elements)
+	})
+
+	t.Run("Includes only selected element types", func(t *testing.T) {
+		elements := extractFromTestPage(t, DefaultExtractorRegistry().Filter([]string{"a", "IMG"}, nil))
+		assert.Equal(t, map[string]string{
+			"/page":        "a",
+			"/logo.png":    "img",
+			"/logo-1x.png": "img",
+			"/logo-2x.png": "img",
+		}, elements)
+	})
+
+	t.Run("Excludes element types", func(t *testing.T) {
+		elements := extractFromTestPage(t, DefaultExtractorRegistry().Filter(nil, []string{"img", "source", "video", "audio", "meta", "unknown"}))
+		assert.Equal(t, map[string]string{
+			"/style.css": "link",
+			"/canonical": "canonical",
+			"/app.js":    "script",
+			"/page":      "a",
+			"/embed":     "iframe",
+			"/search":    "form",
+		}, elements)
+	})
+}
+
+func TestExtractorRegistry(t *testing.T) {
+	registry := DefaultExtractorRegistry()
+
+	assert.Contains(t, registry.ElementTypes(), "canonical")
+	assert.True(t, registry.IsCrawlable("a"))
+	assert.False(t, registry.IsCrawlable("img"))
+	assert.False(t, registry.IsCrawlable("unknown"))
+
+	custom := NewExtractorRegistry(ExtractorRule{Element: "embed", Selector: "embed[src]", Attr: "src"})
+	assert.Equal(t, []string{"embed"}, custom.ElementTypes())
+}
+
+func TestParseSrcset(t *testing.T) {
+	tests := []struct {
+		srcset   string
+		expected []string
+	}{
+		{"image.png", []string{"image.png"}},
+		{"small.png 480w, large.png 1080w", []string{"small.png", "large.png"}},
+		{" a.png 1x ,b.png 2x", []string{"a.png", "b.png"}},
+		{"a.png,b.png 2x", []string{"a.png,b.png"}},
+		{"a.png, b.png", []string{"a.png", "b.png"}},
+		{"", []string{}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, parseSrcset(tt.srcset), tt.srcset)
+	}
+}
+
+func TestOptimizedCrawlerCrawlsOnlyPages(t *testing.T) {
+	logger.SetQuiet(true)
+
+	requested := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			requested <- r.URL.Path
+		}
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><a href="/page">Page</a><iframe src="/frame"></iframe></body></html>`))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body>Leaf</body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	factory := NewServiceFactory()
+	config := factory.CreateCrawlConfigFromParams(1, 2, false, "", "", "")
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	crawler, err := factory.CreateOptimizedCrawlerServiceWithRateLimit(config, "TestAgent", 5*time.Second, httpClient, 100, 100)
+	require.NoError(t, err)
+	defer crawler.Stop()
+
+	require.NoError(t, crawler.Crawl(context.Background(), server.URL, server.URL+"/", 0))
+	crawler.Wait()
+	close(requested)
+
+	// The iframe is checked once, the anchor is checked and then crawled
+	counts := make(map[string]int)
+	for path := range requested {
+		counts[path]++
+	}
+	assert.Equal(t, 1, counts["/frame"])
+	assert.Equal(t, 2, counts["/page"])
+	assert.Len(t, crawler.GetResults(), 2)
+}
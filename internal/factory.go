@@ -1,7 +1,7 @@
 package internal
 
 import (
-	"net/http"
+	"context"
 	"time"
 
 	"github.com/DrakkarStorm/deadlinkr/logger"
@@ -9,91 +9,232 @@ import (
 )
 
 // ServiceFactory creates and wires up all services
-type ServiceFactory struct{}
+type ServiceFactory struct {
+	metrics       *Metrics   // Records the requests of the created services when set
+	resultSink    ResultSink // Receives the results of the created crawlers when set
+	retainResults bool       // Keep streamed results in memory as well
+	credentials   credentials
+
+	domainRateLimits     map[string]float64          // Requests per second per domain
+	domainSoft404Phrases map[string][]string         // Phrases marking pages of a domain as soft 404s
+	domainRules          map[string]model.DomainRule // Request overrides per domain
+}
+
+// credentials are the authentication settings of the requests, as given on the command line
+type credentials struct {
+	basic   string   // Basic auth in "user:password" format
+	bearer  string   // Bearer token
+	headers []string // Custom headers in "Key: Value" format
+	cookies string   // Cookie string
+}
 
 // NewServiceFactory creates a new ServiceFactory
 func NewServiceFactory() *ServiceFactory {
 	return &ServiceFactory{}
 }
 
+// SetMetrics records the latency and status code of the requests sent by the services created afterwards
+func (sf *ServiceFactory) SetMetrics(metrics *Metrics) {
+	sf.metrics = metrics
+}
+
+// SetResultSink streams the results of the crawlers created afterwards to sink as they are produced.
+// Their results are kept in memory for GetResults only if retain is set.
+func (sf *ServiceFactory) SetResultSink(sink ResultSink, retain bool) {
+	sf.resultSink = sink
+	sf.retainResults = retain
+}
+
+// SetCredentials authenticates the requests of the services created afterwards with basic auth in "user:password"
+// format, a bearer token, custom headers in "Key: Value" format and cookies. Those left empty are read from the environment.
+func (sf *ServiceFactory) SetCredentials(basic, bearer string, headers []string, cookies string) {
+	sf.credentials = credentials{basic: basic, bearer: bearer, headers: headers, cookies: cookies}
+}
+
+// SetDomainRules applies per-domain rate limits, soft 404 phrases and request overrides, keyed by host
+// or "*.domain" pattern, to the services created afterwards
+func (sf *ServiceFactory) SetDomainRules(rateLimits map[string]float64, soft404Phrases map[string][]string, rules map[string]model.DomainRule) {
+	sf.domainRateLimits = rateLimits
+	sf.domainSoft404Phrases = soft404Phrases
+	sf.domainRules = rules
+}
+
+// createResultCollector creates the result collector of a crawler, streaming to the result sink when set
+func (sf *ServiceFactory) createResultCollector() *ResultCollectorService {
+	if sf.resultSink == nil {
+		return NewResultCollectorService()
+	}
+	return NewStreamingResultCollectorService(sf.resultSink, sf.retainResults)
+}
+
 // CreateCrawlerService creates a fully configured crawler service
-func (sf *ServiceFactory) CreateCrawlerService(config *CrawlConfig, userAgent string, timeout time.Duration, httpClient *http.Client) *CrawlerService {
-	// Wrap HTTP client with authentication if configured
-	authClient := sf.createAuthenticatedClient(httpClient)
+func (sf *ServiceFactory) CreateCrawlerService(config *CrawlConfig, userAgent string, timeout time.Duration, httpClient HTTPClient) (*CrawlerService, error) {
+	// Wrap HTTP client with authentication if configured, recording redirect chains
+	authClient := sf.createHTTPClient(httpClient)
 	
 	// Create services
 	linkChecker := NewLinkCheckerService(authClient, userAgent, timeout)
 	urlProcessor := NewURLProcessorService(config.IncludePattern, config.ExcludePattern)
-	resultCollector := NewResultCollectorService()
-	pageParser := NewPageParserService(linkChecker, urlProcessor, config.ExcludeHtmlTags, config.OnlyInternal)
+	resultCollector := sf.createResultCollector()
+	pageParser, err := sf.createPageParser(linkChecker, urlProcessor, authClient, config)
+	if err != nil {
+		return nil, err
+	}
+	sf.configureRobots(config, urlProcessor, authClient, userAgent, timeout, linkChecker)
+	sf.applyDomainRules(linkChecker)
+	sf.configureSoft404(config, linkChecker)
 
 	// Create crawler
 	crawler := NewCrawlerService(pageParser, urlProcessor, resultCollector, config)
 
-	return crawler
+	return crawler, nil
 }
 
 // CreateOptimizedCrawlerService creates an optimized crawler with worker pool
-func (sf *ServiceFactory) CreateOptimizedCrawlerService(config *CrawlConfig, userAgent string, timeout time.Duration, httpClient *http.Client) *OptimizedCrawlerService {
-	// Wrap HTTP client with authentication if configured
-	authClient := sf.createAuthenticatedClient(httpClient)
+func (sf *ServiceFactory) CreateOptimizedCrawlerService(config *CrawlConfig, userAgent string, timeout time.Duration, httpClient HTTPClient) (*OptimizedCrawlerService, error) {
+	// Wrap HTTP client with authentication if configured, recording redirect chains
+	authClient := sf.createHTTPClient(httpClient)
 	
 	// Create services
 	linkChecker := NewLinkCheckerService(authClient, userAgent, timeout)
 	urlProcessor := NewURLProcessorService(config.IncludePattern, config.ExcludePattern)
-	resultCollector := NewResultCollectorService()
-	pageParser := NewPageParserService(linkChecker, urlProcessor, config.ExcludeHtmlTags, config.OnlyInternal)
+	resultCollector := sf.createResultCollector()
+	pageParser, err := sf.createPageParser(linkChecker, urlProcessor, authClient, config)
+	if err != nil {
+		return nil, err
+	}
+	sf.configureRobots(config, urlProcessor, authClient, userAgent, timeout, linkChecker)
+	sf.applyDomainRules(linkChecker)
+	sf.configureSoft404(config, linkChecker)
 
 	// Create optimized crawler
 	crawler := NewOptimizedCrawlerService(pageParser, urlProcessor, resultCollector, config)
 
-	return crawler
+	return crawler, nil
 }
 
 // CreateOptimizedCrawlerServiceWithRateLimit creates an optimized crawler with custom rate limiting
-func (sf *ServiceFactory) CreateOptimizedCrawlerServiceWithRateLimit(config *CrawlConfig, userAgent string, timeout time.Duration, httpClient *http.Client, rateLimit, burst float64) *OptimizedCrawlerService {
-	// Wrap HTTP client with authentication if configured
-	authClient := sf.createAuthenticatedClient(httpClient)
+func (sf *ServiceFactory) CreateOptimizedCrawlerServiceWithRateLimit(config *CrawlConfig, userAgent string, timeout time.Duration, httpClient HTTPClient, rateLimit, burst float64) (*OptimizedCrawlerService, error) {
+	// Wrap HTTP client with authentication if configured, recording redirect chains
+	authClient := sf.createHTTPClient(httpClient)
 	
 	// Create services with custom rate limiting
 	linkChecker := NewLinkCheckerServiceWithRateLimit(authClient, userAgent, timeout, rateLimit, burst)
 	urlProcessor := NewURLProcessorService(config.IncludePattern, config.ExcludePattern)
-	resultCollector := NewResultCollectorService()
-	pageParser := NewPageParserService(linkChecker, urlProcessor, config.ExcludeHtmlTags, config.OnlyInternal)
+	resultCollector := sf.createResultCollector()
+	pageParser, err := sf.createPageParser(linkChecker, urlProcessor, authClient, config)
+	if err != nil {
+		return nil, err
+	}
+	sf.configureRobots(config, urlProcessor, authClient, userAgent, timeout, linkChecker)
+	sf.applyDomainRules(linkChecker)
+	sf.configureSoft404(config, linkChecker)
 
 	// Create optimized crawler
 	crawler := NewOptimizedCrawlerService(pageParser, urlProcessor, resultCollector, config)
 
-	return crawler
+	return crawler, nil
 }
 
 // CreateOptimizedCrawlerServiceWithHeadOptimization creates an optimized crawler with HEAD request optimization
-func (sf *ServiceFactory) CreateOptimizedCrawlerServiceWithHeadOptimization(config *CrawlConfig, userAgent string, timeout time.Duration, httpClient *http.Client, rateLimit, burst float64) *OptimizedCrawlerService {
-	// Wrap HTTP client with authentication if configured
-	authClient := sf.createAuthenticatedClient(httpClient)
+func (sf *ServiceFactory) CreateOptimizedCrawlerServiceWithHeadOptimization(config *CrawlConfig, userAgent string, timeout time.Duration, httpClient HTTPClient, rateLimit, burst float64) (*OptimizedCrawlerService, error) {
+	// Wrap HTTP client with authentication if configured, recording redirect chains
+	authClient := sf.createHTTPClient(httpClient)
 	
 	// Create optimized link checker with HEAD requests
 	linkChecker := NewOptimizedLinkCheckerService(authClient, userAgent, timeout, rateLimit, burst)
 	urlProcessor := NewURLProcessorService(config.IncludePattern, config.ExcludePattern)
-	resultCollector := NewResultCollectorService()
-	pageParser := NewPageParserService(linkChecker, urlProcessor, config.ExcludeHtmlTags, config.OnlyInternal)
+	resultCollector := sf.createResultCollector()
+	pageParser, err := sf.createPageParser(linkChecker, urlProcessor, authClient, config)
+	if err != nil {
+		return nil, err
+	}
+	sf.configureRobots(config, urlProcessor, authClient, userAgent, timeout, linkChecker)
+	sf.applyDomainRules(linkChecker)
+	sf.configureSoft404(config, linkChecker)
 
 	// Create optimized crawler
 	crawler := NewOptimizedCrawlerService(pageParser, urlProcessor, resultCollector, config)
 
-	return crawler
+	return crawler, nil
 }
 
 // CreateCachedOptimizedCrawlerService creates an optimized crawler with caching and HEAD optimization
-func (sf *ServiceFactory) CreateCachedOptimizedCrawlerService(config *CrawlConfig, userAgent string, timeout time.Duration, httpClient *http.Client, rateLimit, burst float64, cacheSize int, cacheTTL time.Duration) *OptimizedCrawlerService {
-	// Wrap HTTP client with authentication if configured
-	authClient := sf.createAuthenticatedClient(httpClient)
+func (sf *ServiceFactory) CreateCachedOptimizedCrawlerService(config *CrawlConfig, userAgent string, timeout time.Duration, httpClient HTTPClient, rateLimit, burst float64, cacheSize int, cacheTTL time.Duration) (*OptimizedCrawlerService, error) {
+	// Wrap HTTP client with authentication if configured, recording redirect chains
+	authClient := sf.createHTTPClient(httpClient)
 	
 	// Create cached optimized link checker with HEAD requests
 	linkChecker := NewCachedOptimizedLinkCheckerService(authClient, userAgent, timeout, rateLimit, burst, cacheSize, cacheTTL)
 	urlProcessor := NewURLProcessorService(config.IncludePattern, config.ExcludePattern)
-	resultCollector := NewResultCollectorService()
-	pageParser := NewPageParserService(linkChecker, urlProcessor, config.ExcludeHtmlTags, config.OnlyInternal)
+	resultCollector := sf.createResultCollector()
+	pageParser, err := sf.createPageParser(linkChecker, urlProcessor, authClient, config)
+	if err != nil {
+		return nil, err
+	}
+	sf.configureRobots(config, urlProcessor, authClient, userAgent, timeout, linkChecker)
+	sf.applyDomainRules(linkChecker)
+	sf.configureSoft404(config, linkChecker)
+
+	// Create optimized crawler
+	crawler := NewOptimizedCrawlerService(pageParser, urlProcessor, resultCollector, config)
+
+	return crawler, nil
+}
+
+// CreatePersistentCachedOptimizedCrawlerService creates a cached optimized crawler whose cache is shared across runs
+// through a file under cacheDir. The cache is loaded at startup and flushed when the crawler stops or on shutdown.
+func (sf *ServiceFactory) CreatePersistentCachedOptimizedCrawlerService(config *CrawlConfig, userAgent string, timeout time.Duration, httpClient HTTPClient, rateLimit, burst float64, cacheSize int, cacheTTL time.Duration, cacheDir string) (*OptimizedCrawlerService, error) {
+	store, err := NewFileCacheStore(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	return sf.CreateStoredCachedOptimizedCrawlerService(config, userAgent, timeout, httpClient, rateLimit, burst, cacheSize, cacheTTL, store)
+}
+
+// CreateStoredCachedOptimizedCrawlerService creates a cached optimized crawler whose cache is backed by a store.
+// The cache is loaded from the store at startup and saved to it when the crawler stops or on shutdown.
+func (sf *ServiceFactory) CreateStoredCachedOptimizedCrawlerService(config *CrawlConfig, userAgent string, timeout time.Duration, httpClient HTTPClient, rateLimit, burst float64, cacheSize int, cacheTTL time.Duration, store CacheStore) (*OptimizedCrawlerService, error) {
+	// Wrap HTTP client with authentication if configured, recording redirect chains
+	authClient := sf.createHTTPClient(httpClient)
+	
+	// Create cached optimized link checker backed by the store
+	linkChecker := NewCachedOptimizedLinkCheckerService(authClient, userAgent, timeout, rateLimit, burst, cacheSize, cacheTTL)
+	if err := linkChecker.SetStore(store); err != nil {
+		return nil, err
+	}
+	
+	urlProcessor := NewURLProcessorService(config.IncludePattern, config.ExcludePattern)
+	resultCollector := sf.createResultCollector()
+	pageParser, err := sf.createPageParser(linkChecker, urlProcessor, authClient, config)
+	if err != nil {
+		return nil, err
+	}
+	sf.configureRobots(config, urlProcessor, authClient, userAgent, timeout, linkChecker)
+	sf.applyDomainRules(linkChecker)
+	sf.configureSoft404(config, linkChecker)
+
+	// Create optimized crawler
+	crawler := NewOptimizedCrawlerService(pageParser, urlProcessor, resultCollector, config)
+
+	return crawler, nil
+}
+
+// CreateFileSystemCrawlerService creates an optimized crawler checking a static site build directory.
+// Local links are checked on disk; external links go through a cached HTTP link checker.
+func (sf *ServiceFactory) CreateFileSystemCrawlerService(config *CrawlConfig, site *FileSystemSite, userAgent string, timeout time.Duration, httpClient HTTPClient, rateLimit, burst float64, cacheSize int, cacheTTL time.Duration) *OptimizedCrawlerService {
+	// Wrap HTTP client with authentication if configured, recording redirect chains
+	authClient := sf.createHTTPClient(httpClient)
+	
+	// Create services reading local files and checking external links over HTTP
+	httpChecker := NewCachedOptimizedLinkCheckerService(authClient, userAgent, timeout, rateLimit, burst, cacheSize, cacheTTL)
+	sf.applyDomainRules(httpChecker)
+	sf.configureSoft404(config, httpChecker)
+	linkChecker := NewFileSystemLinkChecker(site, httpChecker)
+	urlProcessor := NewFileSystemURLProcessor(site, config.IncludePattern, config.ExcludePattern)
+	resultCollector := sf.createResultCollector()
+	pageParser := NewFileSystemPageParser(site, linkChecker, urlProcessor, config.ExcludeHtmlTags, config.OnlyInternal)
+	sf.configurePageParser(pageParser.PageParserService, authClient, config)
 
 	// Create optimized crawler
 	crawler := NewOptimizedCrawlerService(pageParser, urlProcessor, resultCollector, config)
@@ -101,6 +242,28 @@ func (sf *ServiceFactory) CreateCachedOptimizedCrawlerService(config *CrawlConfi
 	return crawler
 }
 
+// CreateMarkdownCheckerService creates a checker of Markdown files. Relative links are checked on disk,
+// root-relative ones against root; absolute links go through a cached HTTP link checker.
+func (sf *ServiceFactory) CreateMarkdownCheckerService(config *CrawlConfig, root string, userAgent string, timeout time.Duration, httpClient HTTPClient, rateLimit, burst float64, cacheSize int, cacheTTL time.Duration) (*MarkdownCheckerService, error) {
+	// Wrap HTTP client with authentication if configured, recording redirect chains
+	authClient := sf.createHTTPClient(httpClient)
+	
+	// Create services checking external links over HTTP
+	linkChecker := NewCachedOptimizedLinkCheckerService(authClient, userAgent, timeout, rateLimit, burst, cacheSize, cacheTTL)
+	sf.applyDomainRules(linkChecker)
+	sf.configureSoft404(config, linkChecker)
+	urlProcessor := NewURLProcessorService(config.IncludePattern, config.ExcludePattern)
+	resultCollector := sf.createResultCollector()
+	
+	checker, err := NewMarkdownCheckerService(root, linkChecker, urlProcessor, resultCollector, config)
+	if err != nil {
+		return nil, err
+	}
+	checker.SetRedirectRecorder(authClient, NewRedirectPolicy(config.FlagRedirects, config.MaxRedirectHops))
+
+	return checker, nil
+}
+
 // CreateCrawlConfig creates a CrawlConfig from the global model
 func (sf *ServiceFactory) CreateCrawlConfig() *CrawlConfig {
 	// Import from model package to avoid circular dependency issues
@@ -127,14 +290,122 @@ func (sf *ServiceFactory) CreateCrawlConfigFromParams(maxDepth, concurrency int,
 	}
 }
 
+// createPageParser creates a page parser extracting the element types and checking the anchors selected in the config.
+// Pages are rendered in a browser first when a DevTools endpoint is configured, which must answer.
+func (sf *ServiceFactory) createPageParser(linkChecker LinkChecker, urlProcessor URLProcessor, client *RedirectRecorder, config *CrawlConfig) (PageParser, error) {
+	if config.CDPURL != "" {
+		browser, err := NewCDPBrowser(config.CDPURL, config.CDPOptions)
+		if err != nil {
+			return nil, err
+		}
+		if err := browser.Ping(context.Background()); err != nil {
+			return nil, err
+		}
+		pageParser := NewCDPPageParser(browser, linkChecker, urlProcessor, config.ExcludeHtmlTags, config.OnlyInternal)
+		sf.configurePageParser(pageParser.PageParserService, client, config)
+		return pageParser, nil
+	}
+	
+	pageParser := NewPageParserService(linkChecker, urlProcessor, config.ExcludeHtmlTags, config.OnlyInternal)
+	sf.configurePageParser(pageParser, client, config)
+	return pageParser, nil
+}
+
+// configurePageParser applies the element type, anchor and redirect settings of the config to a page parser
+func (sf *ServiceFactory) configurePageParser(pageParser *PageParserService, client *RedirectRecorder, config *CrawlConfig) {
+	if len(config.IncludeElements) > 0 || len(config.ExcludeElements) > 0 {
+		pageParser.SetExtractors(DefaultExtractorRegistry().Filter(config.IncludeElements, config.ExcludeElements))
+	}
+	pageParser.SetCheckAnchors(config.CheckAnchors)
+	pageParser.SetRedirectRecorder(client, NewRedirectPolicy(config.FlagRedirects, config.MaxRedirectHops))
+}
+
+// configureRobots makes the URL processor honor robots.txt when enabled in the config, downloading it within timeout.
+// Crawl delays are applied to the link checker's rate limiter when it supports them.
+func (sf *ServiceFactory) configureRobots(config *CrawlConfig, urlProcessor *URLProcessorService, client HTTPClient, userAgent string, timeout time.Duration, linkChecker LinkChecker) {
+	if !config.RespectRobots {
+		return
+	}
+	
+	robots := NewRobotsChecker(client, userAgent)
+	robots.SetTimeout(timeout)
+	if delayer, ok := linkChecker.(interface {
+		SetCrawlDelay(domain string, delay time.Duration)
+	}); ok {
+		robots.SetCrawlDelayHandler(delayer.SetCrawlDelay)
+	}
+	
+	urlProcessor.SetRobotsChecker(robots, config.CheckDisallowed)
+}
+
+// applyDomainRules applies the per-domain rate limits and rules of the configuration to a link checker
+func (sf *ServiceFactory) applyDomainRules(linkChecker LinkChecker) {
+	if limiter, ok := linkChecker.(interface {
+		SetDomainRateLimit(domain string, requestsPerSecond float64)
+	}); ok {
+		for domain, requestsPerSecond := range sf.domainRateLimits {
+			limiter.SetDomainRateLimit(domain, requestsPerSecond)
+			logger.Debugf("Configured rate limit for %s: %.2f req/s", domain, requestsPerSecond)
+		}
+	}
+	
+	if limiter, ok := linkChecker.(interface {
+		SetDomainBurst(domain string, burst float64)
+	}); ok {
+		for domain, rule := range sf.domainRules {
+			if rule.Burst > 0 {
+				limiter.SetDomainBurst(domain, rule.Burst)
+				logger.Debugf("Configured rate limit burst for %s: %.0f", domain, rule.Burst)
+			}
+		}
+	}
+	
+	if len(sf.domainRules) == 0 {
+		return
+	}
+	ruled, ok := linkChecker.(interface {
+		SetDomainRules(rules map[string]model.DomainRule)
+	})
+	if !ok {
+		logger.Warnf("Per-domain rules are not supported by the link checker, they are ignored")
+		return
+	}
+	ruled.SetDomainRules(sf.domainRules)
+}
+
+// configureSoft404 enables the soft 404 detection of the config and the per-domain phrases on a link checker
+func (sf *ServiceFactory) configureSoft404(config *CrawlConfig, linkChecker LinkChecker) {
+	if !config.DetectSoft404 && len(config.Soft404Phrases) == 0 && len(sf.domainSoft404Phrases) == 0 {
+		return
+	}
+	
+	detector, ok := linkChecker.(interface {
+		SetSoft404Detection(fingerprint bool, phrases []string, domainPhrases map[string][]string)
+	})
+	if !ok {
+		logger.Warnf("Soft 404 detection is not supported without HEAD optimization, it is disabled")
+		return
+	}
+	detector.SetSoft404Detection(config.DetectSoft404, config.Soft404Phrases, sf.domainSoft404Phrases)
+}
+
+// createHTTPClient wraps an HTTP client with authentication and records the redirect chain of each request
+func (sf *ServiceFactory) createHTTPClient(httpClient HTTPClient) *RedirectRecorder {
+	var client HTTPClient = sf.createAuthenticatedClient(httpClient)
+	if sf.metrics != nil {
+		client = NewMeasuredHTTPClient(client, sf.metrics)
+	}
+	return NewRedirectRecorder(client)
+}
+
 // createAuthenticatedClient wraps an HTTP client with authentication capabilities
-func (sf *ServiceFactory) createAuthenticatedClient(httpClient *http.Client) *AuthenticatedHTTPClient {
+func (sf *ServiceFactory) createAuthenticatedClient(httpClient HTTPClient) *AuthenticatedHTTPClient {
 	// Create authentication config
 	config := NewAuthConfig()
 	
 	// Configure Basic Authentication
-	if model.AuthBasic != "" {
-		user, pass, err := ParseBasicAuthFromString(model.AuthBasic)
+	if sf.credentials.basic != "" {
+		user, pass, err := ParseBasicAuthFromString(sf.credentials.basic)
 		if err != nil {
 			logger.Errorf("Invalid basic auth format: %v", err)
 		} else {
@@ -154,8 +425,8 @@ func (sf *ServiceFactory) createAuthenticatedClient(httpClient *http.Client) *Au
 	}
 	
 	// Configure Bearer Token
-	if model.AuthBearer != "" {
-		config.BearerToken = model.AuthBearer
+	if sf.credentials.bearer != "" {
+		config.BearerToken = sf.credentials.bearer
 		config.BearerEnabled = true
 		logger.Infof("Configured bearer token authentication")
 	} else {
@@ -168,9 +439,9 @@ func (sf *ServiceFactory) createAuthenticatedClient(httpClient *http.Client) *Au
 	}
 	
 	// Configure Custom Headers
-	if len(model.AuthHeaders) > 0 {
+	if len(sf.credentials.headers) > 0 {
 		config.CustomHeaders = make(map[string]string)
-		for _, header := range model.AuthHeaders {
+		for _, header := range sf.credentials.headers {
 			key, value, err := ParseCustomHeaderFromString(header)
 			if err != nil {
 				logger.Errorf("Invalid header format '%s': %v", header, err)
@@ -192,12 +463,15 @@ func (sf *ServiceFactory) createAuthenticatedClient(httpClient *http.Client) *Au
 	}
 	
 	// Configure Cookies
-	if model.AuthCookies != "" {
-		config.Cookies = model.AuthCookies
+	if sf.credentials.cookies != "" {
+		config.Cookies = sf.credentials.cookies
 		config.CookiesEnabled = true
 		logger.Infof("Configured cookie authentication")
 	}
 	
+	// Per-domain credentials and headers
+	config.DomainRules = sf.domainRules
+	
 	// Create authenticated client
 	authClient := NewAuthenticatedHTTPClient(httpClient, config)
 	
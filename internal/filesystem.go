@@ -0,0 +1,294 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// FileSystemSite maps file:// URLs to the files of a static site build directory
+type FileSystemSite struct {
+	root    string   // Absolute path of the site root directory
+	rootURL *url.URL // file:// URL of the site root, with a trailing slash
+}
+
+// NewFileSystemSite creates a FileSystemSite rooted at the given directory
+func NewFileSystemSite(dir string) (*FileSystemSite, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	rootPath := filepath.ToSlash(root)
+	if !strings.HasPrefix(rootPath, "/") {
+		rootPath = "/" + rootPath // Windows drive letters
+	}
+	if !strings.HasSuffix(rootPath, "/") {
+		rootPath += "/"
+	}
+
+	return &FileSystemSite{
+		root:    root,
+		rootURL: &url.URL{Scheme: "file", Path: rootPath},
+	}, nil
+}
+
+// RootURL returns the file:// URL of the site root
+func (fs *FileSystemSite) RootURL() string {
+	return fs.rootURL.String()
+}
+
+// Contains checks whether a URL points inside the site root
+func (fs *FileSystemSite) Contains(target *url.URL) bool {
+	if target.Scheme != "file" {
+		return false
+	}
+	cleaned := path.Clean(target.Path)
+	return cleaned+"/" == fs.rootURL.Path || strings.HasPrefix(cleaned, fs.rootURL.Path)
+}
+
+// Resolve returns the file served for a URL, handling index.html directory indexes and pretty URLs.
+// It returns false when no file matches.
+func (fs *FileSystemSite) Resolve(target *url.URL) (string, bool) {
+	if !fs.Contains(target) {
+		return "", false
+	}
+
+	filePath := fs.filePathOf(target)
+	candidates := []string{filePath, filepath.Join(filePath, "index.html")}
+	if !strings.HasSuffix(target.Path, "/") {
+		// Pretty URLs: /about served from about.html
+		candidates = append(candidates, filePath+".html")
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+			return candidate, true
+		}
+	}
+
+	return "", false
+}
+
+// IsDirectory checks whether a URL points to a directory of the site
+func (fs *FileSystemSite) IsDirectory(target *url.URL) bool {
+	if !fs.Contains(target) {
+		return false
+	}
+	info, err := os.Stat(fs.filePathOf(target))
+	return err == nil && info.IsDir()
+}
+
+// filePathOf converts the path of a file:// URL to a local file path
+func (fs *FileSystemSite) filePathOf(target *url.URL) string {
+	cleaned := path.Clean(target.Path)
+	if filepath.VolumeName(fs.root) != "" {
+		cleaned = strings.TrimPrefix(cleaned, "/") // "/C:/site" on Windows
+	}
+	return filepath.FromSlash(cleaned)
+}
+
+// FileSystemLinkChecker implements the LinkChecker interface for a local site:
+// file:// links are checked on disk and other links are delegated to an HTTP link checker
+type FileSystemLinkChecker struct {
+	site        *FileSystemSite
+	httpChecker LinkChecker
+}
+
+// NewFileSystemLinkChecker creates a new FileSystemLinkChecker
+func NewFileSystemLinkChecker(site *FileSystemSite, httpChecker LinkChecker) *FileSystemLinkChecker {
+	return &FileSystemLinkChecker{
+		site:        site,
+		httpChecker: httpChecker,
+	}
+}
+
+// CheckLink checks if a link is broken
+func (fc *FileSystemLinkChecker) CheckLink(ctx context.Context, linkURL string) (int, string) {
+	target, err := url.Parse(linkURL)
+	if err != nil {
+		return 0, err.Error()
+	}
+
+	if target.Scheme != "file" {
+		return fc.httpChecker.CheckLink(ctx, linkURL)
+	}
+
+	if !fc.site.Contains(target) {
+		return http.StatusNotFound, "link points outside the site root"
+	}
+	if _, found := fc.site.Resolve(target); !found {
+		logger.Debugf("No file found for %s", linkURL)
+		return http.StatusNotFound, ""
+	}
+
+	return http.StatusOK, ""
+}
+
+// FetchWithRetry reads a local file as an HTTP-like response, or fetches other URLs over HTTP
+func (fc *FileSystemLinkChecker) FetchWithRetry(ctx context.Context, rawURL string, retry int) (*model.HTTPResponse, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.Scheme != "file" {
+		return fc.httpChecker.FetchWithRetry(ctx, rawURL, retry)
+	}
+
+	filePath, found := fc.site.Resolve(target)
+	if !found {
+		return &model.HTTPResponse{Response: &http.Response{
+			StatusCode: http.StatusNotFound,
+			Header:     make(http.Header),
+			Body:       io.NopCloser(strings.NewReader("")),
+		}}, nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make(http.Header)
+	header.Set("Content-Type", mime.TypeByExtension(filepath.Ext(filePath)))
+
+	return &model.HTTPResponse{Response: &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       file,
+	}}, nil
+}
+
+// FileSystemURLProcessor implements the URLProcessor interface for a local site,
+// resolving root-relative links against the site root instead of the filesystem root
+type FileSystemURLProcessor struct {
+	*URLProcessorService
+	site *FileSystemSite
+}
+
+// NewFileSystemURLProcessor creates a new FileSystemURLProcessor
+func NewFileSystemURLProcessor(site *FileSystemSite, includePattern, excludePattern string) *FileSystemURLProcessor {
+	return &FileSystemURLProcessor{
+		URLProcessorService: NewURLProcessorService(includePattern, excludePattern),
+		site:                site,
+	}
+}
+
+// ResolveURL resolves a link found on a local page
+func (fp *FileSystemURLProcessor) ResolveURL(pageURL, href string) (*url.URL, error) {
+	pageURLParsed, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Root-relative links point into the site root
+	if pageURLParsed.Scheme == "file" && strings.HasPrefix(href, "/") && !strings.HasPrefix(href, "//") {
+		pageURLParsed = fp.site.rootURL
+		href = "." + href
+	}
+
+	hrefURL, err := url.Parse(href)
+	if err != nil {
+		return nil, err
+	}
+	resolvedURL := pageURLParsed.ResolveReference(hrefURL)
+
+	// Like a web server redirect, directories get a trailing slash so their relative links resolve correctly
+	if resolvedURL.Scheme == "file" && !strings.HasSuffix(resolvedURL.Path, "/") && fp.site.IsDirectory(resolvedURL) {
+		resolvedURL.Path += "/"
+	}
+
+	return resolvedURL, nil
+}
+
+// ShouldSkipURL checks if a URL should be skipped
+func (fp *FileSystemURLProcessor) ShouldSkipURL(baseURL, linkURL *url.URL) bool {
+	if linkURL.Scheme == "file" {
+		return fp.shouldSkipURLBasedOnPattern(linkURL)
+	}
+	return fp.URLProcessorService.ShouldSkipURL(baseURL, linkURL)
+}
+
+// ValidateURL validates and parses the site root URL
+func (fp *FileSystemURLProcessor) ValidateURL(baseURL string) (*url.URL, error) {
+	baseURLParsed, err := url.Parse(baseURL)
+	if err != nil {
+		logger.Errorf("Error parsing base URL %s: %s", baseURL, err)
+		return nil, err
+	}
+	if baseURLParsed.Scheme != "file" {
+		return fp.URLProcessorService.ValidateURL(baseURL)
+	}
+	return baseURLParsed, nil
+}
+
+// FileSystemPageParser implements the PageParser interface for a local site, reading pages from disk
+type FileSystemPageParser struct {
+	*PageParserService
+	site *FileSystemSite
+}
+
+// NewFileSystemPageParser creates a new FileSystemPageParser
+func NewFileSystemPageParser(site *FileSystemSite, linkChecker *FileSystemLinkChecker, urlProcessor *FileSystemURLProcessor, excludeHtmlTags string, onlyInternal bool) *FileSystemPageParser {
+	return &FileSystemPageParser{
+		PageParserService: NewPageParserService(linkChecker, urlProcessor, excludeHtmlTags, onlyInternal),
+		site:              site,
+	}
+}
+
+// ParsePage reads and parses a local HTML page. Files that are not HTML return a nil document.
+func (fpp *FileSystemPageParser) ParsePage(ctx context.Context, pageURL string) (*goquery.Document, error) {
+	target, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	if target.Scheme != "file" {
+		return fpp.PageParserService.ParsePage(ctx, pageURL)
+	}
+
+	filePath, found := fpp.site.Resolve(target)
+	if !found {
+		return nil, fmt.Errorf("no file found for %s", pageURL)
+	}
+
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".html", ".htm":
+	default:
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(content))
+	if err != nil {
+		logger.Errorf("Error parsing HTML from %s: %s", filePath, err)
+		return nil, err
+	}
+	fpp.RecordSource(doc, content)
+
+	return doc, nil
+}
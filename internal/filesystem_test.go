@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSiteFiles(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		filePath := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0o755))
+		require.NoError(t, os.WriteFile(filePath, []byte(content), 0o644))
+	}
+	return root
+}
+
+func TestFileSystemSite(t *testing.T) {
+	root := writeSiteFiles(t, map[string]string{
+		"index.html":       "<html></html>",
+		"about/index.html": "<html></html>",
+		"blog.html":        "<html></html>",
+		"css/style.css":    "body {}",
+	})
+
+	site, err := NewFileSystemSite(root)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(site.RootURL(), "file:///"))
+	assert.True(t, strings.HasSuffix(site.RootURL(), "/"))
+
+	resolve := func(path string) bool {
+		target, err := url.Parse(site.RootURL() + path)
+		require.NoError(t, err)
+		_, found := site.Resolve(target)
+		return found
+	}
+
+	assert.True(t, resolve(""))
+	assert.True(t, resolve("about/"))
+	assert.True(t, resolve("about"))
+	assert.True(t, resolve("blog"))
+	assert.True(t, resolve("blog.html"))
+	assert.True(t, resolve("css/style.css"))
+	assert.False(t, resolve("css/"))
+	assert.False(t, resolve("missing"))
+	assert.False(t, resolve("../outside.html"))
+
+	_, err = NewFileSystemSite(filepath.Join(root, "blog.html"))
+	assert.Error(t, err)
+}
+
+func TestFileSystemURLProcessor(t *testing.T) {
+	root := writeSiteFiles(t, map[string]string{
+		"docs/index.html": "<html></html>",
+	})
+	site, err := NewFileSystemSite(root)
+	require.NoError(t, err)
+	processor := NewFileSystemURLProcessor(site, "", "")
+
+	page := site.RootURL() + "docs/guide.html"
+
+	resolved, err := processor.ResolveURL(page, "/css/site.css")
+	require.NoError(t, err)
+	assert.Equal(t, site.RootURL()+"css/site.css", resolved.String())
+
+	resolved, err = processor.ResolveURL(page, "../docs")
+	require.NoError(t, err)
+	assert.Equal(t, site.RootURL()+"docs/", resolved.String())
+
+	resolved, err = processor.ResolveURL(page, "https://example.com/page")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/page", resolved.String())
+
+	base, err := processor.ValidateURL(site.RootURL())
+	require.NoError(t, err)
+	local, _ := url.Parse(page)
+	mailto, _ := url.Parse("mailto:someone@example.com")
+	assert.False(t, processor.ShouldSkipURL(base, local))
+	assert.True(t, processor.ShouldSkipURL(base, mailto))
+}
+
+func TestFileSystemCrawl(t *testing.T) {
+	logger.SetQuiet(true)
+
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><body>External</body></html>"))
+	}))
+	defer external.Close()
+
+	root := writeSiteFiles(t, map[string]string{
+		"index.html": `<html><head><link rel="stylesheet" href="/css/style.css"></head><body>
+<a href="about/">About</a>
+<a href="/blog">Blog</a>
+<a href="/missing.html">Missing</a>
+<a href="` + external.URL + `/ok">External</a>
+<a href="` + external.URL + `/gone">Gone</a>
+</body></html>`,
+		"about/index.html": `<html><body><a href="../team.html">Team</a><img src="logo.png"></body></html>`,
+		"blog.html":        `<html><body><a href="/about">About</a></body></html>`,
+		"css/style.css":    "body {}",
+	})
+
+	site, err := NewFileSystemSite(root)
+	require.NoError(t, err)
+
+	factory := NewServiceFactory()
+	config := factory.CreateCrawlConfigFromParams(3, 2, false, "", "", "")
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	crawler := factory.CreateFileSystemCrawlerService(config, site, "TestAgent", 5*time.Second, httpClient, 100, 100, 100, time.Hour)
+	defer crawler.Stop()
+
+	require.NoError(t, crawler.StartCrawl(context.Background(), site.RootURL(), site.RootURL(), 0))
+	crawler.Wait()
+
+	broken := []string{}
+	for _, result := range crawler.GetResults() {
+		if result.Status >= 400 || result.Error != "" {
+			broken = append(broken, strings.TrimPrefix(result.TargetURL, site.RootURL()))
+		}
+	}
+
+	assert.ElementsMatch(t, []string{
+		"missing.html",
+		"team.html",
+		"about/logo.png",
+		external.URL + "/gone",
+	}, broken)
+}
+
+func TestFileSystemCrawlWithoutIndex(t *testing.T) {
+	logger.SetQuiet(true)
+
+	root := writeSiteFiles(t, map[string]string{
+		"about.html": "<html></html>",
+	})
+	site, err := NewFileSystemSite(root)
+	require.NoError(t, err)
+
+	factory := NewServiceFactory()
+	config := factory.CreateCrawlConfigFromParams(1, 1, false, "", "", "")
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	crawler := factory.CreateFileSystemCrawlerService(config, site, "TestAgent", 5*time.Second, httpClient, 100, 100, 100, time.Hour)
+	defer crawler.Stop()
+
+	require.NoError(t, crawler.StartCrawl(context.Background(), site.RootURL(), site.RootURL(), 0))
+	crawler.Wait()
+
+	// The start page could not be crawled
+	assert.Error(t, crawler.SeedError())
+}
@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"context"
+	"sync"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+)
+
+// Frontier schedules crawl jobs for the worker pool and tracks crawl completion.
+// Jobs are held in an unbounded pending queue and handed to the pool by a single
+// dispatcher goroutine, so workers can enqueue discovered pages without ever
+// blocking on a full job channel.
+type Frontier struct {
+	pool     *WorkerPool
+	maxDepth int
+
+	queue    []Job
+	enqueued map[string]bool
+	pending  map[string]Job // jobs accepted but not yet completed, kept for checkpoints
+	inFlight int64          // jobs accepted but not yet completed
+	sealed   bool
+	stopped  bool
+	mutex    sync.Mutex
+	cond     *sync.Cond
+
+	ctx            context.Context
+	cancel         context.CancelFunc
+	done           chan struct{}
+	doneOnce       sync.Once
+	dispatcherDone chan struct{}
+}
+
+// NewFrontier creates a frontier feeding the given worker pool
+func NewFrontier(pool *WorkerPool, maxDepth int) *Frontier {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	f := &Frontier{
+		pool:           pool,
+		maxDepth:       maxDepth,
+		queue:          make([]Job, 0),
+		enqueued:       make(map[string]bool),
+		pending:        make(map[string]Job),
+		ctx:            ctx,
+		cancel:         cancel,
+		done:           make(chan struct{}),
+		dispatcherDone: make(chan struct{}),
+	}
+	f.cond = sync.NewCond(&f.mutex)
+
+	return f
+}
+
+// Start launches the dispatcher goroutine
+func (f *Frontier) Start() {
+	go f.dispatch()
+}
+
+// Enqueue schedules a job unless it exceeds the depth limit or its URL was already scheduled.
+// It returns true if the job was accepted.
+func (f *Frontier) Enqueue(job Job) bool {
+	if job.CurrentDepth > f.maxDepth {
+		return false
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	key := frontierKey(job)
+	if f.stopped || f.enqueued[key] {
+		return false
+	}
+
+	f.enqueued[key] = true
+	f.pending[key] = job
+	f.inFlight++
+	f.queue = append(f.queue, job)
+	f.cond.Signal()
+
+	return true
+}
+
+// MarkDone records the completion of a job previously accepted by Enqueue
+func (f *Frontier) MarkDone(job Job) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	delete(f.pending, frontierKey(job))
+	f.inFlight--
+	f.checkDone()
+}
+
+// MarkInterrupted records a job abandoned when the crawl was cancelled.
+// The job stays pending, so the frontier is not done and a crawl resumed from a checkpoint runs it again.
+func (f *Frontier) MarkInterrupted(job Job) {
+	logger.Debugf("Frontier keeps interrupted job %s pending", job.TargetURL)
+}
+
+// PendingJobs returns the jobs accepted but not completed, whether queued, running or interrupted
+func (f *Frontier) PendingJobs() []Job {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	jobs := make([]Job, 0, len(f.pending))
+	for _, job := range f.pending {
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// Seal indicates that no more seed jobs will be added from outside the pool.
+// Once sealed, the frontier is done as soon as no job is queued or running.
+func (f *Frontier) Seal() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.sealed = true
+	f.checkDone()
+}
+
+// Done returns a channel closed when every scheduled job has completed
+func (f *Frontier) Done() <-chan struct{} {
+	return f.done
+}
+
+// Pending returns the number of jobs queued or running
+func (f *Frontier) Pending() int64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.inFlight
+}
+
+// IsStopped returns true once Stop has been called
+func (f *Frontier) IsStopped() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.stopped
+}
+
+// Stop halts the dispatcher; queued jobs that were not handed to the pool are dropped
+func (f *Frontier) Stop() {
+	f.mutex.Lock()
+	if f.stopped {
+		f.mutex.Unlock()
+		return
+	}
+	f.stopped = true
+	f.cond.Broadcast()
+	f.mutex.Unlock()
+
+	f.cancel()
+	<-f.dispatcherDone
+}
+
+// frontierKey identifies a job for deduplication. Jobs that check their target on behalf of
+// a referencing source are keyed by both URLs so the check is not lost if the page is also crawled.
+func frontierKey(job Job) string {
+	if job.SourceURL != "" {
+		return job.SourceURL + " " + job.TargetURL
+	}
+	return job.TargetURL
+}
+
+// checkDone closes the done channel when the crawl is finished (caller must hold lock)
+func (f *Frontier) checkDone() {
+	if f.sealed && f.inFlight <= 0 {
+		f.doneOnce.Do(func() {
+			close(f.done)
+		})
+	}
+}
+
+// dispatch hands queued jobs to the worker pool, blocking while the pool is saturated
+func (f *Frontier) dispatch() {
+	defer close(f.dispatcherDone)
+
+	for {
+		f.mutex.Lock()
+		for len(f.queue) == 0 && !f.stopped {
+			f.cond.Wait()
+		}
+		if f.stopped {
+			f.mutex.Unlock()
+			return
+		}
+		job := f.queue[0]
+		f.queue[0] = Job{}
+		f.queue = f.queue[1:]
+		f.mutex.Unlock()
+
+		if !f.pool.SubmitContext(f.ctx, job) {
+			logger.Debugf("Frontier stopped before dispatching %s", job.TargetURL)
+			return
+		}
+	}
+}
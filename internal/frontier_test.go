@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrontier(t *testing.T) {
+	logger.SetQuiet(true)
+
+	crawler := &CrawlerService{config: &CrawlConfig{MaxDepth: 1}}
+
+	t.Run("Rejects jobs beyond max depth", func(t *testing.T) {
+		frontier := NewFrontier(NewWorkerPool(1, crawler), 1)
+
+		assert.True(t, frontier.Enqueue(Job{TargetURL: "https://example.com", CurrentDepth: 1}))
+		assert.False(t, frontier.Enqueue(Job{TargetURL: "https://example.com/deep", CurrentDepth: 2}))
+	})
+
+	t.Run("Deduplicates scheduled URLs", func(t *testing.T) {
+		frontier := NewFrontier(NewWorkerPool(1, crawler), 1)
+
+		assert.True(t, frontier.Enqueue(Job{TargetURL: "https://example.com/a"}))
+		assert.False(t, frontier.Enqueue(Job{TargetURL: "https://example.com/a"}))
+		assert.Equal(t, int64(1), frontier.Pending())
+	})
+
+	t.Run("Is done once sealed and drained", func(t *testing.T) {
+		frontier := NewFrontier(NewWorkerPool(1, crawler), 1)
+		job := Job{TargetURL: "https://example.com/a"}
+		frontier.Enqueue(job)
+
+		frontier.Seal()
+		select {
+		case <-frontier.Done():
+			t.Fatal("Frontier should not be done with a pending job")
+		default:
+		}
+
+		frontier.MarkDone(job)
+		select {
+		case <-frontier.Done():
+		case <-time.After(time.Second):
+			t.Fatal("Frontier should be done after last job completed")
+		}
+	})
+
+	t.Run("Keeps interrupted jobs pending", func(t *testing.T) {
+		frontier := NewFrontier(NewWorkerPool(1, crawler), 1)
+		done := Job{TargetURL: "https://example.com/done"}
+		interrupted := Job{TargetURL: "https://example.com/interrupted"}
+		frontier.Enqueue(done)
+		frontier.Enqueue(interrupted)
+
+		frontier.MarkDone(done)
+		frontier.MarkInterrupted(interrupted)
+
+		assert.Equal(t, int64(1), frontier.Pending())
+		pending := frontier.PendingJobs()
+		if assert.Len(t, pending, 1) {
+			assert.Equal(t, interrupted.TargetURL, pending[0].TargetURL)
+		}
+	})
+
+	t.Run("Refuses jobs after stop", func(t *testing.T) {
+		frontier := NewFrontier(NewWorkerPool(1, crawler), 1)
+		frontier.Start()
+		frontier.Stop()
+
+		assert.True(t, frontier.IsStopped())
+		assert.False(t, frontier.Enqueue(Job{TargetURL: "https://example.com/a"}))
+	})
+}
+
+func TestOptimizedCrawlerRecursion(t *testing.T) {
+	logger.SetQuiet(true)
+
+	// Chain of pages: / -> /a -> /b -> /c
+	pages := map[string]string{
+		"/":  `<html><body><a href="/a">A</a></body></html>`,
+		"/a": `<html><body><a href="/b">B</a><a href="/">Home</a></body></html>`,
+		"/b": `<html><body><a href="/c">C</a></body></html>`,
+		"/c": `<html><body>End</body></html>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	crawl := func(depth int) []model.LinkResult {
+		factory := NewServiceFactory()
+		config := factory.CreateCrawlConfigFromParams(depth, 2, false, "", "", "")
+		httpClient := &http.Client{Timeout: 5 * time.Second}
+		crawler, err := factory.CreateOptimizedCrawlerServiceWithRateLimit(config, "TestAgent", 5*time.Second, httpClient, 100, 100)
+		require.NoError(t, err)
+		defer crawler.Stop()
+
+		assert.NoError(t, crawler.StartCrawl(context.Background(), server.URL, server.URL+"/", 0))
+
+		done := make(chan bool)
+		go func() {
+			crawler.Wait()
+			done <- true
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("Crawler did not complete within timeout")
+		}
+
+		return crawler.GetResults()
+	}
+
+	t.Run("Depth 0 only checks the start page", func(t *testing.T) {
+		results := crawl(0)
+		assert.Len(t, results, 1)
+	})
+
+	t.Run("Depth 2 follows internal links", func(t *testing.T) {
+		results := crawl(2)
+
+		sources := map[string]bool{}
+		for _, result := range results {
+			sources[result.SourceURL] = true
+		}
+		assert.True(t, sources[server.URL+"/"])
+		assert.True(t, sources[server.URL+"/a"])
+		assert.True(t, sources[server.URL+"/b"])
+		assert.False(t, sources[server.URL+"/c"])
+		assert.Len(t, results, 4)
+	})
+}
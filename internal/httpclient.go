@@ -1,16 +1,16 @@
-package utils
+package internal
 
 import (
 	"net"
 	"net/http"
 	"time"
-
-	"github.com/DrakkarStorm/deadlinkr/model"
 )
 
-var (
-	// ClientHTTP is the shared client for all requests
-	ClientHTTP = &http.Client{
+// NewHTTPClient creates the client the requests of a scan share, keeping connections alive between requests.
+// The client has no overall timeout: link checks are timed out by the link checkers with the timeout of their domain rule
+// or the configured one, and robots.txt downloads by the robots checker with the configured one.
+func NewHTTPClient() *http.Client {
+	return &http.Client{
 		Transport: &http.Transport{
 			// Maximum number of idle connections to keep open
 			MaxIdleConns: 400,
@@ -26,11 +26,7 @@ var (
 			// Timeout TLS handshake if HTTPS
 			TLSHandshakeTimeout: 10 * time.Second,
 		},
-		// Timeout global for the entire request (connect + headers + body)
-		Timeout: time.Duration(model.Timeout) * time.Second,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			// Return nil to follow redirects
-			return nil
-		},
+		// Follow redirects, stopping on loops and overly long chains
+		CheckRedirect: CheckRedirect,
 	}
-)
+}
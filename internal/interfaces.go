@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 
@@ -13,10 +14,11 @@ type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
-// LinkChecker interface defines methods for checking individual links
+// LinkChecker interface defines methods for checking individual links.
+// Cancelling the context aborts rate limit waits, requests in flight and retries.
 type LinkChecker interface {
-	CheckLink(linkURL string) (int, string)
-	FetchWithRetry(url string, retry int) (*model.HTTPResponse, error)
+	CheckLink(ctx context.Context, linkURL string) (int, string)
+	FetchWithRetry(ctx context.Context, url string, retry int) (*model.HTTPResponse, error)
 }
 
 // OptimizedLinkChecker extends LinkChecker with optimization features
@@ -27,10 +29,10 @@ type OptimizedLinkChecker interface {
 	GetRateLimiterStats() map[string]RateLimiterStats
 }
 
-// PageParser interface defines methods for parsing web pages
+// PageParser interface defines methods for parsing web pages, aborted once the context is cancelled
 type PageParser interface {
-	ParsePage(pageURL string) (*goquery.Document, error)
-	ExtractLinks(baseURL *url.URL, pageURL string, doc *goquery.Document) []model.LinkResult
+	ParsePage(ctx context.Context, pageURL string) (*goquery.Document, error)
+	ExtractLinks(ctx context.Context, baseURL *url.URL, pageURL string, doc *goquery.Document) []model.LinkResult
 }
 
 // URLProcessor interface defines methods for URL processing
@@ -38,11 +40,12 @@ type URLProcessor interface {
 	ResolveURL(pageURL, href string) (*url.URL, error)
 	ShouldSkipURL(baseURL, linkURL *url.URL) bool
 	ValidateURL(baseURL string) (*url.URL, error)
+	IsCrawlAllowed(pageURL *url.URL) bool
 }
 
-// Crawler interface defines methods for crawling websites
+// Crawler interface defines methods for crawling websites, stopped once the context is cancelled
 type Crawler interface {
-	Crawl(baseURL, currentURL string, currentDepth int) error
+	Crawl(ctx context.Context, baseURL, currentURL string, currentDepth int) error
 	SetConfig(config *CrawlConfig)
 }
 
@@ -50,12 +53,21 @@ type Crawler interface {
 type ResultCollector interface {
 	AddResult(result model.LinkResult)
 	GetResults() []model.LinkResult
+	CountResults() int
 	CountBrokenLinks() int
 	IsVisited(url string) bool
-	MarkVisited(url string)
+	MarkVisited(url string) bool
+	VisitedURLs() []string
 	Clear()
 }
 
+// ResultSink receives link results as they are produced, e.g. to stream them to a report.
+// Writes are serialized by the result collector.
+type ResultSink interface {
+	Write(result model.LinkResult) error
+	Close() error
+}
+
 // CrawlConfig holds configuration for crawling
 type CrawlConfig struct {
 	MaxDepth        int
@@ -64,4 +76,16 @@ type CrawlConfig struct {
 	IncludePattern  string
 	ExcludePattern  string
 	ExcludeHtmlTags string
-}
\ No newline at end of file
+	RespectRobots   bool       // Honor robots.txt Disallow and Crawl-delay rules
+	CheckDisallowed bool       // Check links disallowed by robots.txt without crawling them
+	IncludeElements []string   // Element types to extract links from (all when empty)
+	ExcludeElements []string   // Element types to ignore
+	CheckAnchors    bool       // Validate #fragment links against the anchors of their target page
+	FlagRedirects   []string   // Redirect reporting modes (permanent, chain, downgrade, login, all)
+	MaxRedirectHops int        // Redirect chains longer than this are flagged in the chain mode
+	DetectSoft404   bool       // Fingerprint the error page of each host to report soft 404s
+	Soft404Phrases  []string   // Phrases marking pages as soft 404s on every domain
+	CDPURL          string     // DevTools endpoint of the browser rendering pages before their links are extracted
+	CDPOptions      CDPOptions // Wait conditions and limits of the rendering
+	ShowProgress    bool       // Render the progress of the crawl in the terminal
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"strings"
@@ -16,6 +17,7 @@ type LinkCheckerService struct {
 	userAgent   string
 	timeout     time.Duration
 	rateLimiter *DomainRateLimiter
+	domainRules map[string]model.DomainRule // Per-domain overrides, keyed by host or "*.domain" pattern
 }
 
 // NewLinkCheckerService creates a new LinkCheckerService
@@ -44,8 +46,13 @@ func NewLinkCheckerServiceWithRateLimit(client HTTPClient, userAgent string, tim
 }
 
 // CheckLink checks if a link is broken
-func (lc *LinkCheckerService) CheckLink(linkURL string) (int, string) {
-	resp, err := lc.FetchWithRetry(linkURL, 3)
+func (lc *LinkCheckerService) CheckLink(ctx context.Context, linkURL string) (int, string) {
+	if domainRuleFor(lc.domainRules, linkURL).Skip {
+		logger.Debugf("Skipping %s as configured for its domain", linkURL)
+		return http.StatusOK, ""
+	}
+
+	resp, err := lc.FetchWithRetry(ctx, linkURL, 3)
 	if err != nil {
 		return 0, err.Error()
 	}
@@ -55,6 +62,10 @@ func (lc *LinkCheckerService) CheckLink(linkURL string) (int, string) {
 		}
 	}()
 
+	if isThrottledResponse(resp) {
+		return resp.StatusCode, ThrottledErrorMessage
+	}
+
 	// Analyse the MIME type to detect files
 	contentType := resp.Header.Get("Content-Type")
 	if strings.Contains(contentType, "application/") ||
@@ -80,30 +91,52 @@ func (lc *LinkCheckerService) CheckLink(linkURL string) (int, string) {
 	return resp.StatusCode, ""
 }
 
-// FetchWithRetry fetches a URL with retry logic
-func (lc *LinkCheckerService) FetchWithRetry(url string, retry int) (*model.HTTPResponse, error) {
+// FetchWithRetry fetches a URL with retry logic, backing off from domains asking to slow down
+// for at most the timeout of the request
+func (lc *LinkCheckerService) FetchWithRetry(ctx context.Context, url string, retry int) (*model.HTTPResponse, error) {
+	maxWait := timeoutFor(domainRuleFor(lc.domainRules, url), lc.timeout)
+	return fetchWithBackoff(lc.rateLimiter, url, maxWait, func() (*model.HTTPResponse, error) {
+		return lc.fetch(ctx, url, retry)
+	})
+}
+
+// fetch fetches a URL once the rate limiter allows it, retrying failed requests
+func (lc *LinkCheckerService) fetch(ctx context.Context, url string, retry int) (*model.HTTPResponse, error) {
 	// Apply rate limiting before each retry attempt
-	if err := lc.rateLimiter.Wait(url); err != nil {
+	if err := lc.rateLimiter.Wait(ctx, url); err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
 		logger.Errorf("Rate limiting error for %s: %s", url, err)
 	}
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("User-Agent", lc.userAgent)
 
+	rule := domainRuleFor(lc.domainRules, url)
+	retry = attemptsFor(rule, http.MethodGet, retry)
+
 	var resp *http.Response
 	var errRequest error
 	for i := 1; i <= retry; i++ {
-		resp, errRequest = lc.client.Do(req)
+		resp, errRequest = doWithTimeout(lc.client, req, timeoutFor(rule, lc.timeout))
 		if errRequest == nil {
 			return &model.HTTPResponse{
 				Response: resp,
 			}, nil
 		}
-		logger.Errorf("Attempt %d failed: %v, retrying in %d seconds...", i, errRequest, 5)
-		time.Sleep(5 * time.Second)
+		if isRedirectError(errRequest) || ctx.Err() != nil {
+			break
+		}
+		if i < retry {
+			logger.Errorf("Attempt %d failed: %v, retrying in %d seconds...", i, errRequest, 5)
+			if err := sleepContext(ctx, 5*time.Second); err != nil {
+				break
+			}
+		}
 	}
 	return nil, errRequest
 }
@@ -113,6 +146,21 @@ func (lc *LinkCheckerService) SetDomainRateLimit(domain string, requestsPerSecon
 	lc.rateLimiter.UpdateConfig(domain, requestsPerSecond)
 }
 
+// SetDomainBurst sets a custom burst capacity for a specific domain
+func (lc *LinkCheckerService) SetDomainBurst(domain string, burst float64) {
+	lc.rateLimiter.UpdateBurst(domain, burst)
+}
+
+// SetDomainRules applies per-domain timeouts, retries and skipping to the checked links
+func (lc *LinkCheckerService) SetDomainRules(rules map[string]model.DomainRule) {
+	lc.domainRules = rules
+}
+
+// SetCrawlDelay limits a domain to one request per delay, as requested by robots.txt
+func (lc *LinkCheckerService) SetCrawlDelay(domain string, delay time.Duration) {
+	lc.rateLimiter.ApplyCrawlDelay(domain, delay)
+}
+
 // GetRateLimiterStats returns current rate limiter statistics
 func (lc *LinkCheckerService) GetRateLimiterStats() map[string]RateLimiterStats {
 	return lc.rateLimiter.GetStats()
@@ -1,6 +1,10 @@
 package internal
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/DrakkarStorm/deadlinkr/logger"
@@ -11,6 +15,12 @@ import (
 type CachedLinkCheckerService struct {
 	checker LinkChecker
 	cache   *LinkCache
+	store   CacheStore // Optional persistent backend
+
+	// Every entry loaded from or saved to the store. Unlike the cache, it is not limited by its size,
+	// so entries the cache evicts are still reused and saved again.
+	persisted      map[string]*CacheEntry
+	persistedMutex sync.Mutex
 }
 
 // NewCachedLinkCheckerService creates a new cached link checker
@@ -22,27 +32,109 @@ func NewCachedLinkCheckerService(checker LinkChecker, cacheSize int, defaultTTL
 }
 
 // CheckLink checks a link with caching
-func (clc *CachedLinkCheckerService) CheckLink(linkURL string) (int, string) {
+func (clc *CachedLinkCheckerService) CheckLink(ctx context.Context, linkURL string) (int, string) {
 	// Try to get from cache first
 	if status, message, found := clc.cache.Get(linkURL); found {
 		logger.Debugf("Cache hit for %s: %d", linkURL, status)
 		return status, message
 	}
+	if entry := clc.persistedEntry(linkURL); entry != nil {
+		logger.Debugf("Persistent cache hit for %s: %d", linkURL, entry.Status)
+		clc.cache.Restore(map[string]*CacheEntry{linkURL: entry})
+		return entry.Status, entry.Message
+	}
 	
 	// Not in cache, check the link
 	logger.Debugf("Cache miss for %s, checking link", linkURL)
-	status, message := clc.checker.CheckLink(linkURL)
+	status, message := clc.checker.CheckLink(ctx, linkURL)
+	
+	// A domain still asking to slow down after backing off, or a cancelled check, says nothing about the link
+	if status == http.StatusTooManyRequests || message == ThrottledErrorMessage || ctx.Err() != nil {
+		return status, message
+	}
 	
 	// Store in cache with intelligent TTL
 	ttl := IntelligentTTLStrategy(status, clc.cache.defaultTTL)
 	clc.cache.SetWithTTL(linkURL, status, message, ttl)
+	clc.persist(linkURL, &CacheEntry{Status: status, Message: message, Timestamp: time.Now(), TTL: ttl})
 	
 	return status, message
 }
 
 // FetchWithRetry implements the LinkChecker interface
-func (clc *CachedLinkCheckerService) FetchWithRetry(url string, retry int) (*model.HTTPResponse, error) {
-	return clc.checker.FetchWithRetry(url, retry)
+func (clc *CachedLinkCheckerService) FetchWithRetry(ctx context.Context, url string, retry int) (*model.HTTPResponse, error) {
+	return clc.checker.FetchWithRetry(ctx, url, retry)
+}
+
+// SetStore attaches a persistent backend and loads its entries into the cache
+func (clc *CachedLinkCheckerService) SetStore(store CacheStore) error {
+	clc.store = store
+	
+	entries, err := store.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load persistent cache: %w", err)
+	}
+	
+	if entries == nil {
+		entries = make(map[string]*CacheEntry)
+	}
+	clc.persistedMutex.Lock()
+	clc.persisted = entries
+	clc.persistedMutex.Unlock()
+
+	restored := clc.cache.Restore(entries)
+	logger.Infof("Loaded %d cached link results from persistent cache", len(entries))
+	if restored < len(entries) {
+		logger.Debugf("%d of them fit in the in-memory cache, the others are read from the persistent cache when needed", restored)
+	}
+	return nil
+}
+
+// persistedEntry returns the non-expired persisted entry of a URL, nil without one or without a store
+func (clc *CachedLinkCheckerService) persistedEntry(url string) *CacheEntry {
+	clc.persistedMutex.Lock()
+	defer clc.persistedMutex.Unlock()
+
+	entry, found := clc.persisted[url]
+	if !found || entry.IsExpired() {
+		return nil
+	}
+	entryCopy := *entry
+	return &entryCopy
+}
+
+// persist records an entry to save to the store, if any
+func (clc *CachedLinkCheckerService) persist(url string, entry *CacheEntry) {
+	clc.persistedMutex.Lock()
+	defer clc.persistedMutex.Unlock()
+
+	if clc.persisted != nil {
+		clc.persisted[url] = entry
+	}
+}
+
+// Flush writes the cache content to the persistent backend, if any, including the entries
+// loaded or checked earlier but evicted from the cache since
+func (clc *CachedLinkCheckerService) Flush() error {
+	if clc.store == nil {
+		return nil
+	}
+	
+	entries := clc.cache.Entries()
+	clc.persistedMutex.Lock()
+	for url, entry := range clc.persisted {
+		if _, cached := entries[url]; !cached && !entry.IsExpired() {
+			entryCopy := *entry
+			entries[url] = &entryCopy
+		}
+	}
+	clc.persistedMutex.Unlock()
+	if err := clc.store.Save(entries); err != nil {
+		return fmt.Errorf("failed to save persistent cache: %w", err)
+	}
+	
+	logger.Debugf("Flushed %d cached link results to persistent cache", len(entries))
+	return nil
 }
 
 // GetCacheStats returns cache statistics
@@ -87,6 +179,26 @@ func (colc *CachedOptimizedLinkCheckerService) SetDomainRateLimit(domain string,
 	colc.optimizedChecker.SetDomainRateLimit(domain, requestsPerSecond)
 }
 
+// SetDomainBurst sets a custom burst capacity for a specific domain
+func (colc *CachedOptimizedLinkCheckerService) SetDomainBurst(domain string, burst float64) {
+	colc.optimizedChecker.SetDomainBurst(domain, burst)
+}
+
+// SetDomainRules applies per-domain overrides, see OptimizedLinkCheckerService.SetDomainRules
+func (colc *CachedOptimizedLinkCheckerService) SetDomainRules(rules map[string]model.DomainRule) {
+	colc.optimizedChecker.SetDomainRules(rules)
+}
+
+// SetCrawlDelay limits a domain to one request per delay, as requested by robots.txt
+func (colc *CachedOptimizedLinkCheckerService) SetCrawlDelay(domain string, delay time.Duration) {
+	colc.optimizedChecker.SetCrawlDelay(domain, delay)
+}
+
+// SetSoft404Detection reports soft 404s, see OptimizedLinkCheckerService.SetSoft404Detection
+func (colc *CachedOptimizedLinkCheckerService) SetSoft404Detection(fingerprint bool, phrases []string, domainPhrases map[string][]string) {
+	colc.optimizedChecker.SetSoft404Detection(fingerprint, phrases, domainPhrases)
+}
+
 // GetRateLimiterStats returns rate limiter statistics
 func (colc *CachedOptimizedLinkCheckerService) GetRateLimiterStats() map[string]RateLimiterStats {
 	return colc.optimizedChecker.GetRateLimiterStats()
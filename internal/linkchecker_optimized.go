@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"strings"
@@ -20,6 +21,8 @@ type OptimizedLinkCheckerService struct {
 	headSupport      map[string]bool // Track which domains support HEAD
 	headSupportMutex sync.RWMutex
 	stats            *OptimizedLinkStats
+	soft404          *Soft404Detector // Detects soft 404s when set
+	domainRules      map[string]model.DomainRule // Per-domain overrides, keyed by host or "*.domain" pattern
 }
 
 // OptimizedLinkStats tracks optimization statistics
@@ -47,18 +50,27 @@ func NewOptimizedLinkCheckerService(client HTTPClient, userAgent string, timeout
 }
 
 // CheckLink uses optimized HEAD/GET strategy
-func (olc *OptimizedLinkCheckerService) CheckLink(linkURL string) (int, string) {
+func (olc *OptimizedLinkCheckerService) CheckLink(ctx context.Context, linkURL string) (int, string) {
 	domain, err := extractDomain(linkURL)
 	if err != nil {
 		return 0, "Invalid URL: " + err.Error()
 	}
 
-	// Check if we know this domain supports HEAD
-	useHead := olc.shouldTryHead(domain)
+	rule, _ := lookupDomain(olc.domainRules, domain)
+	if rule.Skip {
+		logger.Debugf("Skipping %s as configured for its domain", linkURL)
+		return http.StatusOK, ""
+	}
+
+	// Check if we know this domain supports HEAD, pages checked for soft 404s need their body
+	useHead := olc.shouldTryHead(domain) && !olc.checksSoft404(domain) && (rule.Head == nil || *rule.Head)
 	
 	if useHead {
 		// Try HEAD first
-		status, errMsg, success := olc.tryHeadRequest(linkURL)
+		status, errMsg, success := olc.tryHeadRequest(ctx, linkURL)
+		if ctx.Err() != nil {
+			return status, errMsg // Cancelled, HEAD support of the domain is unknown
+		}
 		if success {
 			olc.recordHeadSuccess(domain)
 			olc.stats.incrementHeadRequests()
@@ -72,16 +84,16 @@ func (olc *OptimizedLinkCheckerService) CheckLink(linkURL string) (int, string)
 	}
 
 	// Use GET request
-	status, errMsg := olc.getRequest(linkURL)
+	status, errMsg := olc.getRequest(ctx, linkURL)
 	olc.stats.incrementGetRequests()
 	return status, errMsg
 }
 
 // tryHeadRequest attempts a HEAD request
-func (olc *OptimizedLinkCheckerService) tryHeadRequest(linkURL string) (int, string, bool) {
+func (olc *OptimizedLinkCheckerService) tryHeadRequest(ctx context.Context, linkURL string) (int, string, bool) {
 	start := time.Now()
 	
-	resp, err := olc.FetchWithRetryMethod(linkURL, 1, "HEAD") // Only 1 retry for HEAD
+	resp, err := olc.FetchWithRetryMethod(ctx, linkURL, 1, "HEAD") // Only 1 retry for HEAD
 	if err != nil {
 		return 0, err.Error(), false
 	}
@@ -95,6 +107,9 @@ func (olc *OptimizedLinkCheckerService) tryHeadRequest(linkURL string) (int, str
 	if resp.StatusCode == 405 || resp.StatusCode == 501 { // Method Not Allowed / Not Implemented
 		return 0, "", false
 	}
+	if isThrottledResponse(resp) {
+		return resp.StatusCode, ThrottledErrorMessage, true
+	}
 
 	duration := time.Since(start)
 	olc.stats.addTimeSaved(duration)
@@ -108,8 +123,8 @@ func (olc *OptimizedLinkCheckerService) tryHeadRequest(linkURL string) (int, str
 }
 
 // getRequest performs a standard GET request
-func (olc *OptimizedLinkCheckerService) getRequest(linkURL string) (int, string) {
-	resp, err := olc.FetchWithRetryMethod(linkURL, 3, "GET")
+func (olc *OptimizedLinkCheckerService) getRequest(ctx context.Context, linkURL string) (int, string) {
+	resp, err := olc.FetchWithRetryMethod(ctx, linkURL, 3, "GET")
 	if err != nil {
 		return 0, err.Error()
 	}
@@ -119,6 +134,10 @@ func (olc *OptimizedLinkCheckerService) getRequest(linkURL string) (int, string)
 		}
 	}()
 
+	if isThrottledResponse(resp) {
+		return resp.StatusCode, ThrottledErrorMessage
+	}
+
 	// Analyze the MIME type to detect files
 	contentType := resp.Header.Get("Content-Type")
 	if strings.Contains(contentType, "application/") ||
@@ -133,8 +152,14 @@ func (olc *OptimizedLinkCheckerService) getRequest(linkURL string) (int, string)
 
 	// For HTML content, do a minimal read to check if it's valid
 	if strings.Contains(contentType, "text/html") {
-		// Read only a small portion to verify it's not empty
-		limitedReader := io.LimitReader(resp.Body, 1024) // Read max 1KB
+		// Read only a small portion to verify it's not empty, unless the page is checked for soft 404s
+		limit := int64(1024) // Read max 1KB
+		domain, _ := extractDomain(linkURL)
+		checkSoft404 := resp.StatusCode >= 200 && resp.StatusCode < 300 && olc.checksSoft404(domain)
+		if checkSoft404 {
+			limit = MaxSoft404BodySize
+		}
+		limitedReader := io.LimitReader(resp.Body, limit)
 		body, err := io.ReadAll(limitedReader)
 		if err != nil {
 			return resp.StatusCode, "Error reading response body: " + err.Error()
@@ -146,19 +171,37 @@ func (olc *OptimizedLinkCheckerService) getRequest(linkURL string) (int, string)
 		
 		// Record bytes saved vs full download
 		olc.stats.addBytesSaved(int64(len(body)))
+
+		if checkSoft404 {
+			if reason := olc.soft404.Check(ctx, linkURL, body); reason != "" {
+				return resp.StatusCode, Soft404ErrorPrefix + reason
+			}
+		}
 	}
 
 	return resp.StatusCode, ""
 }
 
-// FetchWithRetryMethod performs HTTP request with specified method
-func (olc *OptimizedLinkCheckerService) FetchWithRetryMethod(url string, retry int, method string) (*model.HTTPResponse, error) {
+// FetchWithRetryMethod performs HTTP request with specified method, backing off from domains asking to slow down
+// for at most the timeout of the request
+func (olc *OptimizedLinkCheckerService) FetchWithRetryMethod(ctx context.Context, url string, retry int, method string) (*model.HTTPResponse, error) {
+	maxWait := timeoutFor(domainRuleFor(olc.domainRules, url), olc.timeout)
+	return fetchWithBackoff(olc.rateLimiter, url, maxWait, func() (*model.HTTPResponse, error) {
+		return olc.fetch(ctx, url, retry, method)
+	})
+}
+
+// fetch performs HTTP request with specified method once the rate limiter allows it, retrying failed requests
+func (olc *OptimizedLinkCheckerService) fetch(ctx context.Context, url string, retry int, method string) (*model.HTTPResponse, error) {
 	// Apply rate limiting
-	if err := olc.rateLimiter.Wait(url); err != nil {
+	if err := olc.rateLimiter.Wait(ctx, url); err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
 		logger.Errorf("Rate limiting error for %s: %s", url, err)
 	}
 
-	req, err := http.NewRequest(method, url, nil)
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -170,25 +213,44 @@ func (olc *OptimizedLinkCheckerService) FetchWithRetryMethod(url string, retry i
 		req.Header.Set("Accept", "*/*")
 	}
 
+	rule := domainRuleFor(olc.domainRules, url)
+	retry = attemptsFor(rule, method, retry)
+
 	var resp *http.Response
 	var errRequest error
 	for i := 1; i <= retry; i++ {
-		resp, errRequest = olc.client.Do(req)
+		resp, errRequest = doWithTimeout(olc.client, req, timeoutFor(rule, olc.timeout))
 		if errRequest == nil {
 			return &model.HTTPResponse{Response: resp}, nil
 		}
+		if isRedirectError(errRequest) || ctx.Err() != nil {
+			break
+		}
 		
 		if i < retry {
 			logger.Errorf("Attempt %d failed: %v, retrying in %d seconds...", i, errRequest, 2)
-			time.Sleep(2 * time.Second)
+			if err := sleepContext(ctx, 2*time.Second); err != nil {
+				break
+			}
 		}
 	}
 	return nil, errRequest
 }
 
 // FetchWithRetry implements the LinkChecker interface (defaults to GET)
-func (olc *OptimizedLinkCheckerService) FetchWithRetry(url string, retry int) (*model.HTTPResponse, error) {
-	return olc.FetchWithRetryMethod(url, retry, "GET")
+func (olc *OptimizedLinkCheckerService) FetchWithRetry(ctx context.Context, url string, retry int) (*model.HTTPResponse, error) {
+	return olc.FetchWithRetryMethod(ctx, url, retry, "GET")
+}
+
+// SetSoft404Detection reports pages answering 200 with a "not found" content as soft 404s,
+// by fingerprinting the error page of each host when enabled and by matching the given phrases
+func (olc *OptimizedLinkCheckerService) SetSoft404Detection(fingerprint bool, phrases []string, domainPhrases map[string][]string) {
+	olc.soft404 = NewSoft404Detector(olc.FetchWithRetryMethod, fingerprint, phrases, domainPhrases)
+}
+
+// checksSoft404 checks whether the pages of a domain are checked for soft 404s
+func (olc *OptimizedLinkCheckerService) checksSoft404(domain string) bool {
+	return olc.soft404 != nil && olc.soft404.Enabled(domain)
 }
 
 // Head support tracking methods
@@ -261,6 +323,21 @@ func (olc *OptimizedLinkCheckerService) SetDomainRateLimit(domain string, reques
 	olc.rateLimiter.UpdateConfig(domain, requestsPerSecond)
 }
 
+// SetDomainBurst sets a custom burst capacity for a specific domain
+func (olc *OptimizedLinkCheckerService) SetDomainBurst(domain string, burst float64) {
+	olc.rateLimiter.UpdateBurst(domain, burst)
+}
+
+// SetDomainRules applies per-domain timeouts, retries, HEAD requests and skipping to the checked links
+func (olc *OptimizedLinkCheckerService) SetDomainRules(rules map[string]model.DomainRule) {
+	olc.domainRules = rules
+}
+
+// SetCrawlDelay limits a domain to one request per delay, as requested by robots.txt
+func (olc *OptimizedLinkCheckerService) SetCrawlDelay(domain string, delay time.Duration) {
+	olc.rateLimiter.ApplyCrawlDelay(domain, delay)
+}
+
 func (olc *OptimizedLinkCheckerService) GetRateLimiterStats() map[string]RateLimiterStats {
 	return olc.rateLimiter.GetStats()
 }
\ No newline at end of file
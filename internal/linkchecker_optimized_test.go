@@ -1,12 +1,14 @@
 package internal
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestOptimizedLinkCheckerService_CheckLink(t *testing.T) {
@@ -30,7 +32,7 @@ func TestOptimizedLinkCheckerService_CheckLink(t *testing.T) {
 	client := &http.Client{Timeout: 5 * time.Second}
 	checker := NewOptimizedLinkCheckerService(client, "Test/1.0", 5*time.Second, 2.0, 5.0)
 
-	status, errMsg := checker.CheckLink(server.URL)
+	status, errMsg := checker.CheckLink(context.Background(), server.URL)
 	if status != 200 {
 		t.Errorf("Expected status 200, got %d", status)
 	}
@@ -64,7 +66,7 @@ func TestOptimizedLinkCheckerService_HeadFallback(t *testing.T) {
 	client := &http.Client{Timeout: 5 * time.Second}
 	checker := NewOptimizedLinkCheckerService(client, "Test/1.0", 5*time.Second, 2.0, 5.0)
 
-	status, errMsg := checker.CheckLink(server.URL)
+	status, errMsg := checker.CheckLink(context.Background(), server.URL)
 	if status != 200 {
 		t.Errorf("Expected status 200, got %d", status)
 	}
@@ -82,7 +84,7 @@ func TestOptimizedLinkCheckerService_HeadFallback(t *testing.T) {
 	}
 
 	// Second request to same domain should skip HEAD
-	status2, errMsg2 := checker.CheckLink(server.URL + "/page2")
+	status2, errMsg2 := checker.CheckLink(context.Background(), server.URL + "/page2")
 	if status2 != 200 {
 		t.Errorf("Expected status 200, got %d", status2)
 	}
@@ -112,8 +114,8 @@ func TestOptimizedLinkCheckerService_RateLimit(t *testing.T) {
 	start := time.Now()
 	
 	// Make two requests - second should be rate limited
-	checker.CheckLink(server.URL)
-	checker.CheckLink(server.URL)
+	checker.CheckLink(context.Background(), server.URL)
+	checker.CheckLink(context.Background(), server.URL)
 	
 	elapsed := time.Since(start)
 	
@@ -146,7 +148,7 @@ func TestOptimizedLinkCheckerService_FileDetection(t *testing.T) {
 	checker := NewOptimizedLinkCheckerService(client, "Test/1.0", 5*time.Second, 2.0, 5.0)
 
 	// Test image file
-	status, errMsg := checker.CheckLink(server.URL + "/image.jpg")
+	status, errMsg := checker.CheckLink(context.Background(), server.URL + "/image.jpg")
 	if status != 200 {
 		t.Errorf("Expected status 200 for image, got %d", status)
 	}
@@ -155,7 +157,7 @@ func TestOptimizedLinkCheckerService_FileDetection(t *testing.T) {
 	}
 
 	// Test PDF file
-	status, errMsg = checker.CheckLink(server.URL + "/document.pdf")
+	status, errMsg = checker.CheckLink(context.Background(), server.URL + "/document.pdf")
 	if status != 200 {
 		t.Errorf("Expected status 200 for PDF, got %d", status)
 	}
@@ -164,7 +166,7 @@ func TestOptimizedLinkCheckerService_FileDetection(t *testing.T) {
 	}
 
 	// Test HTML page
-	status, errMsg = checker.CheckLink(server.URL + "/page.html")
+	status, errMsg = checker.CheckLink(context.Background(), server.URL + "/page.html")
 	if status != 200 {
 		t.Errorf("Expected status 200 for HTML, got %d", status)
 	}
@@ -190,7 +192,7 @@ func TestOptimizedLinkCheckerService_DomainRateLimit(t *testing.T) {
 	checker.SetDomainRateLimit(domain, 1.0)
 
 	// Make one request to initialize the bucket
-	checker.CheckLink(server.URL)
+	checker.CheckLink(context.Background(), server.URL)
 
 	// Check rate limiter stats
 	stats := checker.GetRateLimiterStats()
@@ -229,4 +231,41 @@ func TestOptimizedLinkStats(t *testing.T) {
 	if stats.TimeSaved != 100*time.Millisecond {
 		t.Errorf("Expected 100ms time saved, got %v", stats.TimeSaved)
 	}
-}
\ No newline at end of file
+}
+func TestOptimizedLinkCheckerService_Cancel(t *testing.T) {
+	logger.InitLogger("error")
+	defer logger.CloseLogger()
+
+	// Answers once the test is over
+	release := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer hanging.Close()
+	defer close(release)
+
+	// Refuses connections, so each attempt fails and is retried
+	refused := httptest.NewServer(http.NotFoundHandler())
+	refused.Close()
+
+	client := &http.Client{Timeout: time.Minute}
+	checker := NewCachedOptimizedLinkCheckerService(client, "Test/1.0", time.Minute, 100, 100, 100, time.Hour)
+
+	for name, target := range map[string]string{"Request in flight": hanging.URL, "Retry backoff": refused.URL} {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			status, errMsg := checker.CheckLink(ctx, target)
+			assert.Less(t, time.Since(start), time.Second, "the check is aborted with its context")
+			assert.Zero(t, status)
+			assert.NotEmpty(t, errMsg)
+		})
+	}
+
+	assert.Zero(t, checker.GetCacheStats().Size, "interrupted checks are not cached")
+}
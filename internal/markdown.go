@@ -0,0 +1,514 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MarkdownLink is a link found in a Markdown file
+type MarkdownLink struct {
+	Destination string // URL or path the link points to, as written
+	Line        int    // 1-based line of the destination
+	Column      int    // 1-based column of the destination, counted in characters
+	Image       bool   // The link embeds an image
+	Markup      string // Markdown source of the link
+}
+
+// MarkdownDocument holds the links and anchors of a Markdown file
+type MarkdownDocument struct {
+	Links   []MarkdownLink
+	Anchors map[string]bool // Heading anchors with GitHub's slugs, and HTML id and name attributes
+}
+
+// Block level constructs recognized line by line
+var (
+	atxHeadingPattern      = regexp.MustCompile(`^ {0,3}#{1,6}(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextUnderlinePattern = regexp.MustCompile(`^ {0,3}(?:=+|-+)[ \t]*$`)
+	fencePattern           = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	referencePattern       = regexp.MustCompile(`^ {0,3}\[((?:[^\]\\]|\\.)+)\]:[ \t]*(<[^>]*>|\S+)`)
+	listItemPattern        = regexp.MustCompile(`^ {0,3}(?:[-+*]|\d{1,9}[.)])(?:[ \t]|$)`)
+	htmlAnchorPattern      = regexp.MustCompile(`(?i)<[a-z][^>]*?\s(?:id|name)\s*=\s*["']([^"']+)["']`)
+	autolinkPattern        = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]{1,31}:[^\s<>]*$`)
+)
+
+// Inline markup replaced by its text when computing heading slugs
+var (
+	headingImagePattern = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	headingLinkPattern  = regexp.MustCompile(`\[([^\]]*)\](?:\([^)]*\)|\[[^\]]*\])`)
+	headingHTMLPattern  = regexp.MustCompile(`<[^>]+>`)
+)
+
+// ParseMarkdown extracts the inline, reference-style, autolink and image links of a Markdown document,
+// and the anchors of its headings. Code blocks, code spans and HTML comments are ignored.
+// Reference-style links are reported at their definition, where their destination is written.
+func ParseMarkdown(content []byte) *MarkdownDocument {
+	doc := &MarkdownDocument{Anchors: make(map[string]bool)}
+	slugs := make(map[string]int) // Occurrences of each slug, numbering duplicates like GitHub
+
+	addHeading := func(text string) {
+		slug := GitHubSlug(headingText(text))
+		count := slugs[slug]
+		slugs[slug]++
+		if count > 0 {
+			slug = fmt.Sprintf("%s-%d", slug, count)
+		}
+		doc.Anchors[slug] = true
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	fence := ""        // Marker of the open code fence, if any
+	indented := false  // Inside an indented code block
+	inList := false    // Inside a list, whose indented lines continue its items
+	blank := true      // Previous line is blank, or the document starts
+	inComment := false // Inside a multi-line HTML comment
+	paragraph := ""    // Previous line when it may be the text of a setext heading
+	frontMatter := len(lines) > 0 && strings.TrimSpace(lines[0]) == "---"
+
+	for i, line := range lines {
+		lineNumber := i + 1
+
+		// YAML front matter of static site generators
+		if frontMatter {
+			if i > 0 && (strings.TrimSpace(line) == "---" || strings.TrimSpace(line) == "...") {
+				frontMatter = false
+			}
+			continue
+		}
+
+		isBlank := strings.TrimSpace(line) == ""
+		afterBlank := blank
+		blank = isBlank
+
+		// Indented code blocks start after a blank line outside lists, and go on until a line is less indented
+		if fence == "" && !inComment && !isBlank {
+			codeIndent := indentation(line) >= 4
+			if indented && !codeIndent {
+				indented = false
+			}
+			if indented || (codeIndent && afterBlank && !inList) {
+				indented = true
+				paragraph = ""
+				continue
+			}
+			if listItemPattern.MatchString(line) {
+				inList = true
+			} else if afterBlank && indentation(line) == 0 {
+				inList = false
+			}
+		}
+
+		if fence != "" {
+			if marker := fencePattern.FindStringSubmatch(line); marker != nil && marker[1][0] == fence[0] &&
+				len(marker[1]) >= len(fence) && strings.TrimSpace(line[len(marker[0]):]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if marker := fencePattern.FindStringSubmatch(line); marker != nil && !inComment {
+			fence = marker[1]
+			paragraph = ""
+			continue
+		}
+
+		runes := []rune(line)
+		inComment = maskComments(runes, inComment)
+		maskCodeSpans(runes)
+		masked := string(runes)
+
+		if strings.TrimSpace(masked) == "" {
+			paragraph = ""
+			continue
+		}
+
+		for _, match := range htmlAnchorPattern.FindAllStringSubmatch(masked, -1) {
+			doc.Anchors[match[1]] = true
+		}
+
+		// Footnotes like [^1]: share the syntax of definitions without being links
+		if match := referencePattern.FindStringSubmatchIndex(masked); match != nil && !strings.HasPrefix(masked[match[2]:match[3]], "^") {
+			destination := masked[match[4]:match[5]]
+			start := match[4]
+			if strings.HasPrefix(destination, "<") {
+				destination = strings.TrimSuffix(destination[1:], ">")
+				start++
+			}
+			if destination != "" {
+				doc.Links = append(doc.Links, MarkdownLink{
+					Destination: destination,
+					Line:        lineNumber,
+					Column:      len([]rune(masked[:start])) + 1,
+					Markup:      strings.TrimSpace(line),
+				})
+			}
+			paragraph = ""
+			continue
+		}
+
+		if setextUnderlinePattern.MatchString(masked) {
+			if paragraph != "" {
+				addHeading(paragraph)
+			}
+			paragraph = ""
+			continue
+		}
+
+		// The text of code spans is part of the heading, unlike comments
+		if match := atxHeadingPattern.FindStringSubmatch(line); match != nil && atxHeadingPattern.MatchString(masked) {
+			addHeading(match[1])
+			paragraph = ""
+		} else if listItemPattern.MatchString(masked) || strings.HasPrefix(strings.TrimSpace(masked), ">") {
+			paragraph = ""
+		} else {
+			paragraph = line
+		}
+
+		doc.Links = append(doc.Links, parseInlineLinks(runes, []rune(line), lineNumber)...)
+	}
+
+	return doc
+}
+
+// indentation returns the width of the leading whitespace of a line, tabs counting as 4 columns
+func indentation(line string) int {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// parseInlineLinks returns the inline links, images and autolinks of a line whose code spans and comments are masked.
+// The markup of links is taken from the original line.
+func parseInlineLinks(runes, original []rune, lineNumber int) []MarkdownLink {
+	links := []MarkdownLink{}
+	covered := make([]bool, len(runes)) // Positions inside links, where autolinks are not recognized
+
+	var scanBrackets func(start, end int)
+	scanBrackets = func(start, end int) {
+		for i := start; i < end; i++ {
+			switch runes[i] {
+			case '\\':
+				i++
+			case '[':
+				closing := matchingBracket(runes, i, end)
+				if closing < 0 || closing+1 >= end || runes[closing+1] != '(' {
+					continue
+				}
+				destination, destinationStart, last, ok := parseInlineDestination(runes, closing+1, end)
+				if !ok {
+					continue
+				}
+
+				markupStart := i
+				image := i > 0 && runes[i-1] == '!' && (i < 2 || runes[i-2] != '\\')
+				if image {
+					markupStart--
+				}
+				if destination != "" {
+					links = append(links, MarkdownLink{
+						Destination: destination,
+						Line:        lineNumber,
+						Column:      destinationStart + 1,
+						Image:       image,
+						Markup:      string(original[markupStart : last+1]),
+					})
+				}
+
+				// Images and links in the text of a link, like badges
+				scanBrackets(i+1, closing)
+				for j := markupStart; j <= last; j++ {
+					covered[j] = true
+				}
+				i = last
+			}
+		}
+	}
+	scanBrackets(0, len(runes))
+
+	for i := 0; i < len(runes); i++ {
+		if covered[i] {
+			continue
+		}
+		if runes[i] == '\\' {
+			i++
+			continue
+		}
+
+		// Autolinks: <https://example.com>
+		if runes[i] == '<' {
+			closing := indexRune(runes, '>', i+1, len(runes))
+			if closing > 0 && autolinkPattern.MatchString(string(runes[i+1:closing])) {
+				links = append(links, MarkdownLink{
+					Destination: string(runes[i+1 : closing]),
+					Line:        lineNumber,
+					Column:      i + 2,
+					Markup:      string(original[i : closing+1]),
+				})
+				i = closing
+			}
+			continue
+		}
+
+		// Bare URLs, linked by GitHub
+		if i > 0 && (unicode.IsLetter(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+			continue
+		}
+		prefix := ""
+		for _, candidate := range []string{"https://", "http://", "www."} {
+			if hasPrefixAt(runes, i, candidate) {
+				prefix = candidate
+				break
+			}
+		}
+		if prefix == "" {
+			continue
+		}
+		end := i
+		for end < len(runes) && !covered[end] && !unicode.IsSpace(runes[end]) && !strings.ContainsRune("<>\"`", runes[end]) {
+			end++
+		}
+		end = trimAutolink(runes, i, end)
+		if end <= i+len(prefix) {
+			continue
+		}
+		destination := string(runes[i:end])
+		if prefix == "www." {
+			destination = "http://" + destination
+		}
+		links = append(links, MarkdownLink{
+			Destination: destination,
+			Line:        lineNumber,
+			Column:      i + 1,
+			Markup:      string(original[i:end]),
+		})
+		i = end - 1
+	}
+
+	return links
+}
+
+// parseInlineDestination parses the "(destination "title")" part of an inline link starting at open.
+// It returns the destination, where it starts and the position of the closing parenthesis.
+func parseInlineDestination(runes []rune, open, end int) (string, int, int, bool) {
+	i := skipSpaces(runes, open+1, end)
+
+	start := i
+	var destination string
+	if i < end && runes[i] == '<' {
+		closing := indexRune(runes, '>', i+1, end)
+		if closing < 0 {
+			return "", 0, 0, false
+		}
+		start = i + 1
+		destination = string(runes[start:closing])
+		i = closing + 1
+	} else {
+		depth := 0
+	destination:
+		for i < end {
+			switch runes[i] {
+			case '\\':
+				i++
+			case ' ', '\t':
+				break destination
+			case '(':
+				depth++
+			case ')':
+				if depth == 0 {
+					break destination
+				}
+				depth--
+			}
+			i++
+		}
+		if i > end {
+			return "", 0, 0, false
+		}
+		destination = string(runes[start:i])
+	}
+
+	// Optional title
+	i = skipSpaces(runes, i, end)
+	if i < end && strings.ContainsRune("\"'(", runes[i]) {
+		closer := runes[i]
+		if closer == '(' {
+			closer = ')'
+		}
+		closing := indexRune(runes, closer, i+1, end)
+		if closing < 0 {
+			return "", 0, 0, false
+		}
+		i = skipSpaces(runes, closing+1, end)
+	}
+
+	if i >= end || runes[i] != ')' {
+		return "", 0, 0, false
+	}
+	return destination, start, i, true
+}
+
+// matchingBracket returns the position of the bracket closing the one at open, -1 if there is none
+func matchingBracket(runes []rune, open, end int) int {
+	depth := 0
+	for i := open; i < end; i++ {
+		switch runes[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// trimAutolink drops the trailing punctuation and unbalanced closing parentheses of a bare URL, like GitHub
+func trimAutolink(runes []rune, start, end int) int {
+	for end > start {
+		last := runes[end-1]
+		if strings.ContainsRune("?!.,:*_~'", last) {
+			end--
+			continue
+		}
+		if last == ')' && strings.Count(string(runes[start:end]), "(") < strings.Count(string(runes[start:end]), ")") {
+			end--
+			continue
+		}
+		break
+	}
+	return end
+}
+
+// maskCodeSpans replaces the content of code spans with spaces, keeping the positions of the other characters
+func maskCodeSpans(runes []rune) {
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '\\' {
+			i++
+			continue
+		}
+		if runes[i] != '`' {
+			continue
+		}
+
+		length := backtickRun(runes, i)
+		for j := i + length; j < len(runes); j++ {
+			if runes[j] != '`' {
+				continue
+			}
+			closingLength := backtickRun(runes, j)
+			if closingLength == length {
+				for k := i; k < j+length; k++ {
+					runes[k] = ' '
+				}
+				i = j + length - 1
+				break
+			}
+			j += closingLength - 1
+		}
+	}
+}
+
+// backtickRun returns the number of backticks starting at a position
+func backtickRun(runes []rune, start int) int {
+	end := start
+	for end < len(runes) && runes[end] == '`' {
+		end++
+	}
+	return end - start
+}
+
+// maskComments replaces HTML comments with spaces. It returns whether a comment is still open at the end of the line.
+func maskComments(runes []rune, inComment bool) bool {
+	for i := 0; i < len(runes); i++ {
+		if !inComment {
+			if !hasPrefixAt(runes, i, "<!--") {
+				continue
+			}
+			inComment = true
+		}
+
+		end := len(runes)
+		if closing := indexString(runes, "-->", i); closing >= 0 {
+			end = closing + len("-->")
+			inComment = false
+		}
+		for j := i; j < end; j++ {
+			runes[j] = ' '
+		}
+		i = end - 1
+	}
+	return inComment
+}
+
+// hasPrefixAt checks whether s appears in runes at a position
+func hasPrefixAt(runes []rune, position int, s string) bool {
+	end := min(position+len([]rune(s)), len(runes))
+	return string(runes[position:end]) == s
+}
+
+// indexString returns the position of the first s in runes from start, -1 if there is none
+func indexString(runes []rune, s string, start int) int {
+	for i := start; i < len(runes); i++ {
+		if hasPrefixAt(runes, i, s) {
+			return i
+		}
+	}
+	return -1
+}
+
+// indexRune returns the position of the first r in runes[start:end], -1 if there is none
+func indexRune(runes []rune, r rune, start, end int) int {
+	for i := start; i < end; i++ {
+		if runes[i] == '\\' {
+			i++
+			continue
+		}
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// skipSpaces returns the position of the first rune of runes[start:end] that is not a space or a tab
+func skipSpaces(runes []rune, start, end int) int {
+	for start < end && (runes[start] == ' ' || runes[start] == '\t') {
+		start++
+	}
+	return start
+}
+
+// headingText returns the text of a heading as rendered, without the markup of its links, images and HTML tags
+func headingText(heading string) string {
+	heading = headingImagePattern.ReplaceAllString(heading, "")
+	heading = headingLinkPattern.ReplaceAllString(heading, "$1")
+	return headingHTMLPattern.ReplaceAllString(heading, "")
+}
+
+// GitHubSlug returns the anchor GitHub generates for a heading: lowercase, keeping letters, numbers,
+// hyphens and underscores, with spaces replaced by hyphens. Duplicate headings get a "-1", "-2"... suffix.
+func GitHubSlug(heading string) string {
+	var slug strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(heading)) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsNumber(r), unicode.IsMark(r), r == '-', r == '_':
+			slug.WriteRune(r)
+		case r == ' ':
+			slug.WriteRune('-')
+		}
+	}
+	return slug.String()
+}
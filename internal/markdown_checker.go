@@ -0,0 +1,309 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+)
+
+// markdownExtensions are the extensions of the Markdown files found in directories
+var markdownExtensions = map[string]bool{".md": true, ".markdown": true}
+
+// markdownJob is a link of a Markdown file waiting to be checked
+type markdownJob struct {
+	file string // Path of the Markdown file
+	link MarkdownLink
+}
+
+// markdownEntry holds the parsed document of a Markdown file, loaded at most once
+type markdownEntry struct {
+	once sync.Once
+	doc  *MarkdownDocument // nil when the file cannot be read
+	err  error             // Why the file cannot be read
+}
+
+// MarkdownCheckerService checks the links of Markdown files. Relative links are checked on disk, including
+// the heading anchors of the Markdown files they point to, and absolute links with a link checker.
+type MarkdownCheckerService struct {
+	root            string // Directory root-relative links point into, like the root of a repository
+	linkChecker     LinkChecker
+	urlProcessor    *URLProcessorService
+	resultCollector *ResultCollectorService
+	config          *CrawlConfig
+
+	redirects      *RedirectRecorder // Optional, records the redirects of checked links
+	redirectPolicy *RedirectPolicy
+
+	documents sync.Map // Absolute file path -> *markdownEntry
+}
+
+// NewMarkdownCheckerService creates a MarkdownCheckerService resolving root-relative links against root
+func NewMarkdownCheckerService(root string, linkChecker LinkChecker, urlProcessor *URLProcessorService, resultCollector *ResultCollectorService, config *CrawlConfig) (*MarkdownCheckerService, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	return &MarkdownCheckerService{
+		root:            absRoot,
+		linkChecker:     linkChecker,
+		urlProcessor:    urlProcessor,
+		resultCollector: resultCollector,
+		config:          config,
+	}, nil
+}
+
+// SetRedirectRecorder reports the redirects of checked links flagged by policy
+func (mc *MarkdownCheckerService) SetRedirectRecorder(recorder *RedirectRecorder, policy *RedirectPolicy) {
+	mc.redirects = recorder
+	mc.redirectPolicy = policy
+}
+
+// Check checks the links of Markdown files, walking directories for .md and .markdown files.
+// Cancelling ctx stops the check, leaving out the links not checked yet.
+func (mc *MarkdownCheckerService) Check(ctx context.Context, paths []string) error {
+	files, err := mc.findFiles(paths)
+	if err != nil {
+		return err
+	}
+	logger.Infof("Checking the links of %d Markdown files", len(files))
+
+	jobs := make(chan markdownJob)
+	var wg sync.WaitGroup
+	for range max(mc.config.Concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if result := mc.checkLink(ctx, job.file, job.link); result != nil {
+					mc.resultCollector.AddResult(*result)
+				}
+			}
+		}()
+	}
+
+	// Files that cannot be read fail the check once the links of the other files are checked
+	var readErrors []error
+queue:
+	for _, file := range files {
+		doc, err := mc.document(file)
+		if err != nil {
+			logger.Errorf("Error reading %s: %s", file, err)
+			readErrors = append(readErrors, err)
+			continue
+		}
+		for _, link := range doc.Links {
+			select {
+			case jobs <- markdownJob{file: file, link: link}:
+			case <-ctx.Done():
+				break queue
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return errors.Join(readErrors...)
+}
+
+// findFiles returns the Markdown files of paths. Files are kept whatever their extension,
+// directories are walked, skipping hidden ones.
+func (mc *MarkdownCheckerService) findFiles(paths []string) ([]string, error) {
+	files := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if filePath != path && strings.HasPrefix(entry.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if markdownExtensions[strings.ToLower(filepath.Ext(filePath))] {
+				files = append(files, filePath)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// document returns the parsed content of a Markdown file, or why it cannot be read
+func (mc *MarkdownCheckerService) document(file string) (*MarkdownDocument, error) {
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+
+	value, _ := mc.documents.LoadOrStore(absFile, &markdownEntry{})
+	entry := value.(*markdownEntry)
+	entry.once.Do(func() {
+		content, err := os.ReadFile(absFile)
+		if err != nil {
+			entry.err = err
+			return
+		}
+		entry.doc = ParseMarkdown(content)
+	})
+	return entry.doc, entry.err
+}
+
+// checkLink checks a link of a Markdown file, returning nil if it should be skipped
+func (mc *MarkdownCheckerService) checkLink(ctx context.Context, file string, link MarkdownLink) *model.LinkResult {
+	linkResult := &model.LinkResult{
+		SourceURL: displayPath(file),
+		TargetURL: link.Destination,
+		Element:   "a",
+		Line:      link.Line,
+		Column:    link.Column,
+		Snippet:   snippetOf(link.Markup),
+	}
+	if link.Image {
+		linkResult.Element = "img"
+	}
+
+	// Destinations may hold spaces between angle brackets
+	linkURL, err := url.Parse(strings.ReplaceAll(link.Destination, " ", "%20"))
+	if err != nil {
+		linkResult.Error = err.Error()
+		return linkResult
+	}
+	if strings.HasPrefix(link.Destination, "//") {
+		linkURL.Scheme = "https"
+	}
+
+	if linkURL.Scheme != "" {
+		if !mc.checkRemoteLink(ctx, linkResult, linkURL) {
+			return nil
+		}
+		return linkResult
+	}
+	if !mc.checkLocalLink(linkResult, file, linkURL) {
+		return nil
+	}
+	return linkResult
+}
+
+// checkRemoteLink checks an absolute link with the link checker. It returns false if the link is skipped.
+func (mc *MarkdownCheckerService) checkRemoteLink(ctx context.Context, linkResult *model.LinkResult, linkURL *url.URL) bool {
+	if mc.config.OnlyInternal || mc.urlProcessor.ShouldSkipURL(nil, linkURL) {
+		logger.Debugf("Skipping link %s", linkURL)
+		return false
+	}
+
+	// The fragment is never sent to the server
+	checkedURL := stripFragment(linkURL.String())
+	status, errMsg := mc.linkChecker.CheckLink(ctx, checkedURL)
+	if ctx.Err() != nil {
+		return false // Interrupted, the link is neither working nor broken
+	}
+
+	linkResult.TargetURL = linkURL.String()
+	linkResult.Status = status
+	linkResult.Error = errMsg
+	linkResult.IsExternal = true
+
+	if IsSoft404Error(errMsg) {
+		linkResult.FailureType = model.FailureSoft404
+	}
+	if mc.redirects != nil {
+		linkResult.Redirects = mc.redirects.Chain(checkedURL)
+		linkResult.RedirectIssues = mc.redirectPolicy.Analyze(linkResult.Redirects)
+	}
+	return true
+}
+
+// checkLocalLink checks a relative link on disk, and its fragment against the headings of a Markdown target.
+// It returns false if the link is skipped.
+func (mc *MarkdownCheckerService) checkLocalLink(linkResult *model.LinkResult, file string, linkURL *url.URL) bool {
+	target := file
+	if linkURL.Path != "" {
+		if strings.HasPrefix(linkURL.Path, "/") {
+			target = filepath.Join(mc.root, filepath.FromSlash(linkURL.Path))
+		} else {
+			target = filepath.Join(filepath.Dir(file), filepath.FromSlash(linkURL.Path))
+		}
+	} else if !isCheckableFragment(linkURL.Fragment) {
+		return false
+	}
+
+	linkResult.TargetURL = displayPath(target)
+	if linkURL.Fragment != "" {
+		linkResult.TargetURL += "#" + linkURL.EscapedFragment()
+	}
+	if mc.urlProcessor.shouldSkipURLBasedOnPattern(&url.URL{Path: linkResult.TargetURL}) {
+		logger.Debugf("Skipping link due to pattern match: %s", linkResult.TargetURL)
+		return false
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		logger.Debugf("No file found for %s", target)
+		linkResult.Status = http.StatusNotFound
+		return true
+	}
+	linkResult.Status = http.StatusOK
+
+	// Anchors are only known for Markdown targets
+	fragment := linkURL.Fragment
+	if info.IsDir() || !markdownExtensions[strings.ToLower(filepath.Ext(target))] || !isCheckableFragment(fragment) {
+		return true
+	}
+	doc, err := mc.document(target)
+	if err != nil {
+		logger.Debugf("Error reading %s: %s", target, err)
+		return true
+	}
+	// GitHub also finds anchors written with another case
+	if !hasAnchor(doc.Anchors, fragment) && !hasAnchor(doc.Anchors, strings.ToLower(fragment)) {
+		logger.Debugf("Missing anchor #%s in %s", fragment, target)
+		linkResult.FailureType = model.FailureMissingAnchor
+		linkResult.Error = fmt.Sprintf("missing anchor #%s", fragment)
+	}
+	return true
+}
+
+// displayPath returns a file path relative to the working directory when it is inside it, for reports
+func displayPath(file string) string {
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return filepath.ToSlash(absFile)
+	}
+	relative, err := filepath.Rel(cwd, absFile)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(absFile)
+	}
+	return filepath.ToSlash(relative)
+}
+
+// GetResults returns the results of the checked links
+func (mc *MarkdownCheckerService) GetResults() []model.LinkResult {
+	return mc.resultCollector.GetResults()
+}
@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	content := "---\n" +
+		"title: [Front](front.md)\n" +
+		"---\n" +
+		"# Getting Started\n" +
+		"See [the guide](docs/guide.md#install \"Guide\") and ![logo](<img/my logo.png>).\n" +
+		"[![Build](https://ci.example.com/badge.svg)](https://ci.example.com/)\n" +
+		"Visit <https://example.com/auto> or https://example.com/bare.\n" +
+		"Read the [reference][ref] and `[code](skipped.md)`.\n" +
+		"\n" +
+		"[ref]: https://example.com/reference\n" +
+		"[^1]: A footnote, not a link\n" +
+		"<!-- [hidden](hidden.md) -->\n" +
+		"```\n" +
+		"[fenced](fenced.md)\n" +
+		"```\n" +
+		"Setext heading\n" +
+		"--------------\n" +
+		"## Getting Started\n" +
+		"## API `v2` & [links](x.md)\n" +
+		"<a name=\"custom-anchor\"></a>\n" +
+		"\n" +
+		"    [indented](indented.md)\n" +
+		"\n" +
+		"\t[tabbed](tabbed.md)\n" +
+		"- List item\n" +
+		"\n" +
+		"    [continued](continued.md)\n"
+
+	doc := ParseMarkdown([]byte(content))
+
+	destinations := []string{}
+	for _, link := range doc.Links {
+		destinations = append(destinations, link.Destination)
+	}
+	assert.Equal(t, []string{
+		"docs/guide.md#install",
+		"img/my logo.png",
+		"https://ci.example.com/",
+		"https://ci.example.com/badge.svg",
+		"https://example.com/auto",
+		"https://example.com/bare",
+		"https://example.com/reference",
+		"x.md",
+		"continued.md", // Indented lines of list items are not code
+	}, destinations)
+
+	guide := doc.Links[0]
+	assert.Equal(t, 5, guide.Line)
+	assert.Equal(t, 17, guide.Column)
+	assert.Equal(t, `[the guide](docs/guide.md#install "Guide")`, guide.Markup)
+	assert.True(t, doc.Links[1].Image)
+	assert.True(t, doc.Links[3].Image)
+	assert.Equal(t, 10, doc.Links[6].Line)
+
+	for _, anchor := range []string{"getting-started", "getting-started-1", "setext-heading", "api-v2--links", "custom-anchor"} {
+		assert.True(t, doc.Anchors[anchor], anchor)
+	}
+}
+
+func TestGitHubSlug(t *testing.T) {
+	testCases := map[string]string{
+		"Getting Started":         "getting-started",
+		"What's new in v1.2?":     "whats-new-in-v12",
+		"snake_case & kebab-case": "snake_case--kebab-case",
+		"Überblick":               "überblick",
+		"Emoji 🚀 launch":          "emoji--launch",
+	}
+	for heading, slug := range testCases {
+		assert.Equal(t, slug, GitHubSlug(heading), heading)
+	}
+}
+
+func TestMarkdownCheck(t *testing.T) {
+	logger.SetQuiet(true)
+
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer external.Close()
+
+	root := writeSiteFiles(t, map[string]string{
+		"README.md": "# Project\n" +
+			"[Guide](docs/guide.md#installation)\n" +
+			"[Wrong anchor](docs/guide.md#missing)\n" +
+			"[Missing](docs/missing.md)\n" +
+			"[Top](#project)\n" +
+			"[Root](/docs/guide.md)\n" +
+			"[Mail](mailto:team@example.com)\n" +
+			"[Ok](" + external.URL + "/ok) and [Gone](" + external.URL + "/gone)\n",
+		"docs/guide.md":   "## Installation\n![Logo](../img/logo.png)\n",
+		"docs/notes.txt":  "[not markdown](nowhere.md)\n",
+		".github/skip.md": "[hidden](nowhere.md)\n",
+	})
+	t.Chdir(root)
+
+	factory := NewServiceFactory()
+	config := factory.CreateCrawlConfigFromParams(0, 2, false, "", "", "")
+	checker, err := factory.CreateMarkdownCheckerService(config, root, "TestAgent", 5*time.Second, &http.Client{}, 100, 100, 100, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, checker.Check(context.Background(), []string{"."}))
+
+	results := map[string]model.LinkResult{}
+	for _, result := range checker.GetResults() {
+		results[result.TargetURL] = result
+	}
+	assert.Len(t, results, 8)
+
+	assert.Equal(t, 200, results["docs/guide.md#installation"].Status)
+	assert.Empty(t, results["docs/guide.md#installation"].Error)
+	assert.Equal(t, "README.md", results["docs/guide.md#installation"].SourceURL)
+	assert.Equal(t, 2, results["docs/guide.md#installation"].Line)
+	assert.Equal(t, 9, results["docs/guide.md#installation"].Column)
+
+	assert.Equal(t, model.FailureMissingAnchor, results["docs/guide.md#missing"].FailureType)
+	assert.Equal(t, 404, results["docs/missing.md"].Status)
+	assert.Empty(t, results["README.md#project"].Error)
+	assert.Equal(t, 200, results["docs/guide.md"].Status)
+
+	logo := results[filepath.ToSlash("img/logo.png")]
+	assert.Equal(t, 404, logo.Status)
+	assert.Equal(t, "img", logo.Element)
+	assert.Equal(t, "docs/guide.md", logo.SourceURL)
+
+	assert.Equal(t, 200, results[external.URL+"/ok"].Status)
+	assert.True(t, results[external.URL+"/ok"].IsExternal)
+	assert.Equal(t, 404, results[external.URL+"/gone"].Status)
+}
+
+func TestMarkdownCheckUnreadableFile(t *testing.T) {
+	logger.SetQuiet(true)
+
+	root := writeSiteFiles(t, map[string]string{
+		"README.md": "# Project\n[Guide](guide.md)\n",
+		"guide.md":  "## Guide\n",
+	})
+	// A Markdown file pointing to a path that does not exist
+	require.NoError(t, os.Symlink(filepath.Join(root, "nowhere.md"), filepath.Join(root, "dangling.md")))
+	t.Chdir(root)
+
+	factory := NewServiceFactory()
+	config := factory.CreateCrawlConfigFromParams(0, 2, false, "", "", "")
+	checker, err := factory.CreateMarkdownCheckerService(config, root, "TestAgent", 5*time.Second, &http.Client{}, 100, 100, 100, time.Hour)
+	require.NoError(t, err)
+
+	err = checker.Check(context.Background(), []string{"."})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.Contains(t, err.Error(), "dangling.md")
+
+	results := checker.GetResults()
+	require.Len(t, results, 1, "the links of the other files are still checked")
+	assert.Equal(t, 200, results[0].Status)
+}
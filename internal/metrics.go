@@ -0,0 +1,351 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricsContentType is the content type of the Prometheus text exposition format
+const MetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// latencyBuckets are the upper bounds, in seconds, of the request latency histogram buckets
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// latencyHistogram counts request durations of a domain in latencyBuckets
+type latencyHistogram struct {
+	buckets []int64 // Observations per bucket, not cumulative. The last one is +Inf.
+	count   int64
+	sum     float64
+}
+
+// statusKey identifies the responses of a domain with a status code, or "error" when no response was received
+type statusKey struct {
+	domain string
+	code   string
+}
+
+// Metrics collects request latencies and status codes per domain, along with the statistics of the running crawls,
+// and exposes them in the Prometheus text exposition format
+type Metrics struct {
+	mutex     sync.Mutex
+	latencies map[string]*latencyHistogram
+	responses map[statusKey]int64
+	crawlers  map[string]*OptimizedCrawlerService // Scan label -> crawler
+}
+
+// NewMetrics creates an empty metrics registry
+func NewMetrics() *Metrics {
+	return &Metrics{
+		latencies: make(map[string]*latencyHistogram),
+		responses: make(map[statusKey]int64),
+		crawlers:  make(map[string]*OptimizedCrawlerService),
+	}
+}
+
+// ObserveRequest records the duration of a request to a domain and its status code, 0 when it failed without response
+func (m *Metrics) ObserveRequest(domain string, status int, duration time.Duration) {
+	code := "error"
+	if status > 0 {
+		code = strconv.Itoa(status)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	histogram, found := m.latencies[domain]
+	if !found {
+		histogram = &latencyHistogram{buckets: make([]int64, len(latencyBuckets)+1)}
+		m.latencies[domain] = histogram
+	}
+	seconds := duration.Seconds()
+	bucket := sort.SearchFloat64s(latencyBuckets, seconds)
+	histogram.buckets[bucket]++
+	histogram.count++
+	histogram.sum += seconds
+
+	m.responses[statusKey{domain: domain, code: code}]++
+}
+
+// Track exposes the statistics of a crawler under a scan label until the returned function is called
+func (m *Metrics) Track(scan string, crawler *OptimizedCrawlerService) func() {
+	m.mutex.Lock()
+	m.crawlers[scan] = crawler
+	m.mutex.Unlock()
+
+	return func() {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		if m.crawlers[scan] == crawler {
+			delete(m.crawlers, scan)
+		}
+	}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", MetricsContentType)
+	_ = m.Write(w)
+}
+
+// Write writes the metrics in the Prometheus text exposition format
+func (m *Metrics) Write(w io.Writer) error {
+	m.mutex.Lock()
+	domains := sortedMapKeys(m.latencies)
+	histograms := make([]latencyHistogram, len(domains))
+	for i, domain := range domains {
+		histogram := *m.latencies[domain]
+		histogram.buckets = append([]int64(nil), histogram.buckets...)
+		histograms[i] = histogram
+	}
+	responses := make(map[statusKey]int64, len(m.responses))
+	for key, count := range m.responses {
+		responses[key] = count
+	}
+	scans := sortedMapKeys(m.crawlers)
+	crawlers := make([]*OptimizedCrawlerService, len(scans))
+	for i, scan := range scans {
+		crawlers[i] = m.crawlers[scan]
+	}
+	m.mutex.Unlock()
+
+	mw := &metricsWriter{w: w}
+
+	mw.family("deadlinkr_request_duration_seconds", "histogram", "Duration of the HTTP requests until their response headers are received")
+	for i, domain := range domains {
+		histogram := histograms[i]
+		cumulative := int64(0)
+		for bucket, bound := range latencyBuckets {
+			cumulative += histogram.buckets[bucket]
+			mw.sample("deadlinkr_request_duration_seconds_bucket", float64(cumulative), "domain", domain, "le", formatMetricValue(bound))
+		}
+		mw.sample("deadlinkr_request_duration_seconds_bucket", float64(histogram.count), "domain", domain, "le", "+Inf")
+		mw.sample("deadlinkr_request_duration_seconds_sum", histogram.sum, "domain", domain)
+		mw.sample("deadlinkr_request_duration_seconds_count", float64(histogram.count), "domain", domain)
+	}
+
+	keys := make([]statusKey, 0, len(responses))
+	for key := range responses {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].domain != keys[j].domain {
+			return keys[i].domain < keys[j].domain
+		}
+		return keys[i].code < keys[j].code
+	})
+	mw.family("deadlinkr_responses_total", "counter", "HTTP responses by domain and status code, \"error\" when the request failed without response")
+	for _, key := range keys {
+		mw.sample("deadlinkr_responses_total", float64(responses[key]), "domain", key.domain, "code", key.code)
+	}
+
+	mw.family("deadlinkr_scans_running", "gauge", "Number of scans running")
+	mw.sample("deadlinkr_scans_running", float64(len(scans)))
+
+	stats := make([]crawlerMetrics, len(crawlers))
+	for i, crawler := range crawlers {
+		stats[i] = collectCrawlerMetrics(crawler)
+	}
+	writeCrawlerMetrics(mw, scans, stats)
+
+	return mw.err
+}
+
+// crawlerMetrics are the statistics of a crawler exposed as metrics
+type crawlerMetrics struct {
+	progress     ProgressStats
+	pagesQueued  int64
+	pagesCrawled int64
+	pagesActive  int64
+	cache        *CacheStats
+	optimization *OptimizedLinkStats
+	rateLimits   map[string]RateLimiterStats
+}
+
+// collectCrawlerMetrics reads the statistics of a crawler and of the link checker it uses
+func collectCrawlerMetrics(crawler *OptimizedCrawlerService) crawlerMetrics {
+	stats := crawlerMetrics{progress: crawler.Progress()}
+	pool := crawler.GetStats()
+	stats.pagesQueued, stats.pagesCrawled, stats.pagesActive = pool.JobsQueued, pool.JobsCompleted, pool.JobsActive
+
+	checker := linkCheckerOf(crawler.pageParser)
+	if fileSystemChecker, ok := checker.(*FileSystemLinkChecker); ok {
+		checker = fileSystemChecker.httpChecker
+	}
+	if cached, ok := checker.(interface{ GetCacheStats() CacheStats }); ok {
+		cache := cached.GetCacheStats()
+		stats.cache = &cache
+	}
+	if optimized, ok := checker.(interface{ GetOptimizationStats() OptimizedLinkStats }); ok {
+		optimization := optimized.GetOptimizationStats()
+		stats.optimization = &OptimizedLinkStats{
+			HeadRequestsUsed: optimization.HeadRequestsUsed,
+			GetRequestsUsed:  optimization.GetRequestsUsed,
+			HeadFallbacks:    optimization.HeadFallbacks,
+			BytesSaved:       optimization.BytesSaved,
+			TimeSaved:        optimization.TimeSaved,
+		}
+	}
+	if limited, ok := checker.(interface {
+		GetRateLimiterStats() map[string]RateLimiterStats
+	}); ok {
+		stats.rateLimits = limited.GetRateLimiterStats()
+	}
+	return stats
+}
+
+// writeCrawlerMetrics writes the statistics of the running scans, labelled by scan
+func writeCrawlerMetrics(mw *metricsWriter, scans []string, stats []crawlerMetrics) {
+	scanMetrics := []struct {
+		name, kind, help string
+		value            func(crawlerMetrics) (float64, bool)
+	}{
+		{"deadlinkr_scan_pages_queued_total", "counter", "Pages queued for crawling by the scan", func(s crawlerMetrics) (float64, bool) { return float64(s.pagesQueued), true }},
+		{"deadlinkr_scan_pages_crawled_total", "counter", "Pages crawled by the scan", func(s crawlerMetrics) (float64, bool) { return float64(s.pagesCrawled), true }},
+		{"deadlinkr_scan_pages_active", "gauge", "Pages being crawled by the scan", func(s crawlerMetrics) (float64, bool) { return float64(s.pagesActive), true }},
+		{"deadlinkr_scan_errors_total", "counter", "Pages of the scan that could not be crawled", func(s crawlerMetrics) (float64, bool) { return float64(s.progress.ErrorCount), true }},
+		{"deadlinkr_scan_links_checked_total", "counter", "Links checked by the scan", func(s crawlerMetrics) (float64, bool) { return float64(s.progress.LinksChecked), true }},
+		{"deadlinkr_scan_elapsed_seconds", "gauge", "Time since the scan started", func(s crawlerMetrics) (float64, bool) { return s.progress.ElapsedTime.Seconds(), true }},
+		{"deadlinkr_cache_hits_total", "counter", "Link checks of the scan answered by the cache", func(s crawlerMetrics) (float64, bool) {
+			return cacheMetric(s, func(c *CacheStats) int64 { return c.Hits })
+		}},
+		{"deadlinkr_cache_misses_total", "counter", "Link checks of the scan missing from the cache", func(s crawlerMetrics) (float64, bool) {
+			return cacheMetric(s, func(c *CacheStats) int64 { return c.Misses })
+		}},
+		{"deadlinkr_cache_entries", "gauge", "Links held in the cache of the scan", func(s crawlerMetrics) (float64, bool) {
+			return cacheMetric(s, func(c *CacheStats) int64 { return int64(c.Size) })
+		}},
+		{"deadlinkr_cache_max_entries", "gauge", "Capacity of the cache of the scan", func(s crawlerMetrics) (float64, bool) {
+			return cacheMetric(s, func(c *CacheStats) int64 { return int64(c.MaxSize) })
+		}},
+		{"deadlinkr_head_requests_total", "counter", "Links of the scan checked with a HEAD request", func(s crawlerMetrics) (float64, bool) {
+			return optimizationMetric(s, func(o *OptimizedLinkStats) int64 { return o.HeadRequestsUsed })
+		}},
+		{"deadlinkr_get_requests_total", "counter", "Links of the scan checked with a GET request", func(s crawlerMetrics) (float64, bool) {
+			return optimizationMetric(s, func(o *OptimizedLinkStats) int64 { return o.GetRequestsUsed })
+		}},
+		{"deadlinkr_head_fallbacks_total", "counter", "HEAD requests of the scan retried with GET", func(s crawlerMetrics) (float64, bool) {
+			return optimizationMetric(s, func(o *OptimizedLinkStats) int64 { return o.HeadFallbacks })
+		}},
+		{"deadlinkr_bytes_saved_total", "counter", "Estimated response bytes the scan avoided downloading with HEAD requests", func(s crawlerMetrics) (float64, bool) {
+			return optimizationMetric(s, func(o *OptimizedLinkStats) int64 { return o.BytesSaved })
+		}},
+	}
+
+	for _, metric := range scanMetrics {
+		mw.family(metric.name, metric.kind, metric.help)
+		for i, scan := range scans {
+			if value, ok := metric.value(stats[i]); ok {
+				mw.sample(metric.name, value, "scan", scan)
+			}
+		}
+	}
+
+	mw.family("deadlinkr_rate_limit_requests_per_second", "gauge", "Requests per second allowed to a domain by the scan")
+	for i, scan := range scans {
+		for _, domain := range sortedMapKeys(stats[i].rateLimits) {
+			mw.sample("deadlinkr_rate_limit_requests_per_second", stats[i].rateLimits[domain].Rate, "scan", scan, "domain", domain)
+		}
+	}
+	mw.family("deadlinkr_rate_limit_tokens", "gauge", "Requests to a domain the scan can send without waiting")
+	for i, scan := range scans {
+		for _, domain := range sortedMapKeys(stats[i].rateLimits) {
+			mw.sample("deadlinkr_rate_limit_tokens", stats[i].rateLimits[domain].CurrentTokens, "scan", scan, "domain", domain)
+		}
+	}
+}
+
+// cacheMetric reads a cache statistic, if the scan caches link checks
+func cacheMetric(stats crawlerMetrics, value func(*CacheStats) int64) (float64, bool) {
+	if stats.cache == nil {
+		return 0, false
+	}
+	return float64(value(stats.cache)), true
+}
+
+// optimizationMetric reads a HEAD optimization statistic, if the scan checks links with HEAD requests
+func optimizationMetric(stats crawlerMetrics, value func(*OptimizedLinkStats) int64) (float64, bool) {
+	if stats.optimization == nil {
+		return 0, false
+	}
+	return float64(value(stats.optimization)), true
+}
+
+// metricsWriter writes metric families and samples, keeping the first write error
+type metricsWriter struct {
+	w   io.Writer
+	err error
+}
+
+// family writes the help and type lines of a metric family
+func (mw *metricsWriter) family(name, kind, help string) {
+	mw.printf("# HELP %s %s\n# TYPE %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help), name, kind)
+}
+
+// sample writes a sample with its label names and values given in pairs
+func (mw *metricsWriter) sample(name string, value float64, labels ...string) {
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+`="`+escapeLabelValue(labels[i+1])+`"`)
+	}
+	if len(pairs) > 0 {
+		name += "{" + strings.Join(pairs, ",") + "}"
+	}
+	mw.printf("%s %s\n", name, formatMetricValue(value))
+}
+
+func (mw *metricsWriter) printf(format string, args ...any) {
+	if mw.err != nil {
+		return
+	}
+	_, mw.err = fmt.Fprintf(mw.w, format, args...)
+}
+
+// escapeLabelValue escapes backslashes, double quotes and line feeds in a label value
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatMetricValue formats a sample value in its shortest representation
+func formatMetricValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// sortedMapKeys returns the keys of a map in order, so metrics are written in a stable order
+func sortedMapKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// MeasuredHTTPClient implements the HTTPClient interface, recording the latency and status code of each request
+type MeasuredHTTPClient struct {
+	client  HTTPClient
+	metrics *Metrics
+}
+
+// NewMeasuredHTTPClient creates a MeasuredHTTPClient wrapping an HTTP client
+func NewMeasuredHTTPClient(client HTTPClient, metrics *Metrics) *MeasuredHTTPClient {
+	return &MeasuredHTTPClient{client: client, metrics: metrics}
+}
+
+// Do executes a request and records its latency and status code under the requested host, port included like rate limits
+func (mc *MeasuredHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := mc.client.Do(req)
+
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	mc.metrics.ObserveRequest(req.URL.Host, status, time.Since(start))
+	return resp, err
+}
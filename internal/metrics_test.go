@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingClient fails every request without response
+type failingClient struct{}
+
+func (failingClient) Do(req *http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+// scrapeMetrics returns the metrics as served to Prometheus
+func scrapeMetrics(t *testing.T, metrics *Metrics) string {
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, MetricsContentType, recorder.Header().Get("Content-Type"))
+	return recorder.Body.String()
+}
+
+func TestMetricsRequests(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.ObserveRequest("example.com", 200, 30*time.Millisecond)
+	metrics.ObserveRequest("example.com", 200, 700*time.Millisecond)
+	metrics.ObserveRequest("example.com", 404, time.Minute)
+	metrics.ObserveRequest(`we"ird.example`, 0, time.Second)
+
+	body := scrapeMetrics(t, metrics)
+
+	for _, line := range []string{
+		"# TYPE deadlinkr_request_duration_seconds histogram",
+		`deadlinkr_request_duration_seconds_bucket{domain="example.com",le="0.05"} 1`,
+		`deadlinkr_request_duration_seconds_bucket{domain="example.com",le="0.5"} 1`,
+		`deadlinkr_request_duration_seconds_bucket{domain="example.com",le="1"} 2`,
+		`deadlinkr_request_duration_seconds_bucket{domain="example.com",le="30"} 2`,
+		`deadlinkr_request_duration_seconds_bucket{domain="example.com",le="+Inf"} 3`,
+		`deadlinkr_request_duration_seconds_sum{domain="example.com"} 60.73`,
+		`deadlinkr_request_duration_seconds_count{domain="example.com"} 3`,
+		"# TYPE deadlinkr_responses_total counter",
+		`deadlinkr_responses_total{domain="example.com",code="200"} 2`,
+		`deadlinkr_responses_total{domain="example.com",code="404"} 1`,
+		`deadlinkr_responses_total{domain="we\"ird.example",code="error"} 1`,
+		"deadlinkr_scans_running 0",
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+}
+
+func TestMeasuredHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	metrics := NewMetrics()
+	client := NewMeasuredHTTPClient(&http.Client{Timeout: 5 * time.Second}, metrics)
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	failing := NewMeasuredHTTPClient(failingClient{}, metrics)
+	req, err = http.NewRequest(http.MethodHead, "https://down.example/page", nil)
+	require.NoError(t, err)
+	_, err = failing.Do(req)
+	require.Error(t, err)
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	body := scrapeMetrics(t, metrics)
+	assert.Contains(t, body, `deadlinkr_responses_total{domain="`+host+`",code="503"} 1`)
+	assert.Contains(t, body, `deadlinkr_responses_total{domain="down.example",code="error"} 1`)
+	assert.Contains(t, body, `deadlinkr_request_duration_seconds_count{domain="`+host+`"} 1`)
+}
+
+func TestMetricsTrackCrawler(t *testing.T) {
+	logger.SetQuiet(true)
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><a href="/about">About</a><a href="/missing">Missing</a></body></html>`))
+		case "/about":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body>About</body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	metrics := NewMetrics()
+	factory := NewServiceFactory()
+	factory.SetMetrics(metrics)
+	config := factory.CreateCrawlConfigFromParams(1, 2, false, "", "", "")
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	crawler, err := factory.CreateCachedOptimizedCrawlerService(config, "TestAgent", 5*time.Second, httpClient, 100, 100, 100, time.Hour)
+	require.NoError(t, err)
+	defer crawler.Stop()
+
+	untrack := metrics.Track("site", crawler)
+	require.NoError(t, crawler.StartCrawl(context.Background(), site.URL, site.URL, 0))
+	crawler.Wait()
+
+	host := strings.TrimPrefix(site.URL, "http://")
+	body := scrapeMetrics(t, metrics)
+	for _, line := range []string{
+		"deadlinkr_scans_running 1",
+		`deadlinkr_scan_pages_crawled_total{scan="site"} 3`,
+		`deadlinkr_responses_total{domain="` + host + `",code="404"}`,
+		`deadlinkr_cache_misses_total{scan="site"}`,
+		`deadlinkr_head_requests_total{scan="site"}`,
+		`deadlinkr_rate_limit_requests_per_second{scan="site",domain="` + host + `"} 100`,
+	} {
+		assert.Contains(t, body, line)
+	}
+
+	untrack()
+	body = scrapeMetrics(t, metrics)
+	assert.Contains(t, body, "deadlinkr_scans_running 0\n")
+	assert.NotContains(t, body, `scan="site"`, "finished scans are no longer exposed")
+	assert.Contains(t, body, `deadlinkr_responses_total{domain="`+host+`",code="200"}`, "request counters outlive the scans")
+}
@@ -1,8 +1,14 @@
 package internal
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/DrakkarStorm/deadlinkr/logger"
 	"github.com/DrakkarStorm/deadlinkr/model"
@@ -15,26 +21,46 @@ type PageParserService struct {
 	urlProcessor    URLProcessor
 	excludeHtmlTags string
 	onlyInternal    bool
+	extractors      *ExtractorRegistry
+	checkAnchors    bool
+	anchors         *AnchorIndex
+	redirects       *RedirectRecorder // Records the redirects followed by link checks, when set
+	redirectPolicy  *RedirectPolicy
+	sources         sync.Map // *goquery.Document -> HTML source, kept until its links are extracted
+	loadDocument    func(ctx context.Context, pageURL string) (*goquery.Document, []byte, error) // Loads the pages whose anchors are checked
 }
 
 // NewPageParserService creates a new PageParserService
 func NewPageParserService(linkChecker LinkChecker, urlProcessor URLProcessor, excludeHtmlTags string, onlyInternal bool) *PageParserService {
-	return &PageParserService{
+	pp := &PageParserService{
 		LinkChecker:     linkChecker,
 		urlProcessor:    urlProcessor,
 		excludeHtmlTags: excludeHtmlTags,
 		onlyInternal:    onlyInternal,
+		extractors:      DefaultExtractorRegistry(),
+		anchors:         NewAnchorIndex(),
 	}
+	pp.loadDocument = pp.fetchDocument
+	return pp
 }
 
-// ParsePage fetches and parses a web page
-func (pp *PageParserService) ParsePage(pageURL string) (*goquery.Document, error) {
+// ParsePage fetches and parses a web page, keeping its source to locate the extracted links
+func (pp *PageParserService) ParsePage(ctx context.Context, pageURL string) (*goquery.Document, error) {
+	doc, content, err := pp.fetchDocument(ctx, pageURL)
+	if doc != nil {
+		pp.RecordSource(doc, content)
+	}
+	return doc, err
+}
+
+// fetchDocument fetches and parses a web page, returning its HTML source along with the document
+func (pp *PageParserService) fetchDocument(ctx context.Context, pageURL string) (*goquery.Document, []byte, error) {
 	retry := 3
-	resp, err := pp.LinkChecker.FetchWithRetry(pageURL, retry)
+	resp, err := pp.LinkChecker.FetchWithRetry(ctx, pageURL, retry)
 
 	if err != nil {
 		logger.Errorf("Failed to fetch %s after %d retries: %s", pageURL, retry, err)
-		return nil, err
+		return nil, nil, err
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -43,60 +69,197 @@ func (pp *PageParserService) ParsePage(pageURL string) (*goquery.Document, error
 	}()
 
 	if !strings.Contains(resp.Header.Get("Content-Type"), "text/html") {
-		return nil, nil
+		return nil, nil, nil
 	}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logger.Errorf("Error reading HTML from %s: %s", pageURL, err)
+		return nil, nil, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(content))
 	if err != nil {
 		logger.Errorf("Error parsing HTML from %s: %s", pageURL, err)
-		return nil, err
+		return nil, nil, err
 	}
 
-	return doc, nil
+	return doc, content, nil
+}
+
+// RecordSource keeps the HTML source of a parsed document until its links are extracted
+func (pp *PageParserService) RecordSource(doc *goquery.Document, content []byte) {
+	pp.sources.Store(doc, content)
+}
+
+// sourceIndexOf returns the source index of a document, releasing its recorded source.
+// Documents without a recorded source get an empty index and their links no position.
+func (pp *PageParserService) sourceIndexOf(doc *goquery.Document) *SourceIndex {
+	content, found := pp.sources.LoadAndDelete(doc)
+	if !found {
+		return NewSourceIndex(nil)
+	}
+	return NewSourceIndex(content.([]byte))
 }
 
-// ExtractLinks extracts links from a parsed document
-func (pp *PageParserService) ExtractLinks(baseUrlParsed *url.URL, pageURL string, doc *goquery.Document) []model.LinkResult {
+// ExtractLinks extracts links from a parsed document using the configured extractor rules.
+// Links whose check is interrupted by cancelling ctx are left out.
+func (pp *PageParserService) ExtractLinks(ctx context.Context, baseUrlParsed *url.URL, pageURL string, doc *goquery.Document) []model.LinkResult {
 	pageLinks := []model.LinkResult{}
 
-	doc.Find("body a[href]").Not(pp.excludeHtmlTags).Each(func(i int, s *goquery.Selection) {
-		href, exists := s.Attr("href")
-		if !exists || href == "" || strings.HasPrefix(href, "#") {
-			logger.Debugf("Skipping link due to missing href or #: %s", href)
-			return
-		}
+	// Keep the page's anchors so links to it from other pages reuse this document
+	if pp.checkAnchors {
+		pp.anchors.Record(pageURL, doc)
+	}
 
-		linkURL := pp.resolveAndFilterURL(baseUrlParsed, pageURL, href)
-		if linkURL == nil {
-			logger.Debugf("Skipping link due to invalid URL resolution: %s", href)
-			return
-		}
+	sources := pp.sourceIndexOf(doc)
 
-		isExternal := baseUrlParsed.Hostname() != linkURL.Hostname()
+	for _, rule := range pp.extractors.Rules() {
+		doc.Find(rule.Selector).EachWithBreak(func(i int, s *goquery.Selection) bool {
+			if ctx.Err() != nil {
+				return false
+			}
+			value, exists := s.Attr(rule.Attr)
+			if !exists {
+				return true
+			}
 
-		if pp.onlyInternal && isExternal {
-			return
-		}
+			// Excluded elements still take their place in the source, so they are skipped once located
+			tag, located := sources.Next(goquery.NodeName(s), rule.Attr, value)
+			if pp.excludeHtmlTags != "" && s.Is(pp.excludeHtmlTags) {
+				return true
+			}
 
-		if pp.urlProcessor.ShouldSkipURL(baseUrlParsed, linkURL) {
-			logger.Debugf("Skipping link due to pattern match: %s", href)
-			return
-		}
+			hrefs := []string{strings.TrimSpace(value)}
+			if rule.Srcset {
+				hrefs = parseSrcset(value)
+			}
 
-		status, errMsg := pp.LinkChecker.CheckLink(linkURL.String())
+			for _, href := range hrefs {
+				if linkResult := pp.checkExtractedLink(ctx, baseUrlParsed, pageURL, href, rule.Element); linkResult != nil {
+					if located {
+						tag.Locate(linkResult, href)
+					}
+					pageLinks = append(pageLinks, *linkResult)
+				}
+			}
+			return true
+		})
+	}
 
-		linkResult := model.LinkResult{
-			SourceURL:  pageURL,
-			TargetURL:  linkURL.String(),
-			Status:     status,
-			Error:      errMsg,
-			IsExternal: isExternal,
-		}
+	return pageLinks
+}
+
+// checkExtractedLink checks a URL extracted from an element, returning nil if it should be skipped
+func (pp *PageParserService) checkExtractedLink(ctx context.Context, baseUrlParsed *url.URL, pageURL, href, element string) *model.LinkResult {
+	if strings.HasPrefix(href, "#") && pp.checkAnchors {
+		return pp.checkSamePageAnchor(ctx, pageURL, href, element)
+	}
+
+	if href == "" || strings.HasPrefix(href, "#") {
+		logger.Debugf("Skipping link due to missing href or #: %s", href)
+		return nil
+	}
+
+	linkURL := pp.resolveAndFilterURL(baseUrlParsed, pageURL, href)
+	if linkURL == nil {
+		logger.Debugf("Skipping link due to invalid URL resolution: %s", href)
+		return nil
+	}
+
+	isExternal := baseUrlParsed.Hostname() != linkURL.Hostname()
+
+	if pp.onlyInternal && isExternal {
+		return nil
+	}
+
+	if pp.urlProcessor.ShouldSkipURL(baseUrlParsed, linkURL) {
+		logger.Debugf("Skipping link due to pattern match: %s", href)
+		return nil
+	}
 
-		pageLinks = append(pageLinks, linkResult)
+	// The fragment is never sent to the server
+	checkedURL := stripFragment(linkURL.String())
+	status, errMsg := pp.LinkChecker.CheckLink(ctx, checkedURL)
+	if ctx.Err() != nil {
+		return nil // Interrupted, the link is neither working nor broken
+	}
+
+	linkResult := &model.LinkResult{
+		SourceURL:  pageURL,
+		TargetURL:  linkURL.String(),
+		Status:     status,
+		Error:      errMsg,
+		IsExternal: isExternal,
+		Element:    element,
+	}
+
+	if IsSoft404Error(errMsg) {
+		linkResult.FailureType = model.FailureSoft404
+	}
+
+	if pp.redirects != nil {
+		linkResult.Redirects = pp.redirects.Chain(checkedURL)
+		linkResult.RedirectIssues = pp.redirectPolicy.Analyze(linkResult.Redirects)
+	}
+
+	if pp.checkAnchors && !isExternal && errMsg == "" && status >= 200 && status < 300 {
+		pp.validateAnchor(ctx, linkResult, linkURL)
+	}
+
+	return linkResult
+}
+
+// checkSamePageAnchor checks a "#fragment" link against the anchors of the current page
+func (pp *PageParserService) checkSamePageAnchor(ctx context.Context, pageURL, href, element string) *model.LinkResult {
+	linkURL, err := url.Parse(stripFragment(pageURL) + href)
+	if err != nil || !isCheckableFragment(linkURL.Fragment) {
+		return nil
+	}
+
+	linkResult := &model.LinkResult{
+		SourceURL: pageURL,
+		TargetURL: linkURL.String(),
+		Status:    http.StatusOK, // The page itself was fetched successfully
+		Element:   element,
+	}
+	pp.validateAnchor(ctx, linkResult, linkURL)
+
+	return linkResult
+}
+
+// validateAnchor marks a link as broken when its target page has no element matching the fragment.
+// Pages already parsed during the crawl are reused, other pages are fetched once.
+func (pp *PageParserService) validateAnchor(ctx context.Context, linkResult *model.LinkResult, linkURL *url.URL) {
+	fragment := linkURL.Fragment
+	if !isCheckableFragment(fragment) {
+		return
+	}
+
+	pageURL := stripFragment(linkURL.String())
+	anchors := pp.anchors.Lookup(pageURL, func() *goquery.Document {
+		doc, _, err := pp.loadDocument(ctx, pageURL)
+		if err != nil {
+			return nil
+		}
+		return doc
 	})
 
-	return pageLinks
+	// Anchors cannot be checked on non-HTML targets
+	if anchors == nil {
+		return
+	}
+
+	if !hasAnchor(anchors, fragment) {
+		logger.Debugf("Missing anchor #%s on %s", fragment, pageURL)
+		linkResult.FailureType = model.FailureMissingAnchor
+		linkResult.Error = fmt.Sprintf("missing anchor #%s", fragment)
+	}
+}
+
+// IsCrawlable checks whether links found on a given element type lead to pages worth crawling
+func (pp *PageParserService) IsCrawlable(link model.LinkResult) bool {
+	return link.Element == "" || pp.extractors.IsCrawlable(link.Element)
 }
 
 // resolveAndFilterURL resolves and filters a URL
@@ -112,4 +275,21 @@ func (pp *PageParserService) resolveAndFilterURL(baseUrlParsed *url.URL, pageURL
 func (pp *PageParserService) SetConfig(excludeHtmlTags string, onlyInternal bool) {
 	pp.excludeHtmlTags = excludeHtmlTags
 	pp.onlyInternal = onlyInternal
-}
\ No newline at end of file
+}
+
+// SetCheckAnchors enables validating #fragment links against the id and name attributes of their target page
+func (pp *PageParserService) SetCheckAnchors(checkAnchors bool) {
+	pp.checkAnchors = checkAnchors
+}
+
+// SetRedirectRecorder attaches the redirects recorded for each checked link to its result,
+// flagging the issues selected by the policy
+func (pp *PageParserService) SetRedirectRecorder(recorder *RedirectRecorder, policy *RedirectPolicy) {
+	pp.redirects = recorder
+	pp.redirectPolicy = policy
+}
+
+// SetExtractors replaces the rules used to extract links from pages
+func (pp *PageParserService) SetExtractors(extractors *ExtractorRegistry) {
+	pp.extractors = extractors
+}
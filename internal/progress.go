@@ -150,6 +150,13 @@ func (pt *ProgressTracker) ShouldUpdate() bool {
 	return pt.enabled && time.Since(pt.LastUpdate) >= pt.updateInterval
 }
 
+// SetEnabled enables or disables rendering progress
+func (pt *ProgressTracker) SetEnabled(enabled bool) {
+	pt.mutex.Lock()
+	defer pt.mutex.Unlock()
+	pt.enabled = enabled
+}
+
 // IsEnabled returns whether progress tracking is enabled
 func (pt *ProgressTracker) IsEnabled() bool {
 	pt.mutex.RLock()
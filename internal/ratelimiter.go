@@ -1,8 +1,11 @@
 package internal
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,15 +14,24 @@ import (
 
 // RateLimiter manages rate limiting per domain
 type RateLimiter interface {
-	Wait(domain string) error
+	Wait(ctx context.Context, domain string) error
 	UpdateConfig(domain string, requestsPerSecond float64)
 }
 
+// Adaptive throttling of domains answering 429 Too Many Requests or 503 Service Unavailable
+const (
+	throttleFactor   = 0.5  // Rate multiplier applied each time a domain asks to slow down
+	minThrottledRate = 0.05 // Lowest rate a domain is throttled to, one request every 20 seconds
+	recoveryStep     = 0.1  // Share of the configured rate regained after each request going through
+)
+
 // TokenBucket implements a token bucket rate limiter for a single domain
 type TokenBucket struct {
 	tokens         float64
 	maxTokens      float64
 	refillRate     float64  // tokens per second
+	configuredRate float64  // rate the bucket recovers to after backing off
+	pausedUntil    time.Time // no token is handed out before, as asked by a Retry-After
 	lastRefill     time.Time
 	mutex          sync.Mutex
 }
@@ -29,7 +41,8 @@ type DomainRateLimiter struct {
 	buckets           map[string]*TokenBucket
 	defaultRate       float64 // requests per second
 	maxBurst          float64 // max tokens in bucket
-	domainConfigs     map[string]float64 // custom rates per domain
+	domainConfigs     map[string]float64 // custom rates per domain or "*.domain" pattern
+	domainBursts      map[string]float64 // custom burst capacity per domain or "*.domain" pattern
 	mutex             sync.RWMutex
 }
 
@@ -40,33 +53,137 @@ func NewDomainRateLimiter(defaultRequestsPerSecond float64, maxBurst float64) *D
 		defaultRate:   defaultRequestsPerSecond,
 		maxBurst:      maxBurst,
 		domainConfigs: make(map[string]float64),
+		domainBursts:  make(map[string]float64),
 	}
 }
 
-// Wait blocks until a request can be made to the given domain
-func (drl *DomainRateLimiter) Wait(targetURL string) error {
+// Wait blocks until a request can be made to the given domain, or until ctx is done
+func (drl *DomainRateLimiter) Wait(ctx context.Context, targetURL string) error {
 	domain, err := extractDomain(targetURL)
 	if err != nil {
 		return err
 	}
 
 	bucket := drl.getBucket(domain)
-	return bucket.wait()
+	return bucket.wait(ctx)
 }
 
-// UpdateConfig sets a custom rate limit for a specific domain
+// UpdateConfig sets a custom rate limit for a specific domain, or for the subdomains of a "*.domain" pattern
 func (drl *DomainRateLimiter) UpdateConfig(domain string, requestsPerSecond float64) {
 	drl.mutex.Lock()
 	defer drl.mutex.Unlock()
 	
-	drl.domainConfigs[domain] = requestsPerSecond
+	drl.domainConfigs[strings.ToLower(domain)] = requestsPerSecond
+	
+	// Update existing buckets the new rate applies to
+	for bucketDomain, bucket := range drl.buckets {
+		rate, found := lookupDomain(drl.domainConfigs, bucketDomain)
+		if !found {
+			continue
+		}
+		bucket.mutex.Lock()
+		if bucket.configuredRate != rate {
+			bucket.refillRate = rate
+			bucket.configuredRate = rate
+			logger.Debugf("Updated rate limit for %s to %.2f req/s", bucketDomain, rate)
+		}
+		bucket.mutex.Unlock()
+	}
+}
+
+// UpdateBurst sets a custom burst capacity for a specific domain, or for the subdomains of a "*.domain" pattern
+func (drl *DomainRateLimiter) UpdateBurst(domain string, burst float64) {
+	drl.mutex.Lock()
+	defer drl.mutex.Unlock()
+	
+	drl.domainBursts[strings.ToLower(domain)] = burst
+	
+	// Update existing buckets the new burst applies to
+	for bucketDomain, bucket := range drl.buckets {
+		maxTokens, found := lookupDomain(drl.domainBursts, bucketDomain)
+		if !found {
+			continue
+		}
+		bucket.mutex.Lock()
+		if bucket.maxTokens != maxTokens {
+			bucket.maxTokens = maxTokens
+			if bucket.tokens > maxTokens {
+				bucket.tokens = maxTokens
+			}
+			logger.Debugf("Updated burst for %s to %.0f", bucketDomain, maxTokens)
+		}
+		bucket.mutex.Unlock()
+	}
+}
+
+// ApplyCrawlDelay limits a domain to one request per delay, as requested by a robots.txt Crawl-delay.
+// It never raises the domain's current rate.
+func (drl *DomainRateLimiter) ApplyCrawlDelay(domain string, delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+	rate := 1.0 / delay.Seconds()
+	
+	drl.mutex.Lock()
+	defer drl.mutex.Unlock()
+	
+	currentRate := drl.defaultRate
+	if customRate, hasCustom := lookupDomain(drl.domainConfigs, domain); hasCustom {
+		currentRate = customRate
+	}
+	if rate >= currentRate {
+		return
+	}
+	
+	// Spacing requests evenly means no burst
+	drl.domainConfigs[domain] = rate
+	drl.domainBursts[domain] = 1.0
 	
-	// Update existing bucket if it exists
 	if bucket, exists := drl.buckets[domain]; exists {
 		bucket.mutex.Lock()
-		bucket.refillRate = requestsPerSecond
+		bucket.refillRate = rate
+		bucket.configuredRate = rate
+		bucket.maxTokens = 1.0
+		if bucket.tokens > 1.0 {
+			bucket.tokens = 1.0
+		}
 		bucket.mutex.Unlock()
-		logger.Debugf("Updated rate limit for %s to %.2f req/s", domain, requestsPerSecond)
+	}
+	logger.Debugf("Applied crawl delay of %v for %s (%.2f req/s)", delay, domain, rate)
+}
+
+// Throttle backs a domain off after it answered 429 or 503: its rate is halved, down to minThrottledRate,
+// and no request is sent to it before retryAfter elapsed
+func (drl *DomainRateLimiter) Throttle(domain string, retryAfter time.Duration) {
+	bucket := drl.getBucket(domain)
+	
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+	
+	floor := math.Min(minThrottledRate, bucket.configuredRate)
+	bucket.refillRate = math.Max(bucket.refillRate*throttleFactor, floor)
+	bucket.tokens = 0
+	if pausedUntil := time.Now().Add(retryAfter); pausedUntil.After(bucket.pausedUntil) {
+		bucket.pausedUntil = pausedUntil
+		bucket.lastRefill = pausedUntil // No tokens build up while paused
+	}
+	logger.Debugf("Throttled %s to %.2f req/s, paused for %v", domain, bucket.refillRate, retryAfter)
+}
+
+// Recover raises the rate of a throttled domain a step back toward its configured rate, after a request went through
+func (drl *DomainRateLimiter) Recover(domain string) {
+	drl.mutex.RLock()
+	bucket, exists := drl.buckets[domain]
+	drl.mutex.RUnlock()
+	if !exists {
+		return
+	}
+	
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+	
+	if bucket.refillRate < bucket.configuredRate {
+		bucket.refillRate = math.Min(bucket.refillRate+bucket.configuredRate*recoveryStep, bucket.configuredRate)
 	}
 }
 
@@ -78,28 +195,44 @@ func (drl *DomainRateLimiter) getBucket(domain string) *TokenBucket {
 	bucket, exists := drl.buckets[domain]
 	if !exists {
 		rate := drl.defaultRate
-		if customRate, hasCustom := drl.domainConfigs[domain]; hasCustom {
+		if customRate, hasCustom := lookupDomain(drl.domainConfigs, domain); hasCustom {
 			rate = customRate
 		}
+		burst := drl.maxBurst
+		if customBurst, hasCustom := lookupDomain(drl.domainBursts, domain); hasCustom {
+			burst = customBurst
+		}
 		
 		bucket = &TokenBucket{
-			tokens:     drl.maxBurst, // Start with full bucket
-			maxTokens:  drl.maxBurst,
-			refillRate: rate,
-			lastRefill: time.Now(),
+			tokens:         burst, // Start with full bucket
+			maxTokens:      burst,
+			refillRate:     rate,
+			configuredRate: rate,
+			lastRefill:     time.Now(),
 		}
 		drl.buckets[domain] = bucket
-		logger.Debugf("Created rate limiter for %s: %.2f req/s, %.0f burst", domain, rate, drl.maxBurst)
+		logger.Debugf("Created rate limiter for %s: %.2f req/s, %.0f burst", domain, rate, burst)
 	}
 	
 	return bucket
 }
 
-// wait blocks until a token is available
-func (tb *TokenBucket) wait() error {
+// wait blocks until a token is available, returning the context error if ctx is done first
+func (tb *TokenBucket) wait(ctx context.Context) error {
 	tb.mutex.Lock()
 	defer tb.mutex.Unlock()
 	
+	// Honor a pause asked by the domain before counting tokens
+	if pause := time.Until(tb.pausedUntil); pause > 0 {
+		tb.mutex.Unlock() // Release lock while paused
+		logger.Debugf("Rate limiting: domain paused, waiting %v", pause)
+		err := sleepContext(ctx, pause)
+		tb.mutex.Lock()
+		if err != nil {
+			return err
+		}
+	}
+	
 	// Refill tokens based on elapsed time
 	now := time.Now()
 	elapsed := now.Sub(tb.lastRefill).Seconds()
@@ -123,9 +256,12 @@ func (tb *TokenBucket) wait() error {
 	tb.mutex.Unlock() // Release lock while waiting
 	
 	logger.Debugf("Rate limiting: waiting %v for token", waitTime)
-	time.Sleep(waitTime)
+	err := sleepContext(ctx, waitTime)
 	
 	tb.mutex.Lock() // Re-acquire for final token consumption
+	if err != nil {
+		return err // The token was not waited for
+	}
 	tb.tokens = 0 // Consume the token we waited for
 	
 	return nil
@@ -1,18 +1,18 @@
 package internal
 
 import (
+	"context"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/DrakkarStorm/deadlinkr/logger"
-	"github.com/DrakkarStorm/deadlinkr/model"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestDomainRateLimiter(t *testing.T) {
 	// Initialize logger for tests
-	model.Quiet = true
+	logger.SetQuiet(true)
 	logger.InitLogger("debug")
 	defer logger.CloseLogger()
 	
@@ -27,7 +27,7 @@ func TestDomainRateLimiter(t *testing.T) {
 		// First few requests should be immediate (burst)
 		start := time.Now()
 		for i := 0; i < 3; i++ {
-			err := limiter.Wait("https://example.com/test")
+			err := limiter.Wait(context.Background(), "https://example.com/test")
 			assert.NoError(t, err)
 		}
 		elapsed := time.Since(start)
@@ -41,11 +41,11 @@ func TestDomainRateLimiter(t *testing.T) {
 		
 		// First request should be immediate
 		start := time.Now()
-		err := limiter.Wait("https://example.com/test")
+		err := limiter.Wait(context.Background(), "https://example.com/test")
 		assert.NoError(t, err)
 		
 		// Second request should be rate limited
-		err = limiter.Wait("https://example.com/test")
+		err = limiter.Wait(context.Background(), "https://example.com/test")
 		assert.NoError(t, err)
 		elapsed := time.Since(start)
 		
@@ -59,10 +59,10 @@ func TestDomainRateLimiter(t *testing.T) {
 		// Both domains should allow immediate first request
 		start := time.Now()
 		
-		err1 := limiter.Wait("https://domain1.com/test")
+		err1 := limiter.Wait(context.Background(), "https://domain1.com/test")
 		assert.NoError(t, err1)
 		
-		err2 := limiter.Wait("https://domain2.com/test")
+		err2 := limiter.Wait(context.Background(), "https://domain2.com/test")
 		assert.NoError(t, err2)
 		
 		elapsed := time.Since(start)
@@ -80,7 +80,7 @@ func TestDomainRateLimiter(t *testing.T) {
 		// Multiple requests should be fast
 		start := time.Now()
 		for i := 0; i < 3; i++ {
-			err := limiter.Wait("https://example.com/test")
+			err := limiter.Wait(context.Background(), "https://example.com/test")
 			assert.NoError(t, err)
 		}
 		elapsed := time.Since(start)
@@ -92,7 +92,7 @@ func TestDomainRateLimiter(t *testing.T) {
 		limiter := NewDomainRateLimiter(2.0, 3.0)
 		
 		// Make a request to create a bucket
-		err := limiter.Wait("https://example.com/test")
+		err := limiter.Wait(context.Background(), "https://example.com/test")
 		assert.NoError(t, err)
 		
 		stats := limiter.GetStats()
@@ -115,7 +115,7 @@ func TestDomainRateLimiter(t *testing.T) {
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				err := limiter.Wait("https://example.com/test")
+				err := limiter.Wait(context.Background(), "https://example.com/test")
 				errors <- err
 			}()
 		}
@@ -140,11 +140,37 @@ func TestTokenBucket(t *testing.T) {
 		}
 		
 		// Should not block since bucket should have refilled ~2 tokens
-		err := bucket.wait()
+		err := bucket.wait(context.Background())
 		assert.NoError(t, err)
 	})
 }
 
+func TestDomainRateLimiterCancel(t *testing.T) {
+	logger.SetQuiet(true)
+
+	t.Run("Waiting for a token", func(t *testing.T) {
+		limiter := NewDomainRateLimiter(0.1, 1) // One request every 10 seconds
+		assert.NoError(t, limiter.Wait(context.Background(), "https://example.com/"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		assert.ErrorIs(t, limiter.Wait(ctx, "https://example.com/"), context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("Paused by a Retry-After", func(t *testing.T) {
+		limiter := NewDomainRateLimiter(10, 5)
+		limiter.Throttle("example.com", time.Minute)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		start := time.Now()
+		assert.ErrorIs(t, limiter.Wait(ctx, "https://example.com/"), context.Canceled)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
 func TestExtractDomain(t *testing.T) {
 	tests := []struct {
 		url      string
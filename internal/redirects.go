@@ -0,0 +1,221 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+)
+
+// MaxRedirects is the number of redirects followed before giving up on a link
+const MaxRedirects = 10
+
+// ErrRedirectLoop is returned when a redirect points back to a URL already visited
+var ErrRedirectLoop = errors.New("redirect loop")
+
+// ErrTooManyRedirects is returned when a link goes through more than MaxRedirects redirects
+var ErrTooManyRedirects = fmt.Errorf("stopped after %d redirects", MaxRedirects)
+
+// Redirect reporting modes selecting the issues flagged by a RedirectPolicy
+const (
+	RedirectModePermanent = "permanent"
+	RedirectModeChain     = "chain"
+	RedirectModeDowngrade = "downgrade"
+	RedirectModeLogin     = "login"
+	RedirectModeAll       = "all"
+)
+
+// loginPathMarkers are path segments identifying login pages
+var loginPathMarkers = []string{"login", "signin", "sign-in", "sso", "auth"}
+
+// CheckRedirect is an http.Client redirect policy stopping redirect loops and overly long chains
+func CheckRedirect(req *http.Request, via []*http.Request) error {
+	for _, previous := range via {
+		if previous.URL.String() == req.URL.String() {
+			return fmt.Errorf("%w at %s", ErrRedirectLoop, req.URL)
+		}
+	}
+	if len(via) >= MaxRedirects {
+		return ErrTooManyRedirects
+	}
+	return nil
+}
+
+// isRedirectError checks whether a request failed because of its redirects, which retrying cannot fix
+func isRedirectError(err error) bool {
+	return errors.Is(err, ErrRedirectLoop) || errors.Is(err, ErrTooManyRedirects)
+}
+
+// isRedirectStatus checks whether a status code is a redirect carrying a Location
+func isRedirectStatus(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// redirectChain returns the redirects followed to obtain a response, oldest first.
+// The response itself is part of the chain when following stopped on a redirect.
+func redirectChain(resp *http.Response) []model.RedirectHop {
+	chain := []model.RedirectHop{}
+	for current := resp; current != nil && current.Request != nil; current = current.Request.Response {
+		if !isRedirectStatus(current.StatusCode) {
+			continue
+		}
+		hop := model.RedirectHop{
+			URL:    current.Request.URL.String(),
+			Status: current.StatusCode,
+		}
+		if location, err := current.Location(); err == nil {
+			hop.Location = location.String()
+		}
+		chain = append([]model.RedirectHop{hop}, chain...)
+	}
+	return chain
+}
+
+// RedirectRecorder implements the HTTPClient interface, recording the redirect chain followed for each requested URL
+type RedirectRecorder struct {
+	client HTTPClient
+	chains sync.Map // Requested URL -> []model.RedirectHop
+}
+
+// NewRedirectRecorder creates a RedirectRecorder wrapping an HTTP client
+func NewRedirectRecorder(client HTTPClient) *RedirectRecorder {
+	return &RedirectRecorder{client: client}
+}
+
+// Do executes a request and records its redirect chain, including when following failed
+func (rr *RedirectRecorder) Do(req *http.Request) (*http.Response, error) {
+	requestedURL := req.URL.String()
+	resp, err := rr.client.Do(req)
+	if resp == nil {
+		return resp, err
+	}
+
+	if chain := redirectChain(resp); len(chain) > 0 {
+		rr.chains.Store(requestedURL, chain)
+	} else {
+		rr.chains.Delete(requestedURL)
+	}
+	return resp, err
+}
+
+// Chain returns the redirect chain last followed for a URL
+func (rr *RedirectRecorder) Chain(requestedURL string) []model.RedirectHop {
+	chain, found := rr.chains.Load(requestedURL)
+	if !found {
+		return nil
+	}
+	return chain.([]model.RedirectHop)
+}
+
+// RedirectPolicy decides which redirect issues are flagged on links
+type RedirectPolicy struct {
+	flagPermanent bool
+	flagDowngrade bool
+	flagLogin     bool
+	maxHops       int // Longer chains are flagged; 0 disables the check
+}
+
+// NewRedirectPolicy creates a RedirectPolicy flagging the issues of the given modes.
+// Unknown modes are ignored with a warning. Redirect loops are always flagged.
+func NewRedirectPolicy(modes []string, maxHops int) *RedirectPolicy {
+	policy := &RedirectPolicy{}
+	for _, mode := range modes {
+		switch strings.ToLower(strings.TrimSpace(mode)) {
+		case RedirectModePermanent:
+			policy.flagPermanent = true
+		case RedirectModeChain:
+			policy.maxHops = maxHops
+		case RedirectModeDowngrade:
+			policy.flagDowngrade = true
+		case RedirectModeLogin:
+			policy.flagLogin = true
+		case RedirectModeAll:
+			policy.flagPermanent = true
+			policy.maxHops = maxHops
+			policy.flagDowngrade = true
+			policy.flagLogin = true
+		case "":
+		default:
+			logger.Warnf("Unknown redirect mode %q, expected one of permanent, chain, downgrade, login, all", mode)
+		}
+	}
+	return policy
+}
+
+// Analyze returns the issues found in a redirect chain
+func (rp *RedirectPolicy) Analyze(chain []model.RedirectHop) []string {
+	if len(chain) == 0 {
+		return nil
+	}
+
+	issues := []string{}
+	if isRedirectLoop(chain) {
+		issues = append(issues, model.RedirectIssueLoop)
+	}
+
+	first := chain[0]
+	if rp.flagPermanent && (first.Status == http.StatusMovedPermanently || first.Status == http.StatusPermanentRedirect) {
+		issues = append(issues, model.RedirectIssuePermanent)
+	}
+
+	if rp.maxHops > 0 && len(chain) > rp.maxHops {
+		issues = append(issues, model.RedirectIssueLongChain)
+	}
+
+	if rp.flagDowngrade {
+		for _, hop := range chain {
+			if strings.HasPrefix(hop.URL, "https://") && strings.HasPrefix(hop.Location, "http://") {
+				issues = append(issues, model.RedirectIssueDowngrade)
+				break
+			}
+		}
+	}
+
+	if rp.flagLogin && isLoginURL(chain[len(chain)-1].Location) {
+		issues = append(issues, model.RedirectIssueLogin)
+	}
+
+	if len(issues) == 0 {
+		return nil
+	}
+	return issues
+}
+
+// isRedirectLoop checks whether a redirect points back to a URL already visited in the chain
+func isRedirectLoop(chain []model.RedirectHop) bool {
+	visited := make(map[string]bool, len(chain))
+	for _, hop := range chain {
+		visited[hop.URL] = true
+		if visited[hop.Location] {
+			return true
+		}
+	}
+	return false
+}
+
+// isLoginURL checks whether a URL looks like a login page
+func isLoginURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	for _, segment := range strings.Split(strings.ToLower(parsed.Path), "/") {
+		for _, marker := range loginPathMarkers {
+			if segment == marker || strings.HasPrefix(segment, marker+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
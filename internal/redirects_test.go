@@ -0,0 +1,171 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRedirectServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body>
+<a href="/old">moved</a>
+<a href="/hop1">chain</a>
+<a href="/loop1">loop</a>
+<a href="/private">private</a>
+<a href="/page">direct</a>
+</body></html>`))
+		case "/old":
+			http.Redirect(w, r, "/page", http.StatusMovedPermanently)
+		case "/hop1":
+			http.Redirect(w, r, "/hop2", http.StatusFound)
+		case "/hop2":
+			http.Redirect(w, r, "/hop3", http.StatusFound)
+		case "/hop3":
+			http.Redirect(w, r, "/hop4", http.StatusTemporaryRedirect)
+		case "/hop4":
+			http.Redirect(w, r, "/page", http.StatusFound)
+		case "/loop1":
+			http.Redirect(w, r, "/loop2", http.StatusFound)
+		case "/loop2":
+			http.Redirect(w, r, "/loop1", http.StatusFound)
+		case "/private":
+			http.Redirect(w, r, "/account/login?next=/private", http.StatusFound)
+		case "/page", "/account/login":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<html><body>Page</body></html>"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestRedirectRecorder(t *testing.T) {
+	server := newRedirectServer()
+	defer server.Close()
+
+	recorder := NewRedirectRecorder(&http.Client{Timeout: 5 * time.Second, CheckRedirect: CheckRedirect})
+
+	t.Run("Records each hop", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/hop1", nil)
+		require.NoError(t, err)
+		resp, err := recorder.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		chain := recorder.Chain(server.URL + "/hop1")
+		require.Len(t, chain, 4)
+		assert.Equal(t, model.RedirectHop{URL: server.URL + "/hop1", Status: http.StatusFound, Location: server.URL + "/hop2"}, chain[0])
+		assert.Equal(t, http.StatusTemporaryRedirect, chain[2].Status)
+		assert.Equal(t, server.URL+"/page", chain[3].Location)
+	})
+
+	t.Run("Stops on loops", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/loop1", nil)
+		require.NoError(t, err)
+		_, err = recorder.Do(req)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrRedirectLoop))
+		assert.True(t, isRedirectError(err))
+
+		chain := recorder.Chain(server.URL + "/loop1")
+		require.Len(t, chain, 2)
+		assert.Equal(t, server.URL+"/loop1", chain[1].Location)
+	})
+
+	t.Run("Direct responses have no chain", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/page", nil)
+		require.NoError(t, err)
+		resp, err := recorder.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		assert.Nil(t, recorder.Chain(server.URL+"/page"))
+	})
+}
+
+func TestRedirectPolicy(t *testing.T) {
+	permanent := []model.RedirectHop{{URL: "http://a.com/old", Status: 301, Location: "http://a.com/new"}}
+	downgrade := []model.RedirectHop{{URL: "https://a.com/", Status: 302, Location: "http://b.com/"}}
+	login := []model.RedirectHop{{URL: "https://a.com/admin", Status: 302, Location: "https://a.com/users/sign-in?next=/admin"}}
+	long := []model.RedirectHop{
+		{URL: "http://a.com/1", Status: 302, Location: "http://a.com/2"},
+		{URL: "http://a.com/2", Status: 302, Location: "http://a.com/3"},
+		{URL: "http://a.com/3", Status: 302, Location: "http://a.com/4"},
+	}
+	loop := []model.RedirectHop{
+		{URL: "http://a.com/1", Status: 302, Location: "http://a.com/2"},
+		{URL: "http://a.com/2", Status: 302, Location: "http://a.com/1"},
+	}
+
+	t.Run("Nothing flagged by default except loops", func(t *testing.T) {
+		policy := NewRedirectPolicy(nil, 2)
+		assert.Nil(t, policy.Analyze(permanent))
+		assert.Nil(t, policy.Analyze(long))
+		assert.Equal(t, []string{model.RedirectIssueLoop}, policy.Analyze(loop))
+		assert.Nil(t, policy.Analyze(nil))
+	})
+
+	t.Run("Selected modes", func(t *testing.T) {
+		policy := NewRedirectPolicy([]string{"permanent", "chain", "unknown"}, 2)
+		assert.Equal(t, []string{model.RedirectIssuePermanent}, policy.Analyze(permanent))
+		assert.Equal(t, []string{model.RedirectIssueLongChain}, policy.Analyze(long))
+		assert.Nil(t, policy.Analyze(downgrade))
+	})
+
+	t.Run("All modes", func(t *testing.T) {
+		policy := NewRedirectPolicy([]string{"all"}, 3)
+		assert.Nil(t, policy.Analyze(long))
+		assert.Equal(t, []string{model.RedirectIssueDowngrade}, policy.Analyze(downgrade))
+		assert.Equal(t, []string{model.RedirectIssueLogin}, policy.Analyze(login))
+	})
+}
+
+func TestCrawlRecordsRedirects(t *testing.T) {
+	logger.SetQuiet(true)
+
+	server := newRedirectServer()
+	defer server.Close()
+
+	factory := NewServiceFactory()
+	config := factory.CreateCrawlConfigFromParams(0, 2, false, "", "", "")
+	config.FlagRedirects = []string{"permanent", "chain", "login"}
+	config.MaxRedirectHops = 3
+	httpClient := &http.Client{Timeout: 5 * time.Second, CheckRedirect: CheckRedirect}
+	crawler, err := factory.CreateCachedOptimizedCrawlerService(config, "TestAgent", 5*time.Second, httpClient, 100, 100, 100, time.Hour)
+	require.NoError(t, err)
+	defer crawler.Stop()
+
+	require.NoError(t, crawler.StartCrawl(context.Background(), server.URL, server.URL+"/", 0))
+	crawler.Wait()
+
+	results := make(map[string]model.LinkResult)
+	for _, result := range crawler.GetResults() {
+		results[strings.TrimPrefix(result.TargetURL, server.URL)] = result
+	}
+
+	assert.Equal(t, []string{model.RedirectIssuePermanent}, results["/old"].RedirectIssues)
+	assert.Len(t, results["/old"].Redirects, 1)
+	assert.Equal(t, http.StatusOK, results["/old"].Status)
+
+	assert.Equal(t, []string{model.RedirectIssueLongChain}, results["/hop1"].RedirectIssues)
+	assert.Equal(t, []string{model.RedirectIssueLogin}, results["/private"].RedirectIssues)
+
+	assert.Equal(t, []string{model.RedirectIssueLoop}, results["/loop1"].RedirectIssues)
+	assert.Contains(t, results["/loop1"].Error, "redirect loop")
+
+	assert.Empty(t, results["/page"].Redirects)
+	assert.Empty(t, results["/page"].RedirectIssues)
+}
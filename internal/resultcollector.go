@@ -3,12 +3,18 @@ package internal
 import (
 	"sync"
 
+	"github.com/DrakkarStorm/deadlinkr/logger"
 	"github.com/DrakkarStorm/deadlinkr/model"
 )
 
 // ResultCollectorService implements the ResultCollector interface
 type ResultCollectorService struct {
 	results     []model.LinkResult
+	retain      bool       // Keep results in memory for GetResults
+	sink        ResultSink // Optional writer results are streamed to as they are added
+	sinkFailed  bool       // Set once writing to the sink failed, later results are not written
+	count       int
+	brokenCount int
 	visitedURLs sync.Map
 	mutex       sync.Mutex
 }
@@ -17,18 +23,43 @@ type ResultCollectorService struct {
 func NewResultCollectorService() *ResultCollectorService {
 	return &ResultCollectorService{
 		results:     make([]model.LinkResult, 0),
+		retain:      true,
 		visitedURLs: sync.Map{},
 	}
 }
 
+// NewStreamingResultCollectorService creates a ResultCollectorService writing results to a sink as they are added.
+// Results are kept in memory for GetResults only if retain is set, counts are kept either way.
+func NewStreamingResultCollectorService(sink ResultSink, retain bool) *ResultCollectorService {
+	collector := NewResultCollectorService()
+	collector.sink = sink
+	collector.retain = retain
+	return collector
+}
+
 // AddResult adds a result to the collection
 func (rc *ResultCollectorService) AddResult(result model.LinkResult) {
 	rc.mutex.Lock()
 	defer rc.mutex.Unlock()
-	rc.results = append(rc.results, result)
+
+	rc.count++
+	if result.Status >= 400 || result.Error != "" {
+		rc.brokenCount++
+	}
+
+	if rc.sink != nil && !rc.sinkFailed {
+		if err := rc.sink.Write(result); err != nil {
+			logger.Errorf("Error writing results, further results are not written: %s", err)
+			rc.sinkFailed = true
+		}
+	}
+
+	if rc.retain {
+		rc.results = append(rc.results, result)
+	}
 }
 
-// GetResults returns all collected results
+// GetResults returns all collected results, none if results are streamed without being retained
 func (rc *ResultCollectorService) GetResults() []model.LinkResult {
 	rc.mutex.Lock()
 	defer rc.mutex.Unlock()
@@ -38,18 +69,18 @@ func (rc *ResultCollectorService) GetResults() []model.LinkResult {
 	return resultsCopy
 }
 
+// CountResults returns the number of results added
+func (rc *ResultCollectorService) CountResults() int {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	return rc.count
+}
+
 // CountBrokenLinks counts the number of broken links
 func (rc *ResultCollectorService) CountBrokenLinks() int {
 	rc.mutex.Lock()
 	defer rc.mutex.Unlock()
-	
-	count := 0
-	for _, result := range rc.results {
-		if result.Status >= 400 || result.Error != "" {
-			count++
-		}
-	}
-	return count
+	return rc.brokenCount
 }
 
 // IsVisited checks if a URL has been visited
@@ -58,9 +89,20 @@ func (rc *ResultCollectorService) IsVisited(url string) bool {
 	return exists
 }
 
-// MarkVisited marks a URL as visited
-func (rc *ResultCollectorService) MarkVisited(url string) {
-	rc.visitedURLs.Store(url, true)
+// MarkVisited marks a URL as visited, returning false if it already was
+func (rc *ResultCollectorService) MarkVisited(url string) bool {
+	_, visited := rc.visitedURLs.LoadOrStore(url, true)
+	return !visited
+}
+
+// VisitedURLs returns the URLs marked as visited
+func (rc *ResultCollectorService) VisitedURLs() []string {
+	urls := make([]string, 0)
+	rc.visitedURLs.Range(func(key, _ any) bool {
+		urls = append(urls, key.(string))
+		return true
+	})
+	return urls
 }
 
 // Clear clears all results and visited URLs
@@ -69,5 +111,7 @@ func (rc *ResultCollectorService) Clear() {
 	defer rc.mutex.Unlock()
 	
 	rc.results = make([]model.LinkResult, 0)
+	rc.count = 0
+	rc.brokenCount = 0
 	rc.visitedURLs = sync.Map{}
 }
\ No newline at end of file
@@ -0,0 +1,340 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+)
+
+// maxRobotsSize is the maximum robots.txt size read, as recommended by RFC 9309
+const maxRobotsSize = 500 * 1024
+
+// RobotsData holds the directives parsed from a robots.txt file
+type RobotsData struct {
+	Groups   []RobotsGroup
+	Sitemaps []string
+}
+
+// RobotsGroup holds the rules that apply to a set of user agents
+type RobotsGroup struct {
+	UserAgents []string
+	Rules      []RobotsRule
+	CrawlDelay time.Duration
+}
+
+// RobotsRule is a single Allow or Disallow path pattern
+type RobotsRule struct {
+	Allow   bool
+	Pattern string
+}
+
+// ParseRobotsTxt parses a robots.txt document
+func ParseRobotsTxt(r io.Reader) (*RobotsData, error) {
+	data := &RobotsData{}
+
+	var current *RobotsGroup
+	collectingAgents := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := parseRobotsLine(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "user-agent":
+			// Consecutive user-agent lines share the same group
+			if !collectingAgents {
+				data.Groups = append(data.Groups, RobotsGroup{})
+				current = &data.Groups[len(data.Groups)-1]
+				collectingAgents = true
+			}
+			current.UserAgents = append(current.UserAgents, strings.ToLower(value))
+		case "allow", "disallow":
+			collectingAgents = false
+			// An empty Disallow means everything is allowed and adds no rule
+			if current == nil || value == "" {
+				continue
+			}
+			current.Rules = append(current.Rules, RobotsRule{Allow: key == "allow", Pattern: value})
+		case "crawl-delay":
+			collectingAgents = false
+			if current == nil {
+				continue
+			}
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds < 0 {
+				logger.Debugf("Ignoring invalid crawl-delay %q", value)
+				continue
+			}
+			current.CrawlDelay = time.Duration(seconds * float64(time.Second))
+		case "sitemap":
+			if value != "" {
+				data.Sitemaps = append(data.Sitemaps, value)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// parseRobotsLine splits a robots.txt line into a lowercased directive and its value, ignoring comments
+func parseRobotsLine(line string) (string, string, bool) {
+	if idx := strings.Index(line, "#"); idx >= 0 {
+		line = line[:idx]
+	}
+
+	key, value, found := strings.Cut(line, ":")
+	if !found {
+		return "", "", false
+	}
+
+	return strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value), true
+}
+
+// GroupFor returns the rules applying to the given user agent.
+// Groups naming the agent's product token take precedence over the "*" groups; matching groups are merged.
+func (rd *RobotsData) GroupFor(userAgent string) RobotsGroup {
+	token := robotsProductToken(userAgent)
+
+	var specific, wildcard RobotsGroup
+	foundSpecific := false
+
+	for _, group := range rd.Groups {
+		switch {
+		case robotsGroupNames(group, token):
+			mergeRobotsGroup(&specific, group)
+			foundSpecific = true
+		case robotsGroupNames(group, "*"):
+			mergeRobotsGroup(&wildcard, group)
+		}
+	}
+
+	if foundSpecific {
+		return specific
+	}
+	return wildcard
+}
+
+// IsAllowed checks whether the user agent may fetch the given path (including query string)
+func (rd *RobotsData) IsAllowed(userAgent, path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+
+	group := rd.GroupFor(userAgent)
+
+	// The most specific (longest) matching rule wins, Allow wins ties
+	allowed := true
+	matchLength := -1
+	for _, rule := range group.Rules {
+		if !robotsPatternMatches(rule.Pattern, path) {
+			continue
+		}
+		length := len(rule.Pattern)
+		if length > matchLength || (length == matchLength && rule.Allow) {
+			allowed = rule.Allow
+			matchLength = length
+		}
+	}
+
+	return allowed
+}
+
+// robotsGroupNames checks whether a group applies to the given product token ("*" matches the wildcard group only)
+func robotsGroupNames(group RobotsGroup, token string) bool {
+	if token == "" {
+		return false
+	}
+	for _, agent := range group.UserAgents {
+		if token == "*" {
+			if agent == "*" {
+				return true
+			}
+			continue
+		}
+		if agent != "*" && agent != "" && strings.Contains(token, agent) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeRobotsGroup appends the rules of src to dst, keeping the largest crawl delay
+func mergeRobotsGroup(dst *RobotsGroup, src RobotsGroup) {
+	dst.UserAgents = append(dst.UserAgents, src.UserAgents...)
+	dst.Rules = append(dst.Rules, src.Rules...)
+	if src.CrawlDelay > dst.CrawlDelay {
+		dst.CrawlDelay = src.CrawlDelay
+	}
+}
+
+// robotsProductToken extracts the lowercased product name from a user agent, e.g. "deadlinkr" from "DeadLinkr/1.0"
+func robotsProductToken(userAgent string) string {
+	token := strings.ToLower(strings.TrimSpace(userAgent))
+	if idx := strings.IndexAny(token, "/ ("); idx >= 0 {
+		token = token[:idx]
+	}
+	return token
+}
+
+// robotsPatternMatches matches a path against a robots.txt pattern supporting "*" wildcards and a trailing "$" anchor
+func robotsPatternMatches(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	parts := strings.Split(pattern, "*")
+
+	// The first part must be a prefix of the path
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	position := len(parts[0])
+
+	for i := 1; i < len(parts); i++ {
+		part := parts[i]
+		if i == len(parts)-1 && anchored {
+			return strings.HasSuffix(path[position:], part)
+		}
+		idx := strings.Index(path[position:], part)
+		if idx < 0 {
+			return false
+		}
+		position += idx + len(part)
+	}
+
+	return !anchored || position == len(path)
+}
+
+// robotsEntry caches the robots.txt of one host
+type robotsEntry struct {
+	once sync.Once
+	data *RobotsData
+}
+
+// RobotsChecker fetches and caches robots.txt per host and answers crawl permission queries
+type RobotsChecker struct {
+	client       HTTPClient
+	userAgent    string
+	entries      map[string]*robotsEntry
+	mutex        sync.Mutex
+	onCrawlDelay func(host string, delay time.Duration)
+	timeout      time.Duration // Timeout of robots.txt downloads (0 for none)
+}
+
+// NewRobotsChecker creates a new RobotsChecker using the given client and user agent
+func NewRobotsChecker(client HTTPClient, userAgent string) *RobotsChecker {
+	return &RobotsChecker{
+		client:    client,
+		userAgent: userAgent,
+		entries:   make(map[string]*robotsEntry),
+	}
+}
+
+// SetTimeout aborts robots.txt downloads not completed within the timeout
+func (rc *RobotsChecker) SetTimeout(timeout time.Duration) {
+	rc.timeout = timeout
+}
+
+// SetCrawlDelayHandler registers a function called once per host declaring a Crawl-delay for our user agent
+func (rc *RobotsChecker) SetCrawlDelayHandler(handler func(host string, delay time.Duration)) {
+	rc.onCrawlDelay = handler
+}
+
+// IsAllowed checks whether robots.txt allows crawling the given URL
+func (rc *RobotsChecker) IsAllowed(target *url.URL) bool {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return true
+	}
+
+	data := rc.robotsFor(target)
+	return data.IsAllowed(rc.userAgent, target.RequestURI())
+}
+
+// CrawlDelay returns the Crawl-delay declared for our user agent on the URL's host
+func (rc *RobotsChecker) CrawlDelay(target *url.URL) time.Duration {
+	return rc.robotsFor(target).GroupFor(rc.userAgent).CrawlDelay
+}
+
+// robotsFor returns the cached robots.txt of a host, fetching it on first use
+func (rc *RobotsChecker) robotsFor(target *url.URL) *RobotsData {
+	key := target.Scheme + "://" + target.Host
+
+	rc.mutex.Lock()
+	entry, exists := rc.entries[key]
+	if !exists {
+		entry = &robotsEntry{}
+		rc.entries[key] = entry
+	}
+	rc.mutex.Unlock()
+
+	entry.once.Do(func() {
+		entry.data = rc.fetch(key)
+
+		delay := entry.data.GroupFor(rc.userAgent).CrawlDelay
+		if delay > 0 && rc.onCrawlDelay != nil {
+			logger.Infof("robots.txt of %s requests a crawl delay of %v", target.Host, delay)
+			rc.onCrawlDelay(target.Host, delay)
+		}
+	})
+
+	return entry.data
+}
+
+// fetch downloads and parses robots.txt for a scheme://host origin.
+// Missing or unreachable files allow everything, so an unavailable robots.txt never hides broken links.
+func (rc *RobotsChecker) fetch(origin string) *RobotsData {
+	robotsURL := origin + "/robots.txt"
+
+	data, err := rc.download(robotsURL)
+	if err != nil {
+		logger.Debugf("No robots.txt rules applied for %s: %s", origin, err)
+		return &RobotsData{}
+	}
+
+	logger.Debugf("Loaded robots.txt from %s (%d groups)", robotsURL, len(data.Groups))
+	return data
+}
+
+// download fetches and parses a robots.txt file
+func (rc *RobotsChecker) download(robotsURL string) (*RobotsData, error) {
+	req, err := http.NewRequest(http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", rc.userAgent)
+
+	resp, err := doWithTimeout(rc.client, req, rc.timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Errorf("Error closing response body for %s: %s", robotsURL, err)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return ParseRobotsTxt(io.LimitReader(resp.Body, maxRobotsSize))
+}
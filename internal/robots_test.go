@@ -0,0 +1,235 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRobotsTxt = `# Rules for everyone
+User-agent: *
+Disallow: /private/
+Allow: /private/public.html
+Disallow: /*.pdf$
+Crawl-delay: 2
+
+User-agent: DeadLinkr
+User-agent: OtherBot
+Disallow: /admin
+Allow: /admin/help
+Crawl-delay: 0.5
+`
+
+func TestParseRobotsTxt(t *testing.T) {
+	robots, err := ParseRobotsTxt(strings.NewReader(testRobotsTxt))
+	require.NoError(t, err)
+	require.Len(t, robots.Groups, 2)
+
+	t.Run("Selects the group naming the user agent", func(t *testing.T) {
+		group := robots.GroupFor("DeadLinkr/1.0")
+		assert.Equal(t, []string{"deadlinkr", "otherbot"}, group.UserAgents)
+		assert.Equal(t, 500*time.Millisecond, group.CrawlDelay)
+
+		assert.False(t, robots.IsAllowed("DeadLinkr/1.0", "/admin/users"))
+		assert.True(t, robots.IsAllowed("DeadLinkr/1.0", "/admin/help"))
+		// The wildcard group does not apply when a specific group matches
+		assert.True(t, robots.IsAllowed("DeadLinkr/1.0", "/private/page"))
+	})
+
+	t.Run("Falls back to the wildcard group", func(t *testing.T) {
+		group := robots.GroupFor("Mozilla/5.0 (compatible)")
+		assert.Equal(t, 2*time.Second, group.CrawlDelay)
+
+		assert.False(t, robots.IsAllowed("SomeBot", "/private/page"))
+		assert.True(t, robots.IsAllowed("SomeBot", "/private/public.html"))
+		assert.True(t, robots.IsAllowed("SomeBot", "/admin"))
+	})
+
+	t.Run("Matches wildcards and end anchors", func(t *testing.T) {
+		assert.False(t, robots.IsAllowed("SomeBot", "/docs/file.pdf"))
+		assert.True(t, robots.IsAllowed("SomeBot", "/docs/file.pdf?download=1"))
+		assert.True(t, robots.IsAllowed("SomeBot", "/robots.txt"))
+	})
+
+	t.Run("Allows everything without rules", func(t *testing.T) {
+		empty, err := ParseRobotsTxt(strings.NewReader("User-agent: *\nDisallow:\n"))
+		require.NoError(t, err)
+		assert.True(t, empty.IsAllowed("DeadLinkr/1.0", "/anything"))
+	})
+}
+
+func TestRobotsPatternMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		matches bool
+	}{
+		{"/", "/index.html", true},
+		{"/fish", "/fish.html", true},
+		{"/fish", "/Fish.html", false},
+		{"/fish*.php", "/fish/salmon.php?id=1", true},
+		{"/*.php$", "/index.php", true},
+		{"/*.php$", "/index.php5", false},
+		{"/exact$", "/exact", true},
+		{"/exact$", "/exact/more", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.matches, robotsPatternMatches(tt.pattern, tt.path), "%s vs %s", tt.pattern, tt.path)
+	}
+}
+
+func TestRobotsChecker(t *testing.T) {
+	logger.SetQuiet(true)
+
+	var robotsRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			atomic.AddInt32(&robotsRequests, 1)
+			_, _ = w.Write([]byte(testRobotsTxt))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	checker := NewRobotsChecker(client, "DeadLinkr/1.0")
+
+	var delayedHost string
+	var delay time.Duration
+	checker.SetCrawlDelayHandler(func(host string, d time.Duration) {
+		delayedHost = host
+		delay = d
+	})
+
+	admin, _ := url.Parse(server.URL + "/admin/users")
+	help, _ := url.Parse(server.URL + "/admin/help")
+	mailto, _ := url.Parse("mailto:someone@example.com")
+
+	assert.False(t, checker.IsAllowed(admin))
+	assert.True(t, checker.IsAllowed(help))
+	assert.True(t, checker.IsAllowed(mailto))
+	assert.Equal(t, 500*time.Millisecond, checker.CrawlDelay(admin))
+
+	// robots.txt is fetched once per host and the crawl delay reported once
+	assert.Equal(t, int32(1), atomic.LoadInt32(&robotsRequests))
+	assert.Equal(t, admin.Host, delayedHost)
+	assert.Equal(t, 500*time.Millisecond, delay)
+
+	t.Run("Allows everything when robots.txt is missing", func(t *testing.T) {
+		missing := httptest.NewServer(http.NotFoundHandler())
+		defer missing.Close()
+
+		page, _ := url.Parse(missing.URL + "/admin")
+		assert.True(t, NewRobotsChecker(client, "DeadLinkr/1.0").IsAllowed(page))
+	})
+
+	t.Run("Allows everything when robots.txt times out", func(t *testing.T) {
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(500 * time.Millisecond)
+			_, _ = w.Write([]byte(testRobotsTxt))
+		}))
+		defer slow.Close()
+
+		checker := NewRobotsChecker(&http.Client{}, "DeadLinkr/1.0")
+		checker.SetTimeout(50 * time.Millisecond)
+		page, _ := url.Parse(slow.URL + "/admin/users")
+		start := time.Now()
+		assert.True(t, checker.IsAllowed(page))
+		assert.Less(t, time.Since(start), 400*time.Millisecond)
+	})
+}
+
+func TestDomainRateLimiterCrawlDelay(t *testing.T) {
+	logger.SetQuiet(true)
+
+	limiter := NewDomainRateLimiter(10, 5)
+	limiter.ApplyCrawlDelay("example.com", 500*time.Millisecond)
+
+	start := time.Now()
+	require.NoError(t, limiter.Wait(context.Background(), "https://example.com/a"))
+	require.NoError(t, limiter.Wait(context.Background(), "https://example.com/b"))
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+
+	stats := limiter.GetStats()["example.com"]
+	assert.Equal(t, 2.0, stats.Rate)
+	assert.Equal(t, 1.0, stats.MaxTokens)
+
+	// A crawl delay never speeds a domain up
+	limiter.ApplyCrawlDelay("example.com", 10*time.Millisecond)
+	assert.Equal(t, 2.0, limiter.GetStats()["example.com"].Rate)
+}
+
+func TestOptimizedCrawlerRespectsRobots(t *testing.T) {
+	logger.SetQuiet(true)
+
+	var adminRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /admin\n"))
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><a href="/admin">Admin</a><a href="/about">About</a></body></html>`))
+		case "/admin":
+			atomic.AddInt32(&adminRequests, 1)
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><a href="/admin/missing">Missing</a></body></html>`))
+		case "/about":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body>About</body></html>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	crawl := func(checkDisallowed bool) []model.LinkResult {
+		factory := NewServiceFactory()
+		config := factory.CreateCrawlConfigFromParams(2, 2, false, "", "", "")
+		config.RespectRobots = true
+		config.CheckDisallowed = checkDisallowed
+		httpClient := &http.Client{Timeout: 5 * time.Second}
+		crawler, err := factory.CreateOptimizedCrawlerServiceWithRateLimit(config, "TestAgent", 5*time.Second, httpClient, 100, 100)
+		require.NoError(t, err)
+		defer crawler.Stop()
+
+		require.NoError(t, crawler.Crawl(context.Background(), server.URL, server.URL+"/", 0))
+		crawler.Wait()
+		return crawler.GetResults()
+	}
+
+	targets := func(results []model.LinkResult) []string {
+		urls := []string{}
+		for _, result := range results {
+			urls = append(urls, result.TargetURL)
+		}
+		return urls
+	}
+
+	t.Run("Skips disallowed links", func(t *testing.T) {
+		atomic.StoreInt32(&adminRequests, 0)
+		results := crawl(false)
+		assert.Equal(t, []string{server.URL + "/about"}, targets(results))
+		assert.Equal(t, int32(0), atomic.LoadInt32(&adminRequests))
+	})
+
+	t.Run("Checks disallowed links without crawling them", func(t *testing.T) {
+		atomic.StoreInt32(&adminRequests, 0)
+		results := crawl(true)
+		assert.ElementsMatch(t, []string{server.URL + "/admin", server.URL + "/about"}, targets(results))
+		// Only the link check reached the page, it was never parsed for links
+		assert.Equal(t, int32(1), atomic.LoadInt32(&adminRequests))
+	})
+}
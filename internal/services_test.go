@@ -5,7 +5,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/DrakkarStorm/deadlinkr/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestServiceFactory(t *testing.T) {
@@ -37,7 +39,9 @@ func TestServiceFactory(t *testing.T) {
 		config := factory.CreateCrawlConfigFromParams(1, 5, false, "", "", "")
 		httpClient := &http.Client{Timeout: 10 * time.Second}
 		
-		crawler := factory.CreateCrawlerService(config, "TestAgent", 5*time.Second, httpClient)
+		crawler, err := factory.CreateCrawlerService(config, "TestAgent", 5*time.Second, httpClient)
+		
+		require.NoError(t, err)
 		
 		assert.NotNil(t, crawler)
 	})
@@ -80,8 +84,9 @@ func TestResultCollector(t *testing.T) {
 		url := "https://example.com"
 		
 		assert.False(t, collector.IsVisited(url))
-		collector.MarkVisited(url)
+		assert.True(t, collector.MarkVisited(url))
 		assert.True(t, collector.IsVisited(url))
+		assert.False(t, collector.MarkVisited(url), "URLs are visited once")
 	})
 
 	t.Run("Clears data", func(t *testing.T) {
@@ -91,4 +96,37 @@ func TestResultCollector(t *testing.T) {
 		assert.False(t, collector.IsVisited("https://test.com"))
 		assert.Empty(t, collector.GetResults())
 	})
+}
+
+// recordingSink records the results written to it
+type recordingSink struct {
+	results []model.LinkResult
+}
+
+func (rs *recordingSink) Write(result model.LinkResult) error {
+	rs.results = append(rs.results, result)
+	return nil
+}
+
+func (rs *recordingSink) Close() error {
+	return nil
+}
+
+func TestStreamingResultCollector(t *testing.T) {
+	sink := &recordingSink{}
+	collector := NewStreamingResultCollectorService(sink, false)
+
+	collector.AddResult(model.LinkResult{TargetURL: "https://example.com/ok", Status: 200})
+	collector.AddResult(model.LinkResult{TargetURL: "https://example.com/missing", Status: 404})
+	collector.AddResult(model.LinkResult{TargetURL: "https://example.com/down", Error: "connection refused"})
+
+	// Results go to the sink and are counted without being kept
+	assert.Len(t, sink.results, 3)
+	assert.Empty(t, collector.GetResults())
+	assert.Equal(t, 3, collector.CountResults())
+	assert.Equal(t, 2, collector.CountBrokenLinks())
+
+	retaining := NewStreamingResultCollectorService(&recordingSink{}, true)
+	retaining.AddResult(model.LinkResult{TargetURL: "https://example.com/ok", Status: 200})
+	assert.Len(t, retaining.GetResults(), 1)
 }
\ No newline at end of file
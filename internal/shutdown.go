@@ -72,7 +72,10 @@ func (sm *ShutdownManager) AddShutdownHook(hook func() error) {
 // WaitForShutdown blocks until a shutdown signal is received
 func (sm *ShutdownManager) WaitForShutdown() {
 	select {
-	case sig := <-sm.shutdownSignal:
+	case sig, ok := <-sm.shutdownSignal:
+		if !ok {
+			return // Signal handling stopped by Cleanup
+		}
 		logger.Infof("Received shutdown signal: %v", sig)
 		sm.initiateShutdown()
 	case <-sm.ctx.Done():
@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+)
+
+// maxSitemapSize is the maximum uncompressed size of a sitemap file allowed by the sitemaps protocol
+const maxSitemapSize = 50 * 1024 * 1024
+
+// maxSitemapFiles bounds how many sitemap files are expanded, protecting against index loops
+const maxSitemapFiles = 1000
+
+// SitemapEntry is a page URL listed in a sitemap
+type SitemapEntry struct {
+	Loc     string // Page URL
+	Sitemap string // Sitemap file listing the page
+}
+
+// sitemapDocument covers both <urlset> and <sitemapindex> documents
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// SitemapDiscoverer reads robots.txt and sitemap files to find crawl seeds
+type SitemapDiscoverer struct {
+	linkChecker LinkChecker
+}
+
+// NewSitemapDiscoverer creates a new SitemapDiscoverer fetching files through the given link checker
+func NewSitemapDiscoverer(linkChecker LinkChecker) *SitemapDiscoverer {
+	return &SitemapDiscoverer{
+		linkChecker: linkChecker,
+	}
+}
+
+// Discover returns the page URLs listed in the sitemaps of the site at baseURL.
+// Sitemaps are taken from the Sitemap lines of /robots.txt, falling back to /sitemap.xml.
+// Sitemap index files and gzipped sitemaps are expanded. Cancelling ctx returns the pages found so far with its error.
+func (sd *SitemapDiscoverer) Discover(ctx context.Context, baseURL string) ([]SitemapEntry, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if base.Host == "" {
+		return nil, fmt.Errorf("no host found in %s", baseURL)
+	}
+
+	root := &url.URL{Scheme: base.Scheme, Host: base.Host}
+
+	sitemaps := sd.sitemapsFromRobots(ctx, root.JoinPath("robots.txt").String())
+	if len(sitemaps) == 0 {
+		sitemaps = []string{root.JoinPath("sitemap.xml").String()}
+	}
+
+	return sd.expand(ctx, sitemaps), ctx.Err()
+}
+
+// sitemapsFromRobots returns the sitemap URLs declared in robots.txt
+func (sd *SitemapDiscoverer) sitemapsFromRobots(ctx context.Context, robotsURL string) []string {
+	body, err := sd.fetch(ctx, robotsURL)
+	if err != nil {
+		logger.Debugf("No usable robots.txt at %s: %s", robotsURL, err)
+		return nil
+	}
+
+	robots, err := ParseRobotsTxt(bytes.NewReader(body))
+	if err != nil {
+		logger.Warnf("Error parsing %s: %s", robotsURL, err)
+		return nil
+	}
+
+	logger.Debugf("Found %d sitemaps in %s", len(robots.Sitemaps), robotsURL)
+	return robots.Sitemaps
+}
+
+// expand walks sitemap files breadth-first, following sitemap indexes
+func (sd *SitemapDiscoverer) expand(ctx context.Context, sitemaps []string) []SitemapEntry {
+	entries := []SitemapEntry{}
+	seenPages := make(map[string]bool)
+	seenSitemaps := make(map[string]bool)
+
+	queue := append([]string{}, sitemaps...)
+	for len(queue) > 0 && len(seenSitemaps) < maxSitemapFiles && ctx.Err() == nil {
+		sitemapURL := queue[0]
+		queue = queue[1:]
+
+		if seenSitemaps[sitemapURL] {
+			continue
+		}
+		seenSitemaps[sitemapURL] = true
+
+		doc, err := sd.fetchSitemap(ctx, sitemapURL)
+		if err != nil {
+			logger.Warnf("Error reading sitemap %s: %s", sitemapURL, err)
+			continue
+		}
+
+		for _, child := range doc.Sitemaps {
+			if loc := strings.TrimSpace(child.Loc); loc != "" {
+				queue = append(queue, loc)
+			}
+		}
+
+		for _, page := range doc.URLs {
+			loc := strings.TrimSpace(page.Loc)
+			if loc == "" || seenPages[loc] {
+				continue
+			}
+			seenPages[loc] = true
+			entries = append(entries, SitemapEntry{Loc: loc, Sitemap: sitemapURL})
+		}
+
+		logger.Debugf("Sitemap %s lists %d pages and %d sitemaps", sitemapURL, len(doc.URLs), len(doc.Sitemaps))
+	}
+
+	return entries
+}
+
+// fetchSitemap downloads and decodes a sitemap or sitemap index, decompressing it if gzipped
+func (sd *SitemapDiscoverer) fetchSitemap(ctx context.Context, sitemapURL string) (*sitemapDocument, error) {
+	body, err := sd.fetch(ctx, sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader = bytes.NewReader(body)
+	if isGzip(body) {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err := gzipReader.Close(); err != nil {
+				logger.Errorf("Error closing gzip reader for %s: %s", sitemapURL, err)
+			}
+		}()
+		reader = io.LimitReader(gzipReader, maxSitemapSize)
+	}
+
+	doc := &sitemapDocument{}
+	if err := xml.NewDecoder(bufio.NewReader(reader)).Decode(doc); err != nil {
+		return nil, fmt.Errorf("invalid sitemap XML: %w", err)
+	}
+
+	switch doc.XMLName.Local {
+	case "urlset", "sitemapindex":
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("unexpected root element <%s>", doc.XMLName.Local)
+	}
+}
+
+// fetch downloads a file and returns its body when the response is successful
+func (sd *SitemapDiscoverer) fetch(ctx context.Context, fileURL string) ([]byte, error) {
+	resp, err := sd.linkChecker.FetchWithRetry(ctx, fileURL, 1)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Errorf("Error closing response body for %s: %s", fileURL, err)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxSitemapSize))
+}
+
+// isGzip checks for the gzip magic number
+func isGzip(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
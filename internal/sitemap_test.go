@@ -0,0 +1,206 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipBytes(t *testing.T, content string) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, err := writer.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func newSitemapServer(t *testing.T, robots string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := server.URL
+		switch r.URL.Path {
+		case "/robots.txt":
+			if robots == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(strings.ReplaceAll(robots, "BASE", base)))
+		case "/sitemap_index.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>` + base + `/sitemap-pages.xml</loc></sitemap>
+  <sitemap><loc>` + base + `/sitemap-blog.xml.gz</loc></sitemap>
+</sitemapindex>`))
+		case "/sitemap-pages.xml", "/sitemap.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>` + base + `/</loc></url>
+  <url><loc>` + base + `/about</loc></url>
+</urlset>`))
+		case "/sitemap-blog.xml.gz":
+			w.Header().Set("Content-Type", "application/gzip")
+			_, _ = w.Write(gzipBytes(t, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>`+base+`/blog/post</loc></url>
+  <url><loc>`+base+`/blog/removed</loc></url>
+</urlset>`))
+		case "/", "/about", "/blog/post":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body>Page</body></html>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+func TestSitemapDiscoverer(t *testing.T) {
+	logger.SetQuiet(true)
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	t.Run("Expands sitemaps declared in robots.txt", func(t *testing.T) {
+		server := newSitemapServer(t, "User-agent: *\nDisallow:\n\nSitemap: BASE/sitemap_index.xml # index\n")
+		defer server.Close()
+
+		discoverer := NewSitemapDiscoverer(NewLinkCheckerServiceWithRateLimit(client, "Test/1.0", 5*time.Second, 100, 100))
+		entries, err := discoverer.Discover(context.Background(), server.URL)
+		require.NoError(t, err)
+
+		locs := []string{}
+		for _, entry := range entries {
+			locs = append(locs, entry.Loc)
+		}
+		assert.ElementsMatch(t, []string{
+			server.URL + "/",
+			server.URL + "/about",
+			server.URL + "/blog/post",
+			server.URL + "/blog/removed",
+		}, locs)
+
+		for _, entry := range entries {
+			if entry.Loc == server.URL+"/blog/post" {
+				assert.Equal(t, server.URL+"/sitemap-blog.xml.gz", entry.Sitemap)
+			}
+		}
+	})
+
+	t.Run("Falls back to /sitemap.xml", func(t *testing.T) {
+		server := newSitemapServer(t, "")
+		defer server.Close()
+
+		discoverer := NewSitemapDiscoverer(NewLinkCheckerServiceWithRateLimit(client, "Test/1.0", 5*time.Second, 100, 100))
+		entries, err := discoverer.Discover(context.Background(), server.URL+"/some/page")
+		require.NoError(t, err)
+		assert.Len(t, entries, 2)
+	})
+
+	t.Run("Parses robots.txt sitemap lines", func(t *testing.T) {
+		robots, err := ParseRobotsTxt(strings.NewReader("# comment\nSITEMAP: https://example.com/a.xml\nsitemap:https://example.com/b.xml\nUser-agent: *\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"https://example.com/a.xml", "https://example.com/b.xml"}, robots.Sitemaps)
+	})
+}
+
+func TestOptimizedCrawlerSitemaps(t *testing.T) {
+	logger.SetQuiet(true)
+
+	server := newSitemapServer(t, "Sitemap: BASE/sitemap_index.xml\n")
+	defer server.Close()
+
+	factory := NewServiceFactory()
+	config := factory.CreateCrawlConfigFromParams(0, 2, false, "", "", "")
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	crawler, err := factory.CreateOptimizedCrawlerServiceWithRateLimit(config, "TestAgent", 5*time.Second, httpClient, 100, 100)
+	require.NoError(t, err)
+	defer crawler.Stop()
+
+	count, err := crawler.CrawlSitemaps(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	crawler.Wait()
+
+	broken := []model.LinkResult{}
+	for _, result := range crawler.GetResults() {
+		if result.Status >= 400 {
+			broken = append(broken, result)
+		}
+	}
+
+	require.Len(t, broken, 1)
+	assert.Equal(t, server.URL+"/blog/removed", broken[0].TargetURL)
+	assert.Equal(t, server.URL+"/sitemap-blog.xml.gz", broken[0].SourceURL)
+}
+
+func TestOptimizedCrawlerExternalSitemapEntry(t *testing.T) {
+	logger.SetQuiet(true)
+
+	crawled := make(chan string, 10)
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		crawled <- r.URL.Path
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body><a href="/linked">Linked</a></body></html>`))
+	}))
+	defer external.Close()
+	// Another host name than the 127.0.0.1 of the site, on the same test server
+	externalURL := strings.Replace(external.URL, "127.0.0.1", "localhost", 1) + "/page"
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>` + server.URL + `/</loc></url>
+  <url><loc>` + externalURL + `</loc></url>
+</urlset>`))
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body>Home</body></html>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	factory := NewServiceFactory()
+	config := factory.CreateCrawlConfigFromParams(0, 2, false, "", "", "")
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	crawler, err := factory.CreateOptimizedCrawlerServiceWithRateLimit(config, "TestAgent", 5*time.Second, httpClient, 100, 100)
+	require.NoError(t, err)
+	defer crawler.Stop()
+
+	count, err := crawler.CrawlSitemaps(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	crawler.Wait()
+
+	var entry *model.LinkResult
+	for _, result := range crawler.GetResults() {
+		assert.NotContains(t, result.TargetURL, "/linked", "links of off-site sitemap entries are not followed")
+		if result.TargetURL == externalURL {
+			entry = &result
+		}
+	}
+	require.NotNil(t, entry, "off-site sitemap entries are checked")
+	assert.True(t, entry.IsExternal)
+	assert.Equal(t, http.StatusOK, entry.Status)
+
+	close(crawled)
+	for path := range crawled {
+		assert.Equal(t, "/page", path, "off-site sitemap entries are not crawled")
+	}
+}
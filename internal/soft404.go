@@ -0,0 +1,272 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"golang.org/x/net/html"
+)
+
+// Soft404ErrorPrefix starts the error message of links reported as soft 404s
+const Soft404ErrorPrefix = "soft 404: "
+
+// MaxSoft404BodySize is the amount of a page read to compare it to the error page of its host
+const MaxSoft404BodySize = 256 * 1024
+
+const (
+	soft404Similarity = 0.9 // Minimum word similarity with the error page
+	soft404SizeRatio  = 0.8 // Minimum size ratio with the error page when titles differ
+)
+
+// IsSoft404Error checks whether a link check error message reports a soft 404
+func IsSoft404Error(message string) bool {
+	return strings.HasPrefix(message, Soft404ErrorPrefix)
+}
+
+// pageFingerprint summarizes an HTML page to compare it with others
+type pageFingerprint struct {
+	finalURL string // URL of the page after redirects
+	title    string
+	size     int
+	words    map[string]bool
+}
+
+// fingerprintEntry holds the error page fingerprint of a host, probed once
+type fingerprintEntry struct {
+	once        sync.Once
+	fingerprint *pageFingerprint // nil when the host answers unknown paths with an error status
+}
+
+// Soft404Detector detects pages answering 200 with a "not found" content.
+// The error page of each host is fingerprinted by requesting a random path that cannot exist,
+// and checked pages too similar to it are soft 404s. Configured phrases also mark pages as soft 404s.
+type Soft404Detector struct {
+	fetch         func(ctx context.Context, url string, retry int, method string) (*model.HTTPResponse, error)
+	fingerprint   bool
+	phrases       []string            // Phrases marking soft 404s on every domain
+	domainPhrases map[string][]string // Domain -> phrases marking soft 404s on it
+	hosts         map[string]*fingerprintEntry
+	hostsMutex    sync.Mutex
+}
+
+// NewSoft404Detector creates a Soft404Detector probing hosts with the given fetch function.
+// Fingerprinting is only done when enabled; phrases are matched case-insensitively.
+func NewSoft404Detector(fetch func(ctx context.Context, url string, retry int, method string) (*model.HTTPResponse, error), fingerprint bool, phrases []string, domainPhrases map[string][]string) *Soft404Detector {
+	detector := &Soft404Detector{
+		fetch:         fetch,
+		fingerprint:   fingerprint,
+		phrases:       lowerPhrases(phrases),
+		domainPhrases: make(map[string][]string, len(domainPhrases)),
+		hosts:         make(map[string]*fingerprintEntry),
+	}
+	for domain, domainPhrases := range domainPhrases {
+		detector.domainPhrases[strings.ToLower(domain)] = lowerPhrases(domainPhrases)
+	}
+	return detector
+}
+
+// lowerPhrases lower-cases phrases, dropping empty ones
+func lowerPhrases(phrases []string) []string {
+	lowered := []string{}
+	for _, phrase := range phrases {
+		if phrase = strings.ToLower(strings.TrimSpace(phrase)); phrase != "" {
+			lowered = append(lowered, phrase)
+		}
+	}
+	return lowered
+}
+
+// Enabled checks whether pages of a host are checked for soft 404s, which requires reading their body
+func (sd *Soft404Detector) Enabled(host string) bool {
+	return sd.fingerprint || len(sd.phrases) > 0 || len(sd.phrasesFor(host)) > 0
+}
+
+// phrasesFor returns the phrases configured for a host, or for a "*.domain" pattern it matches
+func (sd *Soft404Detector) phrasesFor(host string) []string {
+	phrases, _ := lookupDomain(sd.domainPhrases, host)
+	return phrases
+}
+
+// Check returns why a page that loaded successfully is a soft 404, or "" when it looks genuine
+func (sd *Soft404Detector) Check(ctx context.Context, linkURL string, body []byte) string {
+	parsed, err := url.Parse(linkURL)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	page := newPageFingerprint(linkURL, body)
+
+	text := strings.ToLower(page.title + " " + strings.Join(pageText(body), " "))
+	for _, phrase := range append(sd.phrasesFor(parsed.Host), sd.phrases...) {
+		if strings.Contains(text, phrase) {
+			return fmt.Sprintf("page contains %q", phrase)
+		}
+	}
+
+	if !sd.fingerprint {
+		return ""
+	}
+	errorPage := sd.errorPageOf(ctx, parsed)
+	// The page unknown paths redirect to is a genuine page when linked directly
+	if errorPage == nil || stripFragment(linkURL) == errorPage.finalURL {
+		return ""
+	}
+
+	similarity := page.similarity(errorPage)
+	sameTitle := page.title != "" && page.title == errorPage.title
+	if similarity >= soft404Similarity && (sameTitle || page.sizeRatio(errorPage) >= soft404SizeRatio) {
+		return fmt.Sprintf("looks like the not found page of %s (%.0f%% similar)", parsed.Host, similarity*100)
+	}
+	return ""
+}
+
+// errorPageOf returns the fingerprint of the page a host serves for unknown paths, probing it once
+func (sd *Soft404Detector) errorPageOf(ctx context.Context, target *url.URL) *pageFingerprint {
+	origin := target.Scheme + "://" + target.Host
+
+	sd.hostsMutex.Lock()
+	entry, exists := sd.hosts[origin]
+	if !exists {
+		entry = &fingerprintEntry{}
+		sd.hosts[origin] = entry
+	}
+	sd.hostsMutex.Unlock()
+
+	entry.once.Do(func() {
+		entry.fingerprint = sd.probe(ctx, origin)
+	})
+	return entry.fingerprint
+}
+
+// probe requests a random path on a host, returning the fingerprint of its error page
+// when the host answers it with a successful HTML page
+func (sd *Soft404Detector) probe(ctx context.Context, origin string) *pageFingerprint {
+	probeURL := origin + "/" + randomPath()
+	resp, err := sd.fetch(ctx, probeURL, 1, "GET")
+	if err != nil {
+		logger.Debugf("Soft 404 probe of %s failed: %s", origin, err)
+		return nil
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Errorf("Error closing response body for %s: %s", probeURL, err)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || !strings.Contains(resp.Header.Get("Content-Type"), "text/html") {
+		logger.Debugf("%s answers unknown paths with status %d", origin, resp.StatusCode)
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxSoft404BodySize))
+	if err != nil {
+		return nil
+	}
+
+	finalURL := probeURL
+	if resp.Request != nil {
+		finalURL = resp.Request.URL.String()
+	}
+	logger.Debugf("%s answers unknown paths with a 200 page, checking its pages for soft 404s", origin)
+	return newPageFingerprint(finalURL, body)
+}
+
+// randomPath returns a path that cannot exist on a site
+func randomPath() string {
+	buf := make([]byte, 12)
+	_, _ = rand.Read(buf)
+	return "deadlinkr-" + hex.EncodeToString(buf)
+}
+
+// newPageFingerprint fingerprints an HTML page
+func newPageFingerprint(pageURL string, body []byte) *pageFingerprint {
+	fingerprint := &pageFingerprint{
+		finalURL: stripFragment(pageURL),
+		size:     len(body),
+		words:    make(map[string]bool),
+	}
+	for _, word := range pageText(body) {
+		fingerprint.words[strings.ToLower(word)] = true
+	}
+	fingerprint.title = pageTitle(body)
+	return fingerprint
+}
+
+// similarity returns the share of words two pages have in common
+func (pf *pageFingerprint) similarity(other *pageFingerprint) float64 {
+	if len(pf.words) == 0 && len(other.words) == 0 {
+		return 1
+	}
+	common := 0
+	for word := range pf.words {
+		if other.words[word] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(pf.words)+len(other.words)-common)
+}
+
+// sizeRatio returns the ratio of the smaller page size to the larger one
+func (pf *pageFingerprint) sizeRatio(other *pageFingerprint) float64 {
+	smaller, larger := pf.size, other.size
+	if smaller > larger {
+		smaller, larger = larger, smaller
+	}
+	if larger == 0 {
+		return 1
+	}
+	return float64(smaller) / float64(larger)
+}
+
+// pageText returns the words of the visible text of an HTML page
+func pageText(body []byte) []string {
+	words := []string{}
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	skip := 0
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return words
+		case html.StartTagToken:
+			if name, _ := tokenizer.TagName(); isHiddenTextTag(string(name)) {
+				skip++
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); isHiddenTextTag(string(name)) && skip > 0 {
+				skip--
+			}
+		case html.TextToken:
+			if skip == 0 {
+				words = append(words, strings.Fields(string(tokenizer.Text()))...)
+			}
+		}
+	}
+}
+
+// isHiddenTextTag checks whether the text of an element is not displayed
+func isHiddenTextTag(name string) bool {
+	return name == "script" || name == "style" || name == "noscript" || name == "template"
+}
+
+// pageTitle returns the title of an HTML page
+func pageTitle(body []byte) string {
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "title" && tokenizer.Next() == html.TextToken {
+				return strings.Join(strings.Fields(string(tokenizer.Text())), " ")
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const notFoundTemplate = `<html><head><title>Example CMS</title></head><body>
+<nav>Home Products Blog Contact</nav>
+<h1>Oops!</h1><p>We could not find the page you were looking for. Try the search or go back home.</p>
+<footer>Copyright Example Inc. All rights reserved.</footer>
+</body></html>`
+
+const productPage = `<html><head><title>Example CMS</title></head><body>
+<nav>Home Products Blog Contact</nav>
+<h1>Widget 3000</h1><p>The Widget 3000 is our fastest widget ever, with twice the battery life and a brand new design.</p>
+<p>Order now and get free shipping on every order above fifty dollars, or visit one of our stores.</p>
+<footer>Copyright Example Inc. All rights reserved.</footer>
+</body></html>`
+
+// newSoft404Server serves a CMS answering unknown paths with a 200 error page
+func newSoft404Server(probes *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch {
+		case r.URL.Path == "/":
+			_, _ = w.Write([]byte(`<html><body>
+<a href="/products/widget">widget</a>
+<a href="/products/retired">retired</a>
+<a href="/legacy">legacy</a>
+</body></html>`))
+		case r.URL.Path == "/products/widget":
+			_, _ = w.Write([]byte(productPage))
+		case r.URL.Path == "/legacy":
+			_, _ = w.Write([]byte("<html><body><h1>Legacy</h1><p>This page is gone for good.</p></body></html>"))
+		default:
+			if strings.HasPrefix(r.URL.Path, "/deadlinkr-") {
+				atomic.AddInt32(probes, 1)
+			}
+			// The requested path is echoed back, like many CMS error pages do
+			_, _ = w.Write([]byte(strings.Replace(notFoundTemplate, "Oops!", "Oops! "+r.URL.Path, 1)))
+		}
+	}))
+}
+
+func TestSoft404Detector(t *testing.T) {
+	var probes int32
+	server := newSoft404Server(&probes)
+	defer server.Close()
+
+	checker := NewOptimizedLinkCheckerService(&http.Client{Timeout: 5 * time.Second}, "TestAgent", 5*time.Second, 100, 100)
+
+	t.Run("Pages like the error page of their host are soft 404s", func(t *testing.T) {
+		detector := NewSoft404Detector(checker.FetchWithRetryMethod, true, nil, nil)
+
+		reason := detector.Check(context.Background(), server.URL+"/products/retired", []byte(strings.Replace(notFoundTemplate, "Oops!", "Oops! /products/retired", 1)))
+		assert.Contains(t, reason, "looks like the not found page of")
+
+		assert.Empty(t, detector.Check(context.Background(), server.URL+"/products/widget", []byte(productPage)))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&probes), "the host is probed once")
+	})
+
+	t.Run("Hosts answering unknown paths with 404 have no soft 404s", func(t *testing.T) {
+		notFound := httptest.NewServer(http.NotFoundHandler())
+		defer notFound.Close()
+
+		detector := NewSoft404Detector(checker.FetchWithRetryMethod, true, nil, nil)
+		assert.Empty(t, detector.Check(context.Background(), notFound.URL+"/page", []byte(notFoundTemplate)))
+	})
+
+	t.Run("Phrases mark soft 404s per domain", func(t *testing.T) {
+		detector := NewSoft404Detector(checker.FetchWithRetryMethod, false, []string{"  "}, map[string][]string{
+			"127.0.0.1": {"Gone For Good"},
+		})
+		assert.True(t, detector.Enabled(strings.TrimPrefix(server.URL, "http://")))
+		assert.False(t, detector.Enabled("example.com"))
+
+		page := []byte("<html><body><h1>Legacy</h1><p>This page is gone for good.</p></body></html>")
+		assert.Equal(t, `page contains "gone for good"`, detector.Check(context.Background(), server.URL+"/legacy", page))
+		assert.Empty(t, detector.Check(context.Background(), "https://example.com/legacy", page))
+	})
+}
+
+func TestPageFingerprint(t *testing.T) {
+	page := newPageFingerprint("https://a.com/#top", []byte(`<html><head><title> Not
+  Found </title><style>body { color: red }</style></head><body><script>var hidden = 1;</script>Nothing here</body></html>`))
+	assert.Equal(t, "https://a.com/", page.finalURL)
+	assert.Equal(t, "Not Found", page.title)
+	assert.Equal(t, map[string]bool{"not": true, "found": true, "nothing": true, "here": true}, page.words)
+
+	other := newPageFingerprint("https://a.com/other", []byte("<p>Nothing here either</p>"))
+	assert.InDelta(t, 0.4, page.similarity(other), 0.001)
+}
+
+func TestCrawlReportsSoft404(t *testing.T) {
+	logger.SetQuiet(true)
+
+	var probes int32
+	server := newSoft404Server(&probes)
+	defer server.Close()
+
+	factory := NewServiceFactory()
+	config := factory.CreateCrawlConfigFromParams(0, 2, false, "", "", "")
+	config.DetectSoft404 = true
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	crawler, err := factory.CreateCachedOptimizedCrawlerService(config, "TestAgent", 5*time.Second, httpClient, 100, 100, 100, time.Hour)
+	require.NoError(t, err)
+	defer crawler.Stop()
+
+	require.NoError(t, crawler.StartCrawl(context.Background(), server.URL, server.URL+"/", 0))
+	crawler.Wait()
+
+	results := make(map[string]model.LinkResult)
+	for _, result := range crawler.GetResults() {
+		results[strings.TrimPrefix(result.TargetURL, server.URL)] = result
+	}
+
+	retired := results["/products/retired"]
+	assert.Equal(t, http.StatusOK, retired.Status)
+	assert.Equal(t, model.FailureSoft404, retired.FailureType)
+	assert.True(t, IsSoft404Error(retired.Error))
+
+	assert.Empty(t, results["/products/widget"].FailureType)
+	assert.Empty(t, results["/legacy"].Error)
+}
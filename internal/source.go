@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"golang.org/x/net/html"
+)
+
+// maxSnippetLength is the maximum length of the markup snippet reported with a link
+const maxSnippetLength = 160
+
+// sourceKey identifies an attribute value on an element type
+type sourceKey struct {
+	tag   string
+	attr  string
+	value string
+}
+
+// SourceTag is the start tag of an element in the HTML source of a page
+type SourceTag struct {
+	index  *SourceIndex
+	offset int    // Byte offset of the tag in the source
+	raw    string // Raw markup of the tag
+}
+
+// SourceIndex locates the attributes of a page in its HTML source.
+// goquery does not keep source positions, so the source is tokenized separately
+// and attributes are matched by element, attribute and value in document order.
+type SourceIndex struct {
+	lineStarts []int // Byte offset of each line
+	content    []byte
+	tags       map[sourceKey][]SourceTag
+	seen       map[sourceKey]int
+}
+
+// NewSourceIndex tokenizes an HTML document and indexes the attributes of its start tags
+func NewSourceIndex(content []byte) *SourceIndex {
+	si := &SourceIndex{
+		lineStarts: []int{0},
+		content:    content,
+		tags:       make(map[sourceKey][]SourceTag),
+		seen:       make(map[sourceKey]int),
+	}
+	for i, b := range content {
+		if b == '\n' {
+			si.lineStarts = append(si.lineStarts, i+1)
+		}
+	}
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(content))
+	offset := 0
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			if tokenizer.Err() != io.EOF {
+				return si
+			}
+			break
+		}
+		raw := string(tokenizer.Raw())
+
+		if tokenType == html.StartTagToken || tokenType == html.SelfClosingTagToken {
+			name, hasAttr := tokenizer.TagName()
+			tag := SourceTag{index: si, offset: offset, raw: raw}
+			for hasAttr {
+				var key, value []byte
+				key, value, hasAttr = tokenizer.TagAttr()
+				sk := sourceKey{tag: string(name), attr: string(key), value: string(value)}
+				si.tags[sk] = append(si.tags[sk], tag)
+			}
+		}
+
+		offset += len(raw)
+	}
+
+	return si
+}
+
+// Next returns the next occurrence of an attribute value on an element type, in document order
+func (si *SourceIndex) Next(tag, attr, value string) (SourceTag, bool) {
+	key := sourceKey{tag: tag, attr: attr, value: value}
+	occurrences := si.tags[key]
+	seen := si.seen[key]
+	if seen >= len(occurrences) {
+		return SourceTag{}, false
+	}
+	si.seen[key] = seen + 1
+	return occurrences[seen], true
+}
+
+// position converts a byte offset to a 1-based line and column, counting columns in characters
+func (si *SourceIndex) position(offset int) (int, int) {
+	line := sort.Search(len(si.lineStarts), func(i int) bool {
+		return si.lineStarts[i] > offset
+	})
+	lineStart := si.lineStarts[line-1]
+	return line, utf8.RuneCount(si.content[lineStart:offset]) + 1
+}
+
+// Locate sets the line, column and snippet of a link found in the tag.
+// The position points at the link inside the tag when it appears verbatim, otherwise at the tag.
+func (st SourceTag) Locate(linkResult *model.LinkResult, href string) {
+	if st.index == nil {
+		return
+	}
+
+	offset := st.offset
+	if href != "" {
+		if index := strings.Index(st.raw, href); index >= 0 {
+			offset += index
+		}
+	}
+
+	linkResult.Line, linkResult.Column = st.index.position(offset)
+	linkResult.Snippet = snippetOf(st.raw)
+}
+
+// snippetOf collapses the whitespace of a tag's markup and shortens it for reports
+func snippetOf(raw string) string {
+	snippet := strings.Join(strings.Fields(raw), " ")
+	if utf8.RuneCountInString(snippet) > maxSnippetLength {
+		runes := []rune(snippet)
+		snippet = string(runes[:maxSnippetLength]) + "…"
+	}
+	return snippet
+}
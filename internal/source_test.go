@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceIndex(t *testing.T) {
+	source := "<html>\n<body>\n  <p>Café <a href=\"/one\">one</a></p>\n" +
+		"  <img\n    alt=\"logo\"\n    srcset=\"/small.png 1x, /large.png 2x\">\n" +
+		"  <a href=\"/one\">again</a>\n" +
+		"  <a href=\"/search?q=a&amp;b\">escaped</a>\n" +
+		"  <script>var s = '<a href=\"/one\">';</script>\n" +
+		"</body>\n</html>\n"
+	index := NewSourceIndex([]byte(source))
+
+	locate := func(tag, attr, value, href string) model.LinkResult {
+		t.Helper()
+		sourceTag, found := index.Next(tag, attr, value)
+		require.True(t, found)
+		var result model.LinkResult
+		sourceTag.Locate(&result, href)
+		return result
+	}
+
+	t.Run("Columns count characters", func(t *testing.T) {
+		result := locate("a", "href", "/one", "/one")
+		assert.Equal(t, 3, result.Line)
+		assert.Equal(t, 20, result.Column)
+		assert.Equal(t, `<a href="/one">`, result.Snippet)
+	})
+
+	t.Run("Each srcset candidate is located in the tag", func(t *testing.T) {
+		sourceTag, found := index.Next("img", "srcset", "/small.png 1x, /large.png 2x")
+		require.True(t, found)
+
+		var small, large model.LinkResult
+		sourceTag.Locate(&small, "/small.png")
+		sourceTag.Locate(&large, "/large.png")
+		assert.Equal(t, 6, small.Line)
+		assert.Equal(t, 13, small.Column)
+		assert.Equal(t, 6, large.Line)
+		assert.Equal(t, 28, large.Column)
+		assert.Equal(t, `<img alt="logo" srcset="/small.png 1x, /large.png 2x">`, small.Snippet)
+	})
+
+	t.Run("Repeated links are located in document order", func(t *testing.T) {
+		result := locate("a", "href", "/one", "/one")
+		assert.Equal(t, 7, result.Line)
+		assert.Equal(t, 12, result.Column)
+	})
+
+	t.Run("Links not written verbatim are located at their tag", func(t *testing.T) {
+		result := locate("a", "href", "/search?q=a&b", "/search?q=a&b")
+		assert.Equal(t, 8, result.Line)
+		assert.Equal(t, 3, result.Column)
+	})
+
+	t.Run("Markup in scripts is not indexed", func(t *testing.T) {
+		_, found := index.Next("a", "href", "/one")
+		assert.False(t, found)
+	})
+}
+
+func TestSnippetOf(t *testing.T) {
+	assert.Equal(t, `<a href="/x" class="nav">`, snippetOf("<a  href=\"/x\"\n\t class=\"nav\">"))
+
+	long := snippetOf("<a title=\"" + strings.Repeat("é", 200) + "\">")
+	assert.Equal(t, maxSnippetLength+1, len([]rune(long)))
+	assert.True(t, strings.HasSuffix(long, "…"))
+}
+
+func TestCrawlLocatesLinks(t *testing.T) {
+	logger.SetQuiet(true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<html>\n<body>\n<nav><a href=\"/missing\">nav</a></nav>\n<p>\n  <a class=\"body\" href=\"/missing\">body</a>\n</p>\n</body>\n</html>"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	factory := NewServiceFactory()
+	config := factory.CreateCrawlConfigFromParams(0, 2, false, "", "", "nav a")
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	crawler, err := factory.CreateCachedOptimizedCrawlerService(config, "TestAgent", 5*time.Second, httpClient, 100, 100, 100, time.Hour)
+	require.NoError(t, err)
+	defer crawler.Stop()
+
+	require.NoError(t, crawler.StartCrawl(context.Background(), server.URL, server.URL+"/", 0))
+	crawler.Wait()
+
+	// The excluded navigation link must not shift the position of the link after it
+	results := crawler.GetResults()
+	require.Len(t, results, 1)
+	assert.Equal(t, http.StatusNotFound, results[0].Status)
+	assert.Equal(t, 5, results[0].Line)
+	assert.Equal(t, 25, results[0].Column)
+	assert.Equal(t, `<a class="body" href="/missing">`, results[0].Snippet)
+}
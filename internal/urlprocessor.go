@@ -9,8 +9,10 @@ import (
 
 // URLProcessorService implements the URLProcessor interface
 type URLProcessorService struct {
-	includePattern string
-	excludePattern string
+	includePattern  string
+	excludePattern  string
+	robots          *RobotsChecker // Optional, nil disables robots.txt rules
+	checkDisallowed bool           // Check links disallowed by robots.txt without crawling them
 }
 
 // NewURLProcessorService creates a new URLProcessorService
@@ -47,7 +49,32 @@ func (up *URLProcessorService) ShouldSkipURL(baseURL, linkURL *url.URL) bool {
 	}
 
 	// Check patterns
-	return up.shouldSkipURLBasedOnPattern(linkURL)
+	if up.shouldSkipURLBasedOnPattern(linkURL) {
+		return true
+	}
+
+	// Skip internal pages disallowed by robots.txt unless they should still be checked
+	if !up.checkDisallowed && baseURL != nil && baseURL.Host == linkURL.Host && !up.IsCrawlAllowed(linkURL) {
+		logger.Debugf("Skipping %s: disallowed by robots.txt", linkURL)
+		return true
+	}
+
+	return false
+}
+
+// IsCrawlAllowed checks whether robots.txt allows crawling the given page
+func (up *URLProcessorService) IsCrawlAllowed(pageURL *url.URL) bool {
+	if up.robots == nil {
+		return true
+	}
+	return up.robots.IsAllowed(pageURL)
+}
+
+// SetRobotsChecker enables robots.txt rules. When checkDisallowed is set,
+// disallowed links are still checked but never crawled.
+func (up *URLProcessorService) SetRobotsChecker(robots *RobotsChecker, checkDisallowed bool) {
+	up.robots = robots
+	up.checkDisallowed = checkDisallowed
 }
 
 // ValidateURL validates and parses a base URL
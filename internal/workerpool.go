@@ -2,6 +2,7 @@ package internal
 
 import (
 	"context"
+	"net/url"
 	"sync"
 	"time"
 
@@ -14,6 +15,7 @@ type Job struct {
 	BaseURL      string
 	TargetURL    string
 	CurrentDepth int
+	SourceURL    string // Optional page or sitemap referencing TargetURL; when set, TargetURL itself is checked and reported
 	Callback     func([]model.LinkResult, error)
 }
 
@@ -27,6 +29,10 @@ type WorkerPool struct {
 	crawler         *CrawlerService
 	stats           *PoolStats
 	progressTracker *ProgressTracker // Optional progress tracker
+	frontier        *Frontier        // Optional scheduler for discovered pages
+	commitMutex     sync.RWMutex     // Held by jobs adding their results, and exclusively by Snapshot
+	visiting        map[string]int   // Pages marked visited by jobs in progress
+	visitingMutex   sync.Mutex
 }
 
 // PoolStats tracks worker pool statistics
@@ -49,6 +55,7 @@ func NewWorkerPool(workers int, crawler *CrawlerService) *WorkerPool {
 		crawler:         crawler,
 		stats:           &PoolStats{},
 		progressTracker: nil, // Will be set by crawler if needed
+		visiting:        make(map[string]int),
 	}
 }
 
@@ -71,6 +78,12 @@ func (wp *WorkerPool) Stop() {
 	logger.Debugf("Worker pool stopped")
 }
 
+// Cancel aborts the jobs being processed: their requests and rate limit waits return early,
+// and workers stop once their current job returns
+func (wp *WorkerPool) Cancel() {
+	wp.cancel()
+}
+
 // ForceStop immediately stops the worker pool without waiting
 func (wp *WorkerPool) ForceStop() {
 	logger.Debugf("Force stopping worker pool...")
@@ -90,17 +103,22 @@ func (wp *WorkerPool) ForceStop() {
 	logger.Debugf("Worker pool force stopped")
 }
 
-// Submit adds a job to the worker pool
+// Submit adds a job to the worker pool, blocking while the queue is full.
+// It returns false if the pool is stopped before the job could be queued.
 func (wp *WorkerPool) Submit(job Job) bool {
+	return wp.SubmitContext(context.Background(), job)
+}
+
+// SubmitContext adds a job to the worker pool, blocking while the queue is full.
+// It returns false if the pool or the given context is cancelled first.
+func (wp *WorkerPool) SubmitContext(ctx context.Context, job Job) bool {
 	select {
 	case wp.jobQueue <- job:
 		wp.incrementJobsQueued()
 		return true
 	case <-wp.ctx.Done():
 		return false
-	default:
-		// Queue is full, could implement backpressure here
-		logger.Warnf("Worker pool queue is full, dropping job for %s", job.TargetURL)
+	case <-ctx.Done():
 		return false
 	}
 }
@@ -120,7 +138,8 @@ func (wp *WorkerPool) worker(id int) {
 			}
 			
 			wp.incrementJobsActive()
-			wp.processJob(id, job)
+			outcome := wp.processJob(id, job)
+			wp.completeJob(job, outcome)
 			wp.decrementJobsActive()
 			wp.incrementJobsCompleted()
 			
@@ -136,22 +155,67 @@ func (wp *WorkerPool) worker(id int) {
 	}
 }
 
-// processJob processes a single job
-func (wp *WorkerPool) processJob(workerID int, job Job) {
+// jobOutcome is what a processed job contributes to the crawl
+type jobOutcome struct {
+	results     []model.LinkResult
+	visited     bool // The job marked its target visited
+	interrupted bool // The job was aborted by cancelling the pool, its results are partial and a resumed crawl runs it again
+}
+
+// processJob processes a single job, aborted when the pool is cancelled
+func (wp *WorkerPool) processJob(workerID int, job Job) jobOutcome {
+	ctx := wp.ctx
 	start := time.Now()
 	logger.Debugf("Worker %d processing %s (depth %d)", workerID, job.TargetURL, job.CurrentDepth)
 	
-	// Check if we should skip this URL (already visited)
-	if wp.crawler.resultCollector.IsVisited(job.TargetURL) {
+	// Jobs still queued when the pool is cancelled are dropped
+	if ctx.Err() != nil {
+		if job.Callback != nil {
+			job.Callback(nil, nil)
+		}
+		return jobOutcome{interrupted: true}
+	}
+	
+	outcome := jobOutcome{}
+	
+	// Check and report the target itself when it was referenced from outside a crawled page
+	if job.SourceURL != "" {
+		result, crawl := wp.checkReferencedTarget(ctx, workerID, job)
+		if ctx.Err() != nil {
+			if job.Callback != nil {
+				job.Callback(nil, nil)
+			}
+			return jobOutcome{interrupted: true}
+		}
+		if result != nil {
+			outcome.results = append(outcome.results, *result)
+		}
+		if !crawl {
+			if job.Callback != nil {
+				job.Callback(nil, nil)
+			}
+			return outcome
+		}
+	}
+	
+	// Mark as visited first, skipping the URL if another job already visited it
+	if !wp.claimVisit(job.TargetURL) {
 		logger.Debugf("Worker %d skipping already visited: %s", workerID, job.TargetURL)
 		if job.Callback != nil {
 			job.Callback(nil, nil)
 		}
-		return
+		return outcome
 	}
+	outcome.visited = true
 	
-	// Mark as visited first
-	wp.crawler.resultCollector.MarkVisited(job.TargetURL)
+	// Never crawl pages disallowed by robots.txt
+	if !wp.crawler.isCrawlAllowed(job.TargetURL) {
+		logger.Infof("Worker %d: not crawling %s, disallowed by robots.txt", workerID, job.TargetURL)
+		if job.Callback != nil {
+			job.Callback(nil, nil)
+		}
+		return outcome
+	}
 	
 	// Validate base URL
 	baseUrlParsed, err := wp.crawler.urlProcessor.ValidateURL(job.BaseURL)
@@ -160,17 +224,26 @@ func (wp *WorkerPool) processJob(workerID int, job Job) {
 		if job.Callback != nil {
 			job.Callback(nil, err)
 		}
-		return
+		return outcome
 	}
 	
 	// Parse the page and extract links
-	doc, err := wp.crawler.pageParser.ParsePage(job.TargetURL)
+	doc, err := wp.crawler.pageParser.ParsePage(ctx, job.TargetURL)
+	if err != nil && ctx.Err() != nil {
+		// An interrupted page is not a crawl error
+		logger.Debugf("Worker %d: crawl of %s interrupted", workerID, job.TargetURL)
+		if job.Callback != nil {
+			job.Callback(nil, nil)
+		}
+		outcome.interrupted = true
+		return outcome
+	}
 	if err != nil {
 		logger.Errorf("Worker %d: Error parsing %s: %s", workerID, job.TargetURL, err)
 		if job.Callback != nil {
 			job.Callback(nil, err)
 		}
-		return
+		return outcome
 	}
 	
 	if doc == nil {
@@ -178,15 +251,22 @@ func (wp *WorkerPool) processJob(workerID int, job Job) {
 		if job.Callback != nil {
 			job.Callback(nil, nil)
 		}
-		return
+		return outcome
 	}
 	
 	// Extract links from the page
-	links := wp.crawler.pageParser.ExtractLinks(baseUrlParsed, job.TargetURL, doc)
+	links := wp.crawler.pageParser.ExtractLinks(ctx, baseUrlParsed, job.TargetURL, doc)
 	
-	// Add results to collector
-	for _, link := range links {
-		wp.crawler.resultCollector.AddResult(link)
+	outcome.results = append(outcome.results, links...)
+	
+	// Links checked before an interruption are reported, but the page is crawled again in full when resuming
+	if ctx.Err() != nil {
+		logger.Debugf("Worker %d: checks of %s interrupted", workerID, job.TargetURL)
+		if job.Callback != nil {
+			job.Callback(links, nil)
+		}
+		outcome.interrupted = true
+		return outcome
 	}
 	
 	duration := time.Since(start)
@@ -194,13 +274,167 @@ func (wp *WorkerPool) processJob(workerID int, job Job) {
 		workerID, job.TargetURL, duration, len(links))
 	
 	// Schedule internal links for further crawling if within depth limit
-	// Note: For now, we'll handle this in the caller to avoid infinite recursion
-	// TODO: Implement proper job scheduling with depth tracking
-	
+	wp.scheduleLinks(job, links)
+
 	// Call callback with results
 	if job.Callback != nil {
 		job.Callback(links, nil)
 	}
+	
+	return outcome
+}
+
+// completeJob adds the results of a job and removes it from the frontier, atomically with respect to Snapshot.
+// An interrupted job stays pending and keeps its page visiting, so snapshots leave its partial results out.
+func (wp *WorkerPool) completeJob(job Job, outcome jobOutcome) {
+	wp.commitMutex.RLock()
+	defer wp.commitMutex.RUnlock()
+	
+	for _, result := range outcome.results {
+		wp.crawler.resultCollector.AddResult(result)
+	}
+	if outcome.visited && !outcome.interrupted {
+		wp.releaseVisit(job.TargetURL)
+	}
+	
+	if wp.frontier == nil {
+		return
+	}
+	if outcome.interrupted {
+		wp.frontier.MarkInterrupted(job)
+	} else {
+		wp.frontier.MarkDone(job)
+	}
+}
+
+// claimVisit marks a page visited by a job in progress, returning false if another job already visited it
+func (wp *WorkerPool) claimVisit(pageURL string) bool {
+	wp.visitingMutex.Lock()
+	wp.visiting[pageURL]++
+	wp.visitingMutex.Unlock()
+	
+	if wp.crawler.resultCollector.MarkVisited(pageURL) {
+		return true
+	}
+	wp.releaseVisit(pageURL)
+	return false
+}
+
+// releaseVisit records that the job visiting a page completed
+func (wp *WorkerPool) releaseVisit(pageURL string) {
+	wp.visitingMutex.Lock()
+	defer wp.visitingMutex.Unlock()
+	
+	if wp.visiting[pageURL] <= 1 {
+		delete(wp.visiting, pageURL)
+	} else {
+		wp.visiting[pageURL]--
+	}
+}
+
+// Snapshot captures the crawl while no job completes: the jobs still pending, the results of completed jobs
+// and the pages they visited. Pages of pending jobs are left out, with their partial results,
+// so that resuming from the snapshot crawls them again.
+func (wp *WorkerPool) Snapshot() ([]Job, []model.LinkResult, []string) {
+	wp.commitMutex.Lock()
+	defer wp.commitMutex.Unlock()
+	
+	var pending []Job
+	if wp.frontier != nil {
+		pending = wp.frontier.PendingJobs()
+	}
+	referenced := make(map[string]bool)
+	for _, job := range pending {
+		if job.SourceURL != "" {
+			referenced[frontierKey(job)] = true
+		}
+	}
+	
+	// Visits are claimed before pages are marked visited, so every visited page in progress is found
+	visitedURLs := wp.crawler.resultCollector.VisitedURLs()
+	wp.visitingMutex.Lock()
+	defer wp.visitingMutex.Unlock()
+	
+	visited := make([]string, 0, len(visitedURLs))
+	for _, pageURL := range visitedURLs {
+		if wp.visiting[pageURL] == 0 {
+			visited = append(visited, pageURL)
+		}
+	}
+	
+	results := make([]model.LinkResult, 0)
+	for _, result := range wp.crawler.resultCollector.GetResults() {
+		if wp.visiting[result.SourceURL] > 0 || referenced[frontierKey(Job{SourceURL: result.SourceURL, TargetURL: result.TargetURL})] {
+			continue
+		}
+		results = append(results, result)
+	}
+	
+	return pending, results, visited
+}
+
+// checkReferencedTarget checks a job target referenced by job.SourceURL. It returns the result to record,
+// nil for an invalid URL or an interrupted check, and whether the target should be crawled.
+// Targets on another host, e.g. off-site sitemap entries, are checked but never crawled.
+func (wp *WorkerPool) checkReferencedTarget(ctx context.Context, workerID int, job Job) (*model.LinkResult, bool) {
+	targetURL, err := url.Parse(job.TargetURL)
+	if err != nil {
+		logger.Errorf("Worker %d: Invalid target URL %s: %s", workerID, job.TargetURL, err)
+		return nil, false
+	}
+	baseURL, err := url.Parse(job.BaseURL)
+	if err != nil {
+		logger.Errorf("Worker %d: Invalid base URL %s: %s", workerID, job.BaseURL, err)
+		return nil, false
+	}
+	isExternal := baseURL.Hostname() != targetURL.Hostname()
+	
+	linkChecker := wp.crawler.linkChecker
+	if linkChecker == nil {
+		return nil, !isExternal
+	}
+	
+	status, errMsg := linkChecker.CheckLink(ctx, job.TargetURL)
+	if ctx.Err() != nil {
+		return nil, false
+	}
+	result := &model.LinkResult{
+		SourceURL:  job.SourceURL,
+		TargetURL:  job.TargetURL,
+		Status:     status,
+		Error:      errMsg,
+		IsExternal: isExternal,
+	}
+	
+	return result, !isExternal && status > 0 && status < 400 && errMsg == ""
+}
+
+// scheduleLinks enqueues the internal links of a page as jobs one level deeper
+func (wp *WorkerPool) scheduleLinks(job Job, links []model.LinkResult) {
+	if wp.frontier == nil || job.CurrentDepth >= wp.crawler.config.MaxDepth {
+		return
+	}
+
+	scheduled := 0
+	for _, link := range links {
+		if link.IsExternal || !wp.crawler.isCrawlableLink(link) {
+			continue
+		}
+
+		child := Job{
+			BaseURL:      job.BaseURL,
+			TargetURL:    stripFragment(link.TargetURL),
+			CurrentDepth: job.CurrentDepth + 1,
+			Callback:     job.Callback,
+		}
+		if wp.frontier.Enqueue(child) {
+			scheduled++
+		}
+	}
+
+	if scheduled > 0 {
+		logger.Debugf("Scheduled %d pages from %s at depth %d", scheduled, job.TargetURL, job.CurrentDepth+1)
+	}
 }
 
 // GetStats returns current pool statistics
@@ -214,6 +448,11 @@ func (wp *WorkerPool) GetStats() PoolStats {
 	}
 }
 
+// SetFrontier sets the scheduler used to enqueue discovered pages
+func (wp *WorkerPool) SetFrontier(frontier *Frontier) {
+	wp.frontier = frontier
+}
+
 // SetProgressTracker sets the progress tracker for this worker pool
 func (wp *WorkerPool) SetProgressTracker(tracker *ProgressTracker) {
 	wp.progressTracker = tracker
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"sync"
@@ -10,11 +11,12 @@ import (
 	"github.com/DrakkarStorm/deadlinkr/logger"
 	"github.com/DrakkarStorm/deadlinkr/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestWorkerPool(t *testing.T) {
 	// Initialize logger for tests
-	model.Quiet = true
+	logger.SetQuiet(true)
 	logger.InitLogger("debug")
 	defer logger.CloseLogger()
 	
@@ -44,6 +46,32 @@ func TestWorkerPool(t *testing.T) {
 		assert.Equal(t, 2, pool.workers)
 	})
 
+	t.Run("Pages are claimed by a single job", func(t *testing.T) {
+		pool := NewWorkerPool(2, crawler)
+		
+		var wg sync.WaitGroup
+		var claims sync.Map
+		for i := range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				claims.Store(i, pool.claimVisit("https://example.com/claimed"))
+			}()
+		}
+		wg.Wait()
+		
+		won := 0
+		claims.Range(func(_, claimed any) bool {
+			if claimed.(bool) {
+				won++
+			}
+			return true
+		})
+		assert.Equal(t, 1, won)
+		pool.releaseVisit("https://example.com/claimed")
+		assert.Empty(t, pool.visiting, "losing claims are released")
+	})
+
 	t.Run("Starts and stops gracefully", func(t *testing.T) {
 		pool := NewWorkerPool(2, crawler)
 		
@@ -108,23 +136,35 @@ func TestWorkerPool(t *testing.T) {
 		assert.Equal(t, int64(1), finalStats.JobsCompleted)
 	})
 
-	t.Run("Handles queue overflow", func(t *testing.T) {
+	t.Run("Applies backpressure when queue is full", func(t *testing.T) {
 		pool := NewWorkerPool(1, crawler)
 		
 		// Don't start the pool so jobs queue up
-		// Fill the queue beyond capacity
 		job := Job{
 			BaseURL:      "https://example.com", 
 			TargetURL:    "https://httpbin.org/status/200",
 			CurrentDepth: 0,
 		}
 		
-		// First few should succeed
+		// Buffer holds workers*2 jobs
 		assert.True(t, pool.Submit(job))
 		assert.True(t, pool.Submit(job))
 		
-		// Eventually should fail due to full queue
-		// (This depends on buffer size)
+		// Next submission blocks instead of dropping the job
+		submitted := make(chan bool, 1)
+		go func() {
+			submitted <- pool.Submit(job)
+		}()
+		
+		select {
+		case <-submitted:
+			t.Fatal("Submit should block while the queue is full")
+		case <-time.After(100 * time.Millisecond):
+		}
+		
+		// Stopping the pool releases the blocked submitter
+		pool.cancel()
+		assert.False(t, <-submitted)
 	})
 }
 
@@ -133,7 +173,8 @@ func TestOptimizedCrawler(t *testing.T) {
 	config := factory.CreateCrawlConfigFromParams(1, 2, false, "", "", "")
 	
 	httpClient := &http.Client{Timeout: 5 * time.Second}
-	crawler := factory.CreateOptimizedCrawlerService(config, "TestAgent", 5*time.Second, httpClient)
+	crawler, err := factory.CreateOptimizedCrawlerService(config, "TestAgent", 5*time.Second, httpClient)
+	require.NoError(t, err)
 	
 	t.Run("Creates optimized crawler", func(t *testing.T) {
 		assert.NotNil(t, crawler)
@@ -151,7 +192,7 @@ func TestOptimizedCrawler(t *testing.T) {
 		defer server.Close()
 		
 		// This should not hang
-		err := crawler.StartCrawl(server.URL, server.URL, 0)
+		err := crawler.StartCrawl(context.Background(), server.URL, server.URL, 0)
 		assert.NoError(t, err)
 		
 		// Wait with timeout
@@ -176,4 +217,39 @@ func TestOptimizedCrawler(t *testing.T) {
 		stats := crawler.GetStats()
 		assert.GreaterOrEqual(t, stats.JobsQueued, int64(0))
 	})
-}
\ No newline at end of file
+}
+func TestOptimizedCrawlerCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body><a href="/ok">OK</a><a href="/slow">Slow</a></body></html>`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	factory := NewServiceFactory()
+	config := factory.CreateCrawlConfigFromParams(0, 2, false, "", "", "")
+	crawler, err := factory.CreateOptimizedCrawlerServiceWithRateLimit(config, "TestAgent", time.Minute, &http.Client{Timeout: time.Minute}, 100, 100)
+	require.NoError(t, err)
+	defer crawler.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	assert.NoError(t, crawler.StartCrawl(ctx, server.URL, server.URL+"/", 0))
+	crawler.Wait()
+
+	assert.Less(t, time.Since(start), 5*time.Second, "the crawl stops once its context is done")
+	assert.NoError(t, crawler.SeedError(), "an interrupted crawl is not a crawl error")
+	for _, result := range crawler.GetResults() {
+		assert.NotContains(t, result.TargetURL, "/slow", "links whose check was interrupted are not reported")
+	}
+}
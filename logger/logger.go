@@ -8,8 +8,6 @@ import (
 	"path/filepath"
 	"runtime"
 	"time"
-
-	"github.com/DrakkarStorm/deadlinkr/model"
 )
 
 type LogLevel int
@@ -34,8 +32,29 @@ var logger *log.Logger
 var logLevel LogLevel
 var logFile *os.File
 
+// quiet disables logging and the creation of the log file
+var quiet bool
+
+// startTime is the start of the program execution reported by Durationf
+var startTime = time.Now()
+
+// SetQuiet disables logging when enabled, InitLogger then creates no log file
+func SetQuiet(enabled bool) {
+	quiet = enabled
+}
+
+// IsQuiet checks whether logging is disabled
+func IsQuiet() bool {
+	return quiet
+}
+
+// SetStartTime sets the start of the program execution reported by Durationf
+func SetStartTime(start time.Time) {
+	startTime = start
+}
+
 func InitLogger(level string) {
-	if !model.Quiet {
+	if !quiet {
 		// Close any previously opened log file to avoid resource leaks
 		CloseLogger()
 
@@ -62,7 +81,7 @@ func InitLogger(level string) {
 		if err == nil {
 			logFile, err = root.Create(logFilePath)
 			if err == nil {
-				fmt.Println("Logging to:", filepath.Join(logDir, logFilePath))
+				fmt.Fprintln(os.Stderr, "Logging to:", filepath.Join(logDir, logFilePath))
 				logger = log.New(logFile, "", log.LstdFlags)
 				_ = root.Close()
 				return
@@ -90,7 +109,7 @@ func InitLogger(level string) {
 		if err != nil {
 			log.Fatalf("Failed to create log file: %v", err)
 		}
-		fmt.Println("Logging to:", filepath.Join(cwd, "deadlinkr.log"))
+		fmt.Fprintln(os.Stderr, "Logging to:", filepath.Join(cwd, "deadlinkr.log"))
 
 		logger = log.New(logFile, "", log.LstdFlags)
 	}
@@ -123,7 +142,8 @@ func CloseLogger() {
 }
 
 func Log(level LogLevel, format string, a ...any) {
-	if !model.Quiet {
+	// Nothing is logged until InitLogger is called, e.g. when deadlinkr is used as a library
+	if !quiet && logger != nil {
 		if level >= logLevel {
 			logger.Printf("%s - %s", logLevels[level], fmt.Sprintf(format, a...))
 		}
@@ -152,6 +172,6 @@ func Fatalf(format string, a ...any) {
 }
 
 func Durationf(format string, a ...any) {
-	duration := time.Since(model.TimeExecution)
+	duration := time.Since(startTime)
 	Infof("%s - %s (durée : %v)", "Program Execution", fmt.Sprintf(format, a...), duration)
 }
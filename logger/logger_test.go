@@ -6,7 +6,6 @@ import (
 	"runtime"
 	"testing"
 
-	"github.com/DrakkarStorm/deadlinkr/model"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -34,9 +33,9 @@ func TestStringToLogLevel(t *testing.T) {
 
 func TestInitLogger(t *testing.T) {
 	// Save original state
-	originalQuiet := model.Quiet
+	originalQuiet := IsQuiet()
 	defer func() {
-		model.Quiet = originalQuiet
+		SetQuiet(originalQuiet)
 		CloseLogger()
 		// Clean up test log files
 		_ = os.Remove("deadlinkr.log")
@@ -50,7 +49,7 @@ func TestInitLogger(t *testing.T) {
 	}()
 
 	t.Run("Logger initialized when not quiet", func(t *testing.T) {
-		model.Quiet = false
+		SetQuiet(false)
 		
 		InitLogger("debug")
 		
@@ -59,7 +58,7 @@ func TestInitLogger(t *testing.T) {
 	})
 
 	t.Run("Logger not initialized when quiet", func(t *testing.T) {
-		model.Quiet = true
+		SetQuiet(true)
 		logger = nil // Reset
 		
 		InitLogger("info")
@@ -71,14 +70,14 @@ func TestInitLogger(t *testing.T) {
 
 func TestLogLevels(t *testing.T) {
 	// Save original state
-	originalQuiet := model.Quiet
+	originalQuiet := IsQuiet()
 	defer func() {
-		model.Quiet = originalQuiet
+		SetQuiet(originalQuiet)
 		CloseLogger()
 		_ = os.Remove("deadlinkr.log")
 	}()
 
-	model.Quiet = false
+	SetQuiet(false)
 	InitLogger("warn") // Set level to warn
 
 	// These should be safe to call even if they don't actually log
@@ -94,16 +93,16 @@ func TestLogLevels(t *testing.T) {
 
 func TestDurationf(t *testing.T) {
 	// Save original state
-	originalQuiet := model.Quiet
-	originalTimeExecution := model.TimeExecution
+	originalQuiet := IsQuiet()
+	originalStartTime := startTime
 	defer func() {
-		model.Quiet = originalQuiet
-		model.TimeExecution = originalTimeExecution
+		SetQuiet(originalQuiet)
+		SetStartTime(originalStartTime)
 		CloseLogger()
 		_ = os.Remove("deadlinkr.log")
 	}()
 
-	model.Quiet = false
+	SetQuiet(false)
 	InitLogger("info")
 	
 	// This should not panic
@@ -112,13 +111,13 @@ func TestDurationf(t *testing.T) {
 
 func TestCloseLogger(t *testing.T) {
 	// Save original state
-	originalQuiet := model.Quiet
+	originalQuiet := IsQuiet()
 	defer func() {
-		model.Quiet = originalQuiet
+		SetQuiet(originalQuiet)
 	}()
 
 	t.Run("Close logger when file exists", func(t *testing.T) {
-		model.Quiet = false
+		SetQuiet(false)
 		InitLogger("info")
 		
 		// This should not panic
@@ -144,14 +143,14 @@ func TestLogFormat(t *testing.T) {
 
 func TestLogFilePath(t *testing.T) {
 	// Save original state
-	originalQuiet := model.Quiet
+	originalQuiet := IsQuiet()
 	defer func() {
-		model.Quiet = originalQuiet
+		SetQuiet(originalQuiet)
 		CloseLogger()
 		_ = os.Remove("deadlinkr.log")
 	}()
 
-	model.Quiet = false
+	SetQuiet(false)
 	
 	// Test that logger initializes without crashing
 	// (actual file path testing is complex due to permissions)
@@ -162,13 +161,13 @@ func TestLogFilePath(t *testing.T) {
 
 func TestLogWithQuietMode(t *testing.T) {
 	// Save original state
-	originalQuiet := model.Quiet
+	originalQuiet := IsQuiet()
 	defer func() {
-		model.Quiet = originalQuiet
+		SetQuiet(originalQuiet)
 	}()
 
 	t.Run("No logging when quiet mode is on", func(t *testing.T) {
-		model.Quiet = true
+		SetQuiet(true)
 		logger = nil
 		
 		// These should not panic even when logger is nil
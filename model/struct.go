@@ -1,13 +1,67 @@
 package model
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
+
+// Failure types of links whose status alone does not tell they are broken
+const (
+	FailureMissingAnchor = "missing_anchor" // The page exists but lacks the #fragment the link points to
+	FailureSoft404       = "soft_404"       // The page answers 200 with a "not found" content
+)
+
+// Redirect issues flagged on links
+const (
+	RedirectIssuePermanent = "permanent_redirect"  // The link permanently redirects and should be updated
+	RedirectIssueLongChain = "long_redirect_chain" // The link goes through more redirects than allowed
+	RedirectIssueDowngrade = "protocol_downgrade"  // A redirect goes from https to http
+	RedirectIssueLogin     = "login_redirect"      // The link redirects to a login page
+	RedirectIssueLoop      = "redirect_loop"       // The redirects loop back to a URL already visited
+)
+
+// Changes of a link compared to a baseline report
+const (
+	ChangeNewlyBroken = "newly_broken" // Broken now, working or absent in the baseline
+	ChangeFixed       = "fixed"        // Working now or removed, broken in the baseline
+	ChangeStillBroken = "still_broken" // Broken now and in the baseline
+	ChangeNewOK       = "new_ok"       // Working now, absent from the baseline
+)
 
 type LinkResult struct {
-	SourceURL  string `json:"source_url"`
-	TargetURL  string `json:"target_url"`
-	Status     int    `json:"status"`
-	Error      string `json:"error,omitempty"`
-	IsExternal bool   `json:"is_external"`
+	SourceURL      string        `json:"source_url"`
+	TargetURL      string        `json:"target_url"`
+	Status         int           `json:"status"`
+	Error          string        `json:"error,omitempty"`
+	IsExternal     bool          `json:"is_external"`
+	Element        string        `json:"element,omitempty"`         // Element type the link was found on, e.g. "a" or "img"
+	FailureType    string        `json:"failure_type,omitempty"`    // Kind of failure beyond the HTTP status, e.g. FailureMissingAnchor
+	Redirects      []RedirectHop `json:"redirects,omitempty"`       // Redirects followed to reach the final status
+	RedirectIssues []string      `json:"redirect_issues,omitempty"` // Problems found in the redirects, e.g. RedirectIssuePermanent
+	Line           int           `json:"line,omitempty"`            // 1-based line of the link in the source page, 0 when unknown
+	Column         int           `json:"column,omitempty"`          // 1-based column of the link, counted in characters
+	Snippet        string        `json:"snippet,omitempty"`         // Markup of the tag holding the link
+	Change         string        `json:"change,omitempty"`          // Change compared to a baseline report, e.g. ChangeNewlyBroken
+}
+
+// RedirectHop is a redirect response followed while checking a link
+type RedirectHop struct {
+	URL      string `json:"url"`
+	Status   int    `json:"status"`
+	Location string `json:"location"`
+}
+
+// DomainRule holds the overrides applied to the requests sent to the hosts of a domain
+type DomainRule struct {
+	Burst         float64           // Burst capacity of the domain's rate limit, 0 for the default
+	Timeout       time.Duration     // Timeout of each request, 0 for the default
+	Retries       *int              // Retries of failed GET requests, nil for the default
+	Head          *bool             // Whether links may be checked with a HEAD request first, nil for the default
+	Headers       map[string]string // Headers added to each request
+	BasicUser     string            // Basic authentication replacing the global credentials
+	BasicPassword string
+	BearerToken   string // Bearer token replacing the global credentials
+	Skip          bool   // Links are reported as working without being requested
 }
 
 // HTTPResponse wraps http.Response for easier testing
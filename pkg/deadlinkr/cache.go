@@ -0,0 +1,61 @@
+package deadlinkr
+
+import (
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/internal"
+)
+
+// CacheEntry is the result of a link check kept in a cache
+type CacheEntry struct {
+	Status    int
+	Message   string
+	Timestamp time.Time     // When the link was checked
+	TTL       time.Duration // Time the result stays valid, depending on its status
+}
+
+// Cache persists link check results between scans, e.g. in a database shared by several runs.
+// Scans keep checked links in memory, loading the entries of the cache when they start and saving
+// the in-memory cache, including the loaded entries still valid, when they end.
+type Cache interface {
+	Load() (map[string]CacheEntry, error)
+	Save(entries map[string]CacheEntry) error
+}
+
+// cacheStore adapts a Cache to the store of the link checker cache
+type cacheStore struct {
+	cache Cache
+}
+
+// Load implements the internal.CacheStore interface
+func (cs cacheStore) Load() (map[string]*internal.CacheEntry, error) {
+	entries, err := cs.cache.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]*internal.CacheEntry, len(entries))
+	for url, entry := range entries {
+		stored[url] = &internal.CacheEntry{
+			Status:    entry.Status,
+			Message:   entry.Message,
+			Timestamp: entry.Timestamp,
+			TTL:       entry.TTL,
+		}
+	}
+	return stored, nil
+}
+
+// Save implements the internal.CacheStore interface
+func (cs cacheStore) Save(entries map[string]*internal.CacheEntry) error {
+	saved := make(map[string]CacheEntry, len(entries))
+	for url, entry := range entries {
+		saved[url] = CacheEntry{
+			Status:    entry.Status,
+			Message:   entry.Message,
+			Timestamp: entry.Timestamp,
+			TTL:       entry.TTL,
+		}
+	}
+	return cs.cache.Save(saved)
+}
@@ -0,0 +1,46 @@
+package deadlinkr
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/DrakkarStorm/deadlinkr/internal"
+	"github.com/DrakkarStorm/deadlinkr/logger"
+)
+
+// resumeCrawl enables checkpoints of the crawl of target and, when resuming, restores the crawl from its checkpoint.
+// It returns false if the crawl must be started from its seeds.
+func (s *Scanner) resumeCrawl(ctx context.Context, crawler *internal.OptimizedCrawlerService, target string) (bool, error) {
+	if path := s.options.checkpointFile(); path != "" {
+		crawler.EnableCheckpoints(path, target, s.options.CheckpointInterval)
+	}
+
+	resumeFile := s.options.ResumeFile
+	if resumeFile == "" {
+		return false, nil
+	}
+
+	state, err := internal.LoadCrawlState(resumeFile)
+	if err != nil {
+		return false, err
+	}
+	if state.Target != target {
+		return false, fmt.Errorf("checkpoint %s was saved by a scan of %s, not %s", resumeFile, state.Target, target)
+	}
+
+	logger.Infof("Resuming scan of %s saved at %s: %d pages pending, %d links already checked",
+		target, state.SavedAt.Format("2006-01-02 15:04:05"), len(state.Pending), len(state.Results))
+	return true, crawler.Resume(ctx, state)
+}
+
+// reportCheckpoint tells how to resume a scan that left a checkpoint behind
+func (s *Scanner) reportCheckpoint() {
+	path := s.options.checkpointFile()
+	if path == "" {
+		return
+	}
+	if _, err := os.Stat(path); err == nil {
+		logger.Infof("Scan state saved to %s, continue the scan with --resume %s", path, path)
+	}
+}
@@ -0,0 +1,23 @@
+package deadlinkr
+
+import (
+	"net/http"
+
+	"github.com/DrakkarStorm/deadlinkr/internal"
+)
+
+// Metrics records the requests and statistics of the scans of the scanners sharing it,
+// and serves them in the Prometheus text format
+type Metrics struct {
+	metrics *internal.Metrics
+}
+
+// NewMetrics creates an empty Metrics
+func NewMetrics() *Metrics {
+	return &Metrics{metrics: internal.NewMetrics()}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.metrics.ServeHTTP(w, r)
+}
@@ -0,0 +1,137 @@
+package deadlinkr
+
+import (
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/internal"
+	"github.com/DrakkarStorm/deadlinkr/model"
+)
+
+// DomainRule overrides the request settings of a domain
+type DomainRule = model.DomainRule
+
+// Options configures a Scanner. Start from DefaultOptions, which matches the defaults of the command line.
+type Options struct {
+	Depth           int      // Maximum crawl depth, 0 checks the links of the start page only
+	Concurrency     int      // Number of pages crawled at once
+	OnlyInternal    bool     // Check only links to the scanned site
+	IncludePattern  string   // Only check URLs matching this regex
+	ExcludePattern  string   // Do not check URLs matching this regex
+	ExcludeHtmlTags string   // Do not check links from elements matching this selector
+	IncludeElements []string // Only check links from these element types (all when empty)
+	ExcludeElements []string // Do not check links from these element types
+	RespectRobots   bool     // Honor robots.txt Disallow and Crawl-delay rules
+	CheckDisallowed bool     // Check links disallowed by robots.txt without crawling them
+	UseSitemap      bool     // Also crawl the pages listed in sitemaps, reporting broken sitemap entries
+	CheckAnchors    bool     // Report #fragment links whose target page has no matching anchor
+	FlagRedirects   []string // Redirect problems to report: permanent, chain, downgrade, login, or all
+	MaxRedirectHops int      // Redirect chains longer than this are reported with the chain mode
+	DetectSoft404   bool     // Report pages answering 200 that look like their site's not found page
+	Soft404Phrases  []string // Report pages containing any of these phrases as soft 404s
+
+	Timeout                  time.Duration // Timeout of each request
+	UserAgent                string
+	RateLimit                float64 // Requests per second per domain
+	RateBurst                float64 // Burst capacity of the rate limit
+	OptimizeWithHeadRequests bool    // Check links with HEAD requests when possible
+	CacheEnabled             bool    // Cache link check results, requiring OptimizeWithHeadRequests
+	CacheSize                int     // Maximum number of results cached in memory, the persistent cache is not limited
+	CacheTTL                 time.Duration
+	CacheDir                 string // Directory of a persistent link cache shared across runs, requiring CacheEnabled and OptimizeWithHeadRequests (disabled if empty)
+	Cache                    Cache  // Persistent link cache shared across runs, replacing CacheDir, if set
+
+	HTTPClient HTTPClient // Sends the requests of the scans, a client keeping connections alive when nil
+
+	AuthBasic   string   // Basic authentication in "user:password" format
+	AuthBearer  string   // Bearer token
+	AuthHeaders []string // Custom headers in "Key: Value" format
+	AuthCookies string   // Cookie string
+
+	DomainRateLimits     map[string]float64    // Requests per second per domain, keyed by host or "*.domain" pattern
+	DomainSoft404Phrases map[string][]string   // Phrases marking pages of a domain as soft 404s
+	DomainRules          map[string]DomainRule // Request overrides per domain
+
+	CDPURL          string        // DevTools endpoint of a browser rendering pages before their links are extracted
+	CDPWaitUntil    string        // Page event rendering waits for (load, domcontentloaded, networkidle)
+	CDPWaitSelector string        // CSS selector of an element rendering also waits for, if any
+	CDPWaitDelay    time.Duration // Extra time given to the scripts of a rendered page
+	CDPTimeout      time.Duration // Maximum time to render a page
+	CDPMaxTabs      int           // Number of pages rendered at once
+
+	MarkdownRoot string // Directory root-relative links of Markdown files point into, the working directory when empty
+
+	CheckpointFile     string        // File the state of a scan is saved to, to resume it once interrupted (disabled if empty)
+	CheckpointInterval time.Duration // Time between two checkpoints
+	ResumeFile         string        // Checkpoint of an interrupted scan to continue from, if any
+
+	ShowProgress bool       // Render the progress of scans in the terminal
+	Name         string     // Label of the scans in metrics, the scanned target when empty
+	Metrics      *Metrics   // Records the requests and statistics of the scans, if set
+	Sink         ResultSink // Receives each result as it is produced, if set, instead of keeping results in memory
+}
+
+// DefaultOptions returns the options matching the defaults of the command line
+func DefaultOptions() Options {
+	return Options{
+		Depth:                    1,
+		Concurrency:              20,
+		RespectRobots:            true,
+		MaxRedirectHops:          3,
+		Timeout:                  15 * time.Second,
+		UserAgent:                "DeadLinkr/1.0",
+		RateLimit:                2.0,
+		RateBurst:                5.0,
+		OptimizeWithHeadRequests: true,
+		CacheEnabled:             true,
+		CacheSize:                1000,
+		CacheTTL:                 60 * time.Minute,
+		CDPWaitUntil:             "load",
+		CDPTimeout:               30 * time.Second,
+		CDPMaxTabs:               4,
+		CheckpointInterval:       time.Minute,
+	}
+}
+
+// crawlConfig creates the crawl configuration of the options, crawling up to depth
+func (options *Options) crawlConfig(factory *internal.ServiceFactory, depth int) *internal.CrawlConfig {
+	config := factory.CreateCrawlConfigFromParams(
+		depth,
+		options.Concurrency,
+		options.OnlyInternal,
+		options.IncludePattern,
+		options.ExcludePattern,
+		options.ExcludeHtmlTags,
+	)
+	config.RespectRobots = options.RespectRobots
+	config.CheckDisallowed = options.CheckDisallowed
+	config.IncludeElements = options.IncludeElements
+	config.ExcludeElements = options.ExcludeElements
+	config.CheckAnchors = options.CheckAnchors
+	config.FlagRedirects = options.FlagRedirects
+	config.MaxRedirectHops = options.MaxRedirectHops
+	config.DetectSoft404 = options.DetectSoft404
+	config.Soft404Phrases = options.Soft404Phrases
+	config.CDPURL = options.CDPURL
+	config.CDPOptions = options.cdpOptions()
+	config.ShowProgress = options.ShowProgress
+	return config
+}
+
+// cdpOptions returns the page rendering settings of the options
+func (options *Options) cdpOptions() internal.CDPOptions {
+	return internal.CDPOptions{
+		WaitUntil:    options.CDPWaitUntil,
+		WaitSelector: options.CDPWaitSelector,
+		WaitDelay:    options.CDPWaitDelay,
+		Timeout:      options.CDPTimeout,
+		MaxTabs:      options.CDPMaxTabs,
+	}
+}
+
+// checkpointFile returns the file checkpoints of a scan are saved to, the resumed checkpoint by default
+func (options *Options) checkpointFile() string {
+	if options.CheckpointFile != "" {
+		return options.CheckpointFile
+	}
+	return options.ResumeFile
+}
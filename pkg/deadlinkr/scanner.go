@@ -0,0 +1,426 @@
+// Package deadlinkr checks websites, static site build directories and single pages for broken links.
+// It is the API the deadlinkr command is built on.
+package deadlinkr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/internal"
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+)
+
+// Result is a checked link
+type Result = model.LinkResult
+
+// HTTPClient sends the requests of the scans. *http.Client implements it; clients following redirects
+// should stop on loops, as the default client does.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// ResultSink receives link results as they are produced, e.g. to stream them to a report.
+// Writes are serialized by the scanner, which does not close the sink.
+type ResultSink interface {
+	Write(result Result) error
+	Close() error
+}
+
+// Scanner checks links with the configuration of its options. Scans of a Scanner run one at a time,
+// separate scanners run independently.
+type Scanner struct {
+	options Options
+	client  HTTPClient // Shared by the requests of every scan, keeping connections alive
+
+	scanMutex sync.Mutex                        // Held while a scan runs
+	mutex     sync.Mutex                        // Guards crawler
+	crawler   *internal.OptimizedCrawlerService // Crawler of the running or last scan
+}
+
+// NewScanner creates a Scanner, checking that the browser used to render pages, if configured, answers and that
+// its wait conditions are valid, and that a persistent link cache comes with the cache and HEAD optimization it is used by
+func NewScanner(options Options) (*Scanner, error) {
+	if (options.CacheDir != "" || options.Cache != nil) && !(options.CacheEnabled && options.OptimizeWithHeadRequests) {
+		return nil, errors.New("the persistent link cache requires the cache and HEAD optimization to be enabled")
+	}
+	if options.CDPURL != "" {
+		browser, err := internal.NewCDPBrowser(options.CDPURL, options.cdpOptions())
+		if err != nil {
+			return nil, err
+		}
+		if err := browser.Ping(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+	var client HTTPClient = internal.NewHTTPClient()
+	if options.HTTPClient != nil {
+		client = options.HTTPClient
+	}
+	return &Scanner{
+		options: options,
+		client:  client,
+	}, nil
+}
+
+// Options returns the options of the scanner
+func (s *Scanner) Options() Options {
+	return s.options
+}
+
+// Scan crawls a site from a URL up to the depth of the options, or checks a static site build directory
+// when target is a local directory or a file:// URL. Cancelling ctx stops the scan, returning the results
+// collected so far. Results written to the sink of the options are not returned, unless checkpoints keep them.
+// The error reports a start page that could not be crawled.
+func (s *Scanner) Scan(ctx context.Context, target string) ([]Result, error) {
+	return s.scan(ctx, target, s.options.Sink)
+}
+
+// Check checks the links of a single page without crawling further. Cancelling ctx stops the check,
+// returning the results collected so far. The error reports a page that could not be checked.
+func (s *Scanner) Check(ctx context.Context, pageURL string) ([]Result, error) {
+	s.scanMutex.Lock()
+	defer s.scanMutex.Unlock()
+
+	factory := s.newServiceFactory(s.options.Sink, false)
+	// Depth 0 so the frontier does not follow links beyond the page
+	crawler, err := s.createCrawler(factory, s.options.crawlConfig(factory, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure cleanup
+	defer crawler.Stop()
+	defer s.track(pageURL, crawler)()
+
+	if err := crawler.StartCrawl(ctx, pageURL, pageURL, 0); err != nil {
+		return nil, err
+	}
+	crawler.Wait()
+
+	return crawler.GetResults(), crawler.SeedError()
+}
+
+// CheckMarkdown checks the links of Markdown files, walking directories for .md and .markdown files.
+// Relative links are checked on disk, including the heading anchors of the Markdown files they point to,
+// and absolute links over HTTP. Results locate links by file, line and column. Cancelling ctx stops the check,
+// returning the results collected so far. The error reports a path that could not be read.
+func (s *Scanner) CheckMarkdown(ctx context.Context, paths ...string) ([]Result, error) {
+	s.scanMutex.Lock()
+	defer s.scanMutex.Unlock()
+
+	root := s.options.MarkdownRoot
+	if root == "" {
+		root = "."
+	}
+	if s.options.CacheDir != "" || s.options.Cache != nil {
+		logger.Warnf("Links of Markdown files are not saved to the persistent link cache")
+	}
+
+	factory := s.newServiceFactory(s.options.Sink, false)
+	checker, err := factory.CreateMarkdownCheckerService(
+		s.options.crawlConfig(factory, 0),
+		root,
+		s.options.UserAgent,
+		s.options.Timeout,
+		s.client,
+		s.options.RateLimit,
+		s.options.RateBurst,
+		s.options.CacheSize,
+		s.options.CacheTTL,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checker.Check(ctx, paths); err != nil {
+		return nil, err
+	}
+	return checker.GetResults(), nil
+}
+
+// scan scans a site or a static site build directory, writing results to sink if set
+func (s *Scanner) scan(ctx context.Context, target string, sink ResultSink) ([]Result, error) {
+	s.scanMutex.Lock()
+	defer s.scanMutex.Unlock()
+
+	if dir, ok := localSiteDir(target); ok {
+		return s.scanDirectory(ctx, dir, sink)
+	}
+	return s.scanSite(ctx, target, sink)
+}
+
+// scanSite crawls a site over HTTP with worker pools
+func (s *Scanner) scanSite(ctx context.Context, baseURL string, sink ResultSink) ([]Result, error) {
+	factory := s.newServiceFactory(sink, true)
+
+	// Create optimized crawler service with rate limiting, HEAD optimization, and caching
+	crawler, err := s.createCrawler(factory, s.options.crawlConfig(factory, s.options.Depth))
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure cleanup
+	defer crawler.Stop()
+	defer s.track(baseURL, crawler)()
+
+	// Continue an interrupted scan, or start crawling
+	resumed, err := s.resumeCrawl(ctx, crawler, baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if !resumed {
+		err = crawler.StartCrawl(ctx, baseURL, baseURL, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		// Seed the crawl with sitemap pages
+		if s.options.UseSitemap {
+			if _, err := crawler.CrawlSitemaps(ctx, baseURL); err != nil {
+				logger.Errorf("Error discovering sitemaps for %s: %s", baseURL, err)
+			}
+		}
+	}
+
+	// Wait for completion
+	crawler.Wait()
+	s.reportCheckpoint()
+
+	// Log some stats
+	stats := crawler.GetStats()
+	logger.Infof("Worker pool stats - Queued: %d, Completed: %d, Active: %d",
+		stats.JobsQueued, stats.JobsCompleted, stats.JobsActive)
+
+	// Report a start page that could not be crawled as a crawl error
+	return crawler.GetResults(), crawler.SeedError()
+}
+
+// scanDirectory checks a static site build directory without a web server
+func (s *Scanner) scanDirectory(ctx context.Context, dir string, sink ResultSink) ([]Result, error) {
+	if s.options.CDPURL != "" {
+		logger.Warnf("Pages of a local directory are not rendered, the browser endpoint is ignored")
+	}
+	if s.options.CacheDir != "" || s.options.Cache != nil {
+		logger.Warnf("Links of a local directory are not saved to the persistent link cache")
+	}
+
+	factory := s.newServiceFactory(sink, true)
+
+	site, err := internal.NewFileSystemSite(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Local sites have no robots.txt to honor and are not rendered
+	config := s.options.crawlConfig(factory, s.options.Depth)
+	config.RespectRobots = false
+	config.CheckDisallowed = false
+	config.CDPURL = ""
+	crawler := factory.CreateFileSystemCrawlerService(
+		config,
+		site,
+		s.options.UserAgent,
+		s.options.Timeout,
+		s.client,
+		s.options.RateLimit,
+		s.options.RateBurst,
+		s.options.CacheSize,
+		s.options.CacheTTL,
+	)
+
+	// Ensure cleanup
+	defer crawler.Stop()
+	defer s.track(dir, crawler)()
+
+	// Continue an interrupted scan, or start crawling from the site root
+	resumed, err := s.resumeCrawl(ctx, crawler, dir)
+	if err != nil {
+		return nil, err
+	}
+	if !resumed {
+		err = crawler.StartCrawl(ctx, site.RootURL(), site.RootURL(), 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Wait for completion
+	crawler.Wait()
+	s.reportCheckpoint()
+
+	// Report a start page that could not be crawled as a crawl error
+	return crawler.GetResults(), crawler.SeedError()
+}
+
+// newServiceFactory creates a service factory applying the credentials, domain rules and metrics of the options,
+// streaming results to sink if set. Streamed results are kept in memory for checkpoints when checkpoints are enabled.
+func (s *Scanner) newServiceFactory(sink ResultSink, checkpoints bool) *internal.ServiceFactory {
+	factory := internal.NewServiceFactory()
+	factory.SetCredentials(s.options.AuthBasic, s.options.AuthBearer, s.options.AuthHeaders, s.options.AuthCookies)
+	factory.SetDomainRules(s.options.DomainRateLimits, s.options.DomainSoft404Phrases, s.options.DomainRules)
+	if s.options.Metrics != nil {
+		factory.SetMetrics(s.options.Metrics.metrics)
+	}
+	if sink != nil {
+		factory.SetResultSink(sink, checkpoints && s.options.checkpointFile() != "")
+	}
+	return factory
+}
+
+// createCrawler creates the optimized crawler for a config, matching the cache and HEAD settings of the options
+func (s *Scanner) createCrawler(factory *internal.ServiceFactory, config *internal.CrawlConfig) (*internal.OptimizedCrawlerService, error) {
+	options := &s.options
+
+	if options.CacheEnabled && options.OptimizeWithHeadRequests {
+		if options.Cache != nil {
+			return factory.CreateStoredCachedOptimizedCrawlerService(
+				config,
+				options.UserAgent,
+				options.Timeout,
+				s.client,
+				options.RateLimit,
+				options.RateBurst,
+				options.CacheSize,
+				options.CacheTTL,
+				cacheStore{cache: options.Cache},
+			)
+		}
+
+		if options.CacheDir != "" {
+			return factory.CreatePersistentCachedOptimizedCrawlerService(
+				config,
+				options.UserAgent,
+				options.Timeout,
+				s.client,
+				options.RateLimit,
+				options.RateBurst,
+				options.CacheSize,
+				options.CacheTTL,
+				options.CacheDir,
+			)
+		}
+
+		return factory.CreateCachedOptimizedCrawlerService(
+			config,
+			options.UserAgent,
+			options.Timeout,
+			s.client,
+			options.RateLimit,
+			options.RateBurst,
+			options.CacheSize,
+			options.CacheTTL,
+		)
+	}
+
+	if options.OptimizeWithHeadRequests {
+		return factory.CreateOptimizedCrawlerServiceWithHeadOptimization(
+			config,
+			options.UserAgent,
+			options.Timeout,
+			s.client,
+			options.RateLimit,
+			options.RateBurst,
+		)
+	}
+
+	return factory.CreateOptimizedCrawlerServiceWithRateLimit(
+		config,
+		options.UserAgent,
+		options.Timeout,
+		s.client,
+		options.RateLimit,
+		options.RateBurst,
+	)
+}
+
+// track records the crawler of a scan for Progress, and exposes its statistics in the metrics of the options
+// under the name of the options, or the scanned target, until the returned function is called
+func (s *Scanner) track(target string, crawler *internal.OptimizedCrawlerService) func() {
+	s.mutex.Lock()
+	s.crawler = crawler
+	s.mutex.Unlock()
+
+	if s.options.Metrics == nil {
+		return func() {}
+	}
+	name := s.options.Name
+	if name == "" {
+		name = target
+	}
+	return s.options.Metrics.metrics.Track(name, crawler)
+}
+
+// Progress is the progress of a scan
+type Progress struct {
+	Links           int // Links checked
+	Broken          int // Broken links found
+	TotalTasks      int64
+	CompletedTasks  int64
+	ActiveTasks     int64
+	ErrorCount      int64
+	ProgressPercent float64
+	LinksChecked    int64 // Links whose check completed, including cached ones
+	LinksPerSecond  float64
+	CacheHitRate    float64
+	CacheHits       int64
+	CacheMisses     int64
+	BandwidthSaved  int64 // Bytes saved by HEAD requests
+	HeadRequests    int64
+	GetRequests     int64
+	Elapsed         time.Duration
+}
+
+// Progress returns the progress of the running scan, or the final progress of the last one.
+// It returns false if the scanner has not started a scan yet.
+func (s *Scanner) Progress() (Progress, bool) {
+	s.mutex.Lock()
+	crawler := s.crawler
+	s.mutex.Unlock()
+	if crawler == nil {
+		return Progress{}, false
+	}
+
+	stats := crawler.Progress()
+	return Progress{
+		Links:           crawler.CountResults(),
+		Broken:          crawler.CountBrokenLinks(),
+		TotalTasks:      stats.TotalTasks,
+		CompletedTasks:  stats.CompletedTasks,
+		ActiveTasks:     stats.ActiveTasks,
+		ErrorCount:      stats.ErrorCount,
+		ProgressPercent: stats.ProgressPercent,
+		LinksChecked:    stats.LinksChecked,
+		LinksPerSecond:  stats.LinksPerSecond,
+		CacheHitRate:    stats.CacheHitRate,
+		CacheHits:       stats.CacheHits,
+		CacheMisses:     stats.CacheMisses,
+		BandwidthSaved:  stats.BandwidthSaved,
+		HeadRequests:    stats.HeadRequests,
+		GetRequests:     stats.GetRequests,
+		Elapsed:         stats.ElapsedTime,
+	}, true
+}
+
+// localSiteDir returns the directory to check when the scan target is a local directory or a file:// URL
+func localSiteDir(target string) (string, bool) {
+	if strings.HasPrefix(target, "file://") {
+		parsed, err := url.Parse(target)
+		if err != nil {
+			return "", false
+		}
+		target = parsed.Path
+	}
+
+	info, err := os.Stat(target)
+	if err != nil || !info.IsDir() {
+		return "", false
+	}
+	return target, true
+}
@@ -0,0 +1,248 @@
+package deadlinkr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSite serves a home page linking to an about page and a missing page
+func newTestSite(t *testing.T) *httptest.Server {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><a href="/about">About</a><a href="/missing">Missing</a></body></html>`))
+		case "/about":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><a href="/">Home</a></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(site.Close)
+	return site
+}
+
+// testOptions returns options fast enough for tests
+func testOptions() Options {
+	logger.SetQuiet(true)
+	options := DefaultOptions()
+	options.RateLimit = 100
+	options.Concurrency = 4
+	return options
+}
+
+// targets returns the target URLs of results
+func targets(results []Result) []string {
+	urls := []string{}
+	for _, result := range results {
+		urls = append(urls, result.TargetURL)
+	}
+	return urls
+}
+
+func TestScannerScan(t *testing.T) {
+	site := newTestSite(t)
+
+	t.Run("Crawls a site", func(t *testing.T) {
+		scanner, err := NewScanner(testOptions())
+		require.NoError(t, err)
+
+		_, started := scanner.Progress()
+		assert.False(t, started)
+
+		results, err := scanner.Scan(context.Background(), site.URL+"/")
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{site.URL + "/about", site.URL + "/missing", site.URL + "/"}, targets(results))
+
+		progress, started := scanner.Progress()
+		assert.True(t, started)
+		assert.Equal(t, len(results), progress.Links)
+		assert.Equal(t, 1, progress.Broken)
+	})
+
+	t.Run("Scanners are independent", func(t *testing.T) {
+		shallow := testOptions()
+		shallow.Depth = 0
+		first, err := NewScanner(shallow)
+		require.NoError(t, err)
+		second, err := NewScanner(testOptions())
+		require.NoError(t, err)
+
+		firstResults, err := first.Scan(context.Background(), site.URL+"/")
+		require.NoError(t, err)
+		secondResults, err := second.Scan(context.Background(), site.URL+"/")
+		require.NoError(t, err)
+
+		assert.Len(t, firstResults, 2)
+		assert.Len(t, secondResults, 3)
+	})
+
+	t.Run("Checks a static site build directory", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<html><body><a href="about.html">About</a></body></html>`), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "about.html"), []byte(`<html><body>About</body></html>`), 0o644))
+
+		scanner, err := NewScanner(testOptions())
+		require.NoError(t, err)
+		results, err := scanner.Scan(context.Background(), dir)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, 200, results[0].Status)
+	})
+
+	t.Run("Unreachable start page", func(t *testing.T) {
+		scanner, err := NewScanner(testOptions())
+		require.NoError(t, err)
+		_, err = scanner.Scan(context.Background(), "http://127.0.0.1:1/")
+		assert.Error(t, err)
+	})
+}
+
+func TestScannerCheck(t *testing.T) {
+	site := newTestSite(t)
+
+	scanner, err := NewScanner(testOptions())
+	require.NoError(t, err)
+
+	results, err := scanner.Check(context.Background(), site.URL+"/")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{site.URL + "/about", site.URL + "/missing"}, targets(results))
+}
+
+func TestNewScannerCDPSettings(t *testing.T) {
+	options := testOptions()
+	options.CDPURL = "http://localhost:9222"
+	options.CDPWaitUntil = "idle"
+	_, err := NewScanner(options)
+	assert.Error(t, err)
+
+	// Pages are not silently parsed without rendering when the browser does not answer
+	browser := httptest.NewServer(http.NotFoundHandler())
+	browser.Close()
+	options.CDPURL, options.CDPWaitUntil = browser.URL, "load"
+	_, err = NewScanner(options)
+	assert.ErrorContains(t, err, "browser unreachable")
+}
+
+func TestNewScannerCacheSettings(t *testing.T) {
+	for name, disable := range map[string]func(*Options){
+		"cache":             func(options *Options) { options.CacheEnabled = false },
+		"HEAD optimization": func(options *Options) { options.OptimizeWithHeadRequests = false },
+	} {
+		t.Run("Persistent cache without "+name, func(t *testing.T) {
+			options := testOptions()
+			options.CacheDir = t.TempDir()
+			disable(&options)
+			_, err := NewScanner(options)
+			assert.Error(t, err)
+
+			options.CacheDir = ""
+			options.Cache = &memoryCache{}
+			_, err = NewScanner(options)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// countingClient counts the requests it sends
+type countingClient struct {
+	mutex    sync.Mutex
+	requests int
+}
+
+func (cc *countingClient) Do(req *http.Request) (*http.Response, error) {
+	cc.mutex.Lock()
+	cc.requests++
+	cc.mutex.Unlock()
+	return http.DefaultClient.Do(req)
+}
+
+func TestScannerHTTPClient(t *testing.T) {
+	site := newTestSite(t)
+
+	client := &countingClient{}
+	options := testOptions()
+	options.HTTPClient = client
+	scanner, err := NewScanner(options)
+	require.NoError(t, err)
+
+	_, err = scanner.Check(context.Background(), site.URL+"/")
+	require.NoError(t, err)
+	assert.Positive(t, client.requests)
+}
+
+// memoryCache keeps cache entries in memory
+type memoryCache struct {
+	entries map[string]CacheEntry
+}
+
+func (mc *memoryCache) Load() (map[string]CacheEntry, error) {
+	return mc.entries, nil
+}
+
+func (mc *memoryCache) Save(entries map[string]CacheEntry) error {
+	mc.entries = entries
+	return nil
+}
+
+func TestScannerCache(t *testing.T) {
+	site := newTestSite(t)
+
+	// A cached result is reported without checking the link again
+	cache := &memoryCache{entries: map[string]CacheEntry{
+		site.URL + "/missing": {Status: 200, Timestamp: time.Now(), TTL: time.Hour},
+	}}
+	options := testOptions()
+	options.Cache = cache
+	scanner, err := NewScanner(options)
+	require.NoError(t, err)
+
+	results, err := scanner.Check(context.Background(), site.URL+"/")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.Equal(t, 200, result.Status, result.TargetURL)
+	}
+
+	// Checked links are saved to the cache once the scan ended
+	assert.Contains(t, cache.entries, site.URL+"/about")
+	assert.Contains(t, cache.entries, site.URL+"/missing")
+}
+
+func TestScannerCheckMarkdown(t *testing.T) {
+	site := newTestSite(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Docs\n\nSee [usage](#usage), [about]("+site.URL+"/about) and [setup](setup.md).\n"), 0o644))
+
+	options := testOptions()
+	options.MarkdownRoot = dir
+	scanner, err := NewScanner(options)
+	require.NoError(t, err)
+
+	results, err := scanner.CheckMarkdown(context.Background(), dir)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	broken := 0
+	for _, result := range results {
+		assert.Equal(t, 3, result.Line, result.TargetURL)
+		if result.Status >= 400 || result.Error != "" {
+			broken++
+		}
+	}
+	assert.Equal(t, 2, broken)
+
+	_, err = scanner.CheckMarkdown(context.Background(), filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
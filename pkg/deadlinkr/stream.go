@@ -0,0 +1,100 @@
+package deadlinkr
+
+import (
+	"context"
+	"iter"
+	"sync"
+)
+
+// Stream scans target like Scan, yielding each result as it is produced instead of returning them once the
+// scan ended. Results are also written to the sink of the options, if set. An error of the scan is yielded
+// last, with an empty result. Breaking out of the loop cancels the scan.
+//
+//	for result, err := range scanner.Stream(ctx, "https://example.com") {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(result.TargetURL, result.Status)
+//	}
+func (s *Scanner) Stream(ctx context.Context, target string) iter.Seq2[Result, error] {
+	return func(yield func(Result, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		sink := newChannelSink(s.options.Sink)
+		done := make(chan error, 1)
+		go func() {
+			_, err := s.scan(ctx, target, sink)
+			sink.close()
+			done <- err
+		}()
+
+		for result := range sink.results {
+			if !yield(result, nil) {
+				// Stop the scan, dropping the results still being written
+				close(sink.stopped)
+				cancel()
+				<-done
+				return
+			}
+		}
+
+		if err := <-done; err != nil {
+			yield(Result{}, err)
+		}
+	}
+}
+
+// channelSink passes the results of a scan to the loop of a Stream
+type channelSink struct {
+	next    ResultSink // Also receives the results, if set
+	results chan Result
+	stopped chan struct{} // Closed once the loop stopped
+
+	mutex  sync.Mutex // Guards closed
+	closed bool
+}
+
+// newChannelSink creates a channelSink, writing results to next as well if set
+func newChannelSink(next ResultSink) *channelSink {
+	return &channelSink{
+		next:    next,
+		results: make(chan Result),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Write implements the ResultSink interface, waiting for the loop to take the result
+func (cs *channelSink) Write(result Result) error {
+	if cs.next != nil {
+		if err := cs.next.Write(result); err != nil {
+			return err
+		}
+	}
+
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	if cs.closed {
+		return nil
+	}
+	select {
+	case cs.results <- result:
+		return nil
+	case <-cs.stopped:
+		// Nobody takes the result anymore
+		return nil
+	}
+}
+
+// Close implements the ResultSink interface. The channel is closed by the stream once the scan ended.
+func (cs *channelSink) Close() error {
+	return nil
+}
+
+// close ends the loop over the results, later writes are dropped
+func (cs *channelSink) close() {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	cs.closed = true
+	close(cs.results)
+}
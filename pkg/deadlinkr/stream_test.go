@@ -0,0 +1,54 @@
+package deadlinkr
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScannerStream(t *testing.T) {
+	site := newTestSite(t)
+
+	t.Run("Yields every result", func(t *testing.T) {
+		scanner, err := NewScanner(testOptions())
+		require.NoError(t, err)
+
+		var results []Result
+		for result, err := range scanner.Stream(context.Background(), site.URL+"/") {
+			require.NoError(t, err)
+			results = append(results, result)
+		}
+		assert.ElementsMatch(t, []string{site.URL + "/about", site.URL + "/missing", site.URL + "/"}, targets(results))
+	})
+
+	t.Run("Stops the scan when the loop breaks", func(t *testing.T) {
+		scanner, err := NewScanner(testOptions())
+		require.NoError(t, err)
+
+		count := 0
+		for _, err := range scanner.Stream(context.Background(), site.URL+"/") {
+			require.NoError(t, err)
+			count++
+			break
+		}
+		assert.Equal(t, 1, count)
+
+		// The scanner is free for another scan
+		results, err := scanner.Scan(context.Background(), site.URL+"/")
+		require.NoError(t, err)
+		assert.Len(t, results, 3)
+	})
+
+	t.Run("Yields the error of the scan last", func(t *testing.T) {
+		scanner, err := NewScanner(testOptions())
+		require.NoError(t, err)
+
+		var lastErr error
+		for _, err := range scanner.Stream(context.Background(), "http://127.0.0.1:1/") {
+			lastErr = err
+		}
+		assert.Error(t, lastErr)
+	})
+}
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/DrakkarStorm/deadlinkr/model"
+)
+
+// changeLabels describes each change of a link compared to a baseline report
+var changeLabels = map[string]string{
+	model.ChangeNewlyBroken: "newly broken",
+	model.ChangeFixed:       "fixed",
+	model.ChangeStillBroken: "still broken",
+	model.ChangeNewOK:       "new and OK",
+}
+
+// changeHeadings titles the groups of changed links in the console output
+var changeHeadings = map[string]string{
+	model.ChangeNewlyBroken: "Newly broken links:",
+	model.ChangeFixed:       "Fixed links:",
+	model.ChangeStillBroken: "Still broken links:",
+	model.ChangeNewOK:       "New working links:",
+}
+
+// changeOrder is the order in which changes are displayed
+var changeOrder = []string{model.ChangeNewlyBroken, model.ChangeFixed, model.ChangeStillBroken, model.ChangeNewOK}
+
+// LoadReport reads the link results of a JSON report written by --format json
+func LoadReport(path string) ([]model.LinkResult, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading report %s: %w", path, err)
+	}
+
+	results := []model.LinkResult{}
+	if err := json.Unmarshal(content, &results); err != nil {
+		return nil, fmt.Errorf("parsing report %s: expected a JSON report: %w", path, err)
+	}
+	return results, nil
+}
+
+// DiffResults compares link results to a baseline, returning the current results with their change set.
+// Links are matched by source page and target URL. Links working in both reports keep an empty change.
+// Links broken in the baseline and no longer found are fixed by their removal: they are appended with
+// the fixed change and no status, while working links no longer found are not reported.
+func DiffResults(baseline, current []model.LinkResult) []model.LinkResult {
+	baselineBroken := make(map[string]bool, len(baseline))
+	for _, result := range baseline {
+		key := diffKey(result)
+		baselineBroken[key] = baselineBroken[key] || FailureCategory(result) != ""
+	}
+
+	found := make(map[string]bool, len(current))
+	diffed := make([]model.LinkResult, 0, len(current))
+	for _, result := range current {
+		key := diffKey(result)
+		wasBroken, known := baselineBroken[key]
+		isBroken := FailureCategory(result) != ""
+		found[key] = true
+
+		switch {
+		case isBroken && wasBroken:
+			result.Change = model.ChangeStillBroken
+		case isBroken:
+			result.Change = model.ChangeNewlyBroken
+		case wasBroken:
+			result.Change = model.ChangeFixed
+		case !known:
+			result.Change = model.ChangeNewOK
+		default:
+			result.Change = ""
+		}
+		diffed = append(diffed, result)
+	}
+
+	for _, result := range baseline {
+		key := diffKey(result)
+		if found[key] || !baselineBroken[key] {
+			continue
+		}
+		found[key] = true
+		diffed = append(diffed, removedLink(result))
+	}
+	return diffed
+}
+
+// removedLink returns a link broken in the baseline and no longer found, keeping where it was found
+func removedLink(result model.LinkResult) model.LinkResult {
+	return model.LinkResult{
+		SourceURL:  result.SourceURL,
+		TargetURL:  result.TargetURL,
+		IsExternal: result.IsExternal,
+		Element:    result.Element,
+		Line:       result.Line,
+		Column:     result.Column,
+		Snippet:    result.Snippet,
+		Change:     model.ChangeFixed,
+	}
+}
+
+// isRemovedLink checks whether a diffed link is a baseline link no longer found, see DiffResults
+func isRemovedLink(result model.LinkResult) bool {
+	return result.Change == model.ChangeFixed && result.Status == 0 && result.Error == ""
+}
+
+// diffKey identifies a link across reports
+func diffKey(result model.LinkResult) string {
+	return result.SourceURL + " " + result.TargetURL
+}
+
+// DisplayDiff displays the links that changed since the baseline report, grouped by change.
+// New working links are listed when showAll is set.
+func DisplayDiff(results []model.LinkResult, showAll bool) {
+	byChange := make(map[string][]model.LinkResult)
+	for _, result := range results {
+		if result.Change != "" {
+			byChange[result.Change] = append(byChange[result.Change], result)
+		}
+	}
+
+	fmt.Printf("\nChanges since baseline: %d newly broken, %d fixed, %d still broken, %d new and OK\n",
+		len(byChange[model.ChangeNewlyBroken]), len(byChange[model.ChangeFixed]),
+		len(byChange[model.ChangeStillBroken]), len(byChange[model.ChangeNewOK]))
+
+	for _, change := range changeOrder {
+		// New working links are only counted unless all links are shown
+		if len(byChange[change]) == 0 || change == model.ChangeNewOK && !showAll {
+			continue
+		}
+
+		fmt.Printf("\n%s\n", changeHeadings[change])
+		for _, result := range byChange[change] {
+			if isRemovedLink(result) {
+				fmt.Printf("- %s (from %s): Removed\n", result.TargetURL, formatSourceLocation(result))
+			} else if result.Error != "" {
+				fmt.Printf("- %s (from %s): Error: %s\n", result.TargetURL, formatSourceLocation(result), result.Error)
+			} else {
+				fmt.Printf("- %s (from %s): Status: %d\n", result.TargetURL, formatSourceLocation(result), result.Status)
+			}
+		}
+	}
+}
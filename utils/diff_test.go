@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDiffResults tests the classification of links against a baseline report
+func TestDiffResults(t *testing.T) {
+	baseline := []model.LinkResult{
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/regressed", Status: 200},
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/repaired", Status: 404},
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/legacy", Status: 500},
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/stable", Status: 200},
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/removed", Status: 404},
+	}
+	current := []model.LinkResult{
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/regressed", Status: 404},
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/repaired", Status: 200},
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/legacy", Error: "i/o timeout"},
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/stable", Status: 200},
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/added", Status: 200},
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/added-broken", Status: 404},
+		{SourceURL: SOURCE_URL + "/other", TargetURL: SOURCE_URL + "/legacy", Status: 500},
+	}
+
+	diffed := DiffResults(baseline, current)
+	changes := make([]string, 0, len(diffed))
+	for _, result := range diffed {
+		changes = append(changes, result.Change)
+	}
+
+	assert.Equal(t, []string{
+		model.ChangeNewlyBroken,
+		model.ChangeFixed,
+		model.ChangeStillBroken,
+		"",
+		model.ChangeNewOK,
+		model.ChangeNewlyBroken,
+		model.ChangeNewlyBroken, // The same target linked from another page is a new link
+		model.ChangeFixed,       // Removing a broken link fixes it
+	}, changes)
+
+	removed := diffed[len(diffed)-1]
+	assert.Equal(t, SOURCE_URL+"/removed", removed.TargetURL)
+	assert.Zero(t, removed.Status, "removed links have no status")
+	assert.Empty(t, FailureCategory(removed))
+
+	// The current results are left untouched
+	assert.Empty(t, current[0].Change)
+
+	t.Run("Only regressions fail with --fail-on-new-only", func(t *testing.T) {
+		policy, err := NewFailurePolicy(DefaultFailOn, 0, true)
+		require.NoError(t, err)
+		assert.Equal(t, 4, policy.CountFailures(diffed))
+
+		policy.SetNewOnly(true)
+		assert.Equal(t, 3, policy.CountFailures(diffed))
+	})
+}
+
+// TestLoadReport tests reading a JSON report back
+func TestLoadReport(t *testing.T) {
+	teardown := setupTest()
+	defer teardown()
+	t.Chdir(t.TempDir())
+
+	links := []model.LinkResult{
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/missing", Status: 404, Line: 3, Column: 5},
+	}
+	ExportResults("json", links, ReportOptions{})
+
+	results, err := LoadReport("deadlinkr-report.json")
+	require.NoError(t, err)
+	assert.Equal(t, links, results)
+
+	require.NoError(t, os.WriteFile("report.csv", []byte("Source URL,Target URL\n"), 0o644))
+	_, err = LoadReport("report.csv")
+	assert.ErrorContains(t, err, "expected a JSON report")
+
+	_, err = LoadReport(filepath.Join("missing", "report.json"))
+	assert.Error(t, err)
+}
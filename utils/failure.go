@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DrakkarStorm/deadlinkr/model"
+)
+
+// Failure categories accepted by --fail-on
+const (
+	FailureCategory4xx      = "4xx"
+	FailureCategory5xx      = "5xx"
+	FailureCategoryTimeout  = "timeout"
+	FailureCategoryError    = "error"    // Network and content errors other than timeouts
+	FailureCategoryAnchor   = "anchor"   // Missing #fragment targets
+	FailureCategorySoft404  = "soft404"  // Pages answering 200 with a "not found" content
+	FailureCategoryRedirect = "redirect" // Redirect problems reported with --flag-redirects
+)
+
+// DefaultFailOn are the failure categories failing a run by default: every broken link
+var DefaultFailOn = []string{
+	FailureCategory4xx,
+	FailureCategory5xx,
+	FailureCategoryTimeout,
+	FailureCategoryError,
+	FailureCategoryAnchor,
+	FailureCategorySoft404,
+}
+
+// failureCategories are all the categories accepted by --fail-on
+var failureCategories = append(append([]string{}, DefaultFailOn...), FailureCategoryRedirect)
+
+// FailurePolicy decides which link results count as failures and whether they fail the run
+type FailurePolicy struct {
+	categories      map[string]bool
+	maxBroken       int
+	includeExternal bool
+	newOnly         bool
+}
+
+// NewFailurePolicy creates a FailurePolicy from the --fail-on, --max-broken and --fail-on-external settings
+func NewFailurePolicy(failOn []string, maxBroken int, includeExternal bool) (*FailurePolicy, error) {
+	if maxBroken < 0 {
+		return nil, fmt.Errorf("max broken must not be negative, got %d", maxBroken)
+	}
+
+	categories := make(map[string]bool)
+	for _, category := range failOn {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if category == "" {
+			continue
+		}
+		known := false
+		for _, knownCategory := range failureCategories {
+			if category == knownCategory {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown failure category %q (expected one of %s)", category, strings.Join(failureCategories, ", "))
+		}
+		categories[category] = true
+	}
+
+	return &FailurePolicy{
+		categories:      categories,
+		maxBroken:       maxBroken,
+		includeExternal: includeExternal,
+	}, nil
+}
+
+// FailureCategory returns the failure category of a link result, or "" when the link works
+func FailureCategory(result model.LinkResult) string {
+	switch {
+	case result.FailureType == model.FailureMissingAnchor:
+		return FailureCategoryAnchor
+	case result.FailureType == model.FailureSoft404:
+		return FailureCategorySoft404
+	case result.Status >= 500:
+		return FailureCategory5xx
+	case result.Status >= 400:
+		return FailureCategory4xx
+	case result.Error != "" && isTimeoutError(result.Error):
+		return FailureCategoryTimeout
+	case result.Error != "":
+		return FailureCategoryError
+	}
+	return ""
+}
+
+// IsFailure checks whether a link result counts as a failure
+func (fp *FailurePolicy) IsFailure(result model.LinkResult) bool {
+	if result.IsExternal && !fp.includeExternal {
+		return false
+	}
+	if fp.newOnly && result.Change != model.ChangeNewlyBroken {
+		return false
+	}
+	category := FailureCategory(result)
+	if category == "" && len(result.RedirectIssues) > 0 {
+		// Flagged redirects are warnings unless the redirect category is selected
+		category = FailureCategoryRedirect
+	}
+	return category != "" && fp.categories[category]
+}
+
+// SetNewOnly counts only the links newly broken since a baseline report as failures, see DiffResults
+func (fp *FailurePolicy) SetNewOnly(newOnly bool) {
+	fp.newOnly = newOnly
+}
+
+// CountFailures counts the link results that count as failures
+func (fp *FailurePolicy) CountFailures(results []model.LinkResult) int {
+	count := 0
+	for _, result := range results {
+		if fp.IsFailure(result) {
+			count++
+		}
+	}
+	return count
+}
+
+// Exceeded checks whether the results fail the run, returning the number of failures
+func (fp *FailurePolicy) Exceeded(results []model.LinkResult) (int, bool) {
+	failures := fp.CountFailures(results)
+	return failures, fp.ExceedsMaxBroken(failures)
+}
+
+// ExceedsMaxBroken checks whether a number of failures, e.g. counted by a ResultStream, fails the run
+func (fp *FailurePolicy) ExceedsMaxBroken(failures int) bool {
+	return failures > fp.maxBroken
+}
+
+// isTimeoutError checks whether a link check error message reports a timeout
+func isTimeoutError(message string) bool {
+	message = strings.ToLower(message)
+	return strings.Contains(message, "timeout") || strings.Contains(message, "deadline exceeded")
+}
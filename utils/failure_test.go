@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFailureCategory tests the classification of link results
+func TestFailureCategory(t *testing.T) {
+	testCases := []struct {
+		name     string
+		result   model.LinkResult
+		expected string
+	}{
+		{"Working link", model.LinkResult{Status: 200}, ""},
+		{"Redirect", model.LinkResult{Status: 301}, ""},
+		{"Not found", model.LinkResult{Status: 404}, FailureCategory4xx},
+		{"Server error", model.LinkResult{Status: 503}, FailureCategory5xx},
+		{"Client timeout", model.LinkResult{Error: `Get "https://example.com": context deadline exceeded (Client.Timeout exceeded while awaiting headers)`}, FailureCategoryTimeout},
+		{"Dial timeout", model.LinkResult{Error: "dial tcp 10.0.0.1:443: i/o timeout"}, FailureCategoryTimeout},
+		{"Connection refused", model.LinkResult{Error: "dial tcp 127.0.0.1:1: connect: connection refused"}, FailureCategoryError},
+		{"Empty body", model.LinkResult{Status: 200, Error: "The response body is empty"}, FailureCategoryError},
+		{"Missing anchor", model.LinkResult{Status: 200, Error: "missing anchor #intro", FailureType: model.FailureMissingAnchor}, FailureCategoryAnchor},
+		{"Soft 404", model.LinkResult{Status: 200, Error: "soft 404: page contains \"not found\"", FailureType: model.FailureSoft404}, FailureCategorySoft404},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, FailureCategory(tc.result))
+		})
+	}
+}
+
+// TestFailurePolicy tests the --fail-on, --max-broken and --fail-on-external thresholds
+func TestFailurePolicy(t *testing.T) {
+	results := []model.LinkResult{
+		{TargetURL: "https://example.com/ok", Status: 200},
+		{TargetURL: "https://example.com/missing", Status: 404},
+		{TargetURL: "https://example.com/down", Status: 500},
+		{TargetURL: "https://external.com/missing", Status: 404, IsExternal: true},
+		{TargetURL: "https://slow.com/", Error: "i/o timeout", IsExternal: true},
+	}
+
+	t.Run("Default categories count every broken link", func(t *testing.T) {
+		policy, err := NewFailurePolicy(DefaultFailOn, 0, true)
+		require.NoError(t, err)
+
+		failures, exceeded := policy.Exceeded(results)
+		assert.Equal(t, 4, failures)
+		assert.True(t, exceeded)
+	})
+
+	t.Run("Only selected categories count", func(t *testing.T) {
+		policy, err := NewFailurePolicy([]string{"5XX", " timeout "}, 0, true)
+		require.NoError(t, err)
+		assert.Equal(t, 2, policy.CountFailures(results))
+	})
+
+	t.Run("External links can be ignored", func(t *testing.T) {
+		policy, err := NewFailurePolicy(DefaultFailOn, 0, false)
+		require.NoError(t, err)
+		assert.Equal(t, 2, policy.CountFailures(results))
+	})
+
+	t.Run("Failures up to max broken are tolerated", func(t *testing.T) {
+		policy, err := NewFailurePolicy(DefaultFailOn, 4, true)
+		require.NoError(t, err)
+
+		_, exceeded := policy.Exceeded(results)
+		assert.False(t, exceeded)
+	})
+
+	t.Run("No categories never fail", func(t *testing.T) {
+		policy, err := NewFailurePolicy([]string{}, 0, true)
+		require.NoError(t, err)
+
+		_, exceeded := policy.Exceeded(results)
+		assert.False(t, exceeded)
+	})
+
+	t.Run("Flagged redirects fail only with the redirect category", func(t *testing.T) {
+		redirected := []model.LinkResult{
+			{TargetURL: "https://example.com/old", Status: 200, RedirectIssues: []string{model.RedirectIssuePermanent}},
+		}
+
+		policy, err := NewFailurePolicy(DefaultFailOn, 0, true)
+		require.NoError(t, err)
+		assert.Equal(t, 0, policy.CountFailures(redirected))
+
+		policy, err = NewFailurePolicy([]string{"4xx", "redirect"}, 0, true)
+		require.NoError(t, err)
+		assert.Equal(t, 1, policy.CountFailures(redirected))
+	})
+
+	t.Run("Invalid settings are rejected", func(t *testing.T) {
+		_, err := NewFailurePolicy([]string{"4xx", "3xx"}, 0, true)
+		assert.Error(t, err)
+
+		_, err = NewFailurePolicy(DefaultFailOn, -1, true)
+		assert.Error(t, err)
+	})
+}
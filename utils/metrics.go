@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+)
+
+// StartMetricsServer exposes metrics on addr under /metrics, until the returned function is called
+func StartMetricsServer(addr string, metrics http.Handler) (func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics)
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("Metrics server stopped: %s", err)
+		}
+	}()
+	logger.Infof("Metrics available on http://%s/metrics", listener.Addr())
+
+	return func() { _ = server.Close() }, nil
+}
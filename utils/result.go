@@ -4,20 +4,31 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"html"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/DrakkarStorm/deadlinkr/logger"
 	"github.com/DrakkarStorm/deadlinkr/model"
 )
 
+// ReportOptions selects the results written to reports and the file they are written to
+type ReportOptions struct {
+	Output       string // Report file, deadlinkr-report.<format> when empty
+	ShowAll      bool   // Include working links in HTML reports and diffs
+	OnlyInternal bool   // Leave external links out of reports
+	OnlyExternal bool   // Leave internal links out of reports
+}
+
 // DisplayResults displays the results of the link check.
-// example: DisplayResults() -> "Broken links: 2"
-func DisplayResults() {
+// example: DisplayResults(results) -> "Broken links: 2"
+func DisplayResults(results []model.LinkResult) {
 	brokenLinks := []model.LinkResult{}
 
-	for _, result := range model.Results {
+	for _, result := range results {
 		if result.Status >= 400 || result.Error != "" {
 			brokenLinks = append(brokenLinks, result)
 		}
@@ -25,21 +36,98 @@ func DisplayResults() {
 
 	if len(brokenLinks) == 0 {
 		fmt.Println("No broken links found!")
-		return
+	} else {
+		fmt.Println("\nBroken links:")
+		fmt.Println("=============")
+
+		for _, link := range brokenLinks {
+			printBrokenLink(os.Stdout, link)
+		}
 	}
 
-	fmt.Println("\nBroken links:")
-	fmt.Println("=============")
+	displayRedirectWarnings(results)
+}
 
-	for _, link := range brokenLinks {
-		if link.Error != "" {
-			fmt.Printf("- %s (from %s): Error: %s\n", link.TargetURL, link.SourceURL, link.Error)
-		} else {
-			fmt.Printf("- %s (from %s): Status: %d\n", link.TargetURL, link.SourceURL, link.Status)
+// printBrokenLink prints a broken link, with the markup holding it when known
+func printBrokenLink(w io.Writer, link model.LinkResult) {
+	if link.Error != "" {
+		_, _ = fmt.Fprintf(w, "- %s (from %s): Error: %s\n", link.TargetURL, formatSourceLocation(link), link.Error)
+	} else {
+		_, _ = fmt.Fprintf(w, "- %s (from %s): Status: %d\n", link.TargetURL, formatSourceLocation(link), link.Status)
+	}
+	if link.Snippet != "" {
+		_, _ = fmt.Fprintf(w, "  %s\n", link.Snippet)
+	}
+}
+
+// displayRedirectWarnings displays the working links flagged by --flag-redirects
+func displayRedirectWarnings(results []model.LinkResult) {
+	printedHeader := false
+	for _, result := range results {
+		if len(result.RedirectIssues) == 0 || result.Status >= 400 || result.Error != "" {
+			continue
+		}
+
+		if !printedHeader {
+			fmt.Println("\nRedirect warnings:")
+			fmt.Println("==================")
+			printedHeader = true
 		}
+		printRedirectWarnings(os.Stdout, result)
+	}
+}
+
+// printRedirectWarnings prints the redirect issues flagged on a link
+func printRedirectWarnings(w io.Writer, result model.LinkResult) {
+	for _, issue := range result.RedirectIssues {
+		_, _ = fmt.Fprintf(w, "- %s (from %s): %s\n", result.TargetURL, formatSourceLocation(result), describeRedirectIssue(result, issue))
 	}
 }
 
+// describeRedirectIssue explains a redirect issue flagged on a link
+func describeRedirectIssue(result model.LinkResult, issue string) string {
+	finalURL := ""
+	if len(result.Redirects) > 0 {
+		finalURL = result.Redirects[len(result.Redirects)-1].Location
+	}
+
+	switch issue {
+	case model.RedirectIssuePermanent:
+		return fmt.Sprintf("permanently redirects to %s, the link should be updated", finalURL)
+	case model.RedirectIssueLongChain:
+		return fmt.Sprintf("goes through %d redirects", len(result.Redirects))
+	case model.RedirectIssueDowngrade:
+		return "redirects from https to http"
+	case model.RedirectIssueLogin:
+		return fmt.Sprintf("redirects to a login page (%s)", finalURL)
+	case model.RedirectIssueLoop:
+		return "redirect loop"
+	}
+	return issue
+}
+
+// formatSourceLocation formats the page a link was found on, with its position when known,
+// e.g. "https://a.com/ line 12, column 5"
+func formatSourceLocation(result model.LinkResult) string {
+	if result.Line == 0 {
+		return result.SourceURL
+	}
+	return fmt.Sprintf("%s line %d, column %d", result.SourceURL, result.Line, result.Column)
+}
+
+// formatRedirectChain formats the redirects of a link, e.g. "301 http://a.com/ -> https://a.com/"
+func formatRedirectChain(chain []model.RedirectHop) string {
+	if len(chain) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(chain)+1)
+	for _, hop := range chain {
+		parts = append(parts, fmt.Sprintf("%d %s", hop.Status, hop.URL))
+	}
+	parts = append(parts, chain[len(chain)-1].Location)
+	return strings.Join(parts, " -> ")
+}
+
 // DetectFormatFromOutput detects the format from the output file extension
 func DetectFormatFromOutput(outputPath string) string {
 	if outputPath == "" {
@@ -52,19 +140,25 @@ func DetectFormatFromOutput(outputPath string) string {
 		return "csv"
 	case ".json":
 		return "json"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
 	case ".html", ".htm":
 		return "html"
+	case ".xml":
+		return "junit"
+	case ".sarif":
+		return "sarif"
 	default:
 		return ""
 	}
 }
 
-// ExportResults exports the results of the link check to a file.
+// ExportResults exports the results of the link check to a file, or to the standard output for "-".
 // Auto-detects format from output file extension if format is empty
-func ExportResults(format string) {
+func ExportResults(format string, results []model.LinkResult, options ReportOptions) error {
 	// Auto-detect format from output file extension if not specified
-	if format == "" && model.Output != "" {
-		format = DetectFormatFromOutput(model.Output)
+	if format == "" && options.Output != "" {
+		format = DetectFormatFromOutput(options.Output)
 		if format != "" {
 			logger.Debugf("Auto-detected format '%s' from output file extension", format)
 		}
@@ -72,40 +166,81 @@ func ExportResults(format string) {
 	
 	// If still no format, default to displaying results
 	if format == "" {
-		DisplayResults()
-		return
+		DisplayResults(results)
+		return nil
 	}
 	
+	// "-" writes the report to the standard output instead of a file
+	if options.Output == "-" && !strings.EqualFold(format, "text") {
+		return WriteReport(os.Stdout, format, results, options)
+	}
+
 	switch strings.ToLower(format) {
 	case "csv":
-		exportToCSV()
+		return exportToCSV(results, options)
 	case "json":
-		exportToJSON()
+		return exportToJSON(results, options)
+	case "ndjson":
+		return exportToNDJSON(results, options)
 	case "html":
-		exportToHTML()
+		return exportToHTML(results, options)
+	case "junit":
+		return exportToJUnit(results, options)
+	case "sarif":
+		return exportToSARIF(results, options)
+	case "text":
+		DisplayResults(results)
+		return nil
 	default:
-		fmt.Printf("Unsupported format: %s. Use csv, json, or html.\n", format)
+		return fmt.Errorf("unsupported format %s: use csv, json, ndjson, html, junit, sarif, or text", format)
 	}
 }
 
-// exportToCSV exports the results to a CSV file.
-func exportToCSV() {
-	filename := "deadlinkr-report.csv"
-	if model.Output != "" {
-		filename = model.Output
+// reportContentTypes maps each export format to the media type of its reports
+var reportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"json":   "application/json",
+	"ndjson": "application/x-ndjson",
+	"html":   "text/html; charset=utf-8",
+	"junit":  "application/xml",
+	"sarif":  "application/sarif+json",
+}
+
+// ReportContentType returns the media type of the reports of an export format, or "" for an unknown format
+func ReportContentType(format string) string {
+	return reportContentTypes[strings.ToLower(format)]
+}
+
+// WriteReport writes results as a report in the given export format. The output file of the options is ignored.
+func WriteReport(w io.Writer, format string, results []model.LinkResult, options ReportOptions) error {
+	switch strings.ToLower(format) {
+	case "csv":
+		return writeCSV(w, results, options)
+	case "json":
+		return writeJSON(w, results)
+	case "ndjson":
+		return writeNDJSON(w, results, options)
+	case "html":
+		return writeHTML(w, results, options)
+	case "junit":
+		return writeJUnit(w, results, options)
+	case "sarif":
+		return writeSARIF(w, results, options)
+	default:
+		return fmt.Errorf("unsupported format %s: use csv, json, ndjson, html, junit, or sarif", format)
 	}
+}
 
-	// Create a root scoped to current working directory to prevent directory traversal
+// createReportFile creates a report file scoped to the current working directory to prevent directory traversal
+func createReportFile(filename string) (*os.File, error) {
 	cwd, err := os.Getwd()
 	if err != nil {
-		logger.Errorf("Error getting working directory: %s\n", err)
-		return
+		return nil, fmt.Errorf("error getting working directory: %w", err)
 	}
 
 	root, err := os.OpenRoot(cwd)
 	if err != nil {
-		logger.Errorf("Error creating root scope: %s\n", err)
-		return
+		return nil, fmt.Errorf("error creating root scope: %w", err)
 	}
 	defer func() {
 		if err := root.Close(); err != nil {
@@ -113,135 +248,195 @@ func exportToCSV() {
 		}
 	}()
 
-	file, err := root.Create(filename)
+	return root.Create(filename)
+}
+
+// isExcludedFromReport checks whether --only-internal or --only-external filters a result out of reports
+func isExcludedFromReport(result model.LinkResult, options ReportOptions) bool {
+	return options.OnlyInternal && result.IsExternal || options.OnlyExternal && !result.IsExternal
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// exportToCSV exports the results to a CSV file.
+func exportToCSV(results []model.LinkResult, options ReportOptions) error {
+	filename := "deadlinkr-report.csv"
+	if options.Output != "" {
+		filename = options.Output
+	}
+
+	// Create the file in a root scoped to current working directory to prevent directory traversal
+	file, err := createReportFile(filename)
 	if err != nil {
-		logger.Errorf("Error creating CSV file: %s\n", err)
-		return
+		return fmt.Errorf("error creating CSV file: %w", err)
 	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			logger.Errorf("Error closing CSV file: %s\n", err)
-		}
-	}()
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	if err := writeCSV(file, results, options); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("error writing CSV report: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error closing CSV file: %w", err)
+	}
+
+	logger.Debugf("Report exported to %s", filename)
+	return nil
+}
+
+// writeCSV writes the results as a CSV report
+func writeCSV(w io.Writer, results []model.LinkResult, options ReportOptions) error {
+	writer := csv.NewWriter(w)
 
 	// Write header
-	if err := writer.Write([]string{"Source URL", "Target URL", "Status", "Error", "Is External"}); err != nil {
-		logger.Errorf("Error writing CSV header: %s\n", err)
-		return
+	if err := writer.Write(csvHeader); err != nil {
+		return fmt.Errorf("writing CSV header: %w", err)
 	}
 
 	// Write data
-	for _, result := range model.Results {
-		if (model.OnlyInternal && result.IsExternal || model.DisplayOnlyExternal && !result.IsExternal) {
+	for _, result := range results {
+		if isExcludedFromReport(result, options) {
 			continue
 		}
 
-		isExternalStr := "false"
-		if result.IsExternal {
-			isExternalStr = "true"
+		if err := writer.Write(csvRecord(result)); err != nil {
+			return fmt.Errorf("writing CSV row: %w", err)
 		}
+	}
 
-		if err := writer.Write([]string{
-			result.SourceURL,
-			result.TargetURL,
-			fmt.Sprintf("%d", result.Status),
-			result.Error,
-			isExternalStr,
-		}); err != nil {
-			logger.Errorf("Error writing CSV row: %s\n", err)
-			return
-		}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvHeader is the header row of CSV reports
+var csvHeader = []string{"Source URL", "Target URL", "Status", "Error", "Is External", "Element", "Redirects", "Redirect Issues", "Line", "Column", "Snippet", "Change"}
 
+// csvRecord returns the CSV row of a result
+func csvRecord(result model.LinkResult) []string {
+	isExternalStr := "false"
+	if result.IsExternal {
+		isExternalStr = "true"
 	}
 
-	logger.Debugf("Report exported to deadlinkr-report.csv")
+	return []string{
+		result.SourceURL,
+		result.TargetURL,
+		fmt.Sprintf("%d", result.Status),
+		result.Error,
+		isExternalStr,
+		result.Element,
+		formatRedirectChain(result.Redirects),
+		strings.Join(result.RedirectIssues, ";"),
+		formatPosition(result.Line),
+		formatPosition(result.Column),
+		result.Snippet,
+		result.Change,
+	}
 }
 
 // exportToJSON exports the results to a JSON file.
-func exportToJSON() {
+func exportToJSON(results []model.LinkResult, options ReportOptions) error {
 	filename := "deadlinkr-report.json"
-	if model.Output != "" {
-		filename = model.Output
+	if options.Output != "" {
+		filename = options.Output
 	}
 
-	// Create a root scoped to current working directory to prevent directory traversal
-	cwd, err := os.Getwd()
+	// Create the file in a root scoped to current working directory to prevent directory traversal
+	file, err := createReportFile(filename)
 	if err != nil {
-		logger.Errorf("Error getting working directory: %s\n", err)
-		return
+		return fmt.Errorf("error creating JSON file: %w", err)
 	}
 
-	root, err := os.OpenRoot(cwd)
-	if err != nil {
-		logger.Errorf("Error creating root scope: %s\n", err)
-		return
+	if err := writeJSON(file, results); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("error writing JSON report: %w", err)
 	}
-	defer func() {
-		if err := root.Close(); err != nil {
-			logger.Errorf("Error closing root scope: %s\n", err)
-		}
-	}()
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error closing JSON file: %w", err)
+	}
+
+	logger.Debugf("Report exported to %s", filename)
+	return nil
+}
+
+// writeJSON writes the results as a JSON report
+func writeJSON(w io.Writer, results []model.LinkResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	// Keep markup snippets readable
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(results)
+}
 
-	file, err := root.Create(filename)
+// exportToNDJSON exports the results to a newline-delimited JSON file.
+func exportToNDJSON(results []model.LinkResult, options ReportOptions) error {
+	stream, err := OpenResultStream("ndjson", options, nil)
 	if err != nil {
-		logger.Errorf("Error creating JSON file: %s\n", err)
-		return
+		return err
 	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			logger.Errorf("Error closing JSON file: %s\n", err)
-		}
-	}()
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(model.Results); err != nil {
-		logger.Errorf("Error encoding JSON: %s\n", err)
-		return
+	for _, result := range results {
+		if err := stream.Write(result); err != nil {
+			_ = stream.Close()
+			return fmt.Errorf("error encoding NDJSON: %w", err)
+		}
+	}
+	if err := stream.Close(); err != nil {
+		return fmt.Errorf("error closing NDJSON file: %w", err)
 	}
 
-	logger.Debugf("Report exported to deadlinkr-report.json")
+	logger.Debugf("Report exported to deadlinkr-report.ndjson")
+	return nil
+}
+
+// writeNDJSON writes the results as newline-delimited JSON, one result per line
+func writeNDJSON(w io.Writer, results []model.LinkResult, options ReportOptions) error {
+	sink := newNDJSONSink(w)
+	for _, result := range results {
+		if isExcludedFromReport(result, options) {
+			continue
+		}
+		if err := sink.Write(result); err != nil {
+			return err
+		}
+	}
+	return sink.Close()
 }
 
 // exportToHTML exports the results to an HTML file.
-func exportToHTML() {
+func exportToHTML(results []model.LinkResult, options ReportOptions) error {
 	filename := "deadlinkr-report.html"
-	if model.Output != "" {
-		filename = model.Output
+	if options.Output != "" {
+		filename = options.Output
 	}
 
-	// Create a root scoped to current working directory to prevent directory traversal
-	cwd, err := os.Getwd()
+	// Create the file in a root scoped to current working directory to prevent directory traversal
+	file, err := createReportFile(filename)
 	if err != nil {
-		logger.Errorf("Error getting working directory: %s\n", err)
-		return
+		return fmt.Errorf("error creating HTML file: %w", err)
 	}
 
-	root, err := os.OpenRoot(cwd)
-	if err != nil {
-		logger.Errorf("Error creating root scope: %s\n", err)
-		return
+	if err := writeHTML(file, results, options); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("error writing HTML report: %w", err)
 	}
-	defer func() {
-		if err := root.Close(); err != nil {
-			logger.Errorf("Error closing root scope: %s\n", err)
-		}
-	}()
-
-	file, err := root.Create(filename)
-	if err != nil {
-		logger.Errorf("Error creating HTML file: %s\n", err)
-		return
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error closing HTML file: %w", err)
 	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			logger.Errorf("Error closing HTML file: %s\n", err)
-		}
-	}()
 
+	logger.Debugf("Report exported to %s", filename)
+	return nil
+}
+
+// writeHTML writes the results as an HTML report
+func writeHTML(w io.Writer, results []model.LinkResult, options ReportOptions) error {
 	// Create simple HTML report
 	html := `<!DOCTYPE html>
 <html>
@@ -261,8 +456,8 @@ func exportToHTML() {
 </head>
 <body>
     <h1>DeadLinkr Report</h1>
-    <p>Total links checked: ` + fmt.Sprintf("%d", len(model.Results)) + `</p>
-    <p>Broken links found: ` + fmt.Sprintf("%d", CountBrokenLinks()) + `</p>
+    <p>Total links checked: ` + fmt.Sprintf("%d", len(results)) + `</p>
+    <p>Broken links found: ` + fmt.Sprintf("%d", CountBrokenLinks(results)) + `</p>
 
     <table>
         <tr>
@@ -271,23 +466,27 @@ func exportToHTML() {
             <th>Status</th>
             <th>Error</th>
             <th>Type</th>
+            <th>Element</th>
+            <th>Redirects</th>
+            <th>Location</th>
+            <th>Change</th>
         </tr>
 `
 
-	for _, result := range model.Results {
-		if (model.OnlyInternal && result.IsExternal || model.DisplayOnlyExternal && !result.IsExternal) {
+	for _, result := range results {
+		if isExcludedFromReport(result, options) {
 			continue
 		}
 
 		rowClass := "good"
 		if result.Status >= 400 || result.Error != "" {
 			rowClass = "error"
-		} else if result.Status >= 300 {
+		} else if result.Status >= 300 || len(result.RedirectIssues) > 0 {
 			rowClass = "warning"
 		}
 
 		if rowClass == "good" {
-			if !model.ShowAll {
+			if !options.ShowAll {
 				continue
 			}
 		}
@@ -300,6 +499,8 @@ func exportToHTML() {
 		statusStr := fmt.Sprintf("%d", result.Status)
 		if result.Status == 0 {
 			statusStr = "Error"
+		} else if result.FailureType == model.FailureSoft404 {
+			statusStr = "Soft 404"
 		}
 
 		html += `        <tr class="` + rowClass + `">
@@ -308,6 +509,10 @@ func exportToHTML() {
             <td>` + statusStr + `</td>
             <td>` + result.Error + `</td>
             <td>` + linkType + `</td>
+            <td>` + result.Element + `</td>
+            <td>` + formatRedirectHTML(result) + `</td>
+            <td>` + formatLocationHTML(result) + `</td>
+            <td>` + changeLabels[result.Change] + `</td>
         </tr>
 `
 	}
@@ -316,10 +521,35 @@ func exportToHTML() {
 </body>
 </html>`
 
-	_, err = file.WriteString(html)
-	if err != nil {
-		logger.Errorf("Error writing to file: %s", err.Error())
-		return
+	_, err := io.WriteString(w, html)
+	return err
+}
+
+// formatPosition formats a line or column for the CSV report, leaving unknown positions empty
+func formatPosition(position int) string {
+	if position == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d", position)
+}
+
+// formatLocationHTML formats the position of a link in its source page and the markup holding it for the HTML report
+func formatLocationHTML(result model.LinkResult) string {
+	if result.Line == 0 {
+		return ""
+	}
+	cell := fmt.Sprintf("line %d, column %d", result.Line, result.Column)
+	if result.Snippet != "" {
+		cell += "<br><code>" + html.EscapeString(result.Snippet) + "</code>"
+	}
+	return cell
+}
+
+// formatRedirectHTML formats the redirects of a link and their issues for the HTML report
+func formatRedirectHTML(result model.LinkResult) string {
+	cell := html.EscapeString(formatRedirectChain(result.Redirects))
+	for _, issue := range result.RedirectIssues {
+		cell += "<br><strong>" + html.EscapeString(describeRedirectIssue(result, issue)) + "</strong>"
 	}
-	logger.Debugf("Report exported to deadlinkr-report.html")
+	return cell
 }
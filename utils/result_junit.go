@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+)
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite groups the links found on one source page
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is a single checked link
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Line      int           `xml:"line,attr,omitempty"` // Line of the link in its source page
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+// junitFailure describes why a link is broken
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// exportToJUnit exports the results to a JUnit XML file.
+// Each source page becomes a testsuite and each link a testcase.
+func exportToJUnit(results []model.LinkResult, options ReportOptions) error {
+	filename := "deadlinkr-report.xml"
+	if options.Output != "" {
+		filename = options.Output
+	}
+
+	// Create the file in a root scoped to current working directory to prevent directory traversal
+	file, err := createReportFile(filename)
+	if err != nil {
+		return fmt.Errorf("error creating JUnit file: %w", err)
+	}
+
+	if err := writeJUnit(file, results, options); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("error writing JUnit report: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error closing JUnit file: %w", err)
+	}
+
+	logger.Debugf("Report exported to %s", filename)
+	return nil
+}
+
+// writeJUnit writes the results as a JUnit XML report, sorted by source page and target URL
+func writeJUnit(w io.Writer, results []model.LinkResult, options ReportOptions) error {
+	suitesBySource := make(map[string]*junitTestSuite)
+	for _, result := range results {
+		if isExcludedFromReport(result, options) {
+			continue
+		}
+
+		suite, exists := suitesBySource[result.SourceURL]
+		if !exists {
+			suite = &junitTestSuite{Name: result.SourceURL}
+			suitesBySource[result.SourceURL] = suite
+		}
+
+		testCase := junitTestCase{
+			ClassName: result.SourceURL,
+			Name:      result.TargetURL,
+			Line:      result.Line,
+		}
+		if category := FailureCategory(result); category != "" {
+			testCase.Failure = &junitFailure{
+				Message: describeFailure(result),
+				Type:    category,
+				Text:    describeLinkOrigin(result),
+			}
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+		suite.Tests++
+	}
+
+	report := junitTestSuites{Name: "deadlinkr"}
+	for _, source := range sortedKeys(suitesBySource) {
+		suite := suitesBySource[source]
+		sort.SliceStable(suite.TestCases, func(i, j int) bool {
+			return suite.TestCases[i].Name < suite.TestCases[j].Name
+		})
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, *suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// describeFailure returns a short description of why a link is broken
+func describeFailure(result model.LinkResult) string {
+	if result.Error != "" {
+		if result.Status > 0 {
+			return fmt.Sprintf("HTTP %d: %s", result.Status, result.Error)
+		}
+		return result.Error
+	}
+	return fmt.Sprintf("HTTP %d", result.Status)
+}
+
+// describeLinkOrigin describes where a link was found, with its position, markup and change when known
+func describeLinkOrigin(result model.LinkResult) string {
+	origin := fmt.Sprintf("%s links to %s", formatSourceLocation(result), result.TargetURL)
+	if result.Snippet != "" {
+		origin += "\n" + result.Snippet
+	}
+	if result.Change != "" {
+		origin += "\nChange since baseline: " + changeLabels[result.Change]
+	}
+	return origin
+}
@@ -0,0 +1,287 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/DrakkarStorm/deadlinkr/internal"
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+)
+
+// sarifRules describes the SARIF rule reported for each failure category
+var sarifRules = map[string]sarifRule{
+	FailureCategory4xx:     {ID: "broken-link", Name: "BrokenLink", ShortDescription: sarifMessage{Text: "Link returns a 4xx client error"}},
+	FailureCategory5xx:     {ID: "server-error", Name: "ServerError", ShortDescription: sarifMessage{Text: "Link returns a 5xx server error"}},
+	FailureCategoryTimeout: {ID: "timeout", Name: "Timeout", ShortDescription: sarifMessage{Text: "Link check timed out"}},
+	FailureCategoryError:   {ID: "link-error", Name: "LinkError", ShortDescription: sarifMessage{Text: "Link could not be checked"}},
+	FailureCategoryAnchor:  {ID: "missing-anchor", Name: "MissingAnchor", ShortDescription: sarifMessage{Text: "Link fragment has no matching anchor on the target page"}},
+	FailureCategorySoft404: {ID: "soft-404", Name: "Soft404", ShortDescription: sarifMessage{Text: "Link returns a page that looks like a not found page"}},
+}
+
+// sarifBaselineStates maps the changes of broken links since a baseline report to SARIF baseline states
+var sarifBaselineStates = map[string]string{
+	model.ChangeNewlyBroken: "new",
+	model.ChangeStillBroken: "unchanged",
+}
+
+// sarifRedirectRules describes the SARIF rule reported for each redirect issue flagged with --flag-redirects
+var sarifRedirectRules = map[string]sarifRule{
+	model.RedirectIssuePermanent: {ID: "permanent-redirect", Name: "PermanentRedirect", ShortDescription: sarifMessage{Text: "Link permanently redirects and should be updated"}},
+	model.RedirectIssueLongChain: {ID: "long-redirect-chain", Name: "LongRedirectChain", ShortDescription: sarifMessage{Text: "Link goes through too many redirects"}},
+	model.RedirectIssueDowngrade: {ID: "protocol-downgrade", Name: "ProtocolDowngrade", ShortDescription: sarifMessage{Text: "Link redirects from https to http"}},
+	model.RedirectIssueLogin:     {ID: "login-redirect", Name: "LoginRedirect", ShortDescription: sarifMessage{Text: "Link redirects to a login page"}},
+}
+
+// sarifLog is the root object of a SARIF 2.1.0 report
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID        string          `json:"ruleId"`
+	Level         string          `json:"level"`
+	Message       sarifMessage    `json:"message"`
+	Locations     []sarifLocation `json:"locations"`
+	BaselineState string          `json:"baselineState,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int           `json:"startLine"`
+	StartColumn int           `json:"startColumn,omitempty"`
+	Snippet     *sarifMessage `json:"snippet,omitempty"`
+}
+
+// exportToSARIF exports the broken links to a SARIF file for code scanning tools.
+// Each broken link becomes a result located at its source page.
+func exportToSARIF(results []model.LinkResult, options ReportOptions) error {
+	filename := "deadlinkr-report.sarif"
+	if options.Output != "" {
+		filename = options.Output
+	}
+
+	// Create the file in a root scoped to current working directory to prevent directory traversal
+	file, err := createReportFile(filename)
+	if err != nil {
+		return fmt.Errorf("error creating SARIF file: %w", err)
+	}
+
+	if err := writeSARIF(file, results, options); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("error writing SARIF report: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error closing SARIF file: %w", err)
+	}
+
+	logger.Debugf("Report exported to %s", filename)
+	return nil
+}
+
+// writeSARIF writes the broken links and flagged redirects as a SARIF 2.1.0 report
+func writeSARIF(w io.Writer, results []model.LinkResult, options ReportOptions) error {
+	locator := newSARIFLocator()
+
+	sarifResults := []sarifResult{}
+	usedRules := make(map[string]sarifRule)
+	for _, result := range results {
+		if isExcludedFromReport(result, options) {
+			continue
+		}
+		locations := []sarifLocation{{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: locator.locate(result.SourceURL),
+				Region:           sarifRegionOf(result),
+			},
+		}}
+
+		category := FailureCategory(result)
+		if category == "" {
+			// Working links flagged by --flag-redirects are reported as warnings
+			for _, issue := range result.RedirectIssues {
+				rule, known := sarifRedirectRules[issue]
+				if !known {
+					continue
+				}
+				usedRules[rule.ID] = rule
+				sarifResults = append(sarifResults, sarifResult{
+					RuleID:    rule.ID,
+					Level:     "warning",
+					Message:   sarifMessage{Text: fmt.Sprintf("Link to %s %s", result.TargetURL, describeRedirectIssue(result, issue))},
+					Locations: locations,
+				})
+			}
+			continue
+		}
+
+		rule := sarifRules[category]
+		usedRules[rule.ID] = rule
+		sarifResults = append(sarifResults, sarifResult{
+			RuleID:        rule.ID,
+			Level:         "error",
+			Message:       sarifMessage{Text: fmt.Sprintf("Broken link to %s (%s)", result.TargetURL, describeFailure(result))},
+			Locations:     locations,
+			BaselineState: sarifBaselineStates[result.Change],
+		})
+	}
+
+	sort.SliceStable(sarifResults, func(i, j int) bool {
+		left, right := sarifResults[i], sarifResults[j]
+		leftURI := left.Locations[0].PhysicalLocation.ArtifactLocation.URI
+		rightURI := right.Locations[0].PhysicalLocation.ArtifactLocation.URI
+		if leftURI != rightURI {
+			return leftURI < rightURI
+		}
+		if leftLine, rightLine := sarifStartLine(left), sarifStartLine(right); leftLine != rightLine {
+			return leftLine < rightLine
+		}
+		return left.Message.Text < right.Message.Text
+	})
+
+	rules := []sarifRule{}
+	for _, id := range sortedKeys(usedRules) {
+		rules = append(rules, usedRules[id])
+	}
+
+	report := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "deadlinkr",
+				InformationURI: "https://github.com/DrakkarStorm/deadlinkr",
+				Rules:          rules,
+			}},
+			Results: sarifResults,
+		}},
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	// Keep markup snippets readable
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(report)
+}
+
+// sarifRegionOf returns the region of a link in its source page, or nil when its position is unknown
+func sarifRegionOf(result model.LinkResult) *sarifRegion {
+	if result.Line == 0 {
+		return nil
+	}
+	region := &sarifRegion{StartLine: result.Line, StartColumn: result.Column}
+	if result.Snippet != "" {
+		region.Snippet = &sarifMessage{Text: result.Snippet}
+	}
+	return region
+}
+
+// sarifStartLine returns the line of a result's first location, 0 when unknown
+func sarifStartLine(result sarifResult) int {
+	region := result.Locations[0].PhysicalLocation.Region
+	if region == nil {
+		return 0
+	}
+	return region.StartLine
+}
+
+// sarifLocator maps source URLs to SARIF artifact locations.
+// In filesystem mode, pages are located by their source file relative to the working directory,
+// like Markdown files, so code scanning tools can annotate the file in the repository.
+type sarifLocator struct {
+	cwd  string
+	site *internal.FileSystemSite
+}
+
+func newSARIFLocator() *sarifLocator {
+	locator := &sarifLocator{}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return locator
+	}
+	site, err := internal.NewFileSystemSite(cwd)
+	if err != nil {
+		return locator
+	}
+	locator.cwd = cwd
+	locator.site = site
+	return locator
+}
+
+// locate returns the artifact location of a source page
+func (sl *sarifLocator) locate(sourceURL string) sarifArtifactLocation {
+	location := sarifArtifactLocation{URI: sourceURL}
+
+	parsed, err := url.Parse(sourceURL)
+	if err != nil {
+		return location
+	}
+
+	// Markdown files are already located by their path relative to the working directory
+	if parsed.Scheme == "" && !filepath.IsAbs(sourceURL) {
+		location.URIBaseID = "%SRCROOT%"
+		return location
+	}
+	if parsed.Scheme != "file" || sl.site == nil {
+		return location
+	}
+
+	filePath, found := sl.site.Resolve(parsed)
+	if !found {
+		return location
+	}
+	relative, err := filepath.Rel(sl.cwd, filePath)
+	if err != nil {
+		return location
+	}
+
+	return sarifArtifactLocation{
+		URI:       filepath.ToSlash(relative),
+		URIBaseID: "%SRCROOT%",
+	}
+}
@@ -0,0 +1,244 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/DrakkarStorm/deadlinkr/internal"
+	"github.com/DrakkarStorm/deadlinkr/model"
+)
+
+// streamingFormats are the export formats written while the scan runs, each result as soon as it is produced
+var streamingFormats = map[string]bool{
+	"csv":    true,
+	"ndjson": true,
+	"text":   true,
+}
+
+// IsStreamingFormat checks whether reports of an export format are written while the scan runs
+func IsStreamingFormat(format string) bool {
+	return streamingFormats[strings.ToLower(format)]
+}
+
+// ResultStream writes the results of a scan to a report in a streaming format as they are produced,
+// counting links, broken links and failures on the way so results need not be kept in memory
+type ResultStream struct {
+	sink     internal.ResultSink
+	file     io.Closer      // Report file, nil when writing to the standard output
+	policy   *FailurePolicy // Optional policy counting failures
+	options  ReportOptions
+	links    int
+	broken   int
+	failures int
+	closed   bool
+	mutex    sync.Mutex
+}
+
+// OpenResultStream creates the report of a streaming format: the output file of the options, deadlinkr-report.<format>
+// when empty, or the standard output for "-" and for the text format without output file
+func OpenResultStream(format string, options ReportOptions, policy *FailurePolicy) (*ResultStream, error) {
+	format = strings.ToLower(format)
+	if !IsStreamingFormat(format) {
+		return nil, fmt.Errorf("format %s cannot be streamed: use csv, ndjson or text", format)
+	}
+
+	stream := &ResultStream{policy: policy, options: options}
+
+	var w io.Writer = os.Stdout
+	output := options.Output
+	if output == "" && format != "text" {
+		output = "deadlinkr-report." + format
+	}
+	if output != "" && output != "-" {
+		// Create the file in a root scoped to current working directory to prevent directory traversal
+		file, err := createReportFile(output)
+		if err != nil {
+			return nil, fmt.Errorf("error creating %s report: %w", format, err)
+		}
+		w = file
+		stream.file = file
+	}
+
+	switch format {
+	case "csv":
+		sink, err := newCSVSink(w)
+		if err != nil {
+			_ = stream.closeFile()
+			return nil, err
+		}
+		stream.sink = sink
+	case "ndjson":
+		stream.sink = newNDJSONSink(w)
+	case "text":
+		stream.sink = newTextSink(w)
+	}
+
+	return stream, nil
+}
+
+// Write counts a result and writes it to the report unless --only-internal or --only-external filters it out
+func (rs *ResultStream) Write(result model.LinkResult) error {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	rs.links++
+	if result.Status >= 400 || result.Error != "" {
+		rs.broken++
+	}
+	if rs.policy != nil && rs.policy.IsFailure(result) {
+		rs.failures++
+	}
+
+	if rs.closed || isExcludedFromReport(result, rs.options) {
+		return nil
+	}
+	return rs.sink.Write(result)
+}
+
+// Close flushes the report and closes its file. Closing a closed stream does nothing.
+func (rs *ResultStream) Close() error {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	if rs.closed {
+		return nil
+	}
+	rs.closed = true
+
+	err := rs.sink.Close()
+	if fileErr := rs.closeFile(); err == nil {
+		err = fileErr
+	}
+	return err
+}
+
+// closeFile closes the report file, if any
+func (rs *ResultStream) closeFile() error {
+	if rs.file == nil {
+		return nil
+	}
+	return rs.file.Close()
+}
+
+// Links returns the number of results written, including those filtered out of the report
+func (rs *ResultStream) Links() int {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	return rs.links
+}
+
+// Broken returns the number of broken links written
+func (rs *ResultStream) Broken() int {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	return rs.broken
+}
+
+// Failures returns the number of results counting as failures under the policy of the stream
+func (rs *ResultStream) Failures() int {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	return rs.failures
+}
+
+// ndjsonSink writes each result as a line of JSON
+type ndjsonSink struct {
+	encoder *json.Encoder
+}
+
+// newNDJSONSink creates a sink writing newline-delimited JSON
+func newNDJSONSink(w io.Writer) *ndjsonSink {
+	encoder := json.NewEncoder(w)
+	// Keep markup snippets readable
+	encoder.SetEscapeHTML(false)
+	return &ndjsonSink{encoder: encoder}
+}
+
+func (ns *ndjsonSink) Write(result model.LinkResult) error {
+	return ns.encoder.Encode(result)
+}
+
+func (ns *ndjsonSink) Close() error {
+	return nil
+}
+
+// csvSink writes each result as a CSV row, after the header
+type csvSink struct {
+	writer *csv.Writer
+}
+
+// newCSVSink creates a sink writing CSV rows, writing the header right away
+func newCSVSink(w io.Writer) (*csvSink, error) {
+	sink := &csvSink{writer: csv.NewWriter(w)}
+	if err := sink.writer.Write(csvHeader); err != nil {
+		return nil, fmt.Errorf("writing CSV header: %w", err)
+	}
+	sink.writer.Flush()
+	return sink, sink.writer.Error()
+}
+
+func (cs *csvSink) Write(result model.LinkResult) error {
+	if err := cs.writer.Write(csvRecord(result)); err != nil {
+		return fmt.Errorf("writing CSV row: %w", err)
+	}
+	cs.writer.Flush()
+	return cs.writer.Error()
+}
+
+func (cs *csvSink) Close() error {
+	cs.writer.Flush()
+	return cs.writer.Error()
+}
+
+// textSink prints broken links as they are found, and the redirect warnings once the scan is done
+type textSink struct {
+	w          io.Writer
+	brokenSeen bool
+	redirects  []model.LinkResult // Working links with redirect issues, printed by Close
+}
+
+// newTextSink creates a sink printing broken links for a human reader
+func newTextSink(w io.Writer) *textSink {
+	return &textSink{w: w}
+}
+
+func (ts *textSink) Write(result model.LinkResult) error {
+	if result.Status < 400 && result.Error == "" {
+		if len(result.RedirectIssues) > 0 {
+			ts.redirects = append(ts.redirects, result)
+		}
+		return nil
+	}
+
+	if !ts.brokenSeen {
+		ts.brokenSeen = true
+		if _, err := fmt.Fprint(ts.w, "\nBroken links:\n=============\n"); err != nil {
+			return err
+		}
+	}
+	printBrokenLink(ts.w, result)
+	return nil
+}
+
+func (ts *textSink) Close() error {
+	if !ts.brokenSeen {
+		if _, err := fmt.Fprintln(ts.w, "No broken links found!"); err != nil {
+			return err
+		}
+	}
+
+	if len(ts.redirects) > 0 {
+		if _, err := fmt.Fprint(ts.w, "\nRedirect warnings:\n==================\n"); err != nil {
+			return err
+		}
+		for _, result := range ts.redirects {
+			printRedirectWarnings(ts.w, result)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestResultStream tests writing reports while the scan runs
+func TestResultStream(t *testing.T) {
+	teardown := setupTest()
+	defer teardown()
+	t.Chdir(t.TempDir())
+
+	results := []model.LinkResult{
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/ok", Status: 200},
+		{SourceURL: SOURCE_URL, TargetURL: "http://broken.com", Status: 404, IsExternal: true},
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/down", Error: "i/o timeout"},
+	}
+
+	t.Run("NDJSON writes one result per line", func(t *testing.T) {
+		policy, err := NewFailurePolicy(DefaultFailOn, 0, false)
+		require.NoError(t, err)
+
+		stream, err := OpenResultStream("ndjson", ReportOptions{}, policy)
+		require.NoError(t, err)
+		for _, result := range results {
+			require.NoError(t, stream.Write(result))
+		}
+		require.NoError(t, stream.Close())
+
+		assert.Equal(t, 3, stream.Links())
+		assert.Equal(t, 2, stream.Broken())
+		// External links do not fail the run with --fail-on-external=false
+		assert.Equal(t, 1, stream.Failures())
+
+		file, err := os.Open("deadlinkr-report.ndjson")
+		require.NoError(t, err)
+		defer func() { _ = file.Close() }()
+
+		var lines []model.LinkResult
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var result model.LinkResult
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &result))
+			lines = append(lines, result)
+		}
+		require.NoError(t, scanner.Err())
+		assert.Equal(t, results, lines)
+	})
+
+	t.Run("CSV writes the header and a row per result", func(t *testing.T) {
+		stream, err := OpenResultStream("csv", ReportOptions{Output: "links.csv"}, nil)
+		require.NoError(t, err)
+		for _, result := range results {
+			require.NoError(t, stream.Write(result))
+		}
+		require.NoError(t, stream.Close())
+
+		file, err := os.Open("links.csv")
+		require.NoError(t, err)
+		defer func() { _ = file.Close() }()
+
+		records, err := csv.NewReader(file).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 4)
+		assert.Equal(t, csvHeader, records[0])
+		assert.Equal(t, "http://broken.com", records[2][1])
+	})
+
+	t.Run("Non streaming formats are rejected", func(t *testing.T) {
+		assert.False(t, IsStreamingFormat("json"))
+		_, err := OpenResultStream("json", ReportOptions{}, nil)
+		assert.Error(t, err)
+	})
+}
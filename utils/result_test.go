@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"encoding/csv"
 	"encoding/json"
+	"encoding/xml"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
-	"sync"
 	"testing"
 
+	"github.com/DrakkarStorm/deadlinkr/internal"
 	"github.com/DrakkarStorm/deadlinkr/logger"
 	"github.com/DrakkarStorm/deadlinkr/model"
 	"github.com/stretchr/testify/assert"
@@ -21,30 +23,9 @@ const SOURCE_URL = "http://127.0.0.1:8085"
 // Helper function to set up test environment
 func setupTest() func() {
 	logger.InitLogger("debug")
-	// Save original model.Results
-	originalResults := model.Results
-	// originalVisitedURLs := model.VisitedURLs
-	originalDepth := model.Depth
-	originalTimeout := model.Timeout
-	originalUserAgent := model.UserAgent
-	// originalWg := model.Wg
-
-	// Set up test environment
-	model.Results = []model.LinkResult{}
-	model.VisitedURLs = sync.Map{}
-	model.Depth = 2
-	model.Timeout = 5
-	model.UserAgent = "TestUserAgent"
-	model.Wg = sync.WaitGroup{}
 
 	// Return a function to restore original state
 	return func() {
-		model.Results = originalResults
-		// model.VisitedURLs = originalVisitedURLs
-		model.Depth = originalDepth
-		model.Timeout = originalTimeout
-		model.UserAgent = originalUserAgent
-		// model.Wg = originalWg
 		logger.CloseLogger()
 
 		// Clean up test files
@@ -103,11 +84,8 @@ func TestDisplayResults(t *testing.T) {
 			r, w, _ := os.Pipe()
 			os.Stdout = w
 
-			// Set test data
-			model.Results = tc.results
-
 			// Call function
-			DisplayResults()
+			DisplayResults(tc.results)
 
 			// Restore stdout and get output
 			_ = w.Close()
@@ -131,7 +109,7 @@ func TestExportToCSV(t *testing.T) {
 	defer teardown()
 
 	// Set up test data
-	model.Results = []model.LinkResult{
+	links := []model.LinkResult{
 		{SourceURL: SOURCE_URL, TargetURL: "http://127.0.0.1:8085", Status: 200, Error: "", IsExternal: false},
 		{SourceURL: SOURCE_URL, TargetURL: "http://broken.com", Status: 404, Error: "", IsExternal: true},
 		{SourceURL: SOURCE_URL, TargetURL: "http://error.com", Status: 0, Error: "timeout", IsExternal: true},
@@ -144,7 +122,7 @@ func TestExportToCSV(t *testing.T) {
 	os.Stdout = w
 
 	// Call exportToCSV (we need to use ExportResults since exportToCSV is private)
-	ExportResults("csv")
+	ExportResults("csv", links, ReportOptions{})
 
 	// Restore stdout
 	_ = w.Close()
@@ -170,7 +148,7 @@ func TestExportToCSV(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify header
-	assert.Equal(t, []string{"Source URL", "Target URL", "Status", "Error", "Is External"}, records[0])
+	assert.Equal(t, []string{"Source URL", "Target URL", "Status", "Error", "Is External", "Element", "Redirects", "Redirect Issues", "Line", "Column", "Snippet", "Change"}, records[0])
 
 	// Verify data rows
 	assert.Equal(t, SOURCE_URL, records[1][0])
@@ -198,7 +176,7 @@ func TestExportToJSON(t *testing.T) {
 	defer teardown()
 
 	// Set up test data
-	model.Results = []model.LinkResult{
+	links := []model.LinkResult{
 		{SourceURL: SOURCE_URL, TargetURL: "http://127.0.0.1:8085", Status: 200, Error: "", IsExternal: false},
 		{SourceURL: SOURCE_URL, TargetURL: "http://broken.com", Status: 404, Error: "", IsExternal: true},
 	}
@@ -210,7 +188,7 @@ func TestExportToJSON(t *testing.T) {
 	os.Stdout = w
 
 	// Call exportToJSON (we need to use ExportResults since exportToJSON is private)
-	ExportResults("json")
+	ExportResults("json", links, ReportOptions{})
 
 	// Restore stdout
 	_ = w.Close()
@@ -250,24 +228,14 @@ func TestExportResults(t *testing.T) {
 	teardown := setupTest()
 	defer teardown()
 
-	// Prepare test output
-	var buf bytes.Buffer
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
 	// Test with unsupported format
-	ExportResults("xml")
+	err := ExportResults("xml", nil, ReportOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported format xml: use csv, json, ndjson, html, junit, sarif, or text")
 
-	// Restore stdout and read output
-	_ = w.Close()
-	os.Stdout = oldStdout
-	_, err := io.Copy(&buf, r)
-	require.NoError(t, err, "Failed to read output")
-	output := buf.String()
-
-	// Verify error message for unsupported format
-	assert.Contains(t, output, "Unsupported format: xml. Use csv, json, or html")
+	// Test with a file that cannot be created
+	err = ExportResults("json", nil, ReportOptions{Output: filepath.Join("missing", "report.json")})
+	assert.Error(t, err)
 }
 
 // TestExportToHTML tests the HTML export functionality specifically
@@ -276,7 +244,7 @@ func TestExportToHTML(t *testing.T) {
 	defer teardown()
 
 	// Set up test data with various scenarios
-	model.Results = []model.LinkResult{
+	links := []model.LinkResult{
 		{
 			SourceURL:  SOURCE_URL,
 			TargetURL:  "http://good.com",
@@ -307,13 +275,8 @@ func TestExportToHTML(t *testing.T) {
 		},
 	}
 
-	// Configure to show all links (not just errors)
-	originalShowAll := model.ShowAll
-	model.ShowAll = true
-	defer func() { model.ShowAll = originalShowAll }()
-
-	// Test HTML export
-	ExportResults("html")
+	// Test HTML export, showing all links (not just errors)
+	ExportResults("html", links, ReportOptions{ShowAll: true})
 
 	// Verify file was created
 	_, err := os.Stat("deadlinkr-report.html")
@@ -362,7 +325,7 @@ func TestExportHTMLWithFilters(t *testing.T) {
 	defer teardown()
 
 	// Set up test data
-	model.Results = []model.LinkResult{
+	links := []model.LinkResult{
 		{
 			SourceURL:  SOURCE_URL,
 			TargetURL:  "http://internal.com",
@@ -380,14 +343,7 @@ func TestExportHTMLWithFilters(t *testing.T) {
 	}
 
 	t.Run("Display only external links", func(t *testing.T) {
-		model.DisplayOnlyExternal = true
-		model.ShowAll = true
-		defer func() {
-			model.DisplayOnlyExternal = false
-			model.ShowAll = false
-		}()
-
-		ExportResults("html")
+		ExportResults("html", links, ReportOptions{ShowAll: true, OnlyExternal: true})
 
 		content, err := os.ReadFile("deadlinkr-report.html")
 		require.NoError(t, err)
@@ -400,13 +356,7 @@ func TestExportHTMLWithFilters(t *testing.T) {
 	})
 
 	t.Run("Display only errors", func(t *testing.T) {
-		model.ShowAll = false
-		model.DisplayOnlyExternal = false
-		defer func() {
-			model.ShowAll = false
-		}()
-
-		ExportResults("html")
+		ExportResults("html", links, ReportOptions{})
 
 		content, err := os.ReadFile("deadlinkr-report.html")
 		require.NoError(t, err)
@@ -424,14 +374,9 @@ func TestExportHTMLWithCustomOutput(t *testing.T) {
 	teardown := setupTest()
 	defer teardown()
 
-	// Set custom output path
-	model.Output = "custom-report.html"
-	defer func() {
-		model.Output = ""
-		_ = os.Remove("custom-report.html")
-	}()
+	defer func() { _ = os.Remove("custom-report.html") }()
 
-	model.Results = []model.LinkResult{
+	links := []model.LinkResult{
 		{
 			SourceURL:  SOURCE_URL,
 			TargetURL:  "http://test.com",
@@ -441,12 +386,8 @@ func TestExportHTMLWithCustomOutput(t *testing.T) {
 		},
 	}
 
-	// Configure to show all links (not just errors)
-	originalShowAll := model.ShowAll
-	model.ShowAll = true
-	defer func() { model.ShowAll = originalShowAll }()
-
-	ExportResults("html")
+	// Set custom output path, showing all links (not just errors)
+	ExportResults("html", links, ReportOptions{Output: "custom-report.html", ShowAll: true})
 
 	// Verify custom file was created
 	_, err := os.Stat("custom-report.html")
@@ -457,3 +398,208 @@ func TestExportHTMLWithCustomOutput(t *testing.T) {
 	require.NoError(t, err)
 	assert.Contains(t, string(content), "http://test.com")
 }
+
+// TestDetectFormatFromOutput tests the format detection from the output file extension
+func TestDetectFormatFromOutput(t *testing.T) {
+	assert.Equal(t, "csv", DetectFormatFromOutput("report.csv"))
+	assert.Equal(t, "json", DetectFormatFromOutput("report.json"))
+	assert.Equal(t, "ndjson", DetectFormatFromOutput("report.ndjson"))
+	assert.Equal(t, "html", DetectFormatFromOutput("report.HTM"))
+	assert.Equal(t, "junit", DetectFormatFromOutput("reports/links.xml"))
+	assert.Equal(t, "sarif", DetectFormatFromOutput("deadlinkr.sarif"))
+	assert.Equal(t, "", DetectFormatFromOutput("report.txt"))
+	assert.Equal(t, "", DetectFormatFromOutput(""))
+}
+
+// TestExportToJUnit tests the JUnit XML export
+func TestExportToJUnit(t *testing.T) {
+	teardown := setupTest()
+	defer teardown()
+	t.Chdir(t.TempDir())
+
+	links := []model.LinkResult{
+		{SourceURL: SOURCE_URL + "/b", TargetURL: "http://broken.com", Status: 404, IsExternal: true},
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/b", Status: 200},
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/a", Error: "i/o timeout"},
+	}
+
+	ExportResults("junit", links, ReportOptions{})
+
+	content, err := os.ReadFile("deadlinkr-report.xml")
+	require.NoError(t, err)
+
+	var report junitTestSuites
+	require.NoError(t, xml.Unmarshal(content, &report))
+
+	assert.Equal(t, 3, report.Tests)
+	assert.Equal(t, 2, report.Failures)
+	require.Len(t, report.Suites, 2)
+
+	// Suites are sorted by source page and testcases by target
+	suite := report.Suites[0]
+	assert.Equal(t, SOURCE_URL, suite.Name)
+	assert.Equal(t, 2, suite.Tests)
+	assert.Equal(t, 1, suite.Failures)
+	require.Len(t, suite.TestCases, 2)
+	assert.Equal(t, SOURCE_URL+"/a", suite.TestCases[0].Name)
+	require.NotNil(t, suite.TestCases[0].Failure)
+	assert.Equal(t, FailureCategoryTimeout, suite.TestCases[0].Failure.Type)
+	assert.Nil(t, suite.TestCases[1].Failure)
+
+	assert.Equal(t, "HTTP 404", report.Suites[1].TestCases[0].Failure.Message)
+}
+
+// TestExportToSARIF tests the SARIF export
+func TestExportToSARIF(t *testing.T) {
+	teardown := setupTest()
+	defer teardown()
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "public", "docs"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "public", "docs", "index.html"), []byte("<html></html>"), 0o644))
+	site, err := internal.NewFileSystemSite(filepath.Join(dir, "public"))
+	require.NoError(t, err)
+
+	links := []model.LinkResult{
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/ok", Status: 200},
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/missing", Status: 404},
+		{SourceURL: site.RootURL() + "docs/", TargetURL: site.RootURL() + "docs/#intro", Status: 200, Error: "missing anchor #intro", FailureType: model.FailureMissingAnchor},
+	}
+
+	options := ReportOptions{Output: "links.sarif"}
+	ExportResults(DetectFormatFromOutput(options.Output), links, options)
+
+	content, err := os.ReadFile("links.sarif")
+	require.NoError(t, err)
+
+	var report sarifLog
+	require.NoError(t, json.Unmarshal(content, &report))
+
+	assert.Equal(t, "2.1.0", report.Version)
+	require.Len(t, report.Runs, 1)
+	run := report.Runs[0]
+	assert.Equal(t, "deadlinkr", run.Tool.Driver.Name)
+	assert.Len(t, run.Tool.Driver.Rules, 2)
+
+	// Only broken links are reported, located at their source page or source file
+	require.Len(t, run.Results, 2)
+	assert.Equal(t, "broken-link", run.Results[0].RuleID)
+	assert.Equal(t, SOURCE_URL, run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Contains(t, run.Results[0].Message.Text, SOURCE_URL+"/missing")
+
+	assert.Equal(t, "missing-anchor", run.Results[1].RuleID)
+	assert.Equal(t, sarifArtifactLocation{URI: "public/docs/index.html", URIBaseID: "%SRCROOT%"}, run.Results[1].Locations[0].PhysicalLocation.ArtifactLocation)
+
+	// Markdown files are located by their path
+	var markdownReport bytes.Buffer
+	require.NoError(t, writeSARIF(&markdownReport, []model.LinkResult{
+		{SourceURL: "docs/README.md", TargetURL: "docs/guide.md", Status: 404, Line: 3, Column: 8},
+	}, ReportOptions{}))
+	require.NoError(t, json.Unmarshal(markdownReport.Bytes(), &report))
+	markdown := report.Runs[0].Results[0]
+	assert.Equal(t, sarifArtifactLocation{URI: "docs/README.md", URIBaseID: "%SRCROOT%"}, markdown.Locations[0].PhysicalLocation.ArtifactLocation)
+	assert.Equal(t, 3, sarifStartLine(markdown))
+}
+
+// TestRedirectReporting tests how flagged redirects appear in the console output and reports
+func TestRedirectReporting(t *testing.T) {
+	teardown := setupTest()
+	defer teardown()
+	t.Chdir(t.TempDir())
+
+	links := []model.LinkResult{
+		{
+			SourceURL: SOURCE_URL,
+			TargetURL: SOURCE_URL + "/old",
+			Status:    200,
+			Redirects: []model.RedirectHop{
+				{URL: SOURCE_URL + "/old", Status: 301, Location: SOURCE_URL + "/new"},
+			},
+			RedirectIssues: []string{model.RedirectIssuePermanent},
+		},
+	}
+
+	assert.Equal(t, "301 "+SOURCE_URL+"/old -> "+SOURCE_URL+"/new", formatRedirectChain(links[0].Redirects))
+
+	var buf bytes.Buffer
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	DisplayResults(links)
+	_ = w.Close()
+	os.Stdout = oldStdout
+	_, err := io.Copy(&buf, r)
+	require.NoError(t, err)
+
+	assert.Contains(t, buf.String(), "No broken links found!")
+	assert.Contains(t, buf.String(), "Redirect warnings:")
+	assert.Contains(t, buf.String(), "permanently redirects to "+SOURCE_URL+"/new, the link should be updated")
+
+	var sarif bytes.Buffer
+	require.NoError(t, writeSARIF(&sarif, links, ReportOptions{}))
+
+	var report sarifLog
+	require.NoError(t, json.Unmarshal(sarif.Bytes(), &report))
+	require.Len(t, report.Runs[0].Results, 1)
+	assert.Equal(t, "permanent-redirect", report.Runs[0].Results[0].RuleID)
+	assert.Equal(t, "warning", report.Runs[0].Results[0].Level)
+}
+
+// TestLinkPositionReporting tests how the position of a link in its source page appears in the console output and reports
+func TestLinkPositionReporting(t *testing.T) {
+	teardown := setupTest()
+	defer teardown()
+	t.Chdir(t.TempDir())
+
+	links := []model.LinkResult{
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/missing", Status: 404, Line: 12, Column: 9, Snippet: `<a href="/missing">`},
+		{SourceURL: SOURCE_URL, TargetURL: SOURCE_URL + "/gone", Status: 410},
+	}
+
+	var buf bytes.Buffer
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	DisplayResults(links)
+	_ = w.Close()
+	os.Stdout = oldStdout
+	_, err := io.Copy(&buf, r)
+	require.NoError(t, err)
+
+	assert.Contains(t, buf.String(), "- "+SOURCE_URL+"/missing (from "+SOURCE_URL+" line 12, column 9): Status: 404\n  <a href=\"/missing\">\n")
+	assert.Contains(t, buf.String(), "- "+SOURCE_URL+"/gone (from "+SOURCE_URL+"): Status: 410\n")
+
+	ExportResults("csv", links, ReportOptions{})
+	file, err := os.Open("deadlinkr-report.csv")
+	require.NoError(t, err)
+	records, err := csv.NewReader(file).ReadAll()
+	_ = file.Close()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, []string{"12", "9", `<a href="/missing">`}, records[1][8:11])
+	assert.Equal(t, []string{"", "", ""}, records[2][8:11])
+
+	ExportResults("html", links, ReportOptions{})
+	content, err := os.ReadFile("deadlinkr-report.html")
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "line 12, column 9<br><code>&lt;a href=&#34;/missing&#34;&gt;</code>")
+
+	var junit bytes.Buffer
+	require.NoError(t, writeJUnit(&junit, links, ReportOptions{}))
+	var junitReport junitTestSuites
+	require.NoError(t, xml.Unmarshal(junit.Bytes(), &junitReport))
+	testCases := junitReport.Suites[0].TestCases
+	assert.Equal(t, 12, testCases[1].Line)
+	assert.Contains(t, testCases[1].Failure.Text, `<a href="/missing">`)
+	assert.Equal(t, 0, testCases[0].Line)
+
+	var sarif bytes.Buffer
+	require.NoError(t, writeSARIF(&sarif, links, ReportOptions{}))
+	var sarifReport sarifLog
+	require.NoError(t, json.Unmarshal(sarif.Bytes(), &sarifReport))
+	results := sarifReport.Runs[0].Results
+	require.Len(t, results, 2)
+	assert.Nil(t, results[0].Locations[0].PhysicalLocation.Region)
+	assert.Equal(t, &sarifRegion{StartLine: 12, StartColumn: 9, Snippet: &sarifMessage{Text: `<a href="/missing">`}}, results[1].Locations[0].PhysicalLocation.Region)
+}
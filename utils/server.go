@@ -0,0 +1,690 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/internal"
+	"github.com/DrakkarStorm/deadlinkr/logger"
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/DrakkarStorm/deadlinkr/pkg/deadlinkr"
+)
+
+// States of a scan submitted to the server
+const (
+	ScanQueued    = "queued"    // Waiting for a free scan slot
+	ScanRunning   = "running"   // Crawling
+	ScanCompleted = "completed" // Crawled every page
+	ScanFailed    = "failed"    // The scan could not run, e.g. the start page is unreachable
+	ScanCancelled = "cancelled" // Cancelled through the API or by the server shutting down
+)
+
+const (
+	maxScanRequestSize = 1 << 20         // Largest scan submission body accepted
+	cancelWaitTimeout  = 5 * time.Second // Time a cancellation request waits for the scan to stop
+)
+
+// ScanOptions are the crawl options of a scan submitted to the server, matching the flags of the scan command.
+// Authentication, domain rules, the persistent cache directory and the browser endpoint are left to the server's
+// flags and configuration file, and rejected as unknown fields in submissions.
+type ScanOptions struct {
+	URL             string   `json:"url"`
+	Depth           int      `json:"depth"`
+	Concurrency     int      `json:"concurrency"`
+	OnlyInternal    bool     `json:"only_internal"`
+	IncludePattern  string   `json:"include_pattern,omitempty"`
+	ExcludePattern  string   `json:"exclude_pattern,omitempty"`
+	ExcludeHtmlTags string   `json:"exclude_html_tags,omitempty"`
+	IncludeElements []string `json:"include_elements,omitempty"`
+	ExcludeElements []string `json:"exclude_elements,omitempty"`
+	RespectRobots   bool     `json:"respect_robots"`
+	CheckDisallowed bool     `json:"check_disallowed"`
+	CheckAnchors    bool     `json:"check_anchors"`
+	FlagRedirects   []string `json:"flag_redirects,omitempty"`
+	MaxRedirectHops int      `json:"max_redirect_hops"`
+	Soft404         bool     `json:"soft_404"`
+	Soft404Phrases  []string `json:"soft_404_phrases,omitempty"`
+	Sitemap         bool     `json:"sitemap"`
+
+	Timeout      int     `json:"timeout"` // Request timeout in seconds
+	UserAgent    string  `json:"user_agent"`
+	RateLimit    float64 `json:"rate_limit"`
+	RateBurst    float64 `json:"rate_burst"`
+	OptimizeHead bool    `json:"optimize_head"`
+	Cache        bool    `json:"cache"`
+	CacheSize    int     `json:"cache_size"`
+	CacheTTL     int     `json:"cache_ttl"` // Cache time-to-live in minutes
+
+	CDPWait         string   `json:"cdp_wait,omitempty"`
+	CDPWaitSelector string   `json:"cdp_wait_selector,omitempty"`
+	CDPWaitDelay    Duration `json:"cdp_wait_delay"`
+	CDPTimeout      Duration `json:"cdp_timeout"`
+	CDPMaxTabs      int      `json:"cdp_max_tabs"`
+}
+
+// Duration is a duration written as a string such as "2s" or "1m30s" in the JSON of the API, as in the flags
+type Duration time.Duration
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON reads a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid duration %s: expected a string such as \"2s\"", data)
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", value, err)
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// newScanOptions returns the crawl options of the server's defaults for a URL
+func newScanOptions(defaults deadlinkr.Options, baseURL string) ScanOptions {
+	return ScanOptions{
+		URL:             baseURL,
+		Depth:           defaults.Depth,
+		Concurrency:     defaults.Concurrency,
+		OnlyInternal:    defaults.OnlyInternal,
+		IncludePattern:  defaults.IncludePattern,
+		ExcludePattern:  defaults.ExcludePattern,
+		ExcludeHtmlTags: defaults.ExcludeHtmlTags,
+		IncludeElements: defaults.IncludeElements,
+		ExcludeElements: defaults.ExcludeElements,
+		RespectRobots:   defaults.RespectRobots,
+		CheckDisallowed: defaults.CheckDisallowed,
+		CheckAnchors:    defaults.CheckAnchors,
+		FlagRedirects:   defaults.FlagRedirects,
+		MaxRedirectHops: defaults.MaxRedirectHops,
+		Soft404:         defaults.DetectSoft404,
+		Soft404Phrases:  defaults.Soft404Phrases,
+		Sitemap:         defaults.UseSitemap,
+		Timeout:         int(defaults.Timeout / time.Second),
+		UserAgent:       defaults.UserAgent,
+		RateLimit:       defaults.RateLimit,
+		RateBurst:       defaults.RateBurst,
+		OptimizeHead:    defaults.OptimizeWithHeadRequests,
+		Cache:           defaults.CacheEnabled,
+		CacheSize:       defaults.CacheSize,
+		CacheTTL:        int(defaults.CacheTTL / time.Minute),
+		CDPWait:         defaults.CDPWaitUntil,
+		CDPWaitSelector: defaults.CDPWaitSelector,
+		CDPWaitDelay:    Duration(defaults.CDPWaitDelay),
+		CDPTimeout:      Duration(defaults.CDPTimeout),
+		CDPMaxTabs:      defaults.CDPMaxTabs,
+	}
+}
+
+// scannerOptions applies the crawl options over the server's defaults. Scans of the server neither render
+// their progress nor save checkpoints.
+func (options ScanOptions) scannerOptions(defaults deadlinkr.Options) deadlinkr.Options {
+	scanner := defaults
+	scanner.Depth = options.Depth
+	scanner.Concurrency = options.Concurrency
+	scanner.OnlyInternal = options.OnlyInternal
+	scanner.IncludePattern = options.IncludePattern
+	scanner.ExcludePattern = options.ExcludePattern
+	scanner.ExcludeHtmlTags = options.ExcludeHtmlTags
+	scanner.IncludeElements = options.IncludeElements
+	scanner.ExcludeElements = options.ExcludeElements
+	scanner.RespectRobots = options.RespectRobots
+	scanner.CheckDisallowed = options.CheckDisallowed
+	scanner.CheckAnchors = options.CheckAnchors
+	scanner.FlagRedirects = options.FlagRedirects
+	scanner.MaxRedirectHops = options.MaxRedirectHops
+	scanner.DetectSoft404 = options.Soft404
+	scanner.Soft404Phrases = options.Soft404Phrases
+	scanner.UseSitemap = options.Sitemap
+	scanner.Timeout = time.Duration(options.Timeout) * time.Second
+	scanner.UserAgent = options.UserAgent
+	scanner.RateLimit = options.RateLimit
+	scanner.RateBurst = options.RateBurst
+	scanner.OptimizeWithHeadRequests = options.OptimizeHead
+	scanner.CacheEnabled = options.Cache
+	scanner.CacheSize = options.CacheSize
+	scanner.CacheTTL = time.Duration(options.CacheTTL) * time.Minute
+	scanner.CDPWaitUntil = options.CDPWait
+	scanner.CDPWaitSelector = options.CDPWaitSelector
+	scanner.CDPWaitDelay = time.Duration(options.CDPWaitDelay)
+	scanner.CDPTimeout = time.Duration(options.CDPTimeout)
+	scanner.CDPMaxTabs = options.CDPMaxTabs
+	scanner.ShowProgress = false
+	scanner.CheckpointFile = ""
+	scanner.ResumeFile = ""
+	scanner.Sink = nil
+	return scanner
+}
+
+// scanProgress is the progress of a scan as reported by the API, from the scanner's progress
+type scanProgress struct {
+	TotalTasks      int64   `json:"total_tasks"`
+	CompletedTasks  int64   `json:"completed_tasks"`
+	ActiveTasks     int64   `json:"active_tasks"`
+	ErrorCount      int64   `json:"error_count"`
+	ProgressPercent float64 `json:"progress_percent"`
+	LinksPerSecond  float64 `json:"links_per_second"`
+	LinksChecked    int64   `json:"links_checked"`
+	CacheHitRate    float64 `json:"cache_hit_rate"`
+	CacheHits       int64   `json:"cache_hits"`
+	CacheMisses     int64   `json:"cache_misses"`
+	BandwidthSaved  int64   `json:"bandwidth_saved"`
+	HeadRequests    int64   `json:"head_requests"`
+	GetRequests     int64   `json:"get_requests"`
+	ElapsedSeconds  float64 `json:"elapsed_seconds"`
+}
+
+// newScanProgress converts the progress of a scanner
+func newScanProgress(stats deadlinkr.Progress) *scanProgress {
+	return &scanProgress{
+		TotalTasks:      stats.TotalTasks,
+		CompletedTasks:  stats.CompletedTasks,
+		ActiveTasks:     stats.ActiveTasks,
+		ErrorCount:      stats.ErrorCount,
+		ProgressPercent: stats.ProgressPercent,
+		LinksPerSecond:  stats.LinksPerSecond,
+		LinksChecked:    stats.LinksChecked,
+		CacheHitRate:    stats.CacheHitRate,
+		CacheHits:       stats.CacheHits,
+		CacheMisses:     stats.CacheMisses,
+		BandwidthSaved:  stats.BandwidthSaved,
+		HeadRequests:    stats.HeadRequests,
+		GetRequests:     stats.GetRequests,
+		ElapsedSeconds:  stats.Elapsed.Seconds(),
+	}
+}
+
+// scanStatus is the state of a scan as reported by the API
+type scanStatus struct {
+	ID         string        `json:"id"`
+	Status     string        `json:"status"`
+	Options    ScanOptions   `json:"options"`
+	Error      string        `json:"error,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	StartedAt  *time.Time    `json:"started_at,omitempty"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	Links      int           `json:"links"`
+	Broken     int           `json:"broken"`
+	Progress   *scanProgress `json:"progress,omitempty"`
+}
+
+// scanJob is a scan submitted to the server
+type scanJob struct {
+	id        string
+	options   ScanOptions
+	createdAt time.Time
+	ctx       context.Context // Cancelled to cancel the scan
+	cancel    context.CancelFunc
+	done      chan struct{} // Closed once the scan finished
+
+	mutex      sync.Mutex
+	status     string
+	err        string
+	startedAt  time.Time
+	finishedAt time.Time
+	scanner    *deadlinkr.Scanner // Set while running
+	progress   *scanProgress      // Final progress, once finished
+	results    []model.LinkResult
+}
+
+// start marks the scan as running with a scanner, whose progress is reported until the scan finishes
+func (job *scanJob) start(scanner *deadlinkr.Scanner) {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	job.status = ScanRunning
+	job.startedAt = time.Now()
+	job.scanner = scanner
+}
+
+// finish records the outcome of the scan, keeping the last progress of its scanner
+func (job *scanJob) finish(status string, results []model.LinkResult, err error) {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	job.status = status
+	job.finishedAt = time.Now()
+	job.results = results
+	if err != nil {
+		job.err = err.Error()
+	}
+	if job.scanner != nil {
+		if progress, ok := job.scanner.Progress(); ok {
+			job.progress = newScanProgress(progress)
+		}
+		job.scanner = nil
+	}
+}
+
+// isFinished checks whether the scan finished, whatever its outcome
+func (job *scanJob) isFinished() bool {
+	select {
+	case <-job.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// snapshot returns the current state of the scan
+func (job *scanJob) snapshot() scanStatus {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+
+	status := scanStatus{
+		ID:        job.id,
+		Status:    job.status,
+		Options:   job.options,
+		Error:     job.err,
+		CreatedAt: job.createdAt,
+		Links:     len(job.results),
+		Broken:    CountBrokenLinks(job.results),
+		Progress:  job.progress,
+	}
+	if startedAt := job.startedAt; !startedAt.IsZero() {
+		status.StartedAt = &startedAt
+	}
+	if finishedAt := job.finishedAt; !finishedAt.IsZero() {
+		status.FinishedAt = &finishedAt
+	}
+	if job.scanner != nil {
+		if progress, ok := job.scanner.Progress(); ok {
+			status.Links = progress.Links
+			status.Broken = progress.Broken
+			status.Progress = newScanProgress(progress)
+		}
+	}
+	return status
+}
+
+// ScanServer runs scans submitted through a REST API, a bounded number at a time.
+// Scans are kept in memory, the oldest finished ones being dropped beyond the retention limit.
+type ScanServer struct {
+	ctx              context.Context // Cancelled when the server shuts down, cancelling every scan
+	cancel           context.CancelFunc
+	slots            chan struct{}     // Limits the number of scans running at once
+	retain           int               // Number of finished scans kept
+	progressInterval time.Duration     // Interval between progress events
+	defaults         deadlinkr.Options // Options of the scans, before the submitted crawl options are applied
+	crawl            func(ctx context.Context, scanner *deadlinkr.Scanner, target string) ([]model.LinkResult, error)
+
+	scans   map[string]*scanJob
+	order   []*scanJob // Scans in submission order
+	mutex   sync.Mutex
+	running sync.WaitGroup
+}
+
+// NewScanServer creates a ScanServer running up to maxScans scans at once and keeping the last retain finished scans.
+// Scans run with the defaults, overridden by the crawl options of each submission.
+func NewScanServer(defaults deadlinkr.Options, maxScans, retain int) *ScanServer {
+	if maxScans < 1 {
+		maxScans = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ScanServer{
+		ctx:              ctx,
+		cancel:           cancel,
+		slots:            make(chan struct{}, maxScans),
+		retain:           retain,
+		progressInterval: 500 * time.Millisecond,
+		defaults:         defaults,
+		crawl:            scanWith,
+		scans:            make(map[string]*scanJob),
+	}
+}
+
+// scanWith runs a scan of target with a scanner
+func scanWith(ctx context.Context, scanner *deadlinkr.Scanner, target string) ([]model.LinkResult, error) {
+	return scanner.Scan(ctx, target)
+}
+
+// Handler returns the HTTP handler of the scan API
+func (s *ScanServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /scans", s.handleSubmit)
+	mux.HandleFunc("GET /scans", s.handleList)
+	mux.HandleFunc("GET /scans/{id}", s.handleStatus)
+	mux.HandleFunc("DELETE /scans/{id}", s.handleCancel)
+	mux.HandleFunc("GET /scans/{id}/events", s.handleEvents)
+	mux.HandleFunc("GET /scans/{id}/results", s.handleResults)
+	return mux
+}
+
+// Shutdown cancels every scan and waits for them to stop
+func (s *ScanServer) Shutdown() {
+	s.cancel()
+	s.running.Wait()
+}
+
+// submit queues a scan, starting it once a scan slot is free
+func (s *ScanServer) submit(options ScanOptions) (*scanJob, error) {
+	if err := validateScanOptions(options); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.ctx.Err() != nil {
+		return nil, errors.New("the server is shutting down")
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	job := &scanJob{
+		id:        newScanID(),
+		options:   options,
+		createdAt: time.Now(),
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		status:    ScanQueued,
+	}
+	s.scans[job.id] = job
+	s.order = append(s.order, job)
+
+	s.running.Add(1)
+	go s.run(job)
+
+	logger.Infof("Queued scan %s of %s", job.id, options.URL)
+	return job, nil
+}
+
+// run runs a scan once a slot is free, unless it is cancelled first
+func (s *ScanServer) run(job *scanJob) {
+	defer s.running.Done()
+	defer s.prune()
+	defer close(job.done)
+	defer job.cancel()
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-job.ctx.Done():
+		job.finish(ScanCancelled, nil, nil)
+		return
+	}
+
+	// Label the metrics of the scan with its ID
+	options := job.options.scannerOptions(s.defaults)
+	options.Name = job.id
+	scanner, err := deadlinkr.NewScanner(options)
+	if err != nil {
+		job.finish(ScanFailed, nil, err)
+		return
+	}
+
+	job.start(scanner)
+	logger.Infof("Starting scan %s of %s", job.id, job.options.URL)
+	results, err := s.crawl(job.ctx, scanner, job.options.URL)
+
+	switch {
+	case job.ctx.Err() != nil:
+		job.finish(ScanCancelled, results, nil)
+	case err != nil:
+		job.finish(ScanFailed, results, err)
+	default:
+		job.finish(ScanCompleted, results, nil)
+	}
+	logger.Infof("Scan %s of %s %s: %d links, %d broken", job.id, job.options.URL, job.status, len(results), CountBrokenLinks(results))
+}
+
+// prune drops the oldest finished scans beyond the retention limit
+func (s *ScanServer) prune() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	finished := 0
+	for _, job := range s.order {
+		if job.isFinished() {
+			finished++
+		}
+	}
+
+	kept := s.order[:0]
+	for _, job := range s.order {
+		if finished > s.retain && job.isFinished() {
+			delete(s.scans, job.id)
+			finished--
+			continue
+		}
+		kept = append(kept, job)
+	}
+	s.order = kept
+}
+
+// lookup returns a scan by ID
+func (s *ScanServer) lookup(id string) (*scanJob, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	job, found := s.scans[id]
+	return job, found
+}
+
+// validateScanOptions checks the options of a submitted scan
+func validateScanOptions(options ScanOptions) error {
+	target, err := url.Parse(options.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("invalid url %q: expected an http or https URL", options.URL)
+	}
+	if options.Depth < 0 {
+		return fmt.Errorf("invalid depth %d: expected 0 or more", options.Depth)
+	}
+	if options.Concurrency < 1 {
+		return fmt.Errorf("invalid concurrency %d: expected 1 or more", options.Concurrency)
+	}
+	for _, pattern := range []string{options.IncludePattern, options.ExcludePattern} {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	if options.Timeout < 1 {
+		return fmt.Errorf("invalid timeout %d: expected 1 second or more", options.Timeout)
+	}
+	if options.RateLimit <= 0 || options.RateBurst < 1 {
+		return fmt.Errorf("invalid rate limit %g with burst %g: expected a positive rate and a burst of 1 or more", options.RateLimit, options.RateBurst)
+	}
+	if options.Cache && (options.CacheSize < 1 || options.CacheTTL < 1) {
+		return fmt.Errorf("invalid cache size %d with TTL %d: expected 1 or more", options.CacheSize, options.CacheTTL)
+	}
+	switch options.CDPWait {
+	case "", internal.CDPWaitLoad, internal.CDPWaitDOMContentLoaded, internal.CDPWaitNetworkIdle:
+	default:
+		return fmt.Errorf("invalid cdp_wait %q: expected %s, %s or %s", options.CDPWait, internal.CDPWaitLoad, internal.CDPWaitDOMContentLoaded, internal.CDPWaitNetworkIdle)
+	}
+	if options.CDPWaitDelay < 0 || options.CDPTimeout < 0 || options.CDPMaxTabs < 0 {
+		return errors.New("invalid page rendering settings: expected durations and tabs of 0 or more")
+	}
+	return nil
+}
+
+// newScanID returns a random scan ID
+func newScanID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// handleSubmit queues a scan. Options left out of the request body default to the server's flags.
+func (s *ScanServer) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	options := newScanOptions(s.defaults, "")
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxScanRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&options); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid scan request: %w", err))
+		return
+	}
+
+	job, err := s.submit(options)
+	if err != nil {
+		status := http.StatusBadRequest
+		if s.ctx.Err() != nil {
+			status = http.StatusServiceUnavailable
+		}
+		writeAPIError(w, status, err)
+		return
+	}
+
+	w.Header().Set("Location", "/scans/"+job.id)
+	writeAPIResponse(w, http.StatusAccepted, job.snapshot())
+}
+
+// handleList lists the scans in submission order
+func (s *ScanServer) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	jobs := append([]*scanJob{}, s.order...)
+	s.mutex.Unlock()
+
+	statuses := make([]scanStatus, 0, len(jobs))
+	for _, job := range jobs {
+		statuses = append(statuses, job.snapshot())
+	}
+	writeAPIResponse(w, http.StatusOK, statuses)
+}
+
+// handleStatus reports the state and progress of a scan
+func (s *ScanServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	job, found := s.lookup(r.PathValue("id"))
+	if !found {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("scan %s not found", r.PathValue("id")))
+		return
+	}
+	writeAPIResponse(w, http.StatusOK, job.snapshot())
+}
+
+// handleCancel cancels a queued or running scan, keeping the results collected so far
+func (s *ScanServer) handleCancel(w http.ResponseWriter, r *http.Request) {
+	job, found := s.lookup(r.PathValue("id"))
+	if !found {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("scan %s not found", r.PathValue("id")))
+		return
+	}
+	if job.isFinished() {
+		writeAPIError(w, http.StatusConflict, fmt.Errorf("scan %s already finished", job.id))
+		return
+	}
+
+	logger.Infof("Cancelling scan %s", job.id)
+	job.cancel()
+
+	// Answer with the final state unless the crawl takes long to stop
+	select {
+	case <-job.done:
+		writeAPIResponse(w, http.StatusOK, job.snapshot())
+	case <-time.After(cancelWaitTimeout):
+		writeAPIResponse(w, http.StatusAccepted, job.snapshot())
+	case <-r.Context().Done():
+	}
+}
+
+// handleEvents streams the progress of a scan as server-sent events: a progress event
+// at every interval and a done event once the scan finished
+func (s *ScanServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	job, found := s.lookup(r.PathValue("id"))
+	if !found {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("scan %s not found", r.PathValue("id")))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	controller := http.NewResponseController(w)
+
+	ticker := time.NewTicker(s.progressInterval)
+	defer ticker.Stop()
+
+	event := "progress"
+	for {
+		if job.isFinished() {
+			event = "done"
+		}
+		if err := writeServerSentEvent(w, event, job.snapshot()); err != nil {
+			return
+		}
+		if err := controller.Flush(); err != nil || event == "done" {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-job.done:
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleResults exports the results of a finished scan in the format of the format query parameter, JSON by default
+func (s *ScanServer) handleResults(w http.ResponseWriter, r *http.Request) {
+	job, found := s.lookup(r.PathValue("id"))
+	if !found {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("scan %s not found", r.PathValue("id")))
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	contentType := ReportContentType(format)
+	if contentType == "" {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("unsupported format %s: use csv, json, html, junit, or sarif", format))
+		return
+	}
+	if !job.isFinished() {
+		writeAPIError(w, http.StatusConflict, fmt.Errorf("scan %s is still %s", job.id, job.snapshot().Status))
+		return
+	}
+
+	job.mutex.Lock()
+	results := job.results
+	job.mutex.Unlock()
+
+	// Render the report first so a failure can still be answered with an error status
+	var report bytes.Buffer
+	if err := WriteReport(&report, format, results, ReportOptions{OnlyInternal: job.options.OnlyInternal}); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	if _, err := report.WriteTo(w); err != nil {
+		logger.Debugf("Error writing results of scan %s: %s", job.id, err)
+	}
+}
+
+// writeServerSentEvent writes a server-sent event with a JSON payload
+func writeServerSentEvent(w http.ResponseWriter, event string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+// writeAPIResponse writes a JSON response
+func writeAPIResponse(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(payload); err != nil {
+		logger.Debugf("Error writing API response: %s", err)
+	}
+}
+
+// writeAPIError writes a JSON error response
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeAPIResponse(w, status, map[string]string{"error": err.Error()})
+}
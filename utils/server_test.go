@@ -0,0 +1,307 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/DrakkarStorm/deadlinkr/pkg/deadlinkr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// submitScan submits a scan to the API, returning the response status and the decoded scan
+func submitScan(t *testing.T, api *httptest.Server, body string) (int, scanStatus) {
+	resp, err := http.Post(api.URL+"/scans", "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	status := scanStatus{}
+	if resp.StatusCode == http.StatusAccepted {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+		assert.Equal(t, "/scans/"+status.ID, resp.Header.Get("Location"))
+	}
+	return resp.StatusCode, status
+}
+
+// getScan fetches the state of a scan
+func getScan(t *testing.T, api *httptest.Server, id string) scanStatus {
+	resp, err := http.Get(api.URL + "/scans/" + id)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	status := scanStatus{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	return status
+}
+
+// readServerSentEvents reads the events of a progress stream until it ends
+func readServerSentEvents(t *testing.T, api *httptest.Server, id string) []string {
+	resp, err := http.Get(api.URL + "/scans/" + id + "/events")
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := []string{}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if event, found := strings.CutPrefix(scanner.Text(), "event: "); found {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestScanServerAPI(t *testing.T) {
+	teardown := setupTest()
+	defer teardown()
+
+	defaults := deadlinkr.DefaultOptions()
+	defaults.Concurrency = 4
+
+	// Scans block until released, or cancelled
+	release := make(chan struct{})
+	server := NewScanServer(defaults, 1, 10)
+	server.progressInterval = 10 * time.Millisecond
+	server.crawl = func(ctx context.Context, scanner *deadlinkr.Scanner, target string) ([]model.LinkResult, error) {
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return []model.LinkResult{
+			{SourceURL: target, TargetURL: target + "/ok", Status: 200},
+			{SourceURL: target, TargetURL: target + "/missing", Status: 404},
+		}, nil
+	}
+	api := httptest.NewServer(server.Handler())
+	defer api.Close()
+	defer server.Shutdown()
+
+	t.Run("Invalid submissions are rejected", func(t *testing.T) {
+		for _, body := range []string{
+			`{"url": "ftp://example.com"}`,
+			`{"url": "https://example.com", "depth": -1}`,
+			`{"url": "https://example.com", "include_pattern": "("}`,
+			`{"url": "https://example.com", "unknown": true}`,
+			`{"url": "https://example.com", "timeout": 0}`,
+			`{"url": "https://example.com", "rate_limit": -1}`,
+			`{"url": "https://example.com", "cdp_wait": "never"}`,
+			`{"url": "https://example.com", "cdp_timeout": 30}`,
+			`{"url": "https://example.com", "auth_bearer": "secret"}`,
+			`not json`,
+		} {
+			status, _ := submitScan(t, api, body)
+			assert.Equal(t, http.StatusBadRequest, status, body)
+		}
+	})
+
+	status, first := submitScan(t, api, `{"url": "https://example.com", "depth": 3, "check_anchors": true}`)
+	require.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, 3, first.Options.Depth)
+	assert.True(t, first.Options.CheckAnchors)
+	assert.Equal(t, defaults.Concurrency, first.Options.Concurrency, "options left out default to the flags")
+
+	_, second := submitScan(t, api, `{"url": "https://example.org"}`)
+	_, third := submitScan(t, api, `{"url": "https://example.net"}`)
+
+	t.Run("Scans beyond the limit are queued", func(t *testing.T) {
+		assert.Eventually(t, func() bool { return getScan(t, api, first.ID).Status == ScanRunning }, time.Second, 10*time.Millisecond)
+		assert.Equal(t, ScanQueued, getScan(t, api, second.ID).Status)
+	})
+
+	t.Run("Results of running scans are not available", func(t *testing.T) {
+		resp, err := http.Get(api.URL + "/scans/" + first.ID + "/results")
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("Queued scans can be cancelled", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodDelete, api.URL+"/scans/"+third.ID, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, ScanCancelled, getScan(t, api, third.ID).Status)
+
+		resp, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusConflict, resp.StatusCode, "finished scans cannot be cancelled")
+	})
+
+	t.Run("Progress is streamed until the scan finishes", func(t *testing.T) {
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+		}()
+		events := readServerSentEvents(t, api, first.ID)
+		require.NotEmpty(t, events)
+		assert.Equal(t, "progress", events[0])
+		assert.Equal(t, "done", events[len(events)-1])
+
+		finished := getScan(t, api, first.ID)
+		assert.Equal(t, ScanCompleted, finished.Status)
+		assert.Equal(t, 2, finished.Links)
+		assert.Equal(t, 1, finished.Broken)
+		assert.NotNil(t, finished.FinishedAt)
+	})
+
+	t.Run("Results are exported in any format", func(t *testing.T) {
+		for format, contentType := range reportContentTypes {
+			resp, err := http.Get(api.URL + "/scans/" + first.ID + "/results?format=" + format)
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode, format)
+			assert.Equal(t, contentType, resp.Header.Get("Content-Type"))
+			assert.Contains(t, string(body), "example.com/missing", format)
+		}
+
+		resp, err := http.Get(api.URL + "/scans/" + first.ID + "/results?format=pdf")
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Scans are listed in submission order", func(t *testing.T) {
+		resp, err := http.Get(api.URL + "/scans")
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+
+		statuses := []scanStatus{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&statuses))
+		require.Len(t, statuses, 3)
+		assert.Equal(t, []string{first.ID, second.ID, third.ID}, []string{statuses[0].ID, statuses[1].ID, statuses[2].ID})
+	})
+
+	t.Run("Unknown scans are not found", func(t *testing.T) {
+		resp, err := http.Get(api.URL + "/scans/unknown")
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestScanServerRetention(t *testing.T) {
+	teardown := setupTest()
+	defer teardown()
+
+	defaults := deadlinkr.DefaultOptions()
+	server := NewScanServer(defaults, 2, 1)
+	server.crawl = func(ctx context.Context, scanner *deadlinkr.Scanner, target string) ([]model.LinkResult, error) {
+		return nil, nil
+	}
+	defer server.Shutdown()
+
+	for _, target := range []string{"https://example.com", "https://example.org"} {
+		job, err := server.submit(newScanOptions(defaults, target))
+		require.NoError(t, err)
+		<-job.done
+	}
+
+	assert.Eventually(t, func() bool {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+		return len(server.order) == 1 && server.order[0].options.URL == "https://example.org"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestScanServerCrawlSettings(t *testing.T) {
+	teardown := setupTest()
+	defer teardown()
+
+	defaults := deadlinkr.DefaultOptions()
+	defaults.AuthBearer = "secret"
+	server := NewScanServer(defaults, 1, 10)
+	applied := make(chan deadlinkr.Options, 1)
+	server.crawl = func(ctx context.Context, scanner *deadlinkr.Scanner, target string) ([]model.LinkResult, error) {
+		applied <- scanner.Options()
+		return nil, nil
+	}
+	api := httptest.NewServer(server.Handler())
+	defer api.Close()
+	defer server.Shutdown()
+
+	status, scan := submitScan(t, api, `{"url": "https://example.com", "timeout": 5, "user_agent": "Bot/2.0",
+		"rate_limit": 10, "rate_burst": 20, "optimize_head": false, "cache": false,
+		"cdp_wait": "networkidle", "cdp_wait_selector": "#app", "cdp_wait_delay": "500ms", "cdp_timeout": "1m", "cdp_max_tabs": 2}`)
+	require.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, Duration(500*time.Millisecond), scan.Options.CDPWaitDelay)
+	assert.Equal(t, defaults.CacheSize, scan.Options.CacheSize, "options left out default to the flags")
+
+	options := <-applied
+	assert.Equal(t, 5*time.Second, options.Timeout)
+	assert.Equal(t, "Bot/2.0", options.UserAgent)
+	assert.Equal(t, 10.0, options.RateLimit)
+	assert.Equal(t, 20.0, options.RateBurst)
+	assert.False(t, options.OptimizeWithHeadRequests)
+	assert.False(t, options.CacheEnabled)
+	assert.Equal(t, defaults.CacheTTL, options.CacheTTL)
+	assert.Equal(t, "networkidle", options.CDPWaitUntil)
+	assert.Equal(t, "#app", options.CDPWaitSelector)
+	assert.Equal(t, 500*time.Millisecond, options.CDPWaitDelay)
+	assert.Equal(t, time.Minute, options.CDPTimeout)
+	assert.Equal(t, 2, options.CDPMaxTabs)
+	assert.Equal(t, "secret", options.AuthBearer, "server-only settings come from the server's flags")
+}
+
+func TestScanServerCrawl(t *testing.T) {
+	teardown := setupTest()
+	defer teardown()
+	t.Chdir(t.TempDir())
+
+	defaults := deadlinkr.DefaultOptions()
+	defaults.RateLimit, defaults.Concurrency = 100, 4
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><a href="/about">About</a><a href="/missing">Missing</a></body></html>`))
+		case "/about":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body>About</body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	server := NewScanServer(defaults, 1, 10)
+	server.progressInterval = 10 * time.Millisecond
+	api := httptest.NewServer(server.Handler())
+	defer api.Close()
+	defer server.Shutdown()
+
+	status, scan := submitScan(t, api, `{"url": "`+site.URL+`", "depth": 1}`)
+	require.Equal(t, http.StatusAccepted, status)
+
+	events := readServerSentEvents(t, api, scan.ID)
+	require.NotEmpty(t, events)
+	assert.Equal(t, "done", events[len(events)-1])
+
+	finished := getScan(t, api, scan.ID)
+	assert.Equal(t, ScanCompleted, finished.Status)
+	assert.Equal(t, 1, finished.Broken)
+	require.NotNil(t, finished.Progress)
+	assert.Positive(t, finished.Progress.CompletedTasks)
+
+	resp, err := http.Get(api.URL + "/scans/" + scan.ID + "/results")
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	results := []model.LinkResult{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&results))
+	assert.Len(t, results, 2)
+}
@@ -1,26 +1,79 @@
 package utils
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 
+	"github.com/DrakkarStorm/deadlinkr/internal"
 	"github.com/DrakkarStorm/deadlinkr/logger"
 	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/DrakkarStorm/deadlinkr/pkg/deadlinkr"
 	"github.com/PuerkitoBio/goquery"
 )
 
-// Crawl crawls the given URL and its links up to the specified depth.
+// PageChecker checks the links of pages one request at a time, crawling sites with a goroutine per page.
+// It honors the depth, filters, timeout and user agent of its options.
+type PageChecker struct {
+	options deadlinkr.Options
+	client  *http.Client
+
+	visitedURLs  sync.Map
+	wg           sync.WaitGroup
+	results      []model.LinkResult
+	resultsMutex sync.Mutex
+}
+
+// NewPageChecker creates a PageChecker with the given options
+func NewPageChecker(options deadlinkr.Options) *PageChecker {
+	client := internal.NewHTTPClient()
+	client.Timeout = options.Timeout
+	return &PageChecker{
+		options: options,
+		client:  client,
+	}
+}
+
+// Wait waits for the pages crawled by Crawl
+func (pc *PageChecker) Wait() {
+	pc.wg.Wait()
+}
+
+// Results returns the links checked so far
+func (pc *PageChecker) Results() []model.LinkResult {
+	pc.resultsMutex.Lock()
+	defer pc.resultsMutex.Unlock()
+	return append([]model.LinkResult{}, pc.results...)
+}
+
+// VisitedURLs returns the pages crawled so far, sorted
+func (pc *PageChecker) VisitedURLs() []string {
+	visited := []string{}
+	pc.visitedURLs.Range(func(key, value any) bool {
+		visited = append(visited, key.(string))
+		return true
+	})
+	sort.Strings(visited)
+	return visited
+}
+
+// Crawl crawls the given URL and its links up to the depth of the options, in the background until Wait returns.
 // example: Crawl("https://example.com", "https://example.com", 0)
 // example: Crawl("https://example.com", "https://example.com/page", 1)
-func Crawl(baseURL, currentURL string, currentDepth int, concurrency int) {
+func (pc *PageChecker) Crawl(baseURL, currentURL string, currentDepth int) {
 	// Stop if max depth reached
-	if currentDepth > model.Depth {
+	if currentDepth > pc.options.Depth {
 		return
 	}
 
 	// Check if URL already visited
-	_, alreadyVisited := model.VisitedURLs.LoadOrStore(currentURL, true)
+	_, alreadyVisited := pc.visitedURLs.LoadOrStore(currentURL, true)
 	if alreadyVisited {
 		logger.Debugf("→ skip (already visited) : %s", currentURL)
 		return
@@ -29,25 +82,25 @@ func Crawl(baseURL, currentURL string, currentDepth int, concurrency int) {
 	logger.Debugf("Crawling: %s (depth %d)", currentURL, currentDepth)
 
 	// Increment wait group
-	model.Wg.Add(1)
+	pc.wg.Add(1)
 	// Start a new goroutine for asynchronous crawling
 	go func(url string, d int) {
 		// Decrement the wait group when this goroutine completes
-		defer model.Wg.Done()
+		defer pc.wg.Done()
 
 		// Check the links on the current page
-		links := CheckLinks(baseURL, url)
+		links := pc.CheckLinks(baseURL, url)
 
 		logger.Debugf("Found %d links on %s", len(links), url)
 
 		// If the current depth is less than the maximum depth, continue crawling
-		if d < model.Depth {
+		if d < pc.options.Depth {
 			// Iterate over each link found on the current page
 			for _, link := range links {
 				// Only recursively crawl internal links
 				if !link.IsExternal {
 					// Start a new goroutine for each internal link to crawl it
-					Crawl(baseURL, link.TargetURL, d+1, model.Concurrency)
+					pc.Crawl(baseURL, link.TargetURL, d+1)
 				}
 			}
 		}
@@ -55,22 +108,31 @@ func Crawl(baseURL, currentURL string, currentDepth int, concurrency int) {
 }
 
 // CheckLinks checks all links on a page and returns a slice of LinkResult structs.
-func CheckLinks(baseURL, pageURL string) []model.LinkResult {
+func (pc *PageChecker) CheckLinks(baseURL, pageURL string) []model.LinkResult {
+	pageLinks, _ := pc.CheckPage(baseURL, pageURL)
+	return pageLinks
+}
+
+// CheckPage checks all links on a page like CheckLinks, returning an error when the page itself cannot be checked.
+func (pc *PageChecker) CheckPage(baseURL, pageURL string) ([]model.LinkResult, error) {
 	pageLinks := []model.LinkResult{}
 
 	baseUrlParsed := parseBaseURL(baseURL)
 	if baseUrlParsed == nil {
-		return pageLinks
+		return pageLinks, fmt.Errorf("invalid base URL %s", baseURL)
 	}
 
-	doc := fetchAndParseDocument(pageURL)
+	doc, content, err := pc.fetchAndParseDocument(pageURL)
+	if err != nil {
+		return pageLinks, err
+	}
 	if doc == nil {
-		return pageLinks
+		return pageLinks, nil
 	}
 
-	pageLinks = extractLinks(baseUrlParsed, pageURL, doc)
+	pageLinks = pc.extractLinks(baseUrlParsed, pageURL, doc, internal.NewSourceIndex(content))
 	logger.Debugf("Found %d links on %s", len(pageLinks), pageURL)
-	return pageLinks
+	return pageLinks, nil
 }
 
 func parseBaseURL(baseURL string) *url.URL {
@@ -86,13 +148,13 @@ func parseBaseURL(baseURL string) *url.URL {
 	return baseUrlParsed
 }
 
-func fetchAndParseDocument(pageURL string) *goquery.Document {
+func (pc *PageChecker) fetchAndParseDocument(pageURL string) (*goquery.Document, []byte, error) {
 	retry := 3
-	resp, err := FetchWithRetry(pageURL, retry)
+	resp, err := pc.FetchWithRetry(pageURL, retry)
 
 	if err != nil {
 		logger.Errorf("Failed to fetch %s after %d retries: %s", pageURL, retry, err)
-		return nil
+		return nil, nil, fmt.Errorf("failed to fetch %s: %w", pageURL, err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -101,23 +163,35 @@ func fetchAndParseDocument(pageURL string) *goquery.Document {
 	}()
 
 	if !strings.Contains(resp.Header.Get("Content-Type"), "text/html") {
-		return nil
+		return nil, nil, nil
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logger.Errorf("Error reading HTML from %s: %s", pageURL, err)
+		return nil, nil, err
 	}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(content))
 	if err != nil {
 		logger.Errorf("Error parsing HTML from %s: %s", pageURL, err)
-		return nil
+		return nil, nil, err
 	}
 
-	return doc
+	return doc, content, nil
 }
 
-func extractLinks(baseUrlParsed *url.URL, pageURL string, doc *goquery.Document) []model.LinkResult {
+func (pc *PageChecker) extractLinks(baseUrlParsed *url.URL, pageURL string, doc *goquery.Document, sources *internal.SourceIndex) []model.LinkResult {
 	pageLinks := []model.LinkResult{}
 
-	doc.Find("body a[href]").Not(model.ExcludeHtmlTags).Each(func(i int, s *goquery.Selection) {
+	doc.Find("body a[href]").Each(func(i int, s *goquery.Selection) {
 		href, exists := s.Attr("href")
+
+		// Excluded links still take their place in the source, so they are skipped once located
+		tag, located := sources.Next("a", "href", href)
+		if pc.options.ExcludeHtmlTags != "" && s.Is(pc.options.ExcludeHtmlTags) {
+			return
+		}
 		if !exists || href == "" || strings.HasPrefix(href, "#") {
 			logger.Debugf("Skipping link due to missing href or #: %s", href)
 			return
@@ -131,16 +205,16 @@ func extractLinks(baseUrlParsed *url.URL, pageURL string, doc *goquery.Document)
 
 		isExternal := baseUrlParsed.Hostname() != linkURL.Hostname()
 
-		if model.OnlyInternal && isExternal {
+		if pc.options.OnlyInternal && isExternal {
 			return
 		}
 
-		if shouldSkipURLBasedOnPattern(linkURL) {
+		if pc.shouldSkipURLBasedOnPattern(linkURL) {
 			logger.Debugf("Skipping link due to pattern match: %s", href)
 			return
 		}
 
-		status, errMsg := CheckLink(linkURL.String())
+		status, errMsg := pc.CheckLink(linkURL.String())
 
 		linkResult := model.LinkResult{
 			SourceURL:  pageURL,
@@ -149,9 +223,12 @@ func extractLinks(baseUrlParsed *url.URL, pageURL string, doc *goquery.Document)
 			Error:      errMsg,
 			IsExternal: isExternal,
 		}
+		if located {
+			tag.Locate(&linkResult, href)
+		}
 
 		pageLinks = append(pageLinks, linkResult)
-		addLinkResultToModel(linkResult)
+		pc.addResult(linkResult)
 	})
 
 	return pageLinks
@@ -165,16 +242,16 @@ func resolveAndFilterURL(baseUrlParsed *url.URL, pageURL, href string) *url.URL
 	return linkURL
 }
 
-func shouldSkipURLBasedOnPattern(linkURL *url.URL) bool {
-	if model.IncludePattern != "" {
-		matched, err := regexp.MatchString(model.IncludePattern, linkURL.String())
+func (pc *PageChecker) shouldSkipURLBasedOnPattern(linkURL *url.URL) bool {
+	if pc.options.IncludePattern != "" {
+		matched, err := regexp.MatchString(pc.options.IncludePattern, linkURL.String())
 		if err != nil || !matched {
 			return true
 		}
 	}
 
-	if model.ExcludePattern != "" {
-		matched, err := regexp.MatchString(model.ExcludePattern, linkURL.String())
+	if pc.options.ExcludePattern != "" {
+		matched, err := regexp.MatchString(pc.options.ExcludePattern, linkURL.String())
 		if err == nil && matched {
 			return true
 		}
@@ -183,8 +260,8 @@ func shouldSkipURLBasedOnPattern(linkURL *url.URL) bool {
 	return false
 }
 
-func addLinkResultToModel(linkResult model.LinkResult) {
-	model.ResultsMutex.Lock()
-	model.Results = append(model.Results, linkResult)
-	model.ResultsMutex.Unlock()
+func (pc *PageChecker) addResult(linkResult model.LinkResult) {
+	pc.resultsMutex.Lock()
+	pc.results = append(pc.results, linkResult)
+	pc.resultsMutex.Unlock()
 }
@@ -4,12 +4,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
-	"sync"
 	"testing"
 	"time"
 
 	"github.com/DrakkarStorm/deadlinkr/logger"
 	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/DrakkarStorm/deadlinkr/pkg/deadlinkr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -72,35 +72,19 @@ func (m *MockHTTPServer) Close() {
 	m.server.Close()
 }
 
-// setupTestState resets all global state for clean tests
+// setupTestState initializes the logger for clean tests
 func setupTestState() func() {
-	// Initialize logger
 	logger.InitLogger("debug")
-	
-	// Save original state (avoid copying locks)
-	originalResults := model.Results
-	originalDepth := model.Depth
-	originalConcurrency := model.Concurrency
-	originalTimeout := model.Timeout
-	
-	// Reset to clean state
-	model.Results = []model.LinkResult{}
-	model.Depth = 1
-	model.Concurrency = 5
-	model.Timeout = 5
-	model.VisitedURLs = sync.Map{}
-	model.Wg = sync.WaitGroup{}
-	
-	// Return cleanup function
-	return func() {
-		model.Results = originalResults
-		model.Depth = originalDepth
-		model.Concurrency = originalConcurrency
-		model.Timeout = originalTimeout
-		// Don't restore sync structures - leave them clean for next test
-		model.VisitedURLs = sync.Map{}
-		model.Wg = sync.WaitGroup{}
-	}
+	return func() {}
+}
+
+// newTestPageChecker creates a page checker crawling up to depth with short timeouts
+func newTestPageChecker(depth int) *PageChecker {
+	options := deadlinkr.DefaultOptions()
+	options.Depth = depth
+	options.Concurrency = 5
+	options.Timeout = 5 * time.Second
+	return NewPageChecker(options)
 }
 
 func TestCheckLinks_WithMocks(t *testing.T) {
@@ -144,7 +128,7 @@ func TestCheckLinks_WithMocks(t *testing.T) {
 	pageURL := mockServer.URL() + "/"
 	
 	// Test CheckLinks function
-	links := CheckLinks(baseURL, pageURL)
+	links := newTestPageChecker(1).CheckLinks(baseURL, pageURL)
 	
 	// Verify results
 	require.NotEmpty(t, links, "Should find links on the page")
@@ -205,7 +189,7 @@ func TestCheckLinks_EmptyPage(t *testing.T) {
 	baseURL := mockServer.URL()
 	pageURL := mockServer.URL() + "/empty"
 	
-	links := CheckLinks(baseURL, pageURL)
+	links := newTestPageChecker(1).CheckLinks(baseURL, pageURL)
 	
 	assert.Empty(t, links, "Should find no links on empty page")
 }
@@ -223,7 +207,7 @@ func TestCheckLinks_InvalidPage(t *testing.T) {
 	pageURL := mockServer.URL() + "/nonexistent"
 	
 	// This should return empty links because the page returns 404
-	links := CheckLinks(baseURL, pageURL)
+	links := newTestPageChecker(1).CheckLinks(baseURL, pageURL)
 	
 	assert.Empty(t, links, "Should return empty slice for 404 page")
 }
@@ -273,15 +257,15 @@ func TestCrawl_WithMocks(t *testing.T) {
 	baseURL := mockServer.URL()
 	
 	// Set depth to 2 to test recursive crawling
-	model.Depth = 2
+	checker := newTestPageChecker(2)
 	
 	// Start crawling
-	Crawl(baseURL, baseURL+"/", 0, model.Concurrency)
+	checker.Crawl(baseURL, baseURL+"/", 0)
 	
 	// Wait for all goroutines to complete with timeout
 	done := make(chan struct{})
 	go func() {
-		model.Wg.Wait()
+		checker.Wait()
 		close(done)
 	}()
 	
@@ -293,23 +277,17 @@ func TestCrawl_WithMocks(t *testing.T) {
 	}
 	
 	// Verify that URLs were visited
-	visited := []string{}
-	model.VisitedURLs.Range(func(key, value interface{}) bool {
-		if url, ok := key.(string); ok {
-			visited = append(visited, url)
-		}
-		return true
-	})
+	visited := checker.VisitedURLs()
 	
 	assert.NotEmpty(t, visited, "Should have visited some URLs")
 	assert.Contains(t, visited, baseURL+"/", "Should have visited root URL")
 	
 	// Verify that results were collected
-	assert.NotEmpty(t, model.Results, "Should have collected some results")
+	assert.NotEmpty(t, checker.Results(), "Should have collected some results")
 	
 	// Print results for debugging (optional)
 	t.Logf("Visited %d URLs: %v", len(visited), visited)
-	t.Logf("Found %d links", len(model.Results))
+	t.Logf("Found %d links", len(checker.Results()))
 }
 
 func TestCrawl_RespectDepthLimit(t *testing.T) {
@@ -347,15 +325,15 @@ func TestCrawl_RespectDepthLimit(t *testing.T) {
 	baseURL := mockServer.URL()
 	
 	// Set depth limit to 1
-	model.Depth = 1
+	checker := newTestPageChecker(1)
 	
 	// Start crawling
-	Crawl(baseURL, baseURL+"/", 0, model.Concurrency)
+	checker.Crawl(baseURL, baseURL+"/", 0)
 	
 	// Wait for completion with timeout
 	done := make(chan struct{})
 	go func() {
-		model.Wg.Wait()
+		checker.Wait()
 		close(done)
 	}()
 	
@@ -367,13 +345,7 @@ func TestCrawl_RespectDepthLimit(t *testing.T) {
 	}
 	
 	// Verify depth limit was respected
-	visited := []string{}
-	model.VisitedURLs.Range(func(key, value interface{}) bool {
-		if url, ok := key.(string); ok {
-			visited = append(visited, url)
-		}
-		return true
-	})
+	visited := checker.VisitedURLs()
 	
 	// Should only visit root and level1, not deeper levels
 	assert.Contains(t, visited, baseURL+"/", "Should visit root")
@@ -413,15 +385,15 @@ func TestCrawl_DuplicateURLHandling(t *testing.T) {
 	})
 	
 	baseURL := mockServer.URL()
-	model.Depth = 2
+	checker := newTestPageChecker(2)
 	
 	// Start crawling
-	Crawl(baseURL, baseURL+"/", 0, model.Concurrency)
+	checker.Crawl(baseURL, baseURL+"/", 0)
 	
 	// Wait for completion
 	done := make(chan struct{})
 	go func() {
-		model.Wg.Wait()
+		checker.Wait()
 		close(done)
 	}()
 	
@@ -434,12 +406,9 @@ func TestCrawl_DuplicateURLHandling(t *testing.T) {
 	
 	// Count how many times each URL was visited
 	visitedCount := make(map[string]int)
-	model.VisitedURLs.Range(func(key, value interface{}) bool {
-		if url, ok := key.(string); ok {
-			visitedCount[url]++
-		}
-		return true
-	})
+	for _, url := range checker.VisitedURLs() {
+		visitedCount[url]++
+	}
 	
 	// Each URL should be visited only once
 	for url, count := range visitedCount {
@@ -44,8 +44,8 @@ func ShouldSkipURL(baseURL, linkURL *url.URL) bool {
 // CheckLink checks if a link is broken.
 // example: CheckLink("https://example.com") -> 200, ""
 // example: CheckLink("https://example.com/404") -> 404, ""
-func CheckLink(linkURL string) (int, string) {
-	resp, err := FetchWithRetry(linkURL, 3)
+func (pc *PageChecker) CheckLink(linkURL string) (int, string) {
+	resp, err := pc.FetchWithRetry(linkURL, 3)
 	if err != nil {
 		return 0, err.Error()
 	}
@@ -80,18 +80,19 @@ func CheckLink(linkURL string) (int, string) {
 	return resp.StatusCode, ""
 }
 
-func FetchWithRetry(url string, retry int) (*http.Response, error) {
+// FetchWithRetry fetches a URL, trying up to retry times when the request fails
+func (pc *PageChecker) FetchWithRetry(url string, retry int) (*http.Response, error) {
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("User-Agent", model.UserAgent)
+	req.Header.Set("User-Agent", pc.options.UserAgent)
 
 	var resp *http.Response
 	var errRequest error
 	for i := 1; i <= retry; i++ {
-		resp, errRequest = ClientHTTP.Do(req)
+		resp, errRequest = pc.client.Do(req)
 		if errRequest == nil {
 			return resp, nil
 		}
@@ -101,10 +102,10 @@ func FetchWithRetry(url string, retry int) (*http.Response, error) {
 	return nil, errRequest
 }
 
-// CountBrokenLinks counts the number of broken links.
-func CountBrokenLinks() int {
+// CountBrokenLinks counts the results with an error or an error status
+func CountBrokenLinks(results []model.LinkResult) int {
 	count := 0
-	for _, result := range model.Results {
+	for _, result := range results {
 		if result.Status >= 400 || result.Error != "" {
 			count++
 		}
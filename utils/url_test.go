@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/DrakkarStorm/deadlinkr/model"
+	"github.com/DrakkarStorm/deadlinkr/pkg/deadlinkr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -167,9 +168,10 @@ func TestCheckLink(t *testing.T) {
 		},
 	}
 
+	checker := NewPageChecker(deadlinkr.DefaultOptions())
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			status, errMsg := CheckLink(tc.url)
+			status, errMsg := checker.CheckLink(tc.url)
 
 			if tc.expectedError != "" {
 				assert.NotEmpty(t, errMsg)
@@ -234,8 +236,7 @@ func TestCountBrokenLinks(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			model.Results = tc.results
-			count := CountBrokenLinks()
+			count := CountBrokenLinks(tc.results)
 			assert.Equal(t, tc.expected, count)
 		})
 	}